@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Pane states recorded in activity samples and shown by status.
+const (
+	stateActive  = "active"
+	stateIdle    = "idle"
+	stateWaiting = "waiting"
+	// stateBusyCPU is a pane with no new output whose processes are still
+	// using CPU, e.g. an agent running a long build or test suite.
+	stateBusyCPU = "busy(cpu)"
+)
+
+// Activity record kinds.
+const (
+	activitySample  = "sample"
+	activityRestart = "restart"
+	activityTask    = "task"
+	// activityPaneClosed is written by watch when an agent pane disappears.
+	activityPaneClosed = "pane_closed"
+)
+
+// activityRecord is one line of the persisted activity log. Samples are
+// written by watch on every scan and cover Span seconds of pane time;
+// restart records are written by restart, and task records (with the
+// final task status as State) when a task finishes.
+type activityRecord struct {
+	Time  time.Time `json:"time"`
+	Kind  string    `json:"kind"`
+	Pane  string    `json:"pane"`
+	Agent string    `json:"agent,omitempty"`
+	Repo  string    `json:"repo,omitempty"`
+	State string    `json:"state,omitempty"`
+	Span  float64   `json:"span,omitempty"`
+}
+
+// activityFilePath returns the path to the activity log.
+func activityFilePath() string {
+	return filepath.Join(configDir(), "activity.jsonl")
+}
+
+// appendActivity appends records to the activity log.
+func appendActivity(records ...activityRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	f, err := openLog(activityFilePath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadActivity reads activity records at or after since. A missing log
+// yields no records; malformed lines are skipped. The log is rotated (see
+// openLog), so only the most recent history is kept.
+func loadActivity(since time.Time) ([]activityRecord, error) {
+	var records []activityRecord
+	err := readLog(activityFilePath(), func(line []byte) {
+		var r activityRecord
+		if err := json.Unmarshal(line, &r); err == nil && !r.Time.Before(since) {
+			records = append(records, r)
+		}
+	})
+	return records, err
+}
+
+// recordRestart logs a restart of the given pane to the activity log.
+func recordRestart(paneID, agent string) {
+	appendActivity(activityRecord{
+		Time:  time.Now(),
+		Kind:  activityRestart,
+		Pane:  paneID,
+		Agent: agent,
+		Repo:  shortDir(paneCurrentPath(paneID)),
+	})
+}
+
+// formatDuration renders d compactly, e.g. "2h05m", "12m30s", "45s".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
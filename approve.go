@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// approvalKeys are the tmux keys that answer an agent's permission prompt:
+// allow once, allow from now on, or refuse.
+type approvalKeys struct {
+	Approve []string `json:"approve,omitempty"`
+	Always  []string `json:"always,omitempty"`
+	Deny    []string `json:"deny,omitempty"`
+}
+
+// defaultApprovalKeys answer the permission prompts of claude, a menu with
+// "Yes" selected, and codex, which takes single-letter shortcuts.
+var defaultApprovalKeys = map[string]approvalKeys{
+	"claude": {Approve: []string{"Enter"}, Always: []string{"Down", "Enter"}, Deny: []string{"Escape"}},
+	"codex":  {Approve: []string{"y"}, Always: []string{"a"}, Deny: []string{"Escape"}},
+}
+
+// yesNoKeys answer a plain "(y/n)" prompt, whatever the agent.
+var yesNoKeys = approvalKeys{Approve: []string{"y", "Enter"}, Deny: []string{"n", "Enter"}}
+
+// yesNoRe matches a "(y/n)" or "[y/N]" style prompt.
+var yesNoRe = regexp.MustCompile(`(?i)[(\[]y/n[)\]]`)
+
+// approveJSON is the result of approve and deny.
+type approveJSON struct {
+	Pane   string   `json:"pane"`
+	Action string   `json:"action"`
+	Prompt string   `json:"prompt,omitempty"`
+	Keys   []string `json:"keys"`
+}
+
+// approvalKeys returns the keys that answer agent's permission prompt,
+// whose matching line is prompt: a "(y/n)" prompt is answered with y or n,
+// other prompts with the agent's approval_keys, falling back to the
+// built-in keys per field.
+func (c *agentConfig) approvalKeys(agent, prompt string) approvalKeys {
+	if yesNoRe.MatchString(prompt) {
+		return yesNoKeys
+	}
+	keys := defaultApprovalKeys[agent]
+	if custom := c.agent(agent).ApprovalKeys; custom != nil {
+		if len(custom.Approve) > 0 {
+			keys.Approve = custom.Approve
+		}
+		if len(custom.Always) > 0 {
+			keys.Always = custom.Always
+		}
+		if len(custom.Deny) > 0 {
+			keys.Deny = custom.Deny
+		}
+	}
+	return keys
+}
+
+// runApprove answers a pane's permission prompt with yes.
+func runApprove(args []string, w io.Writer) error {
+	return answerPrompt("approve", args, w)
+}
+
+// runDeny answers a pane's permission prompt with no.
+func runDeny(args []string, w io.Writer) error {
+	return answerPrompt("deny", args, w)
+}
+
+// answerPrompt sends pane args[0] the keys that approve or deny its
+// permission prompt. Unless --force is given, the pane must be showing one,
+// so a stray approve never submits whatever is in the input box. Text after
+// the pane ID of deny is sent once the prompt is gone, to tell the agent
+// what to do instead.
+func answerPrompt(action string, args []string, w io.Writer) error {
+	usage := fmt.Errorf("usage: tmux-agent %s <pane_id> [--always] [--force]", action)
+	if action == "deny" {
+		usage = fmt.Errorf("usage: tmux-agent deny <pane_id> [--force] [text...]")
+	}
+	if len(args) == 0 || !strings.HasPrefix(args[0], "%") {
+		return usage
+	}
+	paneID := args[0]
+	var always, force bool
+	var words []string
+	for _, a := range args[1:] {
+		switch {
+		case a == "--always" && action == "approve":
+			always = true
+		case a == "--force":
+			force = true
+		case action == "deny":
+			words = append(words, a)
+		default:
+			return usage
+		}
+	}
+
+	p, ok := lookupPane(paneID)
+	if !ok {
+		return fmt.Errorf("pane %s not found", paneID)
+	}
+	output, err := capturePaneOutput(paneID, noticeCaptureLines)
+	if err != nil {
+		return err
+	}
+	cfg := loadConfig()
+	state, prompt, err := newNoticeDetector(cfg).detect(&p, output)
+	if err != nil {
+		return err
+	}
+	// A plain yes/no question is answered the same way.
+	answerable := state == stateNeedsApproval || state == stateWaiting && yesNoRe.MatchString(prompt)
+	if !answerable && !force {
+		return fmt.Errorf("pane %s is not showing a permission prompt (use --force to answer anyway)", paneID)
+	}
+
+	answers := cfg.approvalKeys(p.Command, prompt)
+	keys := answers.Approve
+	switch {
+	case action == "deny":
+		keys = answers.Deny
+	case always:
+		keys = answers.Always
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no %s keys for %s prompts; set agents.%s.approval_keys", action, p.Command, p.Command)
+	}
+	if err := sendRawTmuxKeys(paneID, keys...); err != nil {
+		return err
+	}
+	if text := strings.Join(words, " "); text != "" {
+		if err := sendTmuxText(paneID, text, enterDefault, false); err != nil {
+			return err
+		}
+	}
+	touchPane(paneID)
+
+	if jsonOutput {
+		return writeJSON(w, approveJSON{Pane: paneID, Action: action, Prompt: prompt, Keys: keys})
+	}
+	verb := "Approved"
+	if action == "deny" {
+		verb = "Denied"
+	}
+	if prompt == "" {
+		fmt.Fprintf(w, "%s pane %s\n", verb, paneID)
+	} else {
+		fmt.Fprintf(w, "%s pane %s: %s\n", verb, paneID, prompt)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+const claudePermission = " Bash command\n   npm test\n Do you want to proceed?\n ❯ 1. Yes\n   2. Yes, and don't ask again\n   3. No (esc)\n"
+
+func TestRunApprove(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Output: claudePermission},
+		&runner.FakePane{ID: "%2", Command: "codex", Output: "Would you like to run the following command?\n  $ make\n› 1. Yes, proceed"},
+		&runner.FakePane{ID: "%3", Command: "claude", Output: "Overwrite go.sum? (y/n)"},
+		&runner.FakePane{ID: "%4", Command: "claude", Output: "editing main.go"},
+	)
+	t.Setenv("HOME", t.TempDir())
+
+	var buf bytes.Buffer
+	if err := runApprove([]string{"%1"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Approved pane %1: Do you want to proceed?") {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+	if err := runApprove([]string{"%2", "--always"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := runDeny([]string{"%3"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"%1": {"Enter"}, "%2": {"a"}, "%3": {"n Enter"}}
+	for id, keys := range want {
+		if got := fake.Pane(id).Input; !slices.Equal(got, keys) {
+			t.Errorf("pane %s: sent %q, want %q", id, got, keys)
+		}
+	}
+
+	if err := runApprove([]string{"%4"}, &buf); err == nil || !strings.Contains(err.Error(), "not showing a permission prompt") {
+		t.Errorf("expected an error for a pane without a prompt, got %v", err)
+	}
+	if len(fake.Pane("%4").Input) != 0 {
+		t.Errorf("expected nothing sent to %%4, got %q", fake.Pane("%4").Input)
+	}
+	if err := runApprove([]string{"%4", "--force"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Pane("%4").Input; !slices.Equal(got, []string{"Enter"}) {
+		t.Errorf("--force: sent %q", got)
+	}
+}
+
+func TestRunDeny_WithText(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: claudePermission})
+	t.Setenv("HOME", t.TempDir())
+	saveConfig(&agentConfig{Agents: map[string]*agentProfile{
+		"claude": {ApprovalKeys: &approvalKeys{Deny: []string{"3", "Enter"}}},
+	}})
+
+	var buf bytes.Buffer
+	if err := runDeny([]string{"%1", "use", "pnpm"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	in := fake.Pane("%1").Input
+	if len(in) < 2 || in[0] != "3 Enter" || in[1] != "use pnpm" {
+		t.Errorf("expected the configured keys, then the text, got %q", in)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// logsDir returns the directory where logs saves pane output by default,
+// and where panes are archived before they are killed.
+func logsDir() string {
+	return filepath.Join(configDir(), "logs")
+}
+
+// logFilePath returns the default log file of a pane saved at t, e.g.
+// logs/3-20250102-150405.log.
+func logFilePath(paneID string, t time.Time) string {
+	return filepath.Join(logsDir(), fmt.Sprintf("%s-%s.log",
+		strings.TrimPrefix(paneID, "%"), t.Format("20060102-150405")))
+}
+
+// writeLogFile saves output as a new log file of a pane and returns its
+// path. A pane saved more than once in the same second gets "-2", "-3", ...
+// appended rather than overwriting the earlier file.
+func writeLogFile(paneID, output string) (string, error) {
+	if err := os.MkdirAll(logsDir(), 0755); err != nil {
+		return "", err
+	}
+	f, err := createNewFile(logFilePath(paneID, time.Now()))
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(output + "\n"); err != nil {
+		f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// createNewFile creates path for writing, failing if it exists. A file that
+// is already there, such as one saved in the same second under a
+// timestamped name, gets "-2", "-3", ... inserted before the extension.
+func createNewFile(path string) (*os.File, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 1; ; n++ {
+		file := path
+		if n > 1 {
+			file = fmt.Sprintf("%s-%d%s", base, n, ext)
+		}
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !errors.Is(err, os.ErrExist) {
+			return f, err
+		}
+	}
+}
+
+// archiveOnKill reports whether panes are archived before they are killed.
+// It is on unless archive_on_kill is false.
+func (c *agentConfig) archiveOnKill() bool {
+	return c.ArchiveOnKill == nil || *c.ArchiveOnKill
+}
+
+// archivePane writes the whole scrollback of a pane to the logs directory,
+// as logs does, and returns the file, or "" when the pane shows nothing.
+func archivePane(paneID string) (string, error) {
+	output, err := captureScrollback(paneID)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(output) == "" {
+		return "", nil
+	}
+	file, err := writeLogFile(paneID, output)
+	if err != nil {
+		return "", fmt.Errorf("archiving pane %s: %w", paneID, err)
+	}
+	return file, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRunKill_ArchivesOutput(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Output: "refactored the parser\nall tests pass"},
+		&runner.FakePane{ID: "%2", Command: "claude", Output: "done"},
+	)
+	t.Setenv("HOME", t.TempDir())
+
+	var buf bytes.Buffer
+	if err := runKill([]string{"%1", "--force"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if fake.Pane("%1") != nil {
+		t.Fatal("expected the pane to be killed")
+	}
+	files, _ := filepath.Glob(filepath.Join(logsDir(), "1-*.log"))
+	if len(files) != 1 {
+		t.Fatalf("expected one archive, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "all tests pass") {
+		t.Errorf("expected the pane output in the archive, got %q", data)
+	}
+	if !strings.Contains(buf.String(), files[0]) {
+		t.Errorf("expected the archive to be reported, got %q", buf.String())
+	}
+
+	off := false
+	saveConfig(&agentConfig{ArchiveOnKill: &off})
+	if err := runKill([]string{"%2", "--force"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(logsDir(), "2-*.log")); len(files) != 0 {
+		t.Errorf("expected archive_on_kill false not to archive, got %v", files)
+	}
+}
+
+func TestLogFilePath(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	at := time.Date(2025, 1, 2, 15, 4, 5, 0, time.Local)
+	if got, want := logFilePath("%3", at), "/home/me/.config/tmux-agent/logs/3-20250102-150405.log"; got != want {
+		t.Errorf("logFilePath = %s, want %s", got, want)
+	}
+}
+
+func TestWriteLogFile_KeepsEarlierSaves(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	first, err := writeLogFile("%3", "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := writeLogFile("%3", "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("expected a new file for the second save, got %s twice", first)
+	}
+	if data, _ := os.ReadFile(first); string(data) != "first\n" {
+		t.Errorf("expected the first save to survive, got %q", data)
+	}
+}
+
+func TestRunKill_ArchiveFailureStillKills(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "done"})
+	t.Setenv("HOME", t.TempDir())
+	os.MkdirAll(configDir(), 0755)
+	os.WriteFile(logsDir(), nil, 0644) // a file where the logs directory goes
+
+	if err := runKill([]string{"%1", "--force"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if fake.Pane("%1") != nil {
+		t.Error("expected the pane to be killed even though archiving failed")
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// askUsage is the usage of ask.
+const askUsage = "usage: tmux-agent ask [--model name] [--dir path] [--idle 1m] [--timeout duration] [--] <question...>"
+
+// askExit exits tmux-agent once ask has cleaned up after a signal; replaced
+// in tests.
+var askExit = os.Exit
+
+// askOptions are ask's flags.
+type askOptions struct {
+	model, dir    string
+	idle, timeout time.Duration
+}
+
+// parseAskArgs parses ask's flags and returns the index in args where the
+// question starts. As with run, flags come before the question and "--"
+// ends them, so the question may mention them.
+func parseAskArgs(args []string) (opts askOptions, text int, err error) {
+	opts.idle = defaultRunIdle
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			return opts, i + 1, nil
+		}
+		if n := globalFlagWidth(args[i]); n > 0 {
+			i += n - 1
+			continue
+		}
+		if i+1 == len(args) {
+			return opts, i, nil
+		}
+		switch args[i] {
+		case "--model":
+			i++
+			opts.model = args[i]
+		case "--dir":
+			i++
+			opts.dir = expandHome(args[i])
+		case "--idle":
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d <= 0 {
+				return opts, 0, fmt.Errorf("invalid --idle value: %s", args[i])
+			}
+			opts.idle = d
+		case "--timeout":
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d <= 0 {
+				return opts, 0, fmt.Errorf("invalid --timeout value: %s", args[i])
+			}
+			opts.timeout = d
+		default:
+			return opts, i, nil
+		}
+	}
+	return opts, len(args), nil
+}
+
+// runAsk answers a one-off question with a throwaway agent pane: it opens
+// the active agent in a detached window, sends the question, waits until
+// the agent is done as `run` does, prints the answer and kills the pane.
+// The pane is also killed when ask is interrupted.
+func runAsk(args []string, w io.Writer) error {
+	opts, start, err := parseAskArgs(args)
+	if err != nil {
+		return err
+	}
+	question := strings.TrimSpace(strings.Join(args[start:], " "))
+	if question == "" {
+		return fmt.Errorf(askUsage)
+	}
+
+	command, err := loadConfig().launchCommand(activeAgent, opts.model)
+	if err != nil {
+		return err
+	}
+	paneID, err := createTmuxPaneWithOpts(createPaneOpts{Command: command, Dir: opts.dir, NewWindow: true, Detached: true})
+	if err != nil {
+		return err
+	}
+	defer killTmuxPane(paneID)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case sig := <-sigCh:
+			killTmuxPane(paneID)
+			askExit(128 + int(sig.(syscall.Signal)))
+		case <-done:
+		}
+	}()
+	renameTmuxPane(paneID, "ask")
+	waitReady(paneID, commandAgent(command))
+
+	before, err := captureScrollback(paneID)
+	if err != nil {
+		return err
+	}
+	if err := sendTmuxText(paneID, question, enterDefault, false); err != nil {
+		return err
+	}
+	touchPane(paneID)
+	reason, answer, err := awaitReply(paneID, question, before, opts.idle, opts.timeout, nil)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return writeJSON(w, runJSON{Pane: paneID, Text: question, Reason: reason, Output: answer})
+	}
+	if answer != "" {
+		fmt.Fprintln(w, answer)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRunAsk(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "zsh"})
+	t.Setenv("HOME", t.TempDir())
+	origDelay, origPoll := readyTimeout, waitPollInterval
+	readyTimeout, waitPollInterval = 0, 10*time.Millisecond
+	t.Cleanup(func() { readyTimeout, waitPollInterval = origDelay, origPoll })
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		fake.Print("%2", "> what is 2+2?\n4\n")
+	}()
+	var buf bytes.Buffer
+	if err := runAsk([]string{"--idle", "100ms", "--timeout", "5s", "what", "is", "2+2?"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "> what is 2+2?\n4\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if fake.Pane("%2") != nil {
+		t.Error("expected the temporary pane to be killed")
+	}
+	for _, call := range fake.Calls {
+		if call[0] == "new-window" && !slices.Contains(call, "-d") {
+			t.Errorf("expected a detached window, got %q", call)
+		}
+		if call[0] == "send-keys" && slices.Contains(call, "what is 2+2?") && !slices.Contains(call, "%2") {
+			t.Errorf("expected the question to go to the new pane, got %q", call)
+		}
+	}
+
+	if err := runAsk(nil, &buf); err == nil {
+		t.Error("expected a usage error without a question")
+	}
+}
+
+func TestParseAskArgs(t *testing.T) {
+	args := []string{"--model", "o3", "what", "does", "--dir", "do"}
+	opts, text, err := parseAskArgs(args)
+	if err != nil || opts.model != "o3" || opts.dir != "" || text != 2 {
+		t.Errorf("got %+v, text at %d (%v)", opts, text, err)
+	}
+	if _, text, _ := parseAskArgs([]string{"--", "--timeout", "?"}); text != 1 {
+		t.Errorf("expected the question to start after --, got %d", text)
+	}
+}
+
+func TestRunAsk_Interrupted(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "zsh"})
+	t.Setenv("HOME", t.TempDir())
+	origDelay, origPoll, origExit := readyTimeout, waitPollInterval, askExit
+	readyTimeout, waitPollInterval = 0, 10*time.Millisecond
+	exited := make(chan int, 1)
+	askExit = func(code int) { exited <- code }
+	t.Cleanup(func() { readyTimeout, waitPollInterval, askExit = origDelay, origPoll, origExit })
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		syscall.Kill(os.Getpid(), syscall.SIGINT)
+	}()
+	runAsk([]string{"--idle", "1h", "--timeout", "500ms", "hello"}, &bytes.Buffer{})
+	select {
+	case code := <-exited:
+		if code != 130 {
+			t.Errorf("exit code = %d, want 130", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected ask to exit on SIGINT")
+	}
+	if fake.Pane("%2") != nil {
+		t.Error("expected the temporary pane to be killed")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Auto-resume actions.
+const (
+	// resumeContinue sends the resume prompt to the waiting agent.
+	resumeContinue = "continue"
+	// resumeRestart restarts the agent with its resume command.
+	resumeRestart = "restart"
+)
+
+const (
+	defaultResumePrompt = "continue"
+	defaultResumeDelay  = time.Minute
+)
+
+// autoResumeConfig controls how `watch --auto-resume` recovers panes from
+// rate limits.
+type autoResumeConfig struct {
+	// Action is "continue" (default) or "restart".
+	Action string `json:"action,omitempty"`
+	// Prompt is sent by the continue action. Default: "continue".
+	Prompt string `json:"prompt,omitempty"`
+	// Delay is how long after the reset time to act, e.g. "2m". Default: 1m.
+	Delay string `json:"delay,omitempty"`
+	// Cooldown is how long to back off when the message gives no reset
+	// time, e.g. "30m". Unset, such panes are left alone.
+	Cooldown string `json:"cooldown,omitempty"`
+}
+
+// autoResume is a validated autoResumeConfig.
+type autoResume struct {
+	action   string
+	prompt   string
+	delay    time.Duration
+	cooldown time.Duration
+}
+
+// autoResume validates the auto_resume settings and fills in defaults.
+func (c *agentConfig) autoResume() (*autoResume, error) {
+	r := &autoResume{action: c.AutoResume.Action, prompt: c.AutoResume.Prompt, delay: defaultResumeDelay}
+	switch r.action {
+	case "":
+		r.action = resumeContinue
+	case resumeContinue, resumeRestart:
+	default:
+		return nil, fmt.Errorf("invalid auto_resume.action: %s (want %s or %s)", r.action, resumeContinue, resumeRestart)
+	}
+	if r.prompt == "" {
+		r.prompt = defaultResumePrompt
+	}
+	if c.AutoResume.Delay != "" {
+		d, err := time.ParseDuration(c.AutoResume.Delay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auto_resume.delay: %s", c.AutoResume.Delay)
+		}
+		r.delay = d
+	}
+	if c.AutoResume.Cooldown != "" {
+		d, err := time.ParseDuration(c.AutoResume.Cooldown)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid auto_resume.cooldown: %s", c.AutoResume.Cooldown)
+		}
+		r.cooldown = d
+	}
+	return r, nil
+}
+
+var (
+	// resetClockRe matches "reset at 3pm", "resets 3:30 PM", "resets at 15:00".
+	resetClockRe = regexp.MustCompile(`(?i)resets?\s+(?:at\s+)?(\d{1,2})(?::(\d{2}))?\s*([ap]\.?m\.?)?`)
+	// resetZoneRe matches a parenthesized IANA zone such as "(Europe/Berlin)".
+	resetZoneRe = regexp.MustCompile(`\(([A-Za-z_]+(?:/[A-Za-z_+-]+)+|UTC)\)`)
+	// resetInRe matches "try again in 2 hours 13 minutes" or "resets in 4h 5m".
+	resetInRe = regexp.MustCompile(`(?i)(?:again|resets?)\s+in\s+((?:\d+\s*(?:hours?|hrs?|h|minutes?|mins?|m|seconds?|secs?|s)\b[\s,]*(?:and\s+)?)+)`)
+	// resetPartRe matches one "2 hours" part of a resetInRe duration.
+	resetPartRe = regexp.MustCompile(`(?i)(\d+)\s*(h|m|s)`)
+)
+
+// parseResetTime finds when a rate limit lifts in an agent's message,
+// either as a clock time ("resets 3pm (Europe/Berlin)") or a countdown
+// ("try again in 2 hours 13 minutes"). Clock times are taken as the next
+// such time after now.
+func parseResetTime(msg string, now time.Time) (time.Time, bool) {
+	if m := resetInRe.FindStringSubmatch(msg); m != nil {
+		var d time.Duration
+		for _, part := range resetPartRe.FindAllStringSubmatch(m[1], -1) {
+			n, _ := strconv.Atoi(part[1])
+			switch strings.ToLower(part[2]) {
+			case "h":
+				d += time.Duration(n) * time.Hour
+			case "m":
+				d += time.Duration(n) * time.Minute
+			case "s":
+				d += time.Duration(n) * time.Second
+			}
+		}
+		if d > 0 {
+			return now.Add(d), true
+		}
+	}
+
+	m := resetClockRe.FindStringSubmatch(msg)
+	if m == nil {
+		return time.Time{}, false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	switch ampm := strings.ToLower(strings.ReplaceAll(m[3], ".", "")); {
+	case ampm == "pm" && hour < 12:
+		hour += 12
+	case ampm == "am" && hour == 12:
+		hour = 0
+	case ampm == "" && m[2] == "":
+		// A bare number ("resets 3") is too ambiguous to act on.
+		return time.Time{}, false
+	}
+	if hour > 23 || minute > 59 {
+		return time.Time{}, false
+	}
+	loc := now.Location()
+	if z := resetZoneRe.FindStringSubmatch(msg); z != nil {
+		if l, err := time.LoadLocation(z[1]); err == nil {
+			loc = l
+		}
+	}
+	local := now.In(loc)
+	reset := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if !reset.After(now) {
+		reset = reset.AddDate(0, 0, 1)
+	}
+	return reset, true
+}
+
+// scheduleResume plans an automatic resume for a rate-limited pane: after
+// the reset time in its message, or else after the cooldown, if set.
+func (wt *watcher) scheduleResume(p paneInfo, msg string) {
+	reset, ok := parseResetTime(msg, time.Now())
+	if !ok && wt.autoResume.cooldown == 0 {
+		wt.logger.Warn("no reset time in rate-limit message; not resuming", append(paneAttrs(&p), "message", msg)...)
+		return
+	}
+	at := reset.Add(wt.autoResume.delay)
+	if !ok {
+		at = time.Now().Add(wt.autoResume.cooldown)
+	}
+	wt.resumes[p.ID] = at
+	wt.logger.Info("resume scheduled", append(paneAttrs(&p), "at", at.Format(time.RFC3339), "action", wt.autoResume.action)...)
+}
+
+// runResumes resumes panes whose scheduled time has come and that are
+// still rate-limited.
+func (wt *watcher) runResumes(panes []paneInfo) {
+	for i := range panes {
+		p := panes[i]
+		at, ok := wt.resumes[p.ID]
+		if !ok || time.Now().Before(at) {
+			continue
+		}
+		delete(wt.resumes, p.ID)
+		if wt.notices[p.ID].state != stateRateLimited {
+			continue
+		}
+		var err error
+		switch wt.autoResume.action {
+		case resumeRestart:
+			err = restartPane(p.ID, loadConfig().resumeCommand(p.Command))
+		default:
+			err = sendTmuxKeys(p.ID, wt.autoResume.prompt)
+		}
+		if err != nil {
+			wt.logger.Warn("auto-resume failed", append(paneAttrs(&p), "err", err)...)
+			continue
+		}
+		wt.logger.Info("pane auto-resumed", append(paneAttrs(&p), "action", wt.autoResume.action)...)
+	}
+}
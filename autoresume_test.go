@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestParseResetTime(t *testing.T) {
+	now := time.Date(2025, 3, 10, 14, 20, 0, 0, time.UTC)
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	tests := []struct {
+		msg  string
+		want time.Time
+		ok   bool
+	}{
+		{"Claude usage limit reached. Your limit will reset at 3pm.", time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC), true},
+		{"5-hour limit reached ∙ resets 1:30am", time.Date(2025, 3, 11, 1, 30, 0, 0, time.UTC), true},
+		{"Limit reached, resets 2pm (Europe/Berlin)", time.Date(2025, 3, 11, 14, 0, 0, 0, berlin), true},
+		{"resets at 14:45", time.Date(2025, 3, 10, 14, 45, 0, 0, time.UTC), true},
+		{"You've hit your usage limit. Try again in 2 hours 13 minutes.", now.Add(2*time.Hour + 13*time.Minute), true},
+		{"rate limited, try again in 45s", now.Add(45 * time.Second), true},
+		{"Usage limit resets in 4h 5m", now.Add(4*time.Hour + 5*time.Minute), true},
+		{"Claude usage limit reached.", time.Time{}, false},
+		{"limit resets 3", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseResetTime(tt.msg, now)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseResetTime(%q) = %v, %v; want %v, %v", tt.msg, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAutoResumeConfig(t *testing.T) {
+	r, err := (&agentConfig{}).autoResume()
+	if err != nil || r.action != resumeContinue || r.prompt != "continue" || r.delay != time.Minute {
+		t.Errorf("defaults: %+v, %v", r, err)
+	}
+	if _, err := (&agentConfig{AutoResume: autoResumeConfig{Action: "wait"}}).autoResume(); err == nil {
+		t.Error("expected error for unknown action")
+	}
+	if _, err := (&agentConfig{AutoResume: autoResumeConfig{Delay: "later"}}).autoResume(); err == nil {
+		t.Error("expected error for invalid delay")
+	}
+	if r, err := (&agentConfig{AutoResume: autoResumeConfig{Cooldown: "30m"}}).autoResume(); err != nil || r.cooldown != 30*time.Minute {
+		t.Errorf("cooldown: %+v, %v", r, err)
+	}
+	if _, err := (&agentConfig{AutoResume: autoResumeConfig{Cooldown: "0s"}}).autoResume(); err == nil {
+		t.Error("expected error for a zero cooldown")
+	}
+}
+
+func TestWatcherScan_AutoResumeCooldown(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "API Error: 429 Too Many Requests"})
+
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(io.Discard, "test"))
+	wt.autoResume = &autoResume{action: resumeContinue, prompt: "continue", delay: time.Minute, cooldown: 20 * time.Minute}
+	wt.scan()
+
+	at, ok := wt.resumes["%1"]
+	if left := time.Until(at); !ok || left < 19*time.Minute || left > 20*time.Minute {
+		t.Errorf("expected a resume after the cooldown, got %v, %v", at, ok)
+	}
+}
+
+func TestWatcherScan_AutoResume(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "codex", Output: "You've hit your usage limit. Try again in 1 minute."},
+		&runner.FakePane{ID: "%2", Command: "claude", Output: "Claude usage limit reached."},
+	)
+
+	var logs bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(&logs, "test"))
+	wt.autoResume = &autoResume{action: resumeContinue, prompt: "please continue", delay: 10 * time.Minute}
+	wt.scan()
+
+	at, ok := wt.resumes["%1"]
+	if !ok || at.Sub(time.Now()) < 10*time.Minute {
+		t.Fatalf("expected resume scheduled after reset + delay, got %v, %v", at, ok)
+	}
+	if _, ok := wt.resumes["%2"]; ok {
+		t.Error("expected no resume without a reset time")
+	}
+	if !strings.Contains(logs.String(), "no reset time") {
+		t.Errorf("expected warning for missing reset time, got: %s", logs.String())
+	}
+
+	// Not due yet: nothing is sent.
+	wt.scan()
+	if len(fake.Pane("%1").Input) != 0 {
+		t.Fatalf("sent too early: %q", fake.Pane("%1").Input)
+	}
+
+	wt.resumes["%1"] = time.Now().Add(-time.Second)
+	wt.scan()
+	if got := fake.Pane("%1").Input; len(got) == 0 || got[0] != "please continue" {
+		t.Errorf("expected resume prompt, got %q", got)
+	}
+	if _, ok := wt.resumes["%1"]; ok {
+		t.Error("expected schedule to be cleared")
+	}
+}
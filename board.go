@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const boardRefreshInterval = 5 * time.Second
+
+// Board columns, in display order.
+const (
+	boardTodo = iota
+	boardInProgress
+	boardWaiting
+	boardDone
+	boardColumns
+)
+
+var boardTitles = [boardColumns]string{"TODO", "IN PROGRESS", "WAITING", "DONE"}
+
+// boardCard is one entry in a board column.
+type boardCard struct {
+	Task *task
+	Pane string
+}
+
+// board is the derived state shown by the board view.
+type board struct {
+	Columns [boardColumns][]boardCard
+	// FreePanes are idle agent panes with no task in progress.
+	FreePanes []paneInfo
+}
+
+// buildBoard places tasks into columns using live pane state. In-progress
+// tasks whose pane is idle (or gone) are shown as WAITING.
+func buildBoard(store *taskStore, panes []paneInfo, threshold time.Duration, since time.Time) board {
+	var b board
+	byID := make(map[string]*paneInfo)
+	for i := range panes {
+		byID[panes[i].ID] = &panes[i]
+	}
+
+	busyPanes := make(map[string]bool)
+	for _, t := range store.Tasks {
+		card := boardCard{Task: t, Pane: t.Pane}
+		switch t.Status {
+		case taskTodo:
+			b.Columns[boardTodo] = append(b.Columns[boardTodo], card)
+		case taskInProgress:
+			busyPanes[t.Pane] = true
+			if p, ok := byID[t.Pane]; ok && !detectIdle(p, threshold) {
+				b.Columns[boardInProgress] = append(b.Columns[boardInProgress], card)
+			} else {
+				b.Columns[boardWaiting] = append(b.Columns[boardWaiting], card)
+			}
+		case taskDone, taskFailed:
+			if t.FinishedAt.After(since) {
+				b.Columns[boardDone] = append(b.Columns[boardDone], card)
+			}
+		}
+	}
+
+	for i := range panes {
+		if !busyPanes[panes[i].ID] && detectIdle(&panes[i], threshold) {
+			b.FreePanes = append(b.FreePanes, panes[i])
+		}
+	}
+	return b
+}
+
+// renderBoard draws the board with the selected card highlighted.
+func renderBoard(b board, width, height, selCol, selRow int, message string) string {
+	colWidth := width / boardColumns
+	if colWidth < 10 {
+		colWidth = 10
+	}
+
+	var sb strings.Builder
+	sb.WriteString(ansiClear)
+	for c := 0; c < boardColumns; c++ {
+		title := fmt.Sprintf("%s (%d)", boardTitles[c], len(b.Columns[c]))
+		sb.WriteString(ansiBold + fitString(title, colWidth) + ansiReset)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(strings.Repeat("─", colWidth*boardColumns) + "\n")
+
+	rows := height - 5
+	for r := 0; r < rows; r++ {
+		for c := 0; c < boardColumns; c++ {
+			cell := ""
+			if r < len(b.Columns[c]) {
+				card := b.Columns[c][r]
+				cell = fmt.Sprintf("#%d %s", card.Task.ID, card.Task.Title)
+				if card.Pane != "" {
+					cell += " [" + card.Pane + "]"
+				}
+			}
+			cell = fitString(cell, colWidth-1) + " "
+			if c == selCol && r == selRow && r < len(b.Columns[c]) {
+				cell = ansiReverse + cell + ansiReset
+			}
+			sb.WriteString(cell)
+		}
+		sb.WriteString("\n")
+	}
+
+	var free []string
+	for _, p := range b.FreePanes {
+		free = append(free, p.ID+" ("+p.Command+")")
+	}
+	sb.WriteString(fitString("Idle panes: "+strings.Join(free, ", "), width) + "\n")
+	sb.WriteString(fitString("←→↑↓ move  a assign to idle pane  d done  f fail  r refresh  q quit  "+message, width))
+	return sb.String()
+}
+
+// runBoard shows an interactive kanban view of tasks and panes.
+func runBoard(args []string, w io.Writer) error {
+	threshold := defaultIdleThreshold
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--idle" && i+1 < len(args) {
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil {
+				return fmt.Errorf("invalid --idle value: %s", args[i])
+			}
+			threshold = d
+		}
+	}
+
+	restore, err := enterRawMode()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	keys, stop := readKeys()
+	defer stop()
+
+	tracker := newOutputTracker()
+	selCol, selRow := 0, 0
+	message := ""
+	ticker := time.NewTicker(boardRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		store, err := loadTasks()
+		if err != nil {
+			return err
+		}
+		panes, err := listTmuxPanes()
+		if err != nil {
+			message = err.Error()
+		}
+		for i := range panes {
+			if output, err := capturePaneOutput(panes[i].ID, 10); err == nil {
+				tracker.observe(&panes[i], output)
+			}
+		}
+		b := buildBoard(store, panes, threshold, time.Now().Add(-defaultReportWindow))
+		if n := len(b.Columns[selCol]); selRow >= n && n > 0 {
+			selRow = n - 1
+		}
+
+		width, height := terminalSize()
+		fmt.Fprint(w, rawLines(renderBoard(b, width, height, selCol, selRow, message)))
+		message = ""
+
+		select {
+		case <-ticker.C:
+			continue
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch k {
+			case "q", "esc", "ctrl-c":
+				fmt.Fprint(w, ansiClear)
+				return nil
+			case "left", "h":
+				if selCol > 0 {
+					selCol--
+				}
+			case "right", "l":
+				if selCol < boardColumns-1 {
+					selCol++
+				}
+			case "up", "k":
+				if selRow > 0 {
+					selRow--
+				}
+			case "down", "j":
+				selRow++
+			case "a":
+				message = boardAssign(b, selCol, selRow)
+			case "d", "f":
+				status := taskDone
+				if k == "f" {
+					status = taskFailed
+				}
+				message = boardFinish(b, selCol, selRow, status)
+			}
+		}
+	}
+}
+
+// selectedCard returns the selected card, if any.
+func selectedCard(b board, col, row int) (boardCard, bool) {
+	if row < 0 || row >= len(b.Columns[col]) {
+		return boardCard{}, false
+	}
+	return b.Columns[col][row], true
+}
+
+// boardAssign sends the selected TODO task to the first free idle pane.
+// The task is updated in the current task store, not the one the board
+// was drawn from, so edits made since are kept.
+func boardAssign(b board, col, row int) string {
+	card, ok := selectedCard(b, col, row)
+	if !ok || col != boardTodo {
+		return "select a TODO task to assign"
+	}
+	if len(b.FreePanes) == 0 {
+		return "no idle panes available"
+	}
+	p := b.FreePanes[0]
+	if err := sendTmuxKeys(p.ID, card.Task.Title); err != nil {
+		return err.Error()
+	}
+	err := updateTasks(func(store *taskStore) error {
+		t, err := store.get(card.Task.ID)
+		if err != nil {
+			return err
+		}
+		t.start(p.ID)
+		t.Agent = p.Command
+		if t.Dir == "" {
+			t.Dir = p.Dir
+		}
+		return nil
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("assigned #%d to %s", card.Task.ID, p.ID)
+}
+
+// boardFinish marks the selected in-progress or waiting task finished.
+func boardFinish(b board, col, row int, status string) string {
+	card, ok := selectedCard(b, col, row)
+	if !ok || (col != boardInProgress && col != boardWaiting) {
+		return "select an in-progress task"
+	}
+	err := updateTasks(func(store *taskStore) error {
+		t, err := store.get(card.Task.ID)
+		if err != nil {
+			return err
+		}
+		t.finish(status, "")
+		return nil
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("#%d %s", card.Task.ID, status)
+}
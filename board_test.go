@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildBoard(t *testing.T) {
+	now := time.Now()
+	store := &taskStore{Tasks: []*task{
+		{ID: 1, Title: "queued", Status: taskTodo},
+		{ID: 2, Title: "running", Status: taskInProgress, Pane: "%3"},
+		{ID: 3, Title: "stalled", Status: taskInProgress, Pane: "%5"},
+		{ID: 4, Title: "finished", Status: taskDone, FinishedAt: now.Add(-time.Hour)},
+		{ID: 5, Title: "ancient", Status: taskDone, FinishedAt: now.Add(-72 * time.Hour)},
+	}}
+	panes := []paneInfo{
+		{ID: "%3", Command: "claude", LastChangeAt: now},
+		{ID: "%5", Command: "codex", LastChangeAt: now.Add(-time.Hour)},
+		{ID: "%8", Command: "claude", LastChangeAt: now.Add(-time.Hour)},
+		{ID: "%9", Command: "claude", LastChangeAt: now},
+	}
+
+	b := buildBoard(store, panes, 10*time.Minute, now.Add(-24*time.Hour))
+
+	wantIDs := [boardColumns][]int{{1}, {2}, {3}, {4}}
+	for c := 0; c < boardColumns; c++ {
+		if len(b.Columns[c]) != len(wantIDs[c]) {
+			t.Fatalf("column %s: got %d cards, want %d", boardTitles[c], len(b.Columns[c]), len(wantIDs[c]))
+		}
+		for i, id := range wantIDs[c] {
+			if b.Columns[c][i].Task.ID != id {
+				t.Errorf("column %s card %d: got #%d, want #%d", boardTitles[c], i, b.Columns[c][i].Task.ID, id)
+			}
+		}
+	}
+	if len(b.FreePanes) != 1 || b.FreePanes[0].ID != "%8" {
+		t.Errorf("expected only %%8 as free pane, got %+v", b.FreePanes)
+	}
+}
+
+func TestRenderBoard(t *testing.T) {
+	b := board{}
+	b.Columns[boardTodo] = []boardCard{{Task: &task{ID: 7, Title: "write docs"}}}
+	b.FreePanes = []paneInfo{{ID: "%8", Command: "claude"}}
+
+	out := renderBoard(b, 120, 20, boardTodo, 0, "")
+	for _, want := range []string{"TODO (1)", "IN PROGRESS (0)", "#7 write docs", "Idle panes: %8 (claude)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in board, got:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(out, ansiReverse+"#7 write docs") {
+		t.Error("expected selected card to be highlighted")
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("a\x1b[A\x1b[D\r世"))
+	want := []string{"a", "up", "left", "enter", "世"}
+	for _, w := range want {
+		got, err := readKey(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != w {
+			t.Errorf("readKey() = %q, want %q", got, w)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// agentPaneFilter returns a tmux format that is true for the given panes,
+// for use as a choose-tree filter.
+func agentPaneFilter(panes []paneInfo) string {
+	filter := ""
+	for _, p := range panes {
+		match := "#{==:#{pane_id}," + p.ID + "}"
+		if filter == "" {
+			filter = match
+		} else {
+			filter = "#{||:" + filter + "," + match + "}"
+		}
+	}
+	return filter
+}
+
+// runChoose opens tmux's choose-tree limited to agent panes, to hop
+// between agents in big sessions. With --windows, the tree starts with
+// windows collapsed, like choose-window.
+func runChoose(args []string, w io.Writer) error {
+	tmuxArgs := []string{"choose-tree", "-Z"}
+	for _, a := range args {
+		switch a {
+		case "--windows":
+			tmuxArgs = append(tmuxArgs, "-w")
+		default:
+			return fmt.Errorf("usage: tmux-agent choose [--windows]")
+		}
+	}
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return err
+	}
+	if len(panes) == 0 {
+		fmt.Fprintln(w, "No coding agent panes found")
+		return nil
+	}
+	if _, err := tmuxRunner.Run(append(tmuxArgs, "-f", agentPaneFilter(panes))...); err != nil {
+		return fmt.Errorf("tmux choose-tree: %w", err)
+	}
+	return nil
+}
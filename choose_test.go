@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestAgentPaneFilter(t *testing.T) {
+	got := agentPaneFilter([]paneInfo{{ID: "%1"}, {ID: "%4"}, {ID: "%7"}})
+	want := "#{||:#{||:#{==:#{pane_id},%1},#{==:#{pane_id},%4}},#{==:#{pane_id},%7}}"
+	if got != want {
+		t.Errorf("agentPaneFilter = %s, want %s", got, want)
+	}
+}
+
+func TestRunChoose(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude"},
+		&runner.FakePane{ID: "%2", Command: "zsh"},
+		&runner.FakePane{ID: "%3", Command: "codex"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	if err := runChoose([]string{"--windows"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	call := strings.Join(fake.Calls[len(fake.Calls)-1], " ")
+	want := "choose-tree -Z -w -f #{||:#{==:#{pane_id},%1},#{==:#{pane_id},%3}}"
+	if call != want {
+		t.Errorf("got %q, want %q", call, want)
+	}
+}
+
+func TestRunChoose_NoPanes(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%2", Command: "zsh"})
+	t.Setenv("HOME", t.TempDir())
+	var buf bytes.Buffer
+	if err := runChoose(nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "No coding agent panes found") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+	for _, call := range fake.Calls {
+		if call[0] == "choose-tree" {
+			t.Error("expected choose-tree not to be opened")
+		}
+	}
+}
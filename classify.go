@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// classifyLines is how many lines of pane output are checked against
+// success/error patterns when a task finishes.
+const classifyLines = 50
+
+// errorExcerptLines caps the error output kept for a failed task.
+const errorExcerptLines = 10
+
+// defaultRetryPrompt is used when retry_prompt is not configured.
+// Placeholders: {task}, {error}, {attempt}.
+const defaultRetryPrompt = "{task} (attempt {attempt}; the previous attempt failed with: {error}. Please fix this and try again.)"
+
+// outcomePatterns holds success/error regexes for an agent or project.
+type outcomePatterns struct {
+	SuccessPatterns []string `json:"success_patterns,omitempty"`
+	ErrorPatterns   []string `json:"error_patterns,omitempty"`
+}
+
+// outcomeRules is the compiled set of patterns that applies to one pane.
+type outcomeRules struct {
+	success []*regexp.Regexp
+	errors  []*regexp.Regexp
+}
+
+// project returns the settings for the project containing dir, matched by
+// its ghq-style "owner/repo" name (worktrees and subdirectories included).
+func (c *agentConfig) project(dir string) projectConfig {
+	name := shortDir(dir)
+	for key, p := range c.Projects {
+		if p != nil && (name == key || strings.HasPrefix(name, key+"/")) {
+			return *p
+		}
+	}
+	return projectConfig{}
+}
+
+// outcomeRules compiles the agent's and the project's patterns.
+func (c *agentConfig) outcomeRules(agent, dir string) (*outcomeRules, error) {
+	rules := &outcomeRules{}
+	for _, set := range []outcomePatterns{c.agent(agent).outcomePatterns, c.project(dir).outcomePatterns} {
+		for _, s := range set.SuccessPatterns {
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid success pattern %q: %w", s, err)
+			}
+			rules.success = append(rules.success, re)
+		}
+		for _, s := range set.ErrorPatterns {
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid error pattern %q: %w", s, err)
+			}
+			rules.errors = append(rules.errors, re)
+		}
+	}
+	return rules, nil
+}
+
+// classify decides whether output ends in success or failure. The last
+// line matching any pattern wins, so an error that was later fixed does not
+// count. Returns taskDone or taskFailed and, for failures, the output from
+// the deciding error line on (at most errorExcerptLines lines).
+func (r *outcomeRules) classify(output string) (string, string) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if matchAny(r.success, line) {
+			return taskDone, ""
+		}
+		if matchAny(r.errors, line) {
+			end := min(i+errorExcerptLines, len(lines))
+			return taskFailed, strings.TrimSpace(strings.Join(lines[i:end], "\n"))
+		}
+	}
+	return taskDone, ""
+}
+
+// paneOutcome classifies output from pane paneID by the patterns of its
+// agent and project, for run and wait. It returns an empty outcome when no
+// patterns are configured for the pane.
+func paneOutcome(paneID, output string) (string, string, error) {
+	p, ok := lookupPane(paneID)
+	if !ok {
+		return "", "", fmt.Errorf("pane %s not found", paneID)
+	}
+	rules, err := loadConfig().outcomeRules(p.Command, p.Dir)
+	if err != nil || len(rules.success)+len(rules.errors) == 0 {
+		return "", "", err
+	}
+	outcome, excerpt := rules.classify(strings.Join(lastLines(output, classifyLines), "\n"))
+	return outcome, excerpt, nil
+}
+
+// taskFailedError is the error run and wait return when the output ends in
+// a match of an error pattern, after printing their result.
+func taskFailedError(paneID, excerpt string) error {
+	return fmt.Errorf("pane %s failed: %s", paneID, firstLine(excerpt))
+}
+
+// matchAny reports whether any of the regexes matches s.
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryPrompt fills in the retry prompt template for another attempt at a
+// failed task.
+func retryPrompt(tmpl string, t *task) string {
+	if tmpl == "" {
+		tmpl = defaultRetryPrompt
+	}
+	return strings.NewReplacer(
+		"{task}", t.Title,
+		"{error}", t.LastError,
+		"{attempt}", fmt.Sprintf("%d", t.Attempts+1),
+	).Replace(tmpl)
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOutcomeRules(t *testing.T) {
+	var cfg agentConfig
+	json.Unmarshal([]byte(`{
+  "agents": {"claude": {"error_patterns": ["^error(\\[E\\d+\\])?:", "FAIL"]}},
+  "projects": {"owner/repo": {"error_patterns": ["panic:"], "success_patterns": ["^ok\\s"]}}
+}`), &cfg)
+
+	rules, err := cfg.outcomeRules("claude", "/home/user/ghq/github.com/owner/repo/.worktrees/task-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		output string
+		status string
+		reason string
+	}{
+		{"no match", "All done.\n", taskDone, ""},
+		{"agent pattern", "building...\nerror[E0308]: mismatched types\n\n", taskFailed, "error[E0308]: mismatched types"},
+		{"project pattern", "panic: runtime error\ngoroutine 1 [running]:\n", taskFailed, "panic: runtime error\ngoroutine 1 [running]:"},
+		{"fixed later", "--- FAIL: TestParse\nok  \tpkg\t0.1s\n", taskDone, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, reason := rules.classify(tt.output)
+			if status != tt.status || reason != tt.reason {
+				t.Errorf("classify() = %q, %q; want %q, %q", status, reason, tt.status, tt.reason)
+			}
+		})
+	}
+
+	// Project patterns do not apply elsewhere.
+	rules, _ = cfg.outcomeRules("claude", "/home/user/ghq/github.com/owner/other")
+	if status, _ := rules.classify("panic: boom"); status != taskDone {
+		t.Errorf("expected project pattern not to apply to another repo")
+	}
+
+	cfg.Agents["claude"].ErrorPatterns = []string{"("}
+	if _, err := cfg.outcomeRules("claude", ""); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestRetryPrompt(t *testing.T) {
+	tk := &task{Title: "fix the parser", Attempts: 1, LastError: "--- FAIL: TestParse"}
+	got := retryPrompt("", tk)
+	if got != "fix the parser (attempt 2; the previous attempt failed with: --- FAIL: TestParse. Please fix this and try again.)" {
+		t.Errorf("unexpected default prompt: %s", got)
+	}
+	got = retryPrompt("Retry #{attempt}: {task}\nError:\n{error}", tk)
+	if got != "Retry #2: fix the parser\nError:\n--- FAIL: TestParse" {
+		t.Errorf("unexpected templated prompt: %s", got)
+	}
+}
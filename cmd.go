@@ -1,19 +1,27 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
-	"text/tabwriter"
+	"sync"
+	"syscall"
 	"time"
 )
 
 const defaultIdleThreshold = 10 * time.Minute
 
+// defaultStatusInterval is how often status --watch redraws.
+const defaultStatusInterval = 5 * time.Second
+
 // parseIntFlag finds a named flag in args and returns its integer value.
 // Returns defaultVal if the flag is not present.
 func parseIntFlag(args []string, flag string, defaultVal int) (int, error) {
@@ -29,17 +37,116 @@ func parseIntFlag(args []string, flag string, defaultVal int) (int, error) {
 	return defaultVal, nil
 }
 
-// truncateLastLine extracts the last line from output and truncates it to maxLen.
+// truncateLastLine extracts the last line from output and truncates it to
+// maxLen terminal cells.
 func truncateLastLine(output string, maxLen int) string {
 	if output == "" {
 		return ""
 	}
 	lines := strings.Split(output, "\n")
-	last := lines[len(lines)-1]
-	if len(last) > maxLen {
-		return last[:maxLen-3] + "..."
+	return truncateWidth(lines[len(lines)-1], maxLen)
+}
+
+// mutatingCommands are the subcommands refused in read-only mode because
+// they send input to, create, or kill panes.
+var mutatingCommands = map[string]bool{
+	"send":           true,
+	"create":         true,
+	"kill":           true,
+	"kill-all":       true,
+	"restart":        true,
+	"restart-all":    true,
+	"rename":         true,
+	"broadcast":      true,
+	"workspace":      true,
+	"record":         true,
+	"dispatch":       true,
+	"approve":        true,
+	"deny":           true,
+	"compact":        true,
+	"compact-all":    true,
+	"again":          true,
+	"run":            true,
+	"pipe":           true,
+	"queue":          true,
+	"play":           true,
+	"team":           true,
+	"ask":            true,
+	"prompt":         true,
+	"resurrect-hook": true,
+}
+
+// readOnlyActions are the actions of mutating commands that only read, and
+// so stay allowed in read-only mode ("" is the command without one).
+var readOnlyActions = map[string][]string{
+	"queue":          {"list", "ls"},
+	"prompt":         {"list", "ls", "show"},
+	"resurrect-hook": {"", "save"},
+}
+
+// refusedReadOnly reports whether a command line is refused in read-only
+// mode.
+func refusedReadOnly(args []string) bool {
+	if !mutatingCommands[args[0]] {
+		return false
 	}
-	return last
+	action := ""
+	if len(args) > 1 {
+		action = args[1]
+	}
+	return !slices.Contains(readOnlyActions[args[0]], action)
+}
+
+// subcommands are the commands that may be abbreviated to any unambiguous
+// prefix. Internal commands are left out.
+var subcommands = []string{
+	"panes", "capture", "send", "create", "kill", "kill-all", "status",
+	"rename", "logs", "broadcast", "restart", "workspace", "history", "diff",
+	"watch", "snapshot", "record", "replay", "quota", "report", "digest",
+	"label", "task", "board", "dashboard", "sent-log", "again", "team",
+	"menu-popup", "popup", "menu", "choose", "primary",
+	"reattach", "resurrect-hook", "dispatch", "mcp", "serve", "wait", "run", "queue", "play", "pipe", "ask", "prompt", "statusline",
+	"approve", "deny", "compact", "compact-all", "usage",
+	"stats", "restart-all",
+}
+
+// commandAliases are short names for the most frequently typed commands.
+var commandAliases = map[string]string{
+	"p":  "panes",
+	"s":  "send",
+	"st": "status",
+	"c":  "capture",
+	"b":  "broadcast",
+}
+
+// resolveSubcommand expands an alias or unambiguous prefix to the full
+// command name. A prefix of both a command and its longer variants, such as
+// restart and restart-all, means the command. Unknown names are returned
+// unchanged.
+func resolveSubcommand(name string) (string, error) {
+	if full, ok := commandAliases[name]; ok {
+		return full, nil
+	}
+	var matches []string
+	for _, c := range subcommands {
+		if c == name {
+			return c, nil
+		}
+		if strings.HasPrefix(c, name) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return name, nil
+	case 1:
+		return matches[0], nil
+	}
+	shortest := slices.MinFunc(matches, func(a, b string) int { return len(a) - len(b) })
+	if !slices.ContainsFunc(matches, func(c string) bool { return !strings.HasPrefix(c, shortest) }) {
+		return shortest, nil
+	}
+	return "", fmt.Errorf("ambiguous command %q: could be %s", name, strings.Join(matches, ", "))
 }
 
 // runSubcommand dispatches tmux-agent subcommands.
@@ -47,6 +154,18 @@ func runSubcommand(args []string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("%s", usage())
 	}
+	name, err := resolveSubcommand(args[0])
+	if err != nil {
+		return err
+	}
+	args = append([]string{name}, args[1:]...)
+	if readOnly && refusedReadOnly(args) {
+		return fmt.Errorf("%s is disabled in read-only mode", args[0])
+	}
+	if args, err = resolvePaneArgs(args); err != nil {
+		return err
+	}
+	args = withPrimaryPane(args)
 
 	switch args[0] {
 	case "panes":
@@ -60,7 +179,7 @@ func runSubcommand(args []string) error {
 	case "kill":
 		return runKill(args[1:], os.Stdout)
 	case "kill-all":
-		return runKillAll(os.Stdout)
+		return runKillAll(args[1:], os.Stdout)
 	case "status":
 		return runStatus(args[1:], os.Stdout)
 	case "rename":
@@ -77,38 +196,196 @@ func runSubcommand(args []string) error {
 		return runHistory(args[1:], os.Stdout)
 	case "diff":
 		return runDiff(args[1:], os.Stdout)
+	case "ask":
+		return runAsk(args[1:], os.Stdout)
+	case "statusline":
+		return runStatusline(args[1:], os.Stdout)
+	case "approve":
+		return runApprove(args[1:], os.Stdout)
+	case "deny":
+		return runDeny(args[1:], os.Stdout)
+	case "compact":
+		return runCompact(args[1:], os.Stdout)
+	case "compact-all":
+		return runCompactAll(args[1:], os.Stdout)
+	case "restart-all":
+		return runRestartAll(args[1:], os.Stdout)
+	case "usage":
+		return runUsage(args[1:], os.Stdout)
+	case "stats":
+		return runStats(args[1:], os.Stdout)
+	case "prompt":
+		return runPrompt(args[1:], os.Stdout)
+	case "pipe":
+		return runPipe(args[1:], os.Stdout)
+	case "play":
+		return runPlay(args[1:], os.Stdout)
+	case "queue":
+		return runQueue(args[1:], os.Stdout)
+	case "run":
+		return runRun(args[1:], os.Stdout)
+	case "wait":
+		return runWait(args[1:], os.Stdout)
 	case "watch":
-		return runWatch(args[1:])
+		return runWatch(args[1:], os.Stdout)
+	case "snapshot":
+		return runSnapshot(args[1:], os.Stdout)
+	case "record":
+		return runRecord(args[1:], os.Stdout)
+	case "record-stream":
+		return runRecordStream(args[1:])
+	case "replay":
+		return runReplay(args[1:], os.Stdout)
+	case "quota":
+		return runQuota(args[1:], os.Stdout)
+	case "report":
+		return runReport(args[1:], os.Stdout)
+	case "digest":
+		return runDigest(args[1:], os.Stdout)
+	case "label":
+		return runLabel(args[1:], os.Stdout)
+	case "task":
+		return runTask(args[1:], os.Stdout)
+	case "board":
+		return runBoard(args[1:], os.Stdout)
+	case "dashboard":
+		return runDashboard(args[1:], os.Stdout)
+	case "popup":
+		return runMenuPopup(append([]string{"--quick"}, args[1:]...), os.Stdout)
+	case "menu-popup":
+		return runMenuPopup(args[1:], os.Stdout)
+	case "menu":
+		return runDisplayMenu(args[1:], os.Stdout)
+	case "choose":
+		return runChoose(args[1:], os.Stdout)
+	case "primary":
+		return runPrimary(args[1:], os.Stdout)
+	case "mcp":
+		return runMCP(args[1:], os.Stdout)
+	case "serve":
+		return runServe(args[1:], os.Stdout)
+	case "sent-log":
+		return runSentLog(args[1:], os.Stdout)
+	case "again":
+		return runAgain(args[1:], os.Stdout)
+	case "team":
+		return runTeam(args[1:], os.Stdout)
+	case "reattach":
+		return runReattach(args[1:], os.Stdout)
+	case "resurrect-hook":
+		return runResurrectHook(args[1:], os.Stdout)
+	case "dispatch":
+		return runDispatch(args[1:], os.Stdout)
 	default:
 		return fmt.Errorf("unknown command: %s\n%s", args[0], usage())
 	}
 }
 
 func usage() string {
-	return `usage: tmux-agent [--claude|--codex] <command>
+	return `usage: tmux-agent [--claude|--codex] [--json] <command>
 
 Global flags:
   --claude                       Use claude for this invocation
   --codex                        Use codex for this invocation
   --set-default-agent <name>     Set the default agent (persisted)
+  --container <name>             Manage the tmux server inside a Docker container
+  --read-only                    Observe only: refuse commands that send to, create or kill panes
+  --json                         Print JSON instead of tables and messages (panes, status, capture, send, create, workspace, ...)
+  --log-level <level>            Log level for watch/dispatch: debug, info, warn, error (default: info)
+
+Global flags may also follow the command, but not "--" or the start of a
+prompt or other text, where they are part of the text.
+
+Commands may be abbreviated to any unambiguous prefix (e.g. "rest" for
+restart). Short aliases: p=panes, s=send, st=status, c=capture, b=broadcast.
 
 Pane operations:
-  panes [--session name|--current] [--all]  List panes (default: agents only)
+  panes [--session name|--current] [--all] [--agent claude|codex] [--repo owner/name] [--dir path] [--sort idle|repo|agent|pane] [--width N] [--json]
+                                 List panes (default: agents only)
   capture <pane_id> [--lines N]  Capture pane output
   history <pane_id> [--lines N]  Capture extended scrollback (default 1000)
-  send <pane_id> <text...>       Send text to a pane
+  send <pane_id> [pane_id...] [--no-enter|--enter-count N] [--multiline] [--edit] [--prompt name] [--var name=value] [--file path | - | [--] text...]
+                                 Send text to one or more panes (without text: stdin or $EDITOR)
   create [options]                Create a new pane
-  kill <pane_id>                 Kill a pane
-  kill-all                       Kill all coding agent panes
-  restart <pane_id>              Restart session in a pane
+  team <name> [--session name]   Launch a team of presets in a new window
+  kill <pane_id> [--force] [--confirm|--yes]
+                                 Exit the agent, then kill its pane (--force: kill at once; --confirm: list the panes and ask first)
+  kill [--idle 30m] [--agent claude|codex] [--repo owner/name] [--dir path] [--exclude %id] [--dry-run] [--force] [--confirm|--yes]
+                                 Kill the agent panes that match the filters (--dry-run: only list them)
+  kill-all [--force] [--confirm|--yes]
+                                 Kill all coding agent panes the same way
+  restart <pane_id> [--resume] [--respawn]
+                                 Restart session in a pane (--resume: continue its latest conversation; --respawn: respawn the pane, as when the agent has exited)
+  restart-all [--resume] [--respawn] [--agent claude|codex] [--repo owner/name] [--dir path] [--exclude %id] [--idle-only] [--confirm|--yes]
+                                 Restart every (matching) agent pane
+  approve <pane_id> [--always] [--force]  Answer a pane's permission prompt with yes (--always: don't ask again)
+  deny <pane_id> [--force] [text...]  Answer it with no, then send text telling the agent what to do instead
+  compact <pane_id> [focus...]   Compact the agent's context (/compact, with optional instructions on what to keep)
+  menu-popup [--width size] [--height size]  Pick a pane and capture/send/restart/kill it in a tmux popup
+  popup [--width size] [--height size]  Command palette: the same popup, closed once a prompt is sent or an action done
+  menu [--pane id]               The same through tmux's own display-menu
+  choose [--windows]             Pick an agent pane (or window) with tmux's choose-tree
   rename <pane_id> <title>       Set pane title
+  label [pane_id [text...|--clear]]  Set or show a pane's task label
+  prompt save <name> [text...]   Save a prompt for send --prompt (without text: stdin or $EDITOR)
+  prompt list|show <name>|rm <name>  List, print or remove saved prompts
+  primary [pane_id|--clear]      Show or set this window's primary pane, used by capture, send, kill, ... when no pane ID is given
+  reattach                       Give panes recreated after a tmux restart their labels, tasks and queues
+  resurrect-hook [save|restore]  tmux-resurrect hook; without arguments, print the tmux.conf lines
+  mcp                            Serve list/capture/send/create/kill/workspace as MCP tools over stdio
+  serve [--listen addr] [--token token]  HTTP API for panes, status, capture, send and create
 
 Multi-pane operations:
-  broadcast <text...>            Send text to all coding agent panes
+  broadcast [--stagger duration] [--agent claude|codex] [--repo owner/name] [--dir path] [--exclude %id] [--idle-only] [--] [text...]
+                                 Send text to all (or the matching) coding agent panes (without text: stdin or $EDITOR)
+  compact-all [focus...] [--agent claude|codex] [--repo owner/name] [--dir path] [--exclude %id] [--idle-only]
+                                 Compact the context of all (or the matching) coding agent panes
   diff <pane1> <pane2> [--lines N]  Compare output of two panes
+  pipe <src> <dst> [dst...] [--lines N] [--prefix text]  Send a pane's recent output to the next pane as a prompt
   logs <pane_id> [--file path] [--lines N]  Save pane output to file
-  status [--short] [--idle duration]  Show pane status
+  sent-log [--pane id] [--grep regex] [--json]  Everything sent to panes, with time and repo
+  again [pane_id] [text...]      Re-send the last prompt sent to a pane, with text appended
+  status [--short] [--idle duration] [--idle-backend output|tmux] [--sort idle|repo|agent|pane] [--width N] [--watch] [--interval 5s] [--exit-code]
+                                 Show pane status (--watch: redraw in place until Ctrl-C)
+  statusline [--format text] [--max-age 10s]  Compact, cached pane counts for tmux's status-right
+  queue <pane_id> <text...>      Queue a prompt; watch sends it once the pane is idle
+  queue list|clear [pane_id]     Show or drop queued prompts
+  ask [--model name] [--dir path] [--idle 1m] [--timeout duration] [--] <question...>
+                                 Ask a throwaway agent pane, print the answer, kill the pane
+  run <pane_id> [--idle 1m] [--match regex] [--timeout duration] [--] <prompt...>
+                                 Send a prompt, wait until the agent is done, print its new output
+  play <playbook.yaml>           Create panes and run a playbook's prompts, waits and captures in order
+  wait <pane_id> [--idle duration] [--match regex] [--timeout duration]
+                                 Block until a pane is idle or its new output matches
   watch [options]                 Monitor panes for idle detection
+  watch install-service [--print] [options]  Run watch on login (systemd/launchd)
+  watch uninstall-service         Stop and remove the watch service
+  report [--since 24h] [--json]  Busy/idle/waiting totals per agent and repo
+  stats [--since 24h]            Working/idle/waiting time, prompts sent and restarts per pane
+  quota [--json]                 How close each agent account is to its usage limits
+  usage [--since 168h] [--by pane|repo|day]  Tokens and cost per pane, repo and day, from the agents' /cost and token usage reports
+  digest [--since 24h] [--summarize] [--out file.md]  Markdown standup report
+
+Snapshots:
+  snapshot save-scrollback <pane_id> [--file path]  Save full scrollback
+  snapshot list                  List saved snapshots
+  snapshot restore <name|path>   Open a snapshot in a new pane (via $PAGER)
+
+Recording:
+  record <pane_id> [--out file.cast]  Record a pane in asciicast v2 format
+  record stop <pane_id>          Stop recording a pane
+  replay <file.cast> [--speed 2x] [--max-idle duration] [--new-pane]  Play back a recording
+
+Tasks:
+  task add <title...> [--pane id] [--dir path] [--priority N] [--depends-on id,...]
+                                 Record a task
+  task start <id> [--pane id] [--send]  Mark a task in progress (optionally send it)
+  task done|fail <id> [outcome...]  Finish a task
+  task list [--status s] [--pane id]  List tasks
+  task rm <id>                   Delete a task
+  board [--idle duration]        Interactive TODO/IN PROGRESS/WAITING/DONE board
+  dashboard [--idle duration]    Live pane table; capture/send/restart/kill the selected pane
+  dispatch [options]             Hand queued tasks to idle panes (daemon)
 
 Workspace:
   workspace --repo <owner/repo> [--issue N] [--branch name]  Create worktree + pane
@@ -116,14 +393,41 @@ Workspace:
 Create options:
   --command <cmd>     Command to run (default: configured agent)
   --keys <text>       Send text after startup
+  --model <name>      Model to start the agent with (claude --model, codex -m)
   --session <name>    Target session (default: current)
   --split <h|v>       Split direction: h=horizontal, v=vertical (default: h)
   --new-window        Create as new window instead of split
+  --preset <name>     Start from a preset in config.json (the flags above override it)
+  --count <n>         Create n panes at once, splitting the first (default: 1)
+  --layout <name>     tmux layout to arrange the window in, e.g. tiled or even-horizontal (default with --count: tiled)
 
 Watch options:
   --scan <duration>   Scan interval (default: 10s)
-  --idle <duration>   Idle threshold (default: 10m)
-  --log <path>        Also write output to a log file`
+  --idle <duration>   Idle threshold (default: agents.<name>.idle_threshold, else 10m)
+  --idle-backend <b>  output (compare captures, default) or tmux (pane activity times)
+  --log <path>        Also write output to a log file
+  --daemon            Log only to a file (default: ~/.config/tmux-agent/watch.log)
+  --log-target <t>    stdout (default) or syslog (journald, with priorities by level)
+  --auto-resume       Resume rate-limited panes after their reset time (see auto_resume)
+  --notify desktop    Desktop notification when a pane goes idle or becomes active again
+  --webhook <url>     POST JSON to url when a pane goes idle or closes
+  --event-log <path>  Append one JSON line per scan and pane added, idle, active or closed
+  --once              Scan once, print the panes with their states and exit
+  --until-idle        Exit once every agent pane is idle (exit status 0)
+  --timeout <d>       With --until-idle, fail after d if panes are still busy
+  --nudge <text>      Send text to panes idle longer than --nudge-after (default 15m),
+                      at most --nudge-limit times per pane (default 3, 0 = no limit)
+
+Dispatch options:
+  --from <tasks.md>   Import "- [ ]" checklist items as tasks
+  --scan <duration>   Scan interval (default: 10s)
+  --idle <duration>   Idle threshold before a pane gets the next task (default: 2m)
+  --create            Create a new pane when no idle pane is available
+  --repo <owner/repo> Directory for new panes
+  --workspace         Create a fresh worktree per task (implies --create)
+  --retries <N>       Retry a failed task N times before escalating (see error_patterns)
+  --prefer-headroom   Skip agents at their usage limit; prefer those furthest from it
+  --once              Run a single dispatch cycle and exit`
 }
 
 // gitBranch returns the current git branch for a directory, or "" on error.
@@ -136,6 +440,36 @@ func gitBranch(dir string) string {
 	return strings.TrimSpace(string(out))
 }
 
+// branchCache remembers the git branch of each directory for a while, for
+// views and loops that would otherwise run git for every pane every time.
+type branchCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	branches map[string]string
+	at       time.Time
+}
+
+// newBranchCache returns a cache that forgets all branches every ttl.
+func newBranchCache(ttl time.Duration) *branchCache {
+	return &branchCache{ttl: ttl, branches: make(map[string]string)}
+}
+
+// get returns the git branch checked out in dir, cached for a while.
+func (c *branchCache) get(dir string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.at) > c.ttl {
+		c.branches = make(map[string]string)
+		c.at = time.Now()
+	}
+	b, ok := c.branches[dir]
+	if !ok {
+		b = gitBranch(dir)
+		c.branches[dir] = b
+	}
+	return b
+}
+
 // shortDir returns a compact directory representation.
 // For paths under a ghq root, it returns the repo-relative path (e.g., "sat0b/pulse").
 // Otherwise, it returns the last directory component.
@@ -150,11 +484,60 @@ func shortDir(dir string) string {
 	return filepath.Base(dir)
 }
 
-// runPanes lists coding agent panes, optionally filtered by session.
+// paneColumns are the columns of `panes`. When the terminal is narrow the
+// task label, title and branch shrink first, then are left out.
+var paneColumns = []tableColumn{
+	{Title: "PANE"},
+	{Title: "WINDOW", Min: 8},
+	{Title: "COMMAND"},
+	{Title: "STATE"},
+	{Title: "IDLE"},
+	{Title: "DIR", Min: 12},
+	{Title: "BRANCH", Min: 10, Optional: true},
+	{Title: "TITLE", Min: 10, Optional: true},
+	{Title: "TASK", Min: 10, Optional: true},
+}
+
+// paneLocation returns where a pane lives as session:window, or "" when
+// tmux did not report it.
+func paneLocation(p *paneInfo) string {
+	if p.Session == "" {
+		return ""
+	}
+	return p.Session + ":" + p.Window
+}
+
+// statusColumns are the columns of `status`. The last output line shrinks
+// and is left out before the task label, and that before the context left.
+var statusColumns = []tableColumn{
+	{Title: "PANE"},
+	{Title: "COMMAND"},
+	{Title: "STATUS"},
+	{Title: "CONTEXT", Optional: true},
+	{Title: "TASK", Min: 10, Optional: true},
+	{Title: "LAST OUTPUT", Min: 10, Optional: true},
+}
+
+// maxLastOutputWidth caps the last output column of `status` even on
+// wide terminals.
+const maxLastOutputWidth = 120
+
+// runPanes lists coding agent panes, optionally filtered by session, agent,
+// repo or directory.
 func runPanes(args []string, w io.Writer) error {
-	var session string
+	var session, sortKey string
 	var all bool
+	var filter paneFilter
+	asJSON := jsonOutput
 	for i := 0; i < len(args); i++ {
+		next, ok, err := filter.parseFlag(args, i)
+		if err != nil {
+			return err
+		}
+		if ok {
+			i = next
+			continue
+		}
 		switch args[i] {
 		case "--session":
 			if i+1 < len(args) {
@@ -169,26 +552,91 @@ func runPanes(args []string, w io.Writer) error {
 			session = s
 		case "--all":
 			all = true
+		case "--json":
+			asJSON = true
+		case "--sort":
+			if i+1 < len(args) {
+				i++
+				sortKey = args[i]
+				if err := validSort(sortKey); err != nil {
+					return err
+				}
+			}
 		}
 	}
+	width, err := tableWidth(args)
+	if err != nil {
+		return err
+	}
+
+	cfg := loadConfig()
+	backend, err := cfg.idleBackend("")
+	if err != nil {
+		return err
+	}
 
 	panes, err := listTmuxPanesOpts(session, all)
 	if err != nil {
 		return err
 	}
+	if panes, err = filter.apply(panes); err != nil {
+		return err
+	}
+	trackPanes(panes, false)
+	if backend == idleBackendTmux {
+		if err := applyPaneActivity(panes); err != nil {
+			return err
+		}
+	}
+	if sortKey != "" {
+		sortPanes(panes, sortKey)
+	}
+	threshold, err := cfg.idleThresholds(0)
+	if err != nil {
+		return err
+	}
+	busy := cfg.busyChecker()
+	notices := newNoticeDetector(cfg)
+	states := make([]string, len(panes))
+	for i := range panes {
+		states[i] = paneState(&panes[i], threshold(panes[i].Command), busy)
+		notice, _, err := notices.detect(&panes[i], panes[i].LastOutput)
+		if err != nil {
+			return err
+		}
+		if notice != "" {
+			states[i] = notice
+		}
+	}
+	labels := loadLabels()
+	if asJSON {
+		out := make([]paneJSON, len(panes))
+		for i := range panes {
+			out[i] = newPaneJSON(&panes[i], labels[panes[i].ID])
+			out[i].Branch = gitBranch(panes[i].Dir)
+			out[i].IdleSeconds = idleSeconds(&panes[i])
+			out[i].State = states[i]
+		}
+		return writeJSON(w, out)
+	}
 	if len(panes) == 0 {
-		fmt.Fprintln(w, "No coding agent panes found")
+		if filter.active() {
+			fmt.Fprintln(w, "No coding agent panes match the filters")
+		} else {
+			fmt.Fprintln(w, "No coding agent panes found")
+		}
 		return nil
 	}
 
-	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
-	fmt.Fprintln(tw, "PANE\tCOMMAND\tDIR\tBRANCH")
+	var rows [][]string
 	for i := range panes {
 		dir := shortDir(panes[i].Dir)
 		branch := gitBranch(panes[i].Dir)
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", panes[i].ID, panes[i].Command, dir, branch)
+		idle := formatDuration(time.Since(panes[i].LastChangeAt))
+		rows = append(rows, []string{panes[i].ID, paneLocation(&panes[i]), panes[i].Command, states[i], idle, dir, branch,
+			panes[i].Title, labels[panes[i].ID]})
 	}
-	tw.Flush()
+	renderTable(w, width, paneColumns, rows)
 	return nil
 }
 
@@ -207,31 +655,287 @@ func runCapture(args []string, w io.Writer) error {
 	if err != nil {
 		return err
 	}
+	if jsonOutput {
+		return writeJSON(w, actionJSON{Pane: paneID, Output: output})
+	}
 	fmt.Fprintln(w, output)
 	return nil
 }
 
-// runSend sends text to a pane.
+// paneIDRe matches a tmux pane ID such as %12.
+var paneIDRe = regexp.MustCompile(`^%\d+$`)
+
+// sendUsage is the usage error of send.
+const sendUsage = "usage: tmux-agent send <pane_id> [pane_id...] [--no-enter|--enter-count N] [--multiline] [--edit] [--prompt name] [--var name=value] [--file path | - | [--] text...]"
+
+// sendOptions are the flags of send.
+type sendOptions struct {
+	enter     int    // times to press enter, or enterDefault
+	file      string // file to read the text from, "-" for stdin
+	multiline bool   // paste the text even if it is a single line
+	edit      bool   // edit the text in $EDITOR before sending it
+	prompt    string // name of a saved prompt to send
+	// vars fill in the placeholders of a prompt template (see
+	// expandPrompt); saved prompts are always treated as templates.
+	vars map[string]string
+}
+
+// template reports whether the text to send is a prompt template, filled
+// in for each pane: a saved prompt, or any text sent with --var.
+func (o sendOptions) template() bool {
+	return o.prompt != "" || len(o.vars) > 0
+}
+
+// parseFlag consumes the send flag at args[i], if it is one, and returns
+// the index of its last argument.
+func (o *sendOptions) parseFlag(args []string, i int) (int, bool, error) {
+	switch args[i] {
+	case "--no-enter":
+		o.enter = 0
+	case "--multiline":
+		o.multiline = true
+	case "--edit":
+		o.edit = true
+	case "--enter-count", "--file", "-f", "--prompt", "--var":
+		if i+1 >= len(args) {
+			return i, false, nil
+		}
+		i++
+		switch args[i-1] {
+		case "--enter-count":
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 0 {
+				return i, true, fmt.Errorf("invalid --enter-count value: %s", args[i])
+			}
+			o.enter = n
+		case "--file", "-f":
+			o.file = args[i]
+		case "--prompt":
+			o.prompt = args[i]
+		case "--var":
+			name, value, ok := strings.Cut(args[i], "=")
+			if !ok || name == "" {
+				return i, true, fmt.Errorf("invalid --var value: %s (want name=value)", args[i])
+			}
+			if o.vars == nil {
+				o.vars = make(map[string]string)
+			}
+			o.vars[name] = value
+		}
+	default:
+		return i, false, nil
+	}
+	return i, true, nil
+}
+
+// parseSendArgs splits send's arguments into its flags, the indexes of its
+// targets and the index where the text starts. Flags and targets come
+// first, in any order: a comma-separated --panes list, pane IDs, or any
+// one argument as the first target. The text starts at the first word
+// that is neither, so flags and "--" inside it are sent as they are; a
+// "--" among the flags and targets starts the text early.
+func parseSendArgs(args []string) (targets []int, text int, opts sendOptions, err error) {
+	opts.enter = enterDefault
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			return targets, i + 1, opts, nil
+		}
+		if args[i] == "--panes" && i+1 < len(args) {
+			i++
+			targets = append(targets, i)
+			continue
+		}
+		if n := globalFlagWidth(args[i]); n > 0 {
+			i += n - 1
+			continue
+		}
+		next, ok, err := opts.parseFlag(args, i)
+		if err != nil {
+			return nil, 0, opts, err
+		}
+		if ok {
+			i = next
+			continue
+		}
+		if len(targets) == 0 || paneIDRe.MatchString(args[i]) {
+			targets = append(targets, i)
+			continue
+		}
+		return targets, i, opts, nil
+	}
+	return targets, len(args), opts, nil
+}
+
+// runSend sends text to a pane, or the same text to several panes. The
+// text comes from the arguments, a saved --prompt, --file, or stdin ("-"
+// or piped in); with --edit, it is a template the user finishes in $EDITOR
+// first. Text with line breaks is pasted so they survive (see
+// sendTmuxText). --no-enter and --enter-count override how many times
+// enter is pressed to submit it.
 func runSend(args []string, w io.Writer) error {
-	if len(args) < 2 {
-		return fmt.Errorf("usage: tmux-agent send <pane_id> <text...>")
+	targets, start, opts, err := parseSendArgs(args)
+	if err != nil {
+		return err
 	}
-	paneID := args[0]
-	text := strings.Join(args[1:], " ")
-	if err := sendTmuxKeys(paneID, text); err != nil {
+	if len(targets) == 0 {
+		return fmt.Errorf(sendUsage)
+	}
+	var ids []string
+	for _, i := range targets {
+		if i > 0 && args[i-1] == "--panes" {
+			ids = append(ids, strings.Split(args[i], ",")...)
+		} else {
+			ids = append(ids, args[i])
+		}
+	}
+	words := args[start:]
+	if len(ids) > 1 {
+		for _, id := range ids {
+			if !strings.HasPrefix(id, "%") {
+				return fmt.Errorf("invalid pane ID: %s", id)
+			}
+		}
+	}
+	// A lone "-" reads stdin, unless "--" made it text.
+	if len(words) == 1 && words[0] == "-" && args[start-1] != "--" {
+		opts.file, words = "-", nil
+	}
+	if opts.file != "" && len(words) > 0 {
+		return fmt.Errorf("--file cannot be combined with text arguments")
+	}
+	if opts.prompt != "" && (opts.file != "" || len(words) > 0) {
+		return fmt.Errorf("--prompt cannot be combined with --file or text arguments")
+	}
+	target := "pane " + ids[0]
+	if len(ids) > 1 {
+		target = "panes " + strings.Join(ids, " ")
+	}
+	text := strings.Join(words, " ")
+	if opts.prompt != "" {
+		if text, err = loadPrompt(opts.prompt); err != nil {
+			return err
+		}
+	}
+	switch {
+	case opts.edit:
+		if text, err = editSendTemplate(target, text, opts.file); err != nil {
+			return err
+		}
+	case opts.file != "":
+		if text, err = readMessage(opts.file); err != nil {
+			return err
+		}
+	case text == "":
+		if text, err = composeMessage(target); err != nil {
+			return err
+		}
+	}
+	if len(ids) > 1 {
+		return sendToPanes(ids, text, opts, w)
+	}
+	paneID := ids[0]
+	if opts.template() && strings.Contains(text, "{{") {
+		p, ok := lookupPane(paneID)
+		if !ok {
+			p = paneInfo{ID: paneID}
+		}
+		if text, err = expandPrompt(text, p, opts.vars); err != nil {
+			return err
+		}
+	}
+	if err := sendTmuxText(paneID, text, opts.enter, opts.multiline); err != nil {
 		return err
 	}
+	if jsonOutput {
+		return writeJSON(w, actionJSON{Pane: paneID, Text: text})
+	}
 	fmt.Fprintf(w, "Sent to pane %s: %s\n", paneID, text)
 	return nil
 }
 
+// editSendTemplate has the user write the message for send --edit in
+// $EDITOR, starting from text or the contents of file (a template).
+func editSendTemplate(target, text, file string) (string, error) {
+	if file != "" {
+		if file == "-" {
+			return "", fmt.Errorf("--edit cannot read the template from stdin")
+		}
+		data, err := os.ReadFile(expandHome(file))
+		if err != nil {
+			return "", err
+		}
+		text = string(data)
+	}
+	text, err := editMessage(target, text)
+	if err != nil {
+		return "", err
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return "", fmt.Errorf("empty message, nothing sent")
+	}
+	return text, nil
+}
+
+// sendToPanes sends text to each of the panes ids and reports per pane, as
+// broadcast does. A prompt template is filled in for each pane.
+func sendToPanes(ids []string, text string, opts sendOptions, w io.Writer) error {
+	all, err := listTmuxPanesOpts("", true)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]paneInfo, len(all))
+	for _, p := range all {
+		known[p.ID] = p
+	}
+	panes := make([]paneInfo, len(ids))
+	for i, id := range ids {
+		panes[i] = paneInfo{ID: id}
+		if p, ok := known[id]; ok {
+			panes[i] = p
+		}
+	}
+	results := forEachPane(panes, paneWorkers, func(_ int, p paneInfo) error {
+		if _, ok := known[p.ID]; !ok {
+			return fmt.Errorf("pane not found")
+		}
+		msg := text
+		if opts.template() {
+			var err error
+			if msg, err = expandPrompt(text, p, opts.vars); err != nil {
+				return err
+			}
+		}
+		return sendTmuxText(p.ID, msg, opts.enter, opts.multiline)
+	})
+	return writePaneResults(w, results, "sent")
+}
+
 // runCreate creates a new pane.
 func runCreate(args []string, w io.Writer) error {
+	cfg := loadConfig()
 	opts := createPaneOpts{Command: activeAgent}
-	var keys string
+	var keys, model, title, layout string
+	count := 1
+
+	// A preset supplies defaults that the other flags override.
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "--preset" {
+			p, err := cfg.preset(args[i+1])
+			if err != nil {
+				return err
+			}
+			if p.Agent != "" {
+				opts.Command = p.Agent
+			}
+			opts.Dir, opts.Split, opts.NewWindow = expandHome(p.Dir), p.Split, p.NewWindow
+			model, title, keys = p.Model, p.Title, p.Prompt
+		}
+	}
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
+		case "--preset":
+			i++
 		case "--command":
 			if i+1 < len(args) {
 				i++
@@ -242,6 +946,11 @@ func runCreate(args []string, w io.Writer) error {
 				i++
 				keys = args[i]
 			}
+		case "--model":
+			if i+1 < len(args) {
+				i++
+				model = args[i]
+			}
 		case "--session":
 			if i+1 < len(args) {
 				i++
@@ -254,68 +963,287 @@ func runCreate(args []string, w io.Writer) error {
 			}
 		case "--new-window":
 			opts.NewWindow = true
+		case "--count":
+			if i+1 < len(args) {
+				i++
+				n, err := strconv.Atoi(args[i])
+				if err != nil || n < 1 {
+					return fmt.Errorf("invalid --count value: %s", args[i])
+				}
+				count = n
+			}
+		case "--layout":
+			if i+1 < len(args) {
+				i++
+				layout = args[i]
+			}
 		}
 	}
-
-	paneID, err := createTmuxPaneWithOpts(opts)
+	if layout == "" && count > 1 {
+		layout = defaultTeamLayout
+	}
+	command, err := cfg.launchCommand(opts.Command, model)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(w, "Created pane %s (%s)\n", paneID, opts.Command)
+	opts.Command = command
+
+	// Further panes split the first one, and the layout is reapplied after
+	// each so the next split has room.
+	var created []actionJSON
+	for i := 0; i < count; i++ {
+		paneID, err := createTmuxPaneWithOpts(opts)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			claimPrimary(paneID)
+			opts.NewWindow, opts.Target = false, paneID
+		}
+		if title != "" {
+			renameTmuxPane(paneID, title)
+		}
+		created = append(created, actionJSON{Pane: paneID, Command: opts.Command, Title: title, Text: keys})
+		if !jsonOutput {
+			fmt.Fprintf(w, "Created pane %s (%s)\n", paneID, opts.Command)
+		}
+		if layout != "" {
+			if err := selectTmuxLayout(created[0].Pane, layout); err != nil {
+				return err
+			}
+		}
+	}
 
 	if keys != "" {
-		time.Sleep(createPaneStartupDelay)
-		if err := sendTmuxKeys(paneID, keys); err != nil {
-			return fmt.Errorf("created pane %s but failed to send keys: %w", paneID, err)
+		for _, c := range created {
+			waitReady(c.Pane, commandAgent(opts.Command))
+			if err := sendTmuxKeys(c.Pane, keys); err != nil {
+				return fmt.Errorf("created pane %s but failed to send keys: %w", c.Pane, err)
+			}
+			if !jsonOutput {
+				fmt.Fprintf(w, "Sent to pane %s: %s\n", c.Pane, keys)
+			}
+		}
+	}
+	if jsonOutput {
+		if count > 1 {
+			return writeJSON(w, created)
 		}
-		fmt.Fprintf(w, "Sent to pane %s: %s\n", paneID, keys)
+		return writeJSON(w, created[0])
 	}
 	return nil
 }
 
-// runKill kills a pane.
+// runKill kills a pane, first asking its agent to exit so it can save its
+// session, unless --force is given.
 func runKill(args []string, w io.Writer) error {
+	if !killFilterArgs(args) {
+		return killPane(args, w)
+	}
+	return killMatching(args, w)
+}
+
+// killFilterArgs reports whether kill was given filters rather than a pane
+// ID: any argument other than a pane ID, --force, --confirm and --yes.
+func killFilterArgs(args []string) bool {
+	for _, a := range args {
+		switch {
+		case strings.HasPrefix(a, "%"), a == "--force", a == "--confirm", a == "--yes":
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// killUsage is the usage error of kill.
+var killUsage = fmt.Errorf("usage: tmux-agent kill <pane_id> [--force] [--confirm|--yes] | tmux-agent kill [--idle 30m] [--agent claude|codex] [--repo owner/name] [--dir path] [--exclude %%id] [--dry-run] [--force] [--confirm|--yes]")
+
+// killPane kills the pane given by ID.
+func killPane(args []string, w io.Writer) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: tmux-agent kill <pane_id>")
+		return killUsage
 	}
 	paneID := args[0]
-	if err := killTmuxPane(paneID); err != nil {
+	force, confirm := false, loadConfig().ConfirmDestructive
+	for _, a := range args[1:] {
+		switch {
+		case a == "--force":
+			force = true
+		case parseConfirmFlag(a, &confirm):
+		default:
+			return killUsage
+		}
+	}
+	if confirm {
+		p, ok := lookupPane(paneID)
+		if !ok {
+			return fmt.Errorf("pane %s not found", paneID)
+		}
+		if err := confirmPanes("Kill", []paneInfo{p}); err != nil {
+			return err
+		}
+	}
+	archive, err := stopPane(paneID, force)
+	if err != nil {
 		return err
 	}
-	fmt.Fprintf(w, "Killed pane %s\n", paneID)
+	if jsonOutput {
+		return writeJSON(w, actionJSON{Pane: paneID, Archive: archive})
+	}
+	if archive != "" {
+		fmt.Fprintf(w, "Killed pane %s (output saved to %s)\n", paneID, archive)
+	} else {
+		fmt.Fprintf(w, "Killed pane %s\n", paneID)
+	}
 	return nil
 }
 
-// runKillAll kills all coding agent panes.
-func runKillAll(w io.Writer) error {
+// killMatching kills the coding agent panes that match the filters, e.g.
+// every codex pane idle for 30 minutes. --dry-run only lists them. At least
+// one filter is required; kill-all is the way to kill every pane.
+func killMatching(args []string, w io.Writer) error {
+	var filter paneFilter
+	dryRun, force, confirm := false, false, loadConfig().ConfirmDestructive
+	for i := 0; i < len(args); i++ {
+		next, ok, err := filter.parseFlag(args, i)
+		if err != nil {
+			return err
+		}
+		if ok {
+			i = next
+			continue
+		}
+		switch args[i] {
+		case "--idle":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil || d <= 0 {
+					return fmt.Errorf("invalid --idle value: %s", args[i])
+				}
+				filter.idleOnly, filter.idleFor = true, d
+			}
+		case "--dry-run":
+			dryRun = true
+		case "--force":
+			force = true
+		default:
+			if !parseConfirmFlag(args[i], &confirm) {
+				return killUsage
+			}
+		}
+	}
+	if !filter.active() {
+		return killUsage
+	}
 	panes, err := listTmuxPanes()
 	if err != nil {
 		return err
 	}
-	if len(panes) == 0 {
-		fmt.Fprintln(w, "No coding agent panes found")
+	if panes, err = filter.apply(panes); err != nil {
+		return err
+	}
+	if len(panes) == 0 && !jsonOutput {
+		fmt.Fprintln(w, "No coding agent panes match the filters")
 		return nil
 	}
 
-	for _, p := range panes {
-		if err := killTmuxPane(p.ID); err != nil {
-			fmt.Fprintf(w, "Error killing pane %s: %v\n", p.ID, err)
-			continue
+	if dryRun {
+		results := make([]paneResult, len(panes))
+		for i, p := range panes {
+			results[i] = paneResult{Pane: p}
 		}
-		fmt.Fprintf(w, "Killed pane %s (%s)\n", p.ID, p.Command)
+		return writePaneResults(w, results, "would kill")
 	}
-	return nil
+	if confirm && len(panes) > 0 {
+		if err := confirmPanes("Kill", panes); err != nil {
+			return err
+		}
+	}
+	results := forEachPane(panes, paneWorkers, func(_ int, p paneInfo) error {
+		_, err := stopPane(p.ID, force)
+		return err
+	})
+	return writePaneResults(w, results, "killed")
+}
+
+// runKillAll kills all coding agent panes, asking each agent to exit first
+// unless --force is given.
+func runKillAll(args []string, w io.Writer) error {
+	force, confirm := false, loadConfig().ConfirmDestructive
+	for _, a := range args {
+		switch {
+		case a == "--force":
+			force = true
+		case parseConfirmFlag(a, &confirm):
+		default:
+			return fmt.Errorf("usage: tmux-agent kill-all [--force] [--confirm|--yes]")
+		}
+	}
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return err
+	}
+	if len(panes) == 0 && !jsonOutput {
+		fmt.Fprintln(w, "No coding agent panes found")
+		return nil
+	}
+	if confirm && len(panes) > 0 {
+		if err := confirmPanes("Kill", panes); err != nil {
+			return err
+		}
+	}
+
+	results := forEachPane(panes, paneWorkers, func(_ int, p paneInfo) error {
+		_, err := stopPane(p.ID, force)
+		return err
+	})
+	return writePaneResults(w, results, "killed")
 }
 
 // runStatus shows pane status.
 func runStatus(args []string, w io.Writer) error {
-	short := false
-	threshold := defaultIdleThreshold
+	short, watch, exitCode := false, false, false
+	interval := defaultStatusInterval
+	sortKey := sortIdle
+	var idle time.Duration
+	backendFlag := ""
+	var rest []string
 
 	for i := 0; i < len(args); i++ {
+		start := i
 		switch args[i] {
+		case "--watch":
+			watch = true
+			continue
+		case "--interval":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil || d <= 0 {
+					return fmt.Errorf("invalid --interval value: %s", args[i])
+				}
+				interval = d
+			}
+			continue
 		case "--short", "-short":
 			short = true
+		case "--exit-code":
+			exitCode = true
+		case "--sort":
+			if i+1 < len(args) {
+				i++
+				sortKey = args[i]
+				if err := validSort(sortKey); err != nil {
+					return err
+				}
+			}
+		case "--idle-backend":
+			if i+1 < len(args) {
+				i++
+				backendFlag = args[i]
+			}
 		case "--idle":
 			if i+1 < len(args) {
 				i++
@@ -323,9 +1251,40 @@ func runStatus(args []string, w io.Writer) error {
 				if err != nil {
 					return fmt.Errorf("invalid --idle value: %s", args[i])
 				}
-				threshold = d
+				idle = d
 			}
 		}
+		rest = append(rest, args[start:i+1]...)
+	}
+	if watch {
+		if jsonOutput {
+			return fmt.Errorf("--watch cannot be combined with --json")
+		}
+		if exitCode {
+			return fmt.Errorf("--watch cannot be combined with --exit-code")
+		}
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(sigCh)
+		fmt.Fprint(w, ansiHideCursor)
+		defer fmt.Fprint(w, ansiShowCursor)
+		return refreshLoop(w, interval, sigCh, "tmux-agent status", func(w io.Writer) error {
+			return runStatus(rest, w)
+		})
+	}
+
+	width, err := tableWidth(args)
+	if err != nil {
+		return err
+	}
+	cfg := loadConfig()
+	threshold, err := cfg.idleThresholds(idle)
+	if err != nil {
+		return err
+	}
+	backend, err := cfg.idleBackend(backendFlag)
+	if err != nil {
+		return err
 	}
 
 	panes, err := listTmuxPanes()
@@ -333,37 +1292,145 @@ func runStatus(args []string, w io.Writer) error {
 		return err
 	}
 
-	if len(panes) == 0 {
+	if len(panes) == 0 && !jsonOutput {
 		fmt.Fprintln(w, "No coding agent panes found")
-		return nil
+		return statusExit(exitCode, nil)
 	}
 
-	for i := range panes {
-		output, err := capturePaneOutput(panes[i].ID, 5)
-		if err == nil {
-			panes[i].LastOutput = output
+	trackPanes(panes, true)
+	if backend == idleBackendTmux {
+		if err := applyPaneActivity(panes); err != nil {
+			return err
 		}
 	}
+	sortPanes(panes, sortKey)
 
+	busy := cfg.busyChecker()
+	notices := newNoticeDetector(cfg)
+	states := make([]string, len(panes))
+	messages := make([]string, len(panes))
+	for i := range panes {
+		states[i] = paneState(&panes[i], threshold(panes[i].Command), busy)
+		notice, msg, err := notices.detect(&panes[i], panes[i].LastOutput)
+		if err != nil {
+			return err
+		}
+		if notice != "" {
+			states[i], messages[i] = notice, msg
+		}
+	}
+	if short && jsonOutput {
+		counts := make(map[string]int)
+		for _, s := range states {
+			counts[s]++
+		}
+		if err := writeJSON(w, counts); err != nil {
+			return err
+		}
+		return statusExit(exitCode, states)
+	}
 	if short {
-		fmt.Fprintln(w, statusShort(panes, threshold))
-		return nil
+		fmt.Fprintln(w, statusShort(states))
+		return statusExit(exitCode, states)
 	}
 
-	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
-	fmt.Fprintln(tw, "PANE\tCOMMAND\tSTATUS\tLAST OUTPUT")
+	labels := loadLabels()
+	if jsonOutput {
+		out := make([]paneJSON, len(panes))
+		for i := range panes {
+			out[i] = newPaneJSON(&panes[i], labels[panes[i].ID])
+			out[i].State, out[i].Message = states[i], messages[i]
+			out[i].IdleSeconds = idleSeconds(&panes[i])
+			if last := lastLines(panes[i].LastOutput, 1); len(last) > 0 {
+				out[i].LastLine = last[0]
+			}
+			if pct, ok := contextLeft(panes[i].LastOutput); ok {
+				out[i].ContextLeft = &pct
+			}
+		}
+		if err := writeJSON(w, out); err != nil {
+			return err
+		}
+		return statusExit(exitCode, states)
+	}
+	var rows [][]string
 	for i := range panes {
-		status := "active"
-		if detectIdle(&panes[i], threshold) {
-			status = "idle"
+		status := states[i]
+		if status == stateIdle {
+			status += " " + formatDuration(time.Since(panes[i].LastChangeAt))
+		}
+		lastLine := truncateLastLine(panes[i].LastOutput, maxLastOutputWidth)
+		if messages[i] != "" {
+			lastLine = truncateWidth(messages[i], maxLastOutputWidth)
+		}
+		context := formatContext(contextLeft(panes[i].LastOutput))
+		rows = append(rows, []string{panes[i].ID, panes[i].Command, status, context, labels[panes[i].ID], lastLine})
+	}
+	renderTable(w, width, statusColumns, rows)
+	return statusExit(exitCode, states)
+}
+
+// Exit codes of status --exit-code.
+const (
+	statusExitActive  = 0
+	statusExitIdle    = 1
+	statusExitWaiting = 2
+	statusExitNone    = 3
+)
+
+// fleetExitCode sums up pane states for status --exit-code: no panes, some
+// waiting for input (a question or a permission prompt), some idle, or
+// none of those. Waiting wins over idle, as the more urgent.
+func fleetExitCode(states []string) int {
+	if len(states) == 0 {
+		return statusExitNone
+	}
+	code := statusExitActive
+	for _, s := range states {
+		switch s {
+		case stateWaiting, stateNeedsApproval:
+			return statusExitWaiting
+		case stateIdle:
+			code = statusExitIdle
 		}
-		lastLine := truncateLastLine(panes[i].LastOutput, 60)
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", panes[i].ID, panes[i].Command, status, lastLine)
 	}
-	tw.Flush()
+	return code
+}
+
+// statusExit returns the error that makes status exit with the fleet's
+// code when --exit-code is given, or nil.
+func statusExit(exitCode bool, states []string) error {
+	if !exitCode {
+		return nil
+	}
+	if code := fleetExitCode(states); code != statusExitActive {
+		return exitCodeError(code)
+	}
 	return nil
 }
 
+// refreshLoop redraws the screen with render's output every interval, under
+// a header naming the command and the time, until done receives. A render
+// error is shown in place of the output, so a passing tmux hiccup does not
+// end the loop.
+func refreshLoop(w io.Writer, interval time.Duration, done <-chan os.Signal, title string, render func(io.Writer) error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var buf bytes.Buffer
+		if err := render(&buf); err != nil {
+			fmt.Fprintf(&buf, "Error: %v\n", err)
+		}
+		fmt.Fprintf(w, "%sEvery %s: %s    %s\n\n", ansiClear, interval, title, time.Now().Format("15:04:05"))
+		w.Write(buf.Bytes())
+		select {
+		case <-ticker.C:
+		case <-done:
+			return nil
+		}
+	}
+}
+
 // runRename sets a pane title.
 func runRename(args []string, w io.Writer) error {
 	if len(args) < 2 {
@@ -374,6 +1441,9 @@ func runRename(args []string, w io.Writer) error {
 	if err := renameTmuxPane(paneID, title); err != nil {
 		return err
 	}
+	if jsonOutput {
+		return writeJSON(w, actionJSON{Pane: paneID, Title: title})
+	}
 	fmt.Fprintf(w, "Renamed pane %s to %q\n", paneID, title)
 	return nil
 }
@@ -402,67 +1472,177 @@ func runLogs(args []string, w io.Writer) error {
 	}
 
 	if file == "" {
-		home, _ := os.UserHomeDir()
-		logDir := filepath.Join(home, ".config", "tmux-agent", "logs")
-		os.MkdirAll(logDir, 0755)
-		file = filepath.Join(logDir, fmt.Sprintf("%s-%s.log",
-			strings.TrimPrefix(paneID, "%"),
-			time.Now().Format("20060102-150405")))
+		file, err = writeLogFile(paneID, output)
+	} else {
+		err = os.WriteFile(file, []byte(output+"\n"), 0644)
 	}
-
-	if err := os.WriteFile(file, []byte(output+"\n"), 0644); err != nil {
+	if err != nil {
 		return fmt.Errorf("writing log file: %w", err)
 	}
 	fmt.Fprintf(w, "Saved pane %s output (%d lines) to %s\n", paneID, lines, file)
 	return nil
 }
 
-// runBroadcast sends text to all coding agent panes.
+// parseBroadcastArgs splits broadcast's arguments into its filters, the
+// --stagger delay and the index where the text starts. As with send, flags
+// come before the text and "--" ends them, so the text may mention them.
+func parseBroadcastArgs(args []string) (filter paneFilter, stagger time.Duration, text int, err error) {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			return filter, stagger, i + 1, nil
+		}
+		if n := globalFlagWidth(args[i]); n > 0 {
+			i += n - 1
+			continue
+		}
+		next, ok, err := filter.parseFlag(args, i)
+		if err != nil {
+			return filter, 0, 0, err
+		}
+		if ok {
+			i = next
+			continue
+		}
+		if args[i] != "--stagger" || i+1 == len(args) {
+			return filter, stagger, i, nil
+		}
+		i++
+		if stagger, err = time.ParseDuration(args[i]); err != nil {
+			return filter, 0, 0, fmt.Errorf("invalid --stagger value: %s", args[i])
+		}
+	}
+	return filter, stagger, len(args), nil
+}
+
+// runBroadcast sends text to all coding agent panes, or those passing the
+// --agent/--repo/--exclude/--idle-only filters, optionally waiting --stagger
+// between panes so they do not all wake at once.
 func runBroadcast(args []string, w io.Writer) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: tmux-agent broadcast <text...>")
+	filter, stagger, start, err := parseBroadcastArgs(args)
+	if err != nil {
+		return err
+	}
+	words := args[start:]
+	text := strings.Join(words, " ")
+	if len(words) == 0 {
+		if text, err = composeMessage("all coding agent panes"); err != nil {
+			return err
+		}
+	}
+	guard, err := loadConfig().sendGuard()
+	if err != nil {
+		return err
+	}
+	if err := guard.checkBroadcast(text); err != nil {
+		return err
 	}
-	text := strings.Join(args, " ")
 
 	panes, err := listTmuxPanes()
 	if err != nil {
 		return err
 	}
-	if len(panes) == 0 {
+	if len(panes) > 0 && filter.active() {
+		if panes, err = filter.apply(panes); err != nil {
+			return err
+		}
+		if len(panes) == 0 && !jsonOutput {
+			fmt.Fprintln(w, "No coding agent panes match the filters")
+			return nil
+		}
+	}
+	if len(panes) == 0 && !jsonOutput {
 		fmt.Fprintln(w, "No coding agent panes found")
 		return nil
 	}
 
-	for _, p := range panes {
-		if err := sendTmuxKeys(p.ID, text); err != nil {
-			fmt.Fprintf(w, "Error sending to pane %s: %v\n", p.ID, err)
-			continue
-		}
-		fmt.Fprintf(w, "Sent to pane %s (%s)\n", p.ID, p.Command)
+	// Staggered sends go out one at a time, in order.
+	workers := paneWorkers
+	if stagger > 0 {
+		workers = 1
 	}
-	return nil
+	results := forEachPane(panes, workers, func(i int, p paneInfo) error {
+		if i > 0 && stagger > 0 {
+			rateLimitSleep(stagger)
+		}
+		return sendTmuxText(p.ID, text, enterDefault, false)
+	})
+	return writePaneResults(w, results, "sent")
 }
 
-// restartDelay is the wait time between restart steps.
-var restartDelay = 500 * time.Millisecond
-
-// runRestart restarts a coding agent session in a pane.
+// runRestart restarts the coding agent session in a pane, stopping it with
+// the agent's restart sequence. The agent running there is relaunched with
+// its original flags when ps shows them; if no agent is found, the pane is
+// respawned and the active agent is started. --resume continues the agent's
+// latest session instead of starting a new one; --respawn always respawns
+// the pane. It returns once the agent shows its input prompt, so keys can be
+// sent to it next.
 func runRestart(args []string, w io.Writer) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: tmux-agent restart <pane_id>")
+	usage := fmt.Errorf("usage: tmux-agent restart <pane_id> [--resume] [--respawn]")
+	if len(args) < 1 || !strings.HasPrefix(args[0], "%") {
+		return usage
 	}
 	paneID := args[0]
+	var opts restartOpts
+	for _, a := range args[1:] {
+		switch a {
+		case "--resume":
+			opts.resume = true
+		case "--respawn":
+			opts.respawn = true
+		default:
+			return usage
+		}
+	}
 
-	sendRawTmuxKeys(paneID, "C-c")
-	time.Sleep(restartDelay)
+	before, err := captureScrollback(paneID)
+	if err != nil {
+		return err
+	}
+	command, err := restartAgent(paneID, opts)
+	if err != nil {
+		return err
+	}
+	waitReadySince(paneID, commandAgent(command), before)
+	if jsonOutput {
+		return writeJSON(w, actionJSON{Pane: paneID, Command: command})
+	}
+
+	if opts.resume {
+		fmt.Fprintf(w, "Restarted session in pane %s with %s\n", paneID, command)
+	} else {
+		fmt.Fprintf(w, "Restarted session in pane %s\n", paneID)
+	}
+	return nil
+}
 
-	sendRawTmuxKeys(paneID, "/exit", "Enter")
-	time.Sleep(restartDelay)
+// ghqRepoDir returns the local checkout of owner/repo under the ghq root.
+func ghqRepoDir(repo string) (string, error) {
+	ghqCmd := exec.Command("ghq", "root")
+	rootOut, err := ghqCmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("ghq root: %w", err)
+	}
+	ghqRoot := strings.TrimSpace(string(rootOut))
+	repoDir := filepath.Join(ghqRoot, "github.com", repo)
 
-	sendRawTmuxKeys(paneID, activeAgent, "Enter")
+	if _, err := os.Stat(repoDir); os.IsNotExist(err) {
+		return "", fmt.Errorf("repository not found: %s", repoDir)
+	}
+	return repoDir, nil
+}
 
-	fmt.Fprintf(w, "Restarted session in pane %s\n", paneID)
-	return nil
+// addWorktree creates (or reuses) a worktree for branch under repoDir/.worktrees.
+// The branch is created if it does not exist yet.
+func addWorktree(repoDir, branch string) (string, error) {
+	wtDir := filepath.Join(repoDir, ".worktrees", branch)
+	wtCmd := exec.Command("git", "-C", repoDir, "worktree", "add", "-b", branch, wtDir)
+	if output, err := wtCmd.CombinedOutput(); err != nil {
+		wtCmd = exec.Command("git", "-C", repoDir, "worktree", "add", wtDir, branch)
+		if output2, err2 := wtCmd.CombinedOutput(); err2 != nil {
+			return "", fmt.Errorf("git worktree add: %w\n%s\n%s", err, string(output), string(output2))
+		}
+	}
+	return wtDir, nil
 }
 
 // runWorkspace creates a git worktree and a pane in it.
@@ -493,17 +1673,9 @@ func runWorkspace(args []string, w io.Writer) error {
 		return fmt.Errorf("usage: tmux-agent workspace --repo <owner/repo> [--issue N] [--branch name]")
 	}
 
-	// Find repo directory using ghq
-	ghqCmd := exec.Command("ghq", "root")
-	rootOut, err := ghqCmd.Output()
+	repoDir, err := ghqRepoDir(repo)
 	if err != nil {
-		return fmt.Errorf("ghq root: %w", err)
-	}
-	ghqRoot := strings.TrimSpace(string(rootOut))
-	repoDir := filepath.Join(ghqRoot, "github.com", repo)
-
-	if _, err := os.Stat(repoDir); os.IsNotExist(err) {
-		return fmt.Errorf("repository not found: %s", repoDir)
+		return err
 	}
 
 	if branch == "" {
@@ -514,18 +1686,17 @@ func runWorkspace(args []string, w io.Writer) error {
 		}
 	}
 
-	// Create worktree
-	wtDir := filepath.Join(repoDir, ".worktrees", branch)
-	wtCmd := exec.Command("git", "-C", repoDir, "worktree", "add", "-b", branch, wtDir)
-	if output, err := wtCmd.CombinedOutput(); err != nil {
-		wtCmd = exec.Command("git", "-C", repoDir, "worktree", "add", wtDir, branch)
-		if output2, err2 := wtCmd.CombinedOutput(); err2 != nil {
-			return fmt.Errorf("git worktree add: %w\n%s\n%s", err, string(output), string(output2))
-		}
+	wtDir, err := addWorktree(repoDir, branch)
+	if err != nil {
+		return err
 	}
 
 	// Create pane in worktree directory
-	paneID, err := createTmuxPaneInDir(activeAgent, wtDir)
+	command, err := loadConfig().launchCommand(activeAgent, "")
+	if err != nil {
+		return err
+	}
+	paneID, err := createTmuxPaneInDir(command, wtDir)
 	if err != nil {
 		return fmt.Errorf("creating pane: %w", err)
 	}
@@ -536,18 +1707,25 @@ func runWorkspace(args []string, w io.Writer) error {
 	}
 	renameTmuxPane(paneID, title)
 
-	fmt.Fprintf(w, "Created workspace:\n")
-	fmt.Fprintf(w, "  Worktree: %s\n", wtDir)
-	fmt.Fprintf(w, "  Branch:   %s\n", branch)
-	fmt.Fprintf(w, "  Pane:     %s\n", paneID)
+	if !jsonOutput {
+		fmt.Fprintf(w, "Created workspace:\n")
+		fmt.Fprintf(w, "  Worktree: %s\n", wtDir)
+		fmt.Fprintf(w, "  Branch:   %s\n", branch)
+		fmt.Fprintf(w, "  Pane:     %s\n", paneID)
+	}
 
 	if issueNum != "" {
-		time.Sleep(createPaneStartupDelay)
+		waitReady(paneID, commandAgent(command))
 		issueText := fmt.Sprintf("gh issue view %s to review the issue and start working on it", issueNum)
 		sendTmuxKeys(paneID, issueText)
-		fmt.Fprintf(w, "  Issue:    #%s (sent to pane)\n", issueNum)
+		if !jsonOutput {
+			fmt.Fprintf(w, "  Issue:    #%s (sent to pane)\n", issueNum)
+		}
 	}
 
+	if jsonOutput {
+		return writeJSON(w, actionJSON{Pane: paneID, Command: command, Title: title, Dir: wtDir, Branch: branch, Issue: issueNum})
+	}
 	return nil
 }
 
@@ -566,6 +1744,9 @@ func runHistory(args []string, w io.Writer) error {
 	if err != nil {
 		return err
 	}
+	if jsonOutput {
+		return writeJSON(w, actionJSON{Pane: paneID, Output: output})
+	}
 	fmt.Fprintln(w, output)
 	return nil
 }
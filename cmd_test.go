@@ -2,10 +2,15 @@ package main
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
 )
 
 // --- helper function tests ---
@@ -48,6 +53,8 @@ func TestTruncateLastLine(t *testing.T) {
 		{"short", "hello", 60, "hello"},
 		{"multiline", "line1\nline2\nline3", 60, "line3"},
 		{"truncated", "a very long line that exceeds the maximum length allowed here", 20, "a very long line ..."},
+		{"multibyte", "テストを実行しています", 10, "テスト..."},
+		{"emoji", "✅ all tests passed", 8, "✅ al..."},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -98,6 +105,112 @@ echo "$@" >> `+argsFile+`
 	}
 }
 
+func TestParseSendArgs(t *testing.T) {
+	tests := []struct {
+		args       []string
+		ids, words string
+	}{
+		{[]string{"%5", "hello", "world"}, "%5", "hello world"},
+		{[]string{"%5", "%d", "items"}, "%5", "%d items"},
+		{[]string{"%3", "%5", "%8", "run", "tests"}, "%3 %5 %8", "run tests"},
+		{[]string{"%3", "%5", "--", "%9", "is", "broken"}, "%3 %5", "%9 is broken"},
+		{[]string{"--panes", "%3,%5", "--", "hi"}, "%3,%5", "hi"},
+		{[]string{"--panes", "%3,%5", "hi"}, "%3,%5", "hi"},
+		{[]string{"%5"}, "%5", ""},
+		{[]string{"%5", "--no-enter", "--", "--edit", "the", "plan"}, "%5", "--edit the plan"},
+		// Flags and "--" inside the text are part of it.
+		{[]string{"%3", "run", "git", "log", "--", "README.md"}, "%3", "run git log -- README.md"},
+		{[]string{"%3", "explain", "the", "--file", "option"}, "%3", "explain the --file option"},
+		{[]string{"%3", "what", "does", "-", "mean"}, "%3", "what does - mean"},
+		{[]string{"%3", "use", "--edit", "and", "--prompt", "x", "--no-enter"}, "%3", "use --edit and --prompt x --no-enter"},
+	}
+	for _, tt := range tests {
+		targets, start, _, err := parseSendArgs(tt.args)
+		if err != nil {
+			t.Errorf("parseSendArgs(%q): %v", tt.args, err)
+			continue
+		}
+		var ids []string
+		for _, i := range targets {
+			ids = append(ids, tt.args[i])
+		}
+		if got := strings.Join(ids, " "); got != tt.ids {
+			t.Errorf("parseSendArgs(%q) panes = %q, want %q", tt.args, got, tt.ids)
+		}
+		if got := strings.Join(tt.args[start:], " "); got != tt.words {
+			t.Errorf("parseSendArgs(%q) text = %q, want %q", tt.args, got, tt.words)
+		}
+	}
+}
+
+func TestParseSendArgs_Flags(t *testing.T) {
+	args := []string{"%5", "--no-enter", "--multiline", "--file", "p.md", "--", "--enter-count", "2"}
+	_, start, opts, err := parseSendArgs(args)
+	if err != nil || opts.enter != 0 || opts.file != "p.md" || !opts.multiline || strings.Join(args[start:], " ") != "--enter-count 2" {
+		t.Errorf("got %q, %+v, %v", args[start:], opts, err)
+	}
+	if _, _, opts, _ := parseSendArgs([]string{"%5", "--enter-count", "1", "go"}); opts.enter != 1 {
+		t.Errorf("expected --enter-count 1, got %d", opts.enter)
+	}
+	if _, _, opts, _ := parseSendArgs([]string{"%5", "go"}); opts.enter != enterDefault {
+		t.Errorf("expected the agent default, got %d", opts.enter)
+	}
+	if _, _, _, err := parseSendArgs([]string{"%5", "--enter-count", "-1"}); err == nil {
+		t.Error("expected an error for a negative --enter-count")
+	}
+	if _, _, opts, _ := parseSendArgs([]string{"%5", "fix", "it", "--no-enter"}); opts.enter != enterDefault {
+		t.Error("expected a flag after the text to be sent as text")
+	}
+}
+
+func TestRunSend_TextWithFlags(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%3", Command: "claude"})
+	t.Setenv("HOME", t.TempDir())
+
+	if err := runSend([]string{"%3", "run", "git", "log", "--", "README.md"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSend([]string{"%3", "explain", "the", "--file", "/etc/passwd", "option"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	in := strings.Join(fake.Pane("%3").Input, "|")
+	for _, want := range []string{"run git log -- README.md", "explain the --file /etc/passwd option"} {
+		if !strings.Contains(in, want) {
+			t.Errorf("expected %q to be typed, got %q", want, in)
+		}
+	}
+}
+
+func TestRunSend_MultiplePanes(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude"},
+		&runner.FakePane{ID: "%5", Command: "codex"},
+		&runner.FakePane{ID: "%8", Command: "codex"},
+	)
+	t.Setenv("HOME", t.TempDir())
+
+	var buf bytes.Buffer
+	err := runSend([]string{"%3", "%8", "%9", "--", "rebase", "onto", "main"}, &buf)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 panes failed") {
+		t.Errorf("expected the missing pane to fail, got %v", err)
+	}
+	for _, id := range []string{"%3", "%8"} {
+		if in := fake.Pane(id).Input; len(in) == 0 || in[0] != "rebase onto main" {
+			t.Errorf("pane %s: expected the prompt, got %q", id, in)
+		}
+	}
+	if in := fake.Pane("%5").Input; len(in) != 0 {
+		t.Errorf("pane %%5 was not a target, got %q", in)
+	}
+	if !strings.Contains(buf.String(), "pane not found") {
+		t.Errorf("expected a row for the missing pane, got:\n%s", buf.String())
+	}
+
+	if err := runSend([]string{"--panes", "%3,x", "hi"}, &buf); err == nil {
+		t.Error("expected an error for an invalid pane ID")
+	}
+}
+
 func TestRunSend_MissingArgs(t *testing.T) {
 	var buf bytes.Buffer
 
@@ -106,9 +219,10 @@ func TestRunSend_MissingArgs(t *testing.T) {
 		t.Fatal("expected error for missing args")
 	}
 
+	useMessageStdin(t, "")
 	err = runSend([]string{"%5"}, &buf)
 	if err == nil {
-		t.Fatal("expected error for missing text")
+		t.Fatal("expected error for empty message")
 	}
 }
 
@@ -185,6 +299,28 @@ esac
 	}
 }
 
+func TestRunPanes_WindowAndTitle(t *testing.T) {
+	useFakeTmux(t, &runner.FakePane{ID: "%3", Command: "claude", Session: "work", Window: 2, Title: "auth-refactor"})
+	t.Setenv("HOME", t.TempDir())
+
+	var buf bytes.Buffer
+	if err := runPanes([]string{"--width", "0"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header and one row, got %q", buf.String())
+	}
+	for _, col := range []string{"WINDOW", "TITLE", "IDLE"} {
+		if !strings.Contains(lines[0], col) {
+			t.Errorf("expected %s column, got %q", col, lines[0])
+		}
+	}
+	if !strings.Contains(lines[1], "work:2") || !strings.Contains(lines[1], "auth-refactor") {
+		t.Errorf("expected session:window and title, got %q", lines[1])
+	}
+}
+
 // --- capture subcommand tests ---
 
 func TestRunCapture(t *testing.T) {
@@ -329,6 +465,53 @@ esac
 	}
 }
 
+func TestRunCreate_Count(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "zsh", Session: "work"})
+	t.Setenv("HOME", t.TempDir())
+	orig := readyTimeout
+	readyTimeout = 0
+	defer func() { readyTimeout = orig }()
+
+	var buf bytes.Buffer
+	if err := runCreate([]string{"--count", "3", "--new-window", "--keys", "go"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var created []string
+	for _, p := range fake.Panes[1:] {
+		created = append(created, p.ID)
+		if !strings.Contains(buf.String(), "Created pane "+p.ID) {
+			t.Errorf("expected pane %s to be printed, got %q", p.ID, buf.String())
+		}
+		if len(p.Input) == 0 || p.Input[0] != "go" {
+			t.Errorf("expected the keys to be sent to pane %s, got %q", p.ID, p.Input)
+		}
+	}
+	if len(created) != 3 {
+		t.Fatalf("expected 3 new panes, got %v", created)
+	}
+	var commands, layouts []string
+	for _, c := range fake.Calls {
+		switch c[0] {
+		case "new-window", "split-window":
+			commands = append(commands, c[0])
+		case "select-layout":
+			layouts = append(layouts, strings.Join(c[1:], " "))
+		}
+	}
+	if got := strings.Join(commands, " "); got != "new-window split-window split-window" {
+		t.Errorf("expected one window split twice, got %s", got)
+	}
+	if len(layouts) == 0 || layouts[len(layouts)-1] != "-t "+created[0]+" tiled" {
+		t.Errorf("expected the window to be tiled, got %q", layouts)
+	}
+
+	for _, n := range []string{"0", "many"} {
+		if err := runCreate([]string{"--count", n}, io.Discard); err == nil {
+			t.Errorf("expected an error for --count %s", n)
+		}
+	}
+}
+
 // --- rename subcommand tests ---
 
 func TestRunRename(t *testing.T) {
@@ -403,10 +586,10 @@ esac
 	}
 
 	output := buf.String()
-	if !strings.Contains(output, "Sent to pane %3") {
+	if !strings.Contains(output, "%3    claude  sent") {
 		t.Errorf("expected sent to %%3, got: %s", output)
 	}
-	if !strings.Contains(output, "Sent to pane %5") {
+	if !strings.Contains(output, "%5    codex   sent") {
 		t.Errorf("expected sent to %%5, got: %s", output)
 	}
 }
@@ -439,9 +622,10 @@ esac
 
 func TestRunBroadcast_MissingArgs(t *testing.T) {
 	var buf bytes.Buffer
+	useMessageStdin(t, "\n")
 	err := runBroadcast(nil, &buf)
 	if err == nil {
-		t.Fatal("expected error for missing text")
+		t.Fatal("expected error for empty message")
 	}
 }
 
@@ -466,16 +650,16 @@ esac
 	defer os.Setenv("PATH", origPath)
 
 	var buf bytes.Buffer
-	err := runKillAll(&buf)
+	err := runKillAll(nil, &buf)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
 	output := buf.String()
-	if !strings.Contains(output, "Killed pane %3") {
+	if !strings.Contains(output, "%3    claude  killed") {
 		t.Errorf("expected killed %%3, got: %s", output)
 	}
-	if !strings.Contains(output, "Killed pane %5") {
+	if !strings.Contains(output, "%5    codex   killed") {
 		t.Errorf("expected killed %%5, got: %s", output)
 	}
 
@@ -505,7 +689,7 @@ esac
 	defer os.Setenv("PATH", origPath)
 
 	var buf bytes.Buffer
-	err := runKillAll(&buf)
+	err := runKillAll(nil, &buf)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -529,9 +713,13 @@ echo "$@" >> `+argsFile+`
 	os.Setenv("PATH", dir+":"+origPath)
 	defer os.Setenv("PATH", origPath)
 
-	origDelay := restartDelay
-	restartDelay = 0
-	defer func() { restartDelay = origDelay }()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	origDelay, origReady := restartDelay, readyTimeout
+	restartDelay, readyTimeout = 0, 0
+	defer func() { restartDelay, readyTimeout = origDelay, origReady }()
 
 	var buf bytes.Buffer
 	err := runRestart([]string{"%5"}, &buf)
@@ -560,6 +748,23 @@ echo "$@" >> `+argsFile+`
 	}
 }
 
+func TestRunRestart_DetectsAgent(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "zsh", Dir: "/work/a"})
+	t.Setenv("HOME", t.TempDir())
+	origDelay, origLookup, origReady := restartDelay, agentCommandFn, readyTimeout
+	restartDelay, readyTimeout = 0, 0
+	agentCommandFn = func(paneID string) string { return "codex -m o3" }
+	defer func() { restartDelay, agentCommandFn, readyTimeout = origDelay, origLookup, origReady }()
+
+	if err := runRestart([]string{"%5"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	input := fake.Panes[0].Input
+	if len(input) == 0 || input[len(input)-1] != "codex -m o3 Enter" {
+		t.Errorf("expected codex to be relaunched with its flags, got %q", input)
+	}
+}
+
 func TestRunRestart_MissingArgs(t *testing.T) {
 	var buf bytes.Buffer
 	err := runRestart(nil, &buf)
@@ -806,3 +1011,174 @@ func TestRunSubcommand_NoArgs(t *testing.T) {
 		t.Errorf("expected usage message, got: %v", err)
 	}
 }
+
+func TestResolveSubcommand(t *testing.T) {
+	tests := []struct{ name, want, err string }{
+		{"st", "status", ""},
+		{"s", "send", ""},
+		{"rest", "restart", ""},
+		{"restart-", "restart-all", ""},
+		{"comp", "compact", ""},
+		{"kill", "kill", ""},
+		{"kill-", "kill-all", ""},
+		{"menu", "menu", ""},
+		{"wo", "workspace", ""},
+		{"re", "", "ambiguous"},
+		{"bogus", "bogus", ""},
+	}
+	for _, tt := range tests {
+		got, err := resolveSubcommand(tt.name)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("resolveSubcommand(%q): expected %s error, got %v", tt.name, tt.err, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolveSubcommand(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestRunSubcommand_Alias(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+	t.Setenv("HOME", t.TempDir())
+	if err := runSubcommand([]string{"s", "%1", "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if input := fake.Pane("%1").Input; len(input) == 0 || input[0] != "hello" {
+		t.Errorf("expected alias to send, got %q", input)
+	}
+
+	// Abbreviations are subject to read-only mode like the full names.
+	readOnly = true
+	defer func() { readOnly = false }()
+	if err := runSubcommand([]string{"bro", "hello"}); err == nil || !strings.Contains(err.Error(), "broadcast is disabled") {
+		t.Errorf("expected read-only error, got %v", err)
+	}
+}
+
+func TestRunSubcommand_ReadOnly(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "working"})
+	tmuxRunner = runner.ReadOnly{Runner: fake}
+	readOnly = true
+	defer func() { readOnly = false }()
+
+	for _, args := range [][]string{
+		{"send", "%1", "hello"},
+		{"kill", "%1"},
+		{"restart", "%1"},
+		{"broadcast", "hello"},
+		{"pipe", "%1", "%2"},
+		{"run", "%1", "make", "test"},
+		{"queue", "%1", "later"},
+		{"again", "%1"},
+		{"prompt", "save", "x", "hi"},
+		{"resurrect-hook", "restore"},
+	} {
+		err := runSubcommand(args)
+		if err == nil || !strings.Contains(err.Error(), "read-only") {
+			t.Errorf("%s: expected read-only error, got %v", args[0], err)
+		}
+	}
+	if len(fake.Pane("%1").Input) != 0 {
+		t.Errorf("keys reached the pane: %q", fake.Pane("%1").Input)
+	}
+
+	// Actions that only read stay allowed.
+	for _, args := range [][]string{{"queue", "list"}, {"prompt", "list"}} {
+		if err := runSubcommand(args); err != nil && strings.Contains(err.Error(), "read-only") {
+			t.Errorf("%s: expected %s to be allowed, got %v", args[0], args[1], err)
+		}
+	}
+
+	// Sending keys directly is refused by the runner as well.
+	if err := sendTmuxKeys("%1", "hello"); err == nil {
+		t.Error("expected runner to refuse send-keys")
+	}
+	if _, err := capturePaneOutput("%1", 10); err != nil {
+		t.Errorf("capture should still work: %v", err)
+	}
+}
+
+func TestRefreshLoop(t *testing.T) {
+	done := make(chan os.Signal, 1)
+	renders := 0
+	var buf bytes.Buffer
+	err := refreshLoop(&buf, 10*time.Millisecond, done, "tmux-agent status", func(w io.Writer) error {
+		renders++
+		if renders == 2 {
+			done <- os.Interrupt
+			return fmt.Errorf("no server running")
+		}
+		fmt.Fprintln(w, "PANE  AGENT")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	if renders != 2 || strings.Count(out, ansiClear) != 2 {
+		t.Errorf("expected 2 redraws, got %d: %q", renders, out)
+	}
+	if !strings.Contains(out, "Every 10ms: tmux-agent status") || !strings.Contains(out, "PANE  AGENT") {
+		t.Errorf("expected header and table, got %q", out)
+	}
+	if !strings.Contains(out, "Error: no server running") {
+		t.Errorf("expected render error on screen, got %q", out)
+	}
+}
+
+func TestRunStatus_WatchRejectsJSON(t *testing.T) {
+	useJSONOutput(t)
+	if err := runStatus([]string{"--watch"}, io.Discard); err == nil {
+		t.Error("expected --watch with --json to fail")
+	}
+	if err := runStatus([]string{"--watch", "--interval", "0s"}, io.Discard); err == nil {
+		t.Error("expected invalid --interval to fail")
+	}
+}
+
+func TestFleetExitCode(t *testing.T) {
+	tests := []struct {
+		states []string
+		want   int
+	}{
+		{nil, statusExitNone},
+		{[]string{stateActive, stateBusyCPU, stateRateLimited}, statusExitActive},
+		{[]string{stateActive, stateIdle}, statusExitIdle},
+		{[]string{stateIdle, stateWaiting, stateActive}, statusExitWaiting},
+		{[]string{stateNeedsApproval}, statusExitWaiting},
+	}
+	for _, tt := range tests {
+		if got := fleetExitCode(tt.states); got != tt.want {
+			t.Errorf("fleetExitCode(%v) = %d, want %d", tt.states, got, tt.want)
+		}
+	}
+}
+
+func TestRunStatus_ExitCode(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "editing main.go"})
+
+	var buf bytes.Buffer
+	if err := runStatus([]string{"--short", "--exit-code"}, &buf); err != nil {
+		t.Errorf("expected exit code 0 for an active pane, got %v", err)
+	}
+
+	fake.Pane("%1").Output = "Do you want to create hello.go?\n❯ 1. Yes\n  2. No"
+	err := runStatus([]string{"--short", "--exit-code"}, &buf)
+	if code, ok := err.(exitCodeError); !ok || code != statusExitWaiting {
+		t.Errorf("expected exit code %d, got %v", statusExitWaiting, err)
+	}
+
+	useFakeTmux(t)
+	buf.Reset()
+	err = runStatus([]string{"--exit-code"}, &buf)
+	if code, ok := err.(exitCodeError); !ok || code != statusExitNone {
+		t.Errorf("expected exit code %d, got %v", statusExitNone, err)
+	}
+	if !strings.Contains(buf.String(), "No coding agent panes found") {
+		t.Errorf("expected the usual output too, got %q", buf.String())
+	}
+}
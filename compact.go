@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// defaultCompactCommand is the slash command claude and codex both use to
+// summarize and shrink their context.
+const defaultCompactCommand = "/compact"
+
+// compactCommand returns the command that compacts agent's context, with
+// focus (instructions on what to keep, which claude accepts) appended.
+func (c *agentConfig) compactCommand(agent, focus string) string {
+	command := c.agent(agent).CompactCommand
+	if command == "" {
+		command = defaultCompactCommand
+	}
+	if focus != "" {
+		command += " " + focus
+	}
+	return command
+}
+
+// runCompact sends a pane's agent its context-compaction command.
+func runCompact(args []string, w io.Writer) error {
+	if len(args) == 0 || !strings.HasPrefix(args[0], "%") {
+		return fmt.Errorf("usage: tmux-agent compact <pane_id> [focus...]")
+	}
+	paneID := args[0]
+	p, ok := lookupPane(paneID)
+	if !ok {
+		return fmt.Errorf("pane %s not found", paneID)
+	}
+	agent := p.Command
+	command := loadConfig().compactCommand(agent, strings.Join(args[1:], " "))
+	if err := sendTmuxKeys(paneID, command); err != nil {
+		return err
+	}
+	touchPane(paneID)
+	if jsonOutput {
+		return writeJSON(w, actionJSON{Pane: paneID, Command: agent, Text: command})
+	}
+	fmt.Fprintf(w, "Sent %s to pane %s\n", command, paneID)
+	return nil
+}
+
+// runCompactAll compacts the context of every (matching) coding agent
+// pane.
+func runCompactAll(args []string, w io.Writer) error {
+	var filter paneFilter
+	var words []string
+	for i := 0; i < len(args); i++ {
+		next, ok, err := filter.parseFlag(args, i)
+		if err != nil {
+			return err
+		}
+		if ok {
+			i = next
+			continue
+		}
+		words = append(words, args[i])
+	}
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return err
+	}
+	if panes, err = filter.apply(panes); err != nil {
+		return err
+	}
+	if len(panes) == 0 && !jsonOutput {
+		if filter.active() {
+			fmt.Fprintln(w, "No coding agent panes match the filters")
+		} else {
+			fmt.Fprintln(w, "No coding agent panes found")
+		}
+		return nil
+	}
+
+	cfg := loadConfig()
+	focus := strings.Join(words, " ")
+	results := forEachPane(panes, paneWorkers, func(_ int, p paneInfo) error {
+		return sendTmuxKeys(p.ID, cfg.compactCommand(p.Command, focus))
+	})
+	// The panes are touched together afterwards: the workers would each
+	// rewrite the shared pane state and lose one another's updates.
+	var sent []string
+	for _, r := range results {
+		if r.Err == nil {
+			sent = append(sent, r.Pane.ID)
+		}
+	}
+	if err := touchPane(sent...); err != nil {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Err = err
+			}
+		}
+	}
+	return writePaneResults(w, results, "sent")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRunCompact(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+	t.Setenv("HOME", t.TempDir())
+
+	var buf bytes.Buffer
+	if err := runCompact([]string{"%1", "keep", "the", "API", "design"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if in := fake.Pane("%1").Input; len(in) == 0 || in[0] != "/compact keep the API design" {
+		t.Errorf("unexpected input: %q", in)
+	}
+	if err := runCompact([]string{"%9"}, &buf); err == nil {
+		t.Error("expected an error for a missing pane")
+	}
+}
+
+func TestRunCompactAll(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude"},
+		&runner.FakePane{ID: "%2", Command: "codex"},
+		&runner.FakePane{ID: "%3", Command: "codex"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	saveConfig(&agentConfig{Agents: map[string]*agentProfile{"codex": {CompactCommand: "/summarize"}}})
+	hourAgo := time.Now().Add(-time.Hour)
+	saveState(paneStateFile, map[string]trackedOutput{
+		"%1": {Hash: outputHash(""), Changed: hourAgo},
+		"%2": {Hash: outputHash(""), Changed: hourAgo},
+		"%3": {Hash: outputHash(""), Changed: hourAgo},
+	})
+
+	var buf bytes.Buffer
+	if err := runCompactAll([]string{"--exclude", "%3"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"%1": "/compact", "%2": "/summarize", "%3": ""}
+	for id, text := range want {
+		in := fake.Pane(id).Input
+		if text == "" {
+			if len(in) != 0 {
+				t.Errorf("pane %s: expected nothing sent, got %q", id, in)
+			}
+			continue
+		}
+		if len(in) == 0 || in[0] != text {
+			t.Errorf("pane %s: sent %q, want %q", id, in, text)
+		}
+	}
+	if !strings.Contains(buf.String(), "sent") {
+		t.Errorf("expected a results table, got %q", buf.String())
+	}
+	// Every pane sent to is touched, none lost to another worker's save.
+	tracker := loadOutputTracker()
+	for id, touched := range map[string]bool{"%1": true, "%2": true, "%3": false} {
+		if got := tracker.changed[id].After(hourAgo); got != touched {
+			t.Errorf("pane %s: touched %v, want %v", id, got, touched)
+		}
+	}
+}
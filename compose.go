@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// messageStdin is where send and broadcast read a message from when it is
+// not given as arguments. It can be replaced in tests.
+var messageStdin = os.Stdin
+
+// composeMessage returns the message for send or broadcast when none was
+// given on the command line: everything piped to stdin, or otherwise what
+// the user writes in $VISUAL/$EDITOR. In the editor, the instructions below
+// the scissors line are dropped, as in a git commit message (see
+// editMessage). An empty message is an error.
+func composeMessage(target string) (string, error) {
+	info, err := messageStdin.Stat()
+	if err != nil {
+		return "", err
+	}
+	var text string
+	if info.Mode()&os.ModeCharDevice == 0 {
+		data, err := io.ReadAll(messageStdin)
+		if err != nil {
+			return "", fmt.Errorf("reading stdin: %w", err)
+		}
+		text = string(data)
+	} else if text, err = editMessage(target, ""); err != nil {
+		return "", err
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return "", fmt.Errorf("empty message, nothing sent")
+	}
+	return text, nil
+}
+
+// readMessage returns the message in file, or on stdin for "-", for
+// `send --file` and `send -`. An empty message is an error.
+func readMessage(file string) (string, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		if data, err = io.ReadAll(messageStdin); err != nil {
+			return "", fmt.Errorf("reading stdin: %w", err)
+		}
+	} else if data, err = os.ReadFile(expandHome(file)); err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return "", fmt.Errorf("empty message, nothing sent")
+	}
+	return text, nil
+}
+
+// scissorsLine separates the message being edited from the instructions
+// below it, as in git commit messages. Everything from it on is dropped, so
+// the message itself may contain lines starting with '#', e.g. Markdown
+// headings.
+const scissorsLine = "# ------------------------ >8 ------------------------"
+
+// editMessage opens the user's editor on initial text (if any) followed by
+// instructions, and returns the text above the scissors line.
+func editMessage(target, initial string) (string, error) {
+	f, err := os.CreateTemp("", "tmux-agent-message-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if initial = strings.TrimRight(initial, "\n"); initial != "" {
+		fmt.Fprintln(f, initial)
+	}
+	fmt.Fprintf(f, "\n%s\n# Write the message to send to %s above this line.\n# Everything from the line above on is ignored; an empty message aborts.\n", scissorsLine, target)
+	f.Close()
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", f.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running %s: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	text, _, _ := strings.Cut(string(data), scissorsLine)
+	return text, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+// useMessageStdin makes send and broadcast read text as if it were piped in.
+func useMessageStdin(t *testing.T, text string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := messageStdin
+	messageStdin = f
+	t.Cleanup(func() { messageStdin = orig; f.Close() })
+}
+
+func TestRunSend_Stdin(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "claude"})
+	t.Setenv("HOME", t.TempDir())
+	useMessageStdin(t, "Refactor the parser.\nKeep the public API unchanged.\n")
+
+	if err := runSend([]string{"%5"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	// Text spanning lines is pasted with its line breaks.
+	if got := fake.Panes[0].Input[0]; got != "Refactor the parser.\nKeep the public API unchanged." {
+		t.Errorf("unexpected input %q", got)
+	}
+}
+
+func TestComposeMessage_Editor(t *testing.T) {
+	dir := t.TempDir()
+	editor := filepath.Join(dir, "editor")
+	os.WriteFile(editor, []byte(`#!/bin/sh
+grep -q "all coding agent panes" "$1" || exit 1
+{ printf 'run the tests\n'; cat "$1"; } > "$1.new" && mv "$1.new" "$1"
+`), 0o755)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	// A terminal on stdin means nothing was piped.
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		t.Skip("no terminal:", err)
+	}
+	orig := messageStdin
+	messageStdin = tty
+	defer func() { messageStdin = orig; tty.Close() }()
+
+	text, err := composeMessage("all coding agent panes")
+	if err != nil || text != "run the tests" {
+		t.Errorf("composeMessage = %q, %v", text, err)
+	}
+}
+
+func TestEditMessage(t *testing.T) {
+	dir := t.TempDir()
+	editor := filepath.Join(dir, "editor")
+	os.WriteFile(editor, []byte(`#!/bin/sh
+{ printf '# Heading\nfirst line\n\n#second line\n'; cat "$1"; } > "$1.new" && mv "$1.new" "$1"
+`), 0o755)
+	t.Setenv("VISUAL", editor)
+
+	text, err := editMessage("pane %1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(text) != "# Heading\nfirst line\n\n#second line" {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	t.Setenv("VISUAL", "false")
+	if _, err := editMessage("pane %1", ""); err == nil {
+		t.Error("expected error when the editor fails")
+	}
+}
+
+func TestRunSend_FileAndDash(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "claude"})
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	os.WriteFile(filepath.Join(dir, "prompt.md"), []byte("Write the migration.\n"), 0o644)
+
+	if err := runSend([]string{"%5", "--file", "~/prompt.md"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	useMessageStdin(t, "From stdin\n")
+	if err := runSend([]string{"%5", "-"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	var sent []string
+	for _, in := range fake.Pane("%5").Input {
+		if in != "C-m" {
+			sent = append(sent, in)
+		}
+	}
+	if strings.Join(sent, "|") != "Write the migration.|From stdin" {
+		t.Errorf("unexpected input %q", sent)
+	}
+
+	if err := runSend([]string{"%5", "--file", "~/prompt.md", "extra"}, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for --file with text")
+	}
+	if err := runSend([]string{"%5", "--file", "~/missing.md"}, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestRunSend_Edit(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "claude"})
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	os.WriteFile(filepath.Join(dir, "review.md"), []byte("Review the diff.\n"), 0o644)
+	// The editor finishes the template: it keeps the first line and adds one.
+	editor := filepath.Join(dir, "editor")
+	os.WriteFile(editor, []byte(`#!/bin/sh
+head -n 1 "$1" > "$1.new"
+echo "Focus on error handling." >> "$1.new"
+mv "$1.new" "$1"
+`), 0o755)
+	t.Setenv("VISUAL", editor)
+
+	if err := runSend([]string{"%5", "--edit", "--file", "~/review.md"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSend([]string{"%5", "--edit", "Look", "at", "main.go."}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	in := fake.Pane("%5").Input
+	if len(in) != 6 || in[0] != "Review the diff.\nFocus on error handling." || in[3] != "Look at main.go.\nFocus on error handling." {
+		t.Errorf("unexpected input %q", in)
+	}
+
+	t.Setenv("VISUAL", "true")
+	if err := runSend([]string{"%5", "--edit"}, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error when the message is left empty")
+	}
+}
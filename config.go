@@ -2,12 +2,22 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
 )
 
 const defaultAgentCommand = "claude"
 
+// readOnly disables commands that would disturb running agents. Set with
+// --read-only or locked on with "read_only" in the config file.
+var readOnly bool
+
 // activeAgent is the resolved agent command for this invocation.
 // Set at startup from config file, overridable with --claude/--codex flags.
 var activeAgent = defaultAgentCommand
@@ -15,6 +25,125 @@ var activeAgent = defaultAgentCommand
 // agentConfig holds persisted settings.
 type agentConfig struct {
 	DefaultAgent string `json:"default_agent"`
+	// MaxConcurrentAgents caps how many agents automation keeps busy at once (0 = unlimited).
+	MaxConcurrentAgents int                      `json:"max_concurrent_agents,omitempty"`
+	Agents              map[string]*agentProfile `json:"agents,omitempty"`
+	// RetryPrompt is the template sent when dispatch retries a failed task.
+	RetryPrompt string `json:"retry_prompt,omitempty"`
+	// BlockedPatterns are regexes; text matching any of them is never sent to a pane.
+	BlockedPatterns []string `json:"blocked_patterns,omitempty"`
+	// BroadcastAllowlist restricts broadcast to these prompt templates.
+	BroadcastAllowlist []string `json:"broadcast_allowlist,omitempty"`
+	// SendRateLimit spaces out sends per pane and across all panes.
+	SendRateLimit sendRateLimit `json:"send_rate_limit,omitzero"`
+	// IdleBackend selects how idle panes are detected: "output" or "tmux".
+	IdleBackend string `json:"idle_backend,omitempty"`
+	// TmuxBackend selects how tmux commands are run: "exec" or "control".
+	TmuxBackend string `json:"tmux_backend,omitempty"`
+	// BusyCPUPercent is the CPU usage at which an idle pane counts as
+	// busy(cpu); 0 means the default, a negative value disables sampling.
+	BusyCPUPercent float64 `json:"busy_cpu_percent,omitempty"`
+	// ContextWarning is the context left, in percent, below which watch
+	// warns about a pane; 0 means the default, a negative value disables it.
+	ContextWarning int `json:"context_warning,omitempty"`
+	// Statusline configures the `statusline` segment for the tmux status bar.
+	Statusline statuslineConfig `json:"statusline,omitzero"`
+	// AutoResume configures `watch --auto-resume`.
+	AutoResume autoResumeConfig `json:"auto_resume,omitzero"`
+	// ReadOnly locks every invocation into read-only mode.
+	ReadOnly bool `json:"read_only,omitempty"`
+	// ConfirmDestructive makes kill, kill-all and restart-all ask before
+	// acting, as with --confirm.
+	ConfirmDestructive bool `json:"confirm_destructive,omitempty"`
+	// ArchiveOnKill saves a pane's output to the logs directory before
+	// kill, kill-all and menu kill it; unset means true.
+	ArchiveOnKill *bool `json:"archive_on_kill,omitempty"`
+	// Presets are named create settings, used by create --preset and team.
+	Presets map[string]*createPreset `json:"presets,omitempty"`
+	// Teams are named sets of presets launched together by team.
+	Teams map[string]*teamConfig `json:"teams,omitempty"`
+	// Notifiers are chat webhooks (Slack, Discord) watch posts events to.
+	Notifiers []*notifierConfig `json:"notifiers,omitempty"`
+	// Hooks maps event names (e.g. "pane_closed") to shell commands.
+	Hooks map[string]string `json:"hooks,omitempty"`
+	// Projects holds per-repository settings keyed by "owner/repo".
+	Projects map[string]*projectConfig `json:"projects,omitempty"`
+}
+
+// agentProfile holds settings for one agent command (e.g. "claude").
+type agentProfile struct {
+	// MaxConcurrent caps how many panes of this agent automation keeps busy (0 = unlimited).
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// ResumeCommand relaunches the agent, continuing its last conversation.
+	ResumeCommand string `json:"resume_command,omitempty"`
+	// ResumeSessionCommand relaunches the agent on one session, whose ID
+	// replaces {session}.
+	ResumeSessionCommand string `json:"resume_session_command,omitempty"`
+	// Model is the model new panes of this agent are started with.
+	Model string `json:"model,omitempty"`
+	// ModelFlag is the CLI flag that selects a model, for agents other than
+	// claude (--model) and codex (-m).
+	ModelFlag string `json:"model_flag,omitempty"`
+	// IdleThreshold is how long the agent's output must be unchanged before
+	// status and watch call it idle, e.g. "25m".
+	IdleThreshold string `json:"idle_threshold,omitempty"`
+	// EnterCount is how many times enter is pressed to submit input sent
+	// to the agent (default 2; 0 types the input without submitting it).
+	EnterCount *int `json:"enter_count,omitempty"`
+	// ApprovalKeys replaces the keys approve and deny send to answer the
+	// agent's permission prompts.
+	ApprovalKeys *approvalKeys `json:"approval_keys,omitempty"`
+	// CompactCommand is what compact sends to shrink the agent's context
+	// (default /compact).
+	CompactCommand string `json:"compact_command,omitempty"`
+	// ReadyPatterns are extra regexes matching the agent's input prompt,
+	// which create, team, play and restart wait for before sending keys.
+	ReadyPatterns []string `json:"ready_patterns,omitempty"`
+	// ReadyTimeout is how long to wait for the prompt, e.g. "1m".
+	ReadyTimeout string `json:"ready_timeout,omitempty"`
+	// Restart replaces how restart stops and relaunches the agent.
+	Restart *restartSequence `json:"restart,omitempty"`
+	outcomePatterns
+	noticePatterns
+}
+
+// projectConfig holds settings for one repository.
+type projectConfig struct {
+	outcomePatterns
+}
+
+// agent returns the profile for the named agent, or an empty profile.
+func (c *agentConfig) agent(name string) agentProfile {
+	if p, ok := c.Agents[name]; ok && p != nil {
+		return *p
+	}
+	return agentProfile{}
+}
+
+// idleThresholds returns the idle threshold for each agent: explicit when
+// non-zero (an --idle flag), else the agent's idle_threshold, else
+// defaultIdleThreshold.
+func (c *agentConfig) idleThresholds(explicit time.Duration) (func(agent string) time.Duration, error) {
+	perAgent := make(map[string]time.Duration)
+	for name, p := range c.Agents {
+		if p == nil || p.IdleThreshold == "" {
+			continue
+		}
+		d, err := time.ParseDuration(p.IdleThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agents.%s.idle_threshold: %s", name, p.IdleThreshold)
+		}
+		perAgent[name] = d
+	}
+	return func(agent string) time.Duration {
+		if explicit > 0 {
+			return explicit
+		}
+		if d, ok := perAgent[agent]; ok {
+			return d
+		}
+		return defaultIdleThreshold
+	}, nil
 }
 
 // configDir returns the configuration directory path.
@@ -55,18 +184,46 @@ func saveConfig(cfg *agentConfig) error {
 	return os.WriteFile(configFilePath(), data, 0644)
 }
 
-// parseGlobalFlags extracts global flags (--claude, --codex, --set-default-agent)
-// from args. Returns the remaining args and whether a config-only action was performed.
+// parseGlobalFlags extracts global flags (--claude, --codex, --set-default-agent,
+// --log-level, --container, --read-only, --json) from args. They may come
+// before or after the subcommand, but not after a "--" or in the free text
+// of a command that takes some (see textStart), so prompts can mention
+// them. Returns the remaining args and whether a config-only action was
+// performed.
 func parseGlobalFlags(args []string) (remaining []string, handled bool) {
 	cfg := loadConfig()
 	activeAgent = cfg.DefaultAgent
 
+	command, limit := -1, len(args)
 	for i := 0; i < len(args); i++ {
+		if i >= limit || command >= 0 && args[i] == "--" {
+			remaining = append(remaining, args[i:]...)
+			break
+		}
 		switch args[i] {
 		case "--claude":
 			activeAgent = "claude"
 		case "--codex":
 			activeAgent = "codex"
+		case "--read-only":
+			readOnly = true
+		case "--json":
+			jsonOutput = true
+		case "--container":
+			if i+1 < len(args) {
+				i++
+				tmuxRunner = runner.Docker{Container: args[i]}
+			}
+		case "--log-level":
+			if i+1 < len(args) {
+				i++
+				level, err := parseLogLevel(args[i])
+				if err != nil {
+					os.Stderr.WriteString("error: " + err.Error() + "\n")
+					os.Exit(1)
+				}
+				logLevel.Set(level)
+			}
 		case "--set-default-agent":
 			if i+1 < len(args) {
 				i++
@@ -80,7 +237,130 @@ func parseGlobalFlags(args []string) (remaining []string, handled bool) {
 			}
 		default:
 			remaining = append(remaining, args[i])
+			if command < 0 {
+				command = i
+				name, _ := resolveSubcommand(args[i])
+				if start, ok := textStart(name, args[i+1:]); ok {
+					limit = i + 1 + start
+				}
+			}
+		}
+	}
+	switch cfg.TmuxBackend {
+	case "", tmuxBackendExec:
+	case tmuxBackendControl:
+		// --container keeps running tmux through docker exec.
+		if _, ok := runner.Unwrap(tmuxRunner).(runner.Exec); ok {
+			tmuxRunner = runner.NewControl("")
 		}
+	default:
+		os.Stderr.WriteString("error: invalid tmux_backend: " + cfg.TmuxBackend + " (want " + tmuxBackendExec + " or " + tmuxBackendControl + ")\n")
+		os.Exit(1)
+	}
+	if cfg.ReadOnly {
+		readOnly = true
+	}
+	if readOnly {
+		tmuxRunner = runner.ReadOnly{Runner: tmuxRunner}
 	}
 	return remaining, false
 }
+
+// globalFlagWidth returns how many arguments a global flag starting at arg
+// takes up, with its value, or 0 if arg is not a global flag.
+func globalFlagWidth(arg string) int {
+	switch arg {
+	case "--claude", "--codex", "--read-only", "--json":
+		return 1
+	case "--container", "--log-level", "--set-default-agent":
+		return 2
+	}
+	return 0
+}
+
+// textStart returns the index in args, the arguments of command name,
+// where its free text (a prompt, label, title, ...) starts, for the
+// commands that take some; ok is false for the others. Global flags are
+// only taken from the arguments before it. A leading pane is only skipped
+// when written as a pane ID or @name, since with a primary pane the text
+// may come first.
+func textStart(name string, args []string) (start int, ok bool) {
+	pane := 0
+	if len(args) > 0 && (strings.HasPrefix(args[0], "%") || strings.HasPrefix(args[0], "@")) {
+		pane = 1
+	}
+	switch name {
+	case "send":
+		targets, start, _, err := parseSendArgs(args)
+		if err != nil {
+			return 0, true
+		}
+		for _, t := range targets {
+			if t > 0 && args[t-1] == "--panes" {
+				continue
+			}
+			if !strings.HasPrefix(args[t], "%") && !strings.HasPrefix(args[t], "@") {
+				return t, true
+			}
+		}
+		return start, true
+	case "broadcast":
+		_, _, start, err := parseBroadcastArgs(args)
+		if err != nil {
+			return 0, true
+		}
+		return start, true
+	case "run":
+		_, start, err := parseRunArgs(args[pane:])
+		if err != nil {
+			return 0, true
+		}
+		return pane + start, true
+	case "ask":
+		_, start, err := parseAskArgs(args)
+		if err != nil {
+			return 0, true
+		}
+		return start, true
+	case "compact-all":
+		return skipFlags(args, 0), true
+	case "compact", "rename", "again":
+		return skipFlags(args, pane), true
+	case "deny":
+		return skipFlags(args, pane, "--force"), true
+	case "label":
+		return skipFlags(args, pane, "--clear"), true
+	case "queue":
+		if len(args) > 0 && (args[0] == "list" || args[0] == "ls" || args[0] == "clear") {
+			return 0, false
+		}
+		return skipFlags(args, pane), true
+	case "prompt":
+		if len(args) > 0 && args[0] == "save" {
+			return skipFlags(args, min(2, len(args))), true
+		}
+	case "task":
+		if len(args) > 0 && args[0] == "add" {
+			return skipFlags(args, 1), true
+		}
+		if len(args) > 0 && (args[0] == "done" || args[0] == "fail") {
+			return skipFlags(args, min(2, len(args))), true
+		}
+	}
+	return 0, false
+}
+
+// skipFlags returns the index of the first of args from i on that is
+// neither a global flag nor one of flags.
+func skipFlags(args []string, i int, flags ...string) int {
+	for i < len(args) {
+		if n := globalFlagWidth(args[i]); n > 0 {
+			i += n
+		} else if slices.Contains(flags, args[i]) {
+			i++
+		} else {
+			break
+		}
+	}
+	return min(i, len(args))
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
 )
 
 func TestLoadConfig_Default(t *testing.T) {
@@ -117,3 +123,204 @@ func TestParseGlobalFlags_FlagOverridesConfig(t *testing.T) {
 		t.Errorf("unexpected remaining args: %v", remaining)
 	}
 }
+
+func TestLoadConfig_AgentProfiles(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	os.MkdirAll(filepath.Join(dir, ".config", "tmux-agent"), 0755)
+	os.WriteFile(filepath.Join(dir, ".config", "tmux-agent", "config.json"), []byte(`{
+  "max_concurrent_agents": 4,
+  "agents": {"codex": {"max_concurrent": 2}}
+}`), 0644)
+
+	cfg := loadConfig()
+	if cfg.DefaultAgent != "claude" {
+		t.Errorf("expected default agent to stay 'claude', got %q", cfg.DefaultAgent)
+	}
+	if cfg.MaxConcurrentAgents != 4 {
+		t.Errorf("expected max_concurrent_agents 4, got %d", cfg.MaxConcurrentAgents)
+	}
+	if cfg.agent("codex").MaxConcurrent != 2 {
+		t.Errorf("expected codex max_concurrent 2, got %+v", cfg.agent("codex"))
+	}
+	if cfg.agent("unknown").MaxConcurrent != 0 {
+		t.Errorf("expected empty profile for unknown agent")
+	}
+}
+
+func TestParseGlobalFlags_Container(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	orig := tmuxRunner
+	defer func() { tmuxRunner = orig }()
+
+	rest, handled := parseGlobalFlags([]string{"--container", "sandbox", "panes"})
+	if handled || len(rest) != 1 || rest[0] != "panes" {
+		t.Errorf("unexpected result: %v, %v", rest, handled)
+	}
+	if d, ok := tmuxRunner.(runner.Docker); !ok || d.Container != "sandbox" {
+		t.Errorf("expected docker runner, got %#v", tmuxRunner)
+	}
+
+	// --container in prompt text is part of the text.
+	tmuxRunner = orig
+	args := []string{"send", "%3", "--", "run", "it", "with", "--container", "foo"}
+	rest, _ = parseGlobalFlags(args)
+	if !reflect.DeepEqual(rest, args) {
+		t.Errorf("unexpected remaining args: %v", rest)
+	}
+	if _, ok := tmuxRunner.(runner.Docker); ok {
+		t.Errorf("expected --container after the subcommand to be ignored, got %#v", tmuxRunner)
+	}
+}
+
+func TestParseGlobalFlags_ReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	orig := tmuxRunner
+	defer func() { tmuxRunner = orig; readOnly = false }()
+
+	rest, _ := parseGlobalFlags([]string{"--read-only", "panes"})
+	if !readOnly || len(rest) != 1 || rest[0] != "panes" {
+		t.Errorf("unexpected result: %v, readOnly=%v", rest, readOnly)
+	}
+	if _, ok := tmuxRunner.(runner.ReadOnly); !ok {
+		t.Errorf("expected read-only runner, got %#v", tmuxRunner)
+	}
+	// --container stays visible through the wrapper, for ps.
+	parseGlobalFlags([]string{"--container", "sandbox", "--read-only", "panes"})
+	if _, ok := runner.Unwrap(tmuxRunner).(runner.Docker); !ok {
+		t.Errorf("expected the docker runner under read-only, got %#v", tmuxRunner)
+	}
+
+	// --read-only in prompt text is part of the text.
+	tmuxRunner, readOnly = orig, false
+	args := []string{"broadcast", "drop", "the", "--read-only", "check"}
+	rest, _ = parseGlobalFlags(args)
+	if readOnly || !reflect.DeepEqual(rest, args) {
+		t.Errorf("unexpected result: %v, readOnly=%v", rest, readOnly)
+	}
+
+	// The config lock applies without the flag.
+	tmuxRunner, readOnly = orig, false
+	saveConfig(&agentConfig{DefaultAgent: "claude", ReadOnly: true})
+	parseGlobalFlags([]string{"panes"})
+	if !readOnly {
+		t.Error("expected read_only in config to enable read-only mode")
+	}
+}
+
+func TestParseGlobalFlags_JSON(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer func() { jsonOutput = false }()
+
+	rest, _ := parseGlobalFlags([]string{"--json", "quota"})
+	if !jsonOutput || len(rest) != 1 || rest[0] != "quota" {
+		t.Errorf("unexpected result: %v, jsonOutput=%v", rest, jsonOutput)
+	}
+
+	// After the command, for the commands that print JSON.
+	for _, args := range [][]string{
+		{"status", "--json"},
+		{"capture", "%5", "--json", "--lines", "50"},
+		{"workspace", "owner/repo", "--json"},
+		{"watch", "--once", "--json"},
+	} {
+		jsonOutput = false
+		rest, _ := parseGlobalFlags(args)
+		if !jsonOutput || slices.Contains(rest, "--json") {
+			t.Errorf("parseGlobalFlags(%q) = %q, jsonOutput=%v", args, rest, jsonOutput)
+		}
+	}
+
+	jsonOutput = false
+	args := []string{"send", "%3", "--", "explain", "the", "--json", "flag"}
+	rest, _ = parseGlobalFlags(args)
+	if jsonOutput || !reflect.DeepEqual(rest, args) {
+		t.Errorf("unexpected result: %v, jsonOutput=%v", rest, jsonOutput)
+	}
+}
+
+func TestIdleThresholds(t *testing.T) {
+	cfg := &agentConfig{Agents: map[string]*agentProfile{
+		"codex":  {IdleThreshold: "25m"},
+		"claude": {MaxConcurrent: 2},
+	}}
+	threshold, err := cfg.idleThresholds(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := threshold("codex"); got != 25*time.Minute {
+		t.Errorf("codex: got %v, want 25m", got)
+	}
+	if got := threshold("claude"); got != defaultIdleThreshold {
+		t.Errorf("claude: got %v, want default", got)
+	}
+
+	threshold, _ = cfg.idleThresholds(time.Minute)
+	if got := threshold("codex"); got != time.Minute {
+		t.Errorf("explicit --idle should win, got %v", got)
+	}
+
+	cfg.Agents["codex"].IdleThreshold = "soon"
+	if _, err := cfg.idleThresholds(0); err == nil {
+		t.Error("expected error for invalid idle_threshold")
+	}
+}
+
+func TestParseGlobalFlags_Text(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer logLevel.Set(slog.LevelInfo)
+
+	args := []string{"send", "%3", "set", "--log-level", "to", "debug"}
+	rest, handled := parseGlobalFlags(append([]string{"--log-level", "warn"}, args...))
+	if handled || !reflect.DeepEqual(rest, args) {
+		t.Errorf("unexpected result: %v, %v", rest, handled)
+	}
+	if logLevel.Level() != slog.LevelWarn {
+		t.Errorf("expected level warn, got %v", logLevel.Level())
+	}
+
+	// Global flags may follow the subcommand, up to the text or "--".
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"send", "%3", "--log-level", "debug", "hi"}, []string{"send", "%3", "hi"}},
+		{[]string{"s", "--panes", "%1,%2", "--log-level", "debug", "hi"}, []string{"s", "--panes", "%1,%2", "hi"}},
+		{[]string{"capture", "%3", "--log-level", "debug", "--lines", "5"}, []string{"capture", "%3", "--lines", "5"}},
+		{[]string{"broadcast", "--idle-only", "--log-level", "debug", "a", "--log-level", "b"}, []string{"broadcast", "--idle-only", "a", "--log-level", "b"}},
+		{[]string{"deny", "%3", "--force", "--log-level", "debug", "use", "--log-level"}, []string{"deny", "%3", "--force", "use", "--log-level"}},
+		{[]string{"task", "add", "drop", "--log-level"}, []string{"task", "add", "drop", "--log-level"}},
+		{[]string{"play", "x.yaml", "--", "--log-level", "debug"}, []string{"play", "x.yaml", "--", "--log-level", "debug"}},
+	}
+	for _, tt := range tests {
+		logLevel.Set(slog.LevelInfo)
+		rest, _ := parseGlobalFlags(tt.args)
+		if !reflect.DeepEqual(rest, tt.want) {
+			t.Errorf("parseGlobalFlags(%q) = %q, want %q", tt.args, rest, tt.want)
+		}
+	}
+}
+
+func TestParseGlobalFlags_AfterSubcommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	orig := tmuxRunner
+	defer func() { tmuxRunner, readOnly, jsonOutput, activeAgent = orig, false, false, defaultAgentCommand }()
+
+	rest, _ := parseGlobalFlags([]string{"create", "--codex"})
+	if activeAgent != "codex" || !reflect.DeepEqual(rest, []string{"create"}) {
+		t.Errorf("unexpected result: %v, agent %s", rest, activeAgent)
+	}
+	rest, _ = parseGlobalFlags([]string{"status", "--read-only", "--json"})
+	if !readOnly || !jsonOutput || !reflect.DeepEqual(rest, []string{"status"}) {
+		t.Errorf("unexpected result: %v, readOnly=%v, jsonOutput=%v", rest, readOnly, jsonOutput)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	// confirmInput is where --confirm reads the answer from.
+	confirmInput io.Reader = os.Stdin
+	// confirmOutput is where --confirm lists the panes and asks, stderr so
+	// the question stays out of --json output.
+	confirmOutput io.Writer = os.Stderr
+)
+
+// errAborted is returned when the answer to a confirmation is not yes.
+var errAborted = errors.New("aborted")
+
+// parseConfirmFlag handles arg if it is --confirm or --yes: --confirm asks
+// before acting, --yes does not even when confirm_destructive is set.
+func parseConfirmFlag(arg string, confirm *bool) bool {
+	switch arg {
+	case "--confirm":
+		*confirm = true
+	case "--yes":
+		*confirm = false
+	default:
+		return false
+	}
+	return true
+}
+
+// tmuxSocketPath returns the socket of the tmux server the panes are on, or
+// "" if tmux does not say.
+func tmuxSocketPath() string {
+	out, err := tmuxRunner.Run("display-message", "-p", "#{socket_path}")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// confirmColumns are the columns of the pane list confirmPanes shows.
+var confirmColumns = []tableColumn{
+	{Title: "PANE"},
+	{Title: "WINDOW"},
+	{Title: "AGENT"},
+	{Title: "REPO", Min: 10, Optional: true},
+	{Title: "BRANCH", Min: 10, Optional: true},
+	{Title: "TITLE", Min: 10, Optional: true},
+}
+
+// canAsk reports whether a question can be answered on r: false for a
+// file that is not a terminal, such as stdin in a script or cron job.
+func canAsk(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return true
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmPanes lists the panes action (e.g. "Kill") is about to hit, with
+// the tmux server they are on, and asks y/N. It returns errAborted unless
+// the answer is yes, and fails without asking when stdin is not a terminal.
+func confirmPanes(action string, panes []paneInfo) error {
+	if !canAsk(confirmInput) {
+		return fmt.Errorf("%w: %s %d pane(s) needs confirmation, but stdin is not a terminal (pass --yes to skip the question)",
+			errAborted, strings.ToLower(action), len(panes))
+	}
+	server := ""
+	if socket := tmuxSocketPath(); socket != "" {
+		server = " on tmux server " + socket
+	}
+	fmt.Fprintf(confirmOutput, "%s %d pane(s)%s:\n", action, len(panes), server)
+	var rows [][]string
+	for i := range panes {
+		p := &panes[i]
+		rows = append(rows, []string{p.ID, orDash(paneLocation(p)), orDash(p.Command),
+			orDash(shortDir(p.Dir)), orDash(gitBranch(p.Dir)), orDash(p.Title)})
+	}
+	width, _ := terminalSize()
+	renderTable(confirmOutput, width, confirmColumns, rows)
+	fmt.Fprintf(confirmOutput, "%s? [y/N] ", action)
+	answer, _ := bufio.NewReader(confirmInput).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	fmt.Fprintln(confirmOutput, "Aborted")
+	return errAborted
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestConfirmPanes(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "codex", Session: "work", Dir: "/src/github.com/owner/name", Title: "fix tests"},
+		&runner.FakePane{ID: "%2", Command: "claude", Session: "work"},
+	)
+	fake.SocketPath = "/tmp/tmux-1000/default"
+	t.Setenv("HOME", t.TempDir())
+	origIn, origOut, origGrace := confirmInput, confirmOutput, killGracePeriod
+	killGracePeriod = 0
+	defer func() { confirmInput, confirmOutput, killGracePeriod = origIn, origOut, origGrace }()
+	var prompt bytes.Buffer
+	confirmOutput = &prompt
+
+	confirmInput = strings.NewReader("n\n")
+	if err := runKillAll([]string{"--confirm", "--force"}, io.Discard); !errors.Is(err, errAborted) {
+		t.Fatalf("expected the kill to be aborted, got %v", err)
+	}
+	if fake.Pane("%1") == nil || fake.Pane("%2") == nil {
+		t.Fatal("expected no pane to be killed after no")
+	}
+	for _, want := range []string{"/tmp/tmux-1000/default", "owner/name", "fix tests", "[y/N]"} {
+		if !strings.Contains(prompt.String(), want) {
+			t.Errorf("expected the question to show %q, got %q", want, prompt.String())
+		}
+	}
+
+	// confirm_destructive asks by default; --yes skips the question.
+	saveConfig(&agentConfig{ConfirmDestructive: true})
+	confirmInput = strings.NewReader("")
+	if err := runKill([]string{"%1", "--force"}, io.Discard); !errors.Is(err, errAborted) {
+		t.Errorf("expected confirm_destructive to ask, got %v", err)
+	}
+	if err := runKill([]string{"%1", "--force", "--yes"}, io.Discard); err != nil || fake.Pane("%1") != nil {
+		t.Errorf("expected --yes to kill without asking, got %v", err)
+	}
+	confirmInput = strings.NewReader("y\n")
+	if err := runKill([]string{"--agent", "claude", "--force"}, io.Discard); err != nil || fake.Pane("%2") != nil {
+		t.Errorf("expected yes to kill, got %v", err)
+	}
+}
+
+func TestConfirmPanes_NoTerminal(t *testing.T) {
+	useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "codex"})
+	t.Setenv("HOME", t.TempDir())
+	origIn, origOut := confirmInput, confirmOutput
+	defer func() { confirmInput, confirmOutput = origIn, origOut }()
+	confirmOutput = io.Discard
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	w.Close()
+	confirmInput = r
+	err = runKillAll([]string{"--confirm", "--force"}, io.Discard)
+	if !errors.Is(err, errAborted) || !strings.Contains(err.Error(), "stdin is not a terminal") {
+		t.Errorf("expected a clear error without a terminal, got %v", err)
+	}
+}
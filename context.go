@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultContextWarning is the context left, in percent, below which watch
+// warns about a pane.
+const defaultContextWarning = 15
+
+// contextLeftRes match the context indicators of claude ("Context left
+// until auto-compact: 18%", "Context low (8% remaining)") and codex ("18%
+// context left"). The first group is the percentage left.
+var contextLeftRes = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)context left[^\d%]{0,30}?(\d{1,3})%`),
+	regexp.MustCompile(`(?i)(\d{1,3})%\s+(?:of\s+)?context left`),
+	regexp.MustCompile(`(?i)context low \((\d{1,3})% remaining\)`),
+}
+
+// contextLeft returns the percentage of its context window the agent says
+// it has left, from the last line of output that shows it.
+func contextLeft(output string) (int, bool) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		for _, re := range contextLeftRes {
+			m := re.FindStringSubmatch(lines[i])
+			if m == nil {
+				continue
+			}
+			if pct, err := strconv.Atoi(m[1]); err == nil && pct <= 100 {
+				return pct, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// formatContext renders a context percentage for the CONTEXT column, or ""
+// when the agent did not show one.
+func formatContext(pct int, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d%%", pct)
+}
+
+// contextWarning returns the context percentage below which watch warns,
+// or 0 when the warning is disabled.
+func (c *agentConfig) contextWarning() int {
+	switch {
+	case c.ContextWarning < 0:
+		return 0
+	case c.ContextWarning == 0:
+		return defaultContextWarning
+	}
+	return c.ContextWarning
+}
+
+// checkContext warns once when a pane's context left drops below the
+// configured threshold: it logs the context_low event and runs its hook and
+// notifiers. Compacting brings the percentage back up, and the next drop
+// warns again.
+func (wt *watcher) checkContext(p *paneInfo, output string) {
+	pct, ok := contextLeft(output)
+	if !ok || wt.contextWarning == 0 {
+		return
+	}
+	if pct >= wt.contextWarning {
+		delete(wt.contextLow, p.ID)
+		return
+	}
+	if wt.contextLow[p.ID] {
+		return
+	}
+	wt.contextLow[p.ID] = true
+	msg := fmt.Sprintf("%d%% context left", pct)
+	wt.logger.Warn("pane context low", append(paneAttrs(p), "event", eventContextLow, "context_left", pct)...)
+	wt.logEvent(newPaneEvent(eventContextLow, p, wt.states[p.ID]))
+	wt.runHook(eventContextLow, p, msg)
+	wt.notifyChat(eventContextLow, p, msg)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestContextLeft(t *testing.T) {
+	tests := []struct {
+		output string
+		want   int
+		ok     bool
+	}{
+		{"> \n  ? for shortcuts                Context left until auto-compact: 18%", 18, true},
+		{"Context low (8% remaining) · Run /compact to compact & continue", 8, true},
+		{"› \n  42% context left · ? for shortcuts", 42, true},
+		{"Context left until auto-compact: 30%\n...\nContext left until auto-compact: 12%", 12, true},
+		{"Tests: 100% passing", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := contextLeft(tt.output)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("contextLeft(%q) = %d, %v, want %d, %v", tt.output, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRunStatus_Context(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "codex", Output: "working\n› \n  23% context left"},
+		&runner.FakePane{ID: "%2", Command: "claude", Output: "working"},
+	)
+	var buf bytes.Buffer
+	if err := runStatus([]string{"--width", "0"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "CONTEXT") || !strings.Contains(buf.String(), "23%") {
+		t.Errorf("expected a context column:\n%s", buf.String())
+	}
+}
+
+func TestWatcherScan_ContextLow(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "Context left until auto-compact: 40%"})
+	saveConfig(&agentConfig{ContextWarning: 20})
+	var events bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Hour }, newLogger(io.Discard, "test"))
+	wt.events = &events
+
+	wt.scan()
+	fake.Pane("%1").Output = "Context left until auto-compact: 12%"
+	wt.scan()
+	fake.Pane("%1").Output = "Context left until auto-compact: 9%"
+	wt.scan()
+	if n := strings.Count(events.String(), `"event":"context_low"`); n != 1 {
+		t.Fatalf("expected one context_low event, got %d:\n%s", n, events.String())
+	}
+
+	// After compacting, the next drop warns again.
+	fake.Pane("%1").Output = "Context left until auto-compact: 85%"
+	wt.scan()
+	fake.Pane("%1").Output = "Context left until auto-compact: 15%"
+	wt.scan()
+	if n := strings.Count(events.String(), `"event":"context_low"`); n != 2 {
+		t.Errorf("expected a second context_low event, got %d", n)
+	}
+}
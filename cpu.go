@@ -0,0 +1,186 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultBusyCPUPercent is the CPU usage above which an idle-looking pane
+// is reported as busy(cpu).
+const defaultBusyCPUPercent = 20.0
+
+// cpuSampleInterval is how far apart the two process table samples that
+// CPU usage is measured over are taken, when no earlier sample is recent.
+var cpuSampleInterval = time.Second
+
+// cpuUsage maps a process ID to its children and %CPU.
+type cpuUsage struct {
+	children map[string][]string
+	percent  map[string]float64
+}
+
+// cpuTimes is a snapshot of the process table: each process's parent and
+// the CPU time it has used since it started.
+type cpuTimes struct {
+	parent  map[string]string
+	seconds map[string]float64
+	at      time.Time
+}
+
+// parseCPUTimes parses `ps -o pid=,ppid=,time=` output, taken at at.
+func parseCPUTimes(psOutput string, at time.Time) *cpuTimes {
+	t := &cpuTimes{parent: make(map[string]string), seconds: make(map[string]float64), at: at}
+	for _, line := range strings.Split(strings.TrimSpace(psOutput), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		secs, ok := parseCPUTime(fields[2])
+		if !ok {
+			continue
+		}
+		t.parent[fields[0]] = fields[1]
+		t.seconds[fields[0]] = secs
+	}
+	return t
+}
+
+// parseCPUTime parses a ps CPU time, "[dd-]hh:mm:ss" or "m:ss.ss", into
+// seconds.
+func parseCPUTime(s string) (float64, bool) {
+	days := 0.0
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, false
+		}
+		days, s = float64(n), rest
+	}
+	secs := 0.0
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, false
+		}
+		secs = secs*60 + n
+	}
+	return days*86400 + secs, true
+}
+
+// usageSince returns each process's %CPU between prev and t: the CPU time
+// it used in between over the time that passed. Processes started in
+// between count all of their CPU time.
+func (t *cpuTimes) usageSince(prev *cpuTimes) *cpuUsage {
+	u := &cpuUsage{children: make(map[string][]string), percent: make(map[string]float64)}
+	elapsed := t.at.Sub(prev.at).Seconds()
+	for pid, secs := range t.seconds {
+		u.children[t.parent[pid]] = append(u.children[t.parent[pid]], pid)
+		if elapsed > 0 {
+			u.percent[pid] = max(secs-prev.seconds[pid], 0) / elapsed * 100
+		}
+	}
+	return u
+}
+
+// tree returns the %CPU of pid and all of its descendants.
+func (u *cpuUsage) tree(pid string) float64 {
+	total := u.percent[pid]
+	for _, child := range u.children[pid] {
+		total += u.tree(child)
+	}
+	return total
+}
+
+// readCPUTimes reads the process table's CPU times.
+func readCPUTimes() (*cpuTimes, error) {
+	out, err := listProcesses("pid=,ppid=,time=")
+	if err != nil {
+		return nil, err
+	}
+	return parseCPUTimes(string(out), time.Now()), nil
+}
+
+// lastCPUTimes is the previous process table sample, which a long-running
+// command such as watch measures the next one against instead of sampling
+// twice.
+var (
+	lastCPUTimes   *cpuTimes
+	lastCPUTimesMu sync.Mutex
+)
+
+// sampleCPUUsage measures the CPU usage of every process since the last
+// sample, over at least cpuSampleInterval, taking a first sample when there
+// is no recent one. ps reports %CPU averaged over each process's
+// lifetime, so CPU time is sampled twice instead.
+func sampleCPUUsage() (*cpuUsage, error) {
+	lastCPUTimesMu.Lock()
+	defer lastCPUTimesMu.Unlock()
+	prev := lastCPUTimes
+	if prev == nil || time.Since(prev.at) > time.Minute {
+		var err error
+		if prev, err = readCPUTimes(); err != nil {
+			return nil, err
+		}
+	}
+	time.Sleep(cpuSampleInterval - time.Since(prev.at))
+	cur, err := readCPUTimes()
+	if err != nil {
+		return nil, err
+	}
+	lastCPUTimes = cur
+	return cur.usageSince(prev), nil
+}
+
+// cpuSampleFn reads the process table. It can be replaced in tests.
+var cpuSampleFn = sampleCPUUsage
+
+// busyChecker finds idle-looking panes whose processes are still working.
+// The process table is read at most once, when first needed.
+type busyChecker struct {
+	percent float64
+	usage   *cpuUsage
+	loaded  bool
+}
+
+// busyChecker returns a checker using busy_cpu_percent. A negative value
+// disables CPU sampling.
+func (c *agentConfig) busyChecker() *busyChecker {
+	pct := c.BusyCPUPercent
+	if pct == 0 {
+		pct = defaultBusyCPUPercent
+	}
+	return &busyChecker{percent: pct}
+}
+
+// fresh returns a checker with the same settings that will read the
+// process table again.
+func (b *busyChecker) fresh() *busyChecker {
+	return &busyChecker{percent: b.percent}
+}
+
+// busy reports whether the pane's process tree is using at least the
+// configured CPU percentage.
+func (b *busyChecker) busy(p *paneInfo) bool {
+	if b == nil || b.percent < 0 || p.PID == "" {
+		return false
+	}
+	if !b.loaded {
+		b.loaded = true
+		b.usage, _ = cpuSampleFn()
+	}
+	return b.usage != nil && b.usage.tree(p.PID) >= b.percent
+}
+
+// paneState classifies a pane as active (output changed recently),
+// busy(cpu) (no output, but its processes are using CPU) or idle.
+func paneState(p *paneInfo, threshold time.Duration, busy *busyChecker) string {
+	if !detectIdle(p, threshold) {
+		return stateActive
+	}
+	if busy.busy(p) {
+		return stateBusyCPU
+	}
+	return stateIdle
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+// testPSBefore and testPSAfter are CPU times 100 seconds apart.
+const testPSBefore = `    1     0 00:00:00
+  100     1 00:10:00
+  101   100 1-02:00:00
+  102   101 00:00:15
+  200     1 00:00:00
+  201   200 00:00:00
+`
+
+const testPSAfter = `    1     0 00:00:00
+  100     1 00:10:01
+  101   100 1-02:00:02
+  102   101 00:01:40
+  103   102 00:00:30
+  200     1 00:00:02
+  201   200 00:00:00
+`
+
+// testUsage returns the CPU usage between testPSBefore and testPSAfter.
+func testUsage() *cpuUsage {
+	now := time.Now()
+	before := parseCPUTimes(testPSBefore, now.Add(-100*time.Second))
+	return parseCPUTimes(testPSAfter, now).usageSince(before)
+}
+
+func TestParseCPUTime(t *testing.T) {
+	tests := map[string]float64{"00:01:40": 100, "1-02:00:00": 93600, "0:12.50": 12.5, "bad": -1}
+	for in, want := range tests {
+		got, ok := parseCPUTime(in)
+		if !ok {
+			got = -1
+		}
+		if got != want {
+			t.Errorf("parseCPUTime(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestCPUUsageTree(t *testing.T) {
+	u := testUsage()
+	if got := u.tree("100"); got != 118 {
+		t.Errorf("tree(100) = %v, want 118", got)
+	}
+	if got := u.tree("200"); got != 2 {
+		t.Errorf("tree(200) = %v, want 2", got)
+	}
+	if got := u.tree("999"); got != 0 {
+		t.Errorf("tree(999) = %v, want 0", got)
+	}
+}
+
+func TestPaneState(t *testing.T) {
+	samples := 0
+	orig := cpuSampleFn
+	cpuSampleFn = func() (*cpuUsage, error) { samples++; return testUsage(), nil }
+	defer func() { cpuSampleFn = orig }()
+
+	quiet := time.Now().Add(-time.Hour)
+	busy := (&agentConfig{}).busyChecker()
+	tests := []struct {
+		pane paneInfo
+		want string
+	}{
+		{paneInfo{PID: "100", LastChangeAt: time.Now()}, stateActive},
+		{paneInfo{PID: "100", LastChangeAt: quiet}, stateBusyCPU},
+		{paneInfo{PID: "200", LastChangeAt: quiet}, stateIdle},
+	}
+	for _, tt := range tests {
+		if got := paneState(&tt.pane, 10*time.Minute, busy); got != tt.want {
+			t.Errorf("pane %s: got %s, want %s", tt.pane.PID, got, tt.want)
+		}
+	}
+	if samples != 1 {
+		t.Errorf("expected one ps sample, got %d", samples)
+	}
+
+	disabled := (&agentConfig{BusyCPUPercent: -1}).busyChecker()
+	if got := paneState(&tests[1].pane, 10*time.Minute, disabled); got != stateIdle {
+		t.Errorf("disabled: got %s, want idle", got)
+	}
+	strict := (&agentConfig{BusyCPUPercent: 150}).busyChecker()
+	if got := paneState(&tests[1].pane, 10*time.Minute, strict); got != stateIdle {
+		t.Errorf("busy_cpu_percent 150: got %s, want idle", got)
+	}
+}
+
+func TestRunStatus_BusyCPU(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", PID: 100, Activity: time.Now().Add(-time.Hour)},
+		&runner.FakePane{ID: "%2", Command: "codex", PID: 200, Activity: time.Now().Add(-time.Hour)},
+	)
+	cpuSampleFn = func() (*cpuUsage, error) { return testUsage(), nil }
+
+	var buf bytes.Buffer
+	if err := runStatus([]string{"--idle-backend", "tmux"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "busy(cpu)") {
+		t.Errorf("expected busy(cpu) pane:\n%s", buf.String())
+	}
+	buf.Reset()
+	runStatus([]string{"--short", "--idle-backend", "tmux"}, &buf)
+	if !strings.Contains(buf.String(), "0 active, 1 busy(cpu), 1 idle") {
+		t.Errorf("unexpected summary: %s", buf.String())
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// dashboardRefreshInterval is how often the dashboard re-reads pane state.
+const dashboardRefreshInterval = 2 * time.Second
+
+// dashboardBranchTTL is how long looked-up git branches are reused.
+const dashboardBranchTTL = 30 * time.Second
+
+// dashboardColumns are the columns of the dashboard table.
+var dashboardColumns = []tableColumn{
+	{Title: "PANE"},
+	{Title: "AGENT"},
+	{Title: "STATUS"},
+	{Title: "REPO", Min: 8},
+	{Title: "BRANCH", Min: 8, Optional: true},
+	{Title: "LAST OUTPUT", Min: 10, Optional: true},
+}
+
+// dashboard holds what the dashboard keeps between refreshes.
+type dashboard struct {
+	threshold func(agent string) time.Duration
+	backend   string
+	busy      *busyChecker
+	notices   *noticeDetector
+	branches  *branchCache
+}
+
+// newDashboard returns a dashboard using cfg's idle and notice settings.
+func newDashboard(cfg *agentConfig, idle time.Duration) (*dashboard, error) {
+	threshold, err := cfg.idleThresholds(idle)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := cfg.idleBackend("")
+	if err != nil {
+		return nil, err
+	}
+	return &dashboard{
+		threshold: threshold,
+		backend:   backend,
+		busy:      cfg.busyChecker(),
+		notices:   newNoticeDetector(cfg),
+		branches:  newBranchCache(dashboardBranchTTL),
+	}, nil
+}
+
+// refresh lists agent panes and returns them with their table rows.
+func (d *dashboard) refresh() ([]paneInfo, [][]string, error) {
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return nil, nil, err
+	}
+	trackPanes(panes, true)
+	if d.backend == idleBackendTmux {
+		if err := applyPaneActivity(panes); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	busy := d.busy.fresh()
+	rows := make([][]string, len(panes))
+	for i := range panes {
+		p := &panes[i]
+		state := paneState(p, d.threshold(p.Command), busy)
+		last := truncateLastLine(p.LastOutput, maxLastOutputWidth)
+		notice, msg, err := d.notices.detect(p, p.LastOutput)
+		if err != nil {
+			return nil, nil, err
+		}
+		if notice != "" {
+			state, last = notice, truncateWidth(msg, maxLastOutputWidth)
+		}
+		rows[i] = []string{p.ID, p.Command, state, shortDir(p.Dir), d.branches.get(p.Dir), last}
+	}
+	return panes, rows, nil
+}
+
+// renderDashboard draws the pane table with the selected row highlighted.
+// The last line shows message, or the key help when there is none.
+func renderDashboard(rows [][]string, sel, width, height int, message string) string {
+	var table strings.Builder
+	renderTable(&table, width, dashboardColumns, rows)
+	lines := strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n")
+
+	var sb strings.Builder
+	sb.WriteString(ansiClear)
+	sb.WriteString(ansiBold + fitString(lines[0], width) + ansiReset + "\n")
+	body := lines[1:]
+	visible := max(height-2, 1)
+	start := 0
+	if sel >= visible {
+		start = sel - visible + 1
+	}
+	for i := start; i < len(body) && i < start+visible; i++ {
+		line := fitString(body[i], width)
+		if i == sel {
+			line = ansiReverse + line + ansiReset
+		}
+		sb.WriteString(line + "\n")
+	}
+	if len(body) == 0 {
+		sb.WriteString("No coding agent panes found\n")
+	}
+	footer := paneKeysHelp
+	if message != "" {
+		footer = message
+	}
+	sb.WriteString(fitString(footer, width))
+	return sb.String()
+}
+
+// runDashboard shows a live table of agent panes with keys to act on the
+// selected one.
+func runDashboard(args []string, w io.Writer) error {
+	var idle time.Duration
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--idle" && i+1 < len(args):
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil {
+				return fmt.Errorf("invalid --idle value: %s", args[i])
+			}
+			idle = d
+		default:
+			return fmt.Errorf("usage: tmux-agent dashboard [--idle duration]")
+		}
+	}
+	d, err := newDashboard(loadConfig(), idle)
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	load := func() ([]paneInfo, error) {
+		var panes []paneInfo
+		var err error
+		panes, rows, err = d.refresh()
+		return panes, err
+	}
+	render := func(_ []paneInfo, sel, width, height int, message string) string {
+		return renderDashboard(rows, sel, width, height, message)
+	}
+	return runPaneList(w, dashboardRefreshInterval, false, load, render)
+}
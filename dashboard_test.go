@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRenderDashboard(t *testing.T) {
+	rows := [][]string{
+		{"%1", "claude", "active", "~/api", "main", "Running tests"},
+		{"%2", "codex", "idle", "~/web", "fix-login", "Done."},
+		{"%3", "claude", "waiting", "~/cli", "main", "Allow edit? (y/n)"},
+	}
+	out := renderDashboard(rows, 1, 100, 10, "")
+	for _, want := range []string{"PANE", "BRANCH", "LAST OUTPUT", "fix-login", "Allow edit?", "q quit"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in dashboard, got:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(out, ansiReverse+"%2 ") {
+		t.Errorf("expected the selected pane to be highlighted, got:\n%s", out)
+	}
+
+	// Only the rows that fit are shown, scrolled to keep the selection visible.
+	out = renderDashboard(rows, 2, 100, 4, "killed %4")
+	if strings.Contains(out, "%1 ") || !strings.Contains(out, "%3 ") {
+		t.Errorf("expected the table to scroll to the selection, got:\n%s", out)
+	}
+	if !strings.HasSuffix(strings.TrimRight(out, " "), "killed %4") {
+		t.Errorf("expected the message in the footer, got:\n%s", out)
+	}
+
+	if out := renderDashboard(nil, 0, 80, 10, ""); !strings.Contains(out, "No coding agent panes found") {
+		t.Errorf("expected an empty notice, got:\n%s", out)
+	}
+}
+
+func TestDashboardRefresh(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Dir: t.TempDir(), Output: "Reading files\nRunning tests"},
+		&runner.FakePane{ID: "%2", Command: "zsh", Output: "$"},
+	)
+
+	d, err := newDashboard(&agentConfig{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	panes, rows, err := d.refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(panes) != 1 || len(rows) != 1 {
+		t.Fatalf("expected only the agent pane, got %v", rows)
+	}
+	if rows[0][0] != "%1" || rows[0][2] != "active" || rows[0][5] != "Running tests" {
+		t.Errorf("unexpected row: %q", rows[0])
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultDigestLines = 200
+
+// agentPrintArgs maps agents to the arguments that run them non-interactively
+// with a prompt, printing the response to stdout.
+var agentPrintArgs = map[string][]string{
+	"claude": {"-p"},
+	"codex":  {"exec"},
+}
+
+// digestEntry holds the information reported for one pane.
+type digestEntry struct {
+	Pane      paneInfo
+	Repo      string
+	Branch    string
+	Branches  []string
+	Commits   []string
+	Summary   []string
+	Questions []string
+	Tasks     []*task
+}
+
+// summarizeFn produces a summary of a transcript. It can be replaced in tests.
+var summarizeFn = summarizeWithAgent
+
+// summarizeWithAgent asks the given agent to summarize a transcript.
+func summarizeWithAgent(agent, transcript string) (string, error) {
+	printArgs, ok := agentPrintArgs[agent]
+	if !ok {
+		return "", fmt.Errorf("agent %s has no non-interactive mode", agent)
+	}
+	prompt := "Summarize what the coding agent did in this terminal transcript as 3-5 short " +
+		"standup-style bullet points. Mention unfinished work and anything it is waiting on.\n\n" +
+		transcript
+	args := append(append([]string{}, printArgs...), prompt)
+	out, err := exec.Command(agent, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", agent, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// lastLines returns up to n non-empty trailing lines of text.
+func lastLines(text string, n int) []string {
+	var result []string
+	lines := strings.Split(text, "\n")
+	for i := len(lines) - 1; i >= 0 && len(result) < n; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			result = append([]string{line}, result...)
+		}
+	}
+	return result
+}
+
+// extractQuestions returns distinct transcript lines that look like questions,
+// keeping the most recent ones.
+func extractQuestions(text string, max int) []string {
+	seen := make(map[string]bool)
+	var questions []string
+	lines := strings.Split(text, "\n")
+	for i := len(lines) - 1; i >= 0 && len(questions) < max; i-- {
+		line := strings.TrimSpace(strings.TrimLeft(lines[i], "│>•*- "))
+		if len(line) < 10 || !strings.HasSuffix(line, "?") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		questions = append([]string{line}, questions...)
+	}
+	return questions
+}
+
+// gitChangedBranches returns local branches with commits since the given time.
+func gitChangedBranches(dir string, since time.Time) []string {
+	out, err := exec.Command("git", "-C", dir, "for-each-ref", "--sort=-committerdate",
+		"--format=%(refname:short) %(committerdate:unix)", "refs/heads").Output()
+	if err != nil {
+		return nil
+	}
+	var branches []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		var ts int64
+		if _, err := fmt.Sscan(fields[1], &ts); err != nil || time.Unix(ts, 0).Before(since) {
+			continue
+		}
+		branches = append(branches, fields[0])
+	}
+	return branches
+}
+
+// gitRecentCommits returns one-line commit summaries on HEAD since the given time.
+func gitRecentCommits(dir string, since time.Time, max int) []string {
+	out, err := exec.Command("git", "-C", dir, "log", "--oneline", "-n", fmt.Sprint(max),
+		"--since", since.Format(time.RFC3339)).Output()
+	if err != nil {
+		return nil
+	}
+	text := strings.TrimSpace(string(out))
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// activePanesSince returns the set of pane IDs with active samples since the given time.
+// The second result is false when no activity has been recorded at all.
+func activePanesSince(since time.Time) (map[string]bool, bool) {
+	records, err := loadActivity(since)
+	if err != nil || len(records) == 0 {
+		return nil, false
+	}
+	active := make(map[string]bool)
+	for _, r := range records {
+		if r.Kind == activitySample && (r.State == stateActive || r.State == stateBusyCPU) {
+			active[r.Pane] = true
+		}
+	}
+	return active, true
+}
+
+// runDigest writes a Markdown standup report of what each agent worked on.
+func runDigest(args []string, w io.Writer) error {
+	window := defaultReportWindow
+	out := ""
+	summarize := false
+	lines, err := parseIntFlag(args, "--lines", defaultDigestLines)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil {
+					return fmt.Errorf("invalid --since value: %s", args[i])
+				}
+				window = d
+			}
+		case "--out":
+			if i+1 < len(args) {
+				i++
+				out = args[i]
+			}
+		case "--summarize":
+			summarize = true
+		}
+	}
+	since := time.Now().Add(-window)
+
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return err
+	}
+	if active, ok := activePanesSince(since); ok {
+		var filtered []paneInfo
+		for _, p := range panes {
+			if active[p.ID] {
+				filtered = append(filtered, p)
+			}
+		}
+		panes = filtered
+	}
+	sort.Slice(panes, func(i, j int) bool { return panes[i].ID < panes[j].ID })
+
+	store, err := loadTasks()
+	if err != nil {
+		return err
+	}
+
+	var entries []digestEntry
+	for _, p := range panes {
+		e := digestEntry{Pane: p, Repo: shortDir(p.Dir), Tasks: tasksForPane(store, p.ID, since)}
+		if p.Dir != "" {
+			e.Branch = gitBranch(p.Dir)
+			e.Branches = gitChangedBranches(p.Dir, since)
+			e.Commits = gitRecentCommits(p.Dir, since, 10)
+		}
+
+		transcript, err := capturePaneOutput(p.ID, lines)
+		if err != nil {
+			transcript = ""
+		}
+		e.Questions = extractQuestions(transcript, 5)
+		if summarize && transcript != "" {
+			if summary, err := summarizeFn(p.Command, transcript); err == nil && summary != "" {
+				e.Summary = strings.Split(summary, "\n")
+			} else if err != nil {
+				slog.Warn("summarizing pane failed", append(paneAttrs(&p), "err", err)...)
+			}
+		}
+		if e.Summary == nil {
+			e.Summary = lastLines(transcript, 5)
+		}
+		entries = append(entries, e)
+	}
+
+	report := renderDigest(entries, since, time.Now())
+	if out != "" {
+		if err := os.WriteFile(out, []byte(report), 0644); err != nil {
+			return fmt.Errorf("writing digest: %w", err)
+		}
+		fmt.Fprintf(w, "Wrote digest for %d panes to %s\n", len(entries), out)
+		return nil
+	}
+	_, err = io.WriteString(w, report)
+	return err
+}
+
+// renderDigest formats digest entries as Markdown.
+func renderDigest(entries []digestEntry, since, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Agent digest — %s\n\n", now.Format("2006-01-02"))
+	fmt.Fprintf(&b, "Activity since %s.\n", since.Format("2006-01-02 15:04"))
+
+	if len(entries) == 0 {
+		b.WriteString("\nNo agent panes were active in this period.\n")
+		return b.String()
+	}
+
+	for _, e := range entries {
+		title := e.Repo
+		if title == "" {
+			title = "(unknown directory)"
+		}
+		fmt.Fprintf(&b, "\n## %s — %s (%s)\n\n", e.Pane.ID, title, e.Pane.Command)
+		if e.Branch != "" {
+			fmt.Fprintf(&b, "- **Branch:** %s\n", e.Branch)
+		}
+		if len(e.Branches) > 0 {
+			fmt.Fprintf(&b, "- **Branches changed:** %s\n", strings.Join(e.Branches, ", "))
+		}
+
+		if len(e.Tasks) > 0 {
+			b.WriteString("\n### Tasks\n\n")
+			for _, t := range e.Tasks {
+				line := fmt.Sprintf("- #%d %s — %s", t.ID, t.Title, t.Status)
+				if t.Outcome != "" {
+					line += ": " + t.Outcome
+				}
+				b.WriteString(line + "\n")
+			}
+		}
+
+		b.WriteString("\n### Worked on\n\n")
+		if len(e.Summary) == 0 {
+			b.WriteString("- (no output captured)\n")
+		}
+		for _, line := range e.Summary {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if !strings.HasPrefix(line, "- ") && !strings.HasPrefix(line, "* ") {
+				line = "- " + line
+			}
+			b.WriteString(line + "\n")
+		}
+
+		if len(e.Commits) > 0 {
+			b.WriteString("\n### Commits\n\n")
+			for _, c := range e.Commits {
+				fmt.Fprintf(&b, "- `%s`\n", c)
+			}
+		}
+
+		if len(e.Questions) > 0 {
+			b.WriteString("\n### Open questions\n\n")
+			for _, q := range e.Questions {
+				fmt.Fprintf(&b, "- %s\n", q)
+			}
+		}
+	}
+	return b.String()
+}
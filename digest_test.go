@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractQuestions(t *testing.T) {
+	text := "Working on it\n> Should I also update the README?\nok\n│ Do you want me to push the branch?\nShould I also update the README?\nwhy?\n"
+	got := extractQuestions(text, 5)
+	want := []string{"Do you want me to push the branch?", "Should I also update the README?"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("question %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	got := lastLines("a\n\nb\nc\n\n", 2)
+	if len(got) != 2 || got[0] != "b" || got[1] != "c" {
+		t.Errorf("unexpected lines: %v", got)
+	}
+}
+
+func TestRenderDigest(t *testing.T) {
+	now := time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)
+	entries := []digestEntry{{
+		Pane:      paneInfo{ID: "%3", Command: "claude"},
+		Repo:      "owner/repo",
+		Branch:    "feature",
+		Branches:  []string{"feature", "main"},
+		Commits:   []string{"abc123 Add feature"},
+		Summary:   []string{"Implemented the parser", "- Wrote tests"},
+		Questions: []string{"Should I open a PR?"},
+	}}
+
+	got := renderDigest(entries, now.Add(-24*time.Hour), now)
+	for _, want := range []string{
+		"# Agent digest — 2025-01-02",
+		"## %3 — owner/repo (claude)",
+		"**Branches changed:** feature, main",
+		"- Implemented the parser",
+		"- Wrote tests",
+		"- `abc123 Add feature`",
+		"### Open questions",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in digest, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunDigest(t *testing.T) {
+	dir := t.TempDir()
+
+	tmuxScript := filepath.Join(dir, "tmux")
+	os.WriteFile(tmuxScript, []byte(`#!/bin/sh
+case "$1" in
+  list-panes)
+    printf "%%3\tclaude\t12345\n%%5\tcodex\t12346\n"
+    ;;
+  capture-pane)
+    echo "Refactored the auth module"
+    echo "Should I also migrate the old tokens?"
+    ;;
+esac
+`), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	// Only %3 was active in the window.
+	appendActivity(activityRecord{Time: time.Now(), Kind: activitySample, Pane: "%3", State: stateActive, Span: 10})
+
+	origSummarize := summarizeFn
+	summarizeFn = func(agent, transcript string) (string, error) {
+		return "- summarized by " + agent, nil
+	}
+	defer func() { summarizeFn = origSummarize }()
+
+	var buf bytes.Buffer
+	if err := runDigest([]string{"--summarize"}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "## %3") || strings.Contains(output, "## %5") {
+		t.Errorf("expected only the active pane, got:\n%s", output)
+	}
+	if !strings.Contains(output, "- summarized by claude") {
+		t.Errorf("expected agent summary, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Should I also migrate the old tokens?") {
+		t.Errorf("expected open question, got:\n%s", output)
+	}
+}
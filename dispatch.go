@@ -0,0 +1,614 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const defaultDispatchIdle = 2 * time.Minute
+
+// taskFileItemRe matches Markdown checklist items: "- [ ] text" or "- [x] text".
+var taskFileItemRe = regexp.MustCompile(`^(\s*[-*]\s+\[)([ xX~])(\]\s+)(.+?)\s*$`)
+
+// dispatchOpts configures the dispatcher.
+type dispatchOpts struct {
+	From      string        // Markdown task file to import (empty = task store only)
+	Idle      time.Duration // how long a pane must be quiet to count as idle
+	Create    bool          // create a new pane when no idle pane is available
+	Repo      string        // owner/repo for new panes
+	Workspace bool          // create a fresh worktree per task for new panes
+	Retries   int           // times to retry a task whose output matches an error pattern
+	// PreferHeadroom skips agents at their usage limit and hands tasks to
+	// the agents furthest from their limits first (see quota).
+	PreferHeadroom bool
+}
+
+// dispatcher assigns queued tasks to idle agent panes.
+type dispatcher struct {
+	opts    dispatchOpts
+	cfg     *agentConfig
+	tracker *outputTracker
+	logger  *slog.Logger
+	// held records tasks already reported as held back (by a concurrency
+	// limit or a failed dependency), so the log is not repeated every scan.
+	held map[int]bool
+	// notice spots rate-limited panes; limited holds those seen so far.
+	notice  *noticeDetector
+	limited map[string]bool
+}
+
+// newDispatcher returns a dispatcher with empty pane state and the
+// concurrency limits from the config file.
+func newDispatcher(opts dispatchOpts, logger *slog.Logger) *dispatcher {
+	cfg := loadConfig()
+	return &dispatcher{
+		opts:    opts,
+		cfg:     cfg,
+		tracker: newOutputTracker(),
+		logger:  logger,
+		held:    make(map[int]bool),
+		notice:  newNoticeDetector(cfg),
+		limited: make(map[string]bool),
+	}
+}
+
+// allowed reports whether one more task may run on the given agent without
+// exceeding max_concurrent_agents or the agent's max_concurrent.
+func (d *dispatcher) allowed(agent string, running map[string]int, total int) (bool, string) {
+	if max := d.cfg.MaxConcurrentAgents; max > 0 && total >= max {
+		return false, fmt.Sprintf("max_concurrent_agents (%d) reached", max)
+	}
+	if max := d.cfg.agent(agent).MaxConcurrent; max > 0 && running[agent] >= max {
+		return false, fmt.Sprintf("%s max_concurrent (%d) reached", agent, max)
+	}
+	return true, ""
+}
+
+// holdBack logs (once) that a task stays queued and why.
+func (d *dispatcher) holdBack(t *task, reason string) {
+	if !d.held[t.ID] {
+		d.held[t.ID] = true
+		d.logger.Info("task waiting", "task", t.ID, "reason", reason)
+	}
+}
+
+// importTaskFile adds unchecked items from a Markdown checklist to the store.
+// Items already imported (by source key) are skipped. Returns the number added.
+func importTaskFile(path string, store *taskStore) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	known := make(map[string]bool)
+	for _, t := range store.Tasks {
+		if t.Source != "" {
+			known[t.Source] = true
+		}
+	}
+
+	added := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		m := taskFileItemRe.FindStringSubmatch(line)
+		if m == nil || m[2] != " " {
+			continue
+		}
+		source := taskSource(path, m[4])
+		if known[source] {
+			continue
+		}
+		t := store.add(m[4])
+		t.Source = source
+		known[source] = true
+		added++
+	}
+	return added, nil
+}
+
+// taskSource returns the dedupe key for a task imported from a file.
+func taskSource(path, title string) string {
+	return filepath.Base(path) + ":" + title
+}
+
+// markTaskFileItem rewrites the checkbox of the item with the given title.
+// mark is 'x' for done and '~' for in progress.
+func markTaskFileItem(path, title string, mark byte) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		m := taskFileItemRe.FindStringSubmatch(line)
+		if m != nil && m[4] == title {
+			lines[i] = m[1] + string(mark) + m[3] + m[4]
+			return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+		}
+	}
+	return nil
+}
+
+// queuedTasks returns todo tasks whose dependencies are done, highest
+// priority first and otherwise in the order they were added.
+func queuedTasks(store *taskStore) []*task {
+	var queue []*task
+	for _, t := range store.Tasks {
+		if t.Status == taskTodo && store.ready(t) {
+			queue = append(queue, t)
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].Priority > queue[j].Priority
+	})
+	return queue
+}
+
+// taskPane returns the pane a task should run in: its own pane if set,
+// otherwise the pane of its last dependency, so follow-up work lands in
+// the same session. Empty means any pane.
+func taskPane(store *taskStore, t *task) string {
+	if t.Pane != "" {
+		return t.Pane
+	}
+	for i := len(t.DependsOn) - 1; i >= 0; i-- {
+		if dep, err := store.get(t.DependsOn[i]); err == nil && dep.Pane != "" {
+			return dep.Pane
+		}
+	}
+	return ""
+}
+
+// failedDependency returns the ID of a dependency of t that failed, or 0.
+func failedDependency(store *taskStore, t *task) int {
+	for _, id := range t.DependsOn {
+		if dep, err := store.get(id); err == nil && dep.Status == taskFailed {
+			return id
+		}
+	}
+	return 0
+}
+
+// nextTask returns the next queued task to run on paneID, or nil. Tasks
+// tied to another live pane are left for that pane; an empty paneID
+// (a pane about to be created) only takes untied tasks.
+func nextTask(store *taskStore, paneID string, live map[string]*paneInfo) *task {
+	for _, t := range queuedTasks(store) {
+		pane := taskPane(store, t)
+		if pane == "" || pane == paneID || live[pane] == nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// checkLimited records whether pane p is showing a rate-limit message,
+// logging when it starts and stops. A rate-limited pane gets no new tasks,
+// and its running task is not taken for finished.
+func (d *dispatcher) checkLimited(p *paneInfo, output string) {
+	state, msg, err := d.notice.detect(p, output)
+	if err != nil {
+		d.logger.Warn("checking for rate limits failed", append(paneAttrs(p), "err", err)...)
+		return
+	}
+	switch limited := state == stateRateLimited; {
+	case limited && !d.limited[p.ID]:
+		d.limited[p.ID] = true
+		d.logger.Warn("pane rate-limited; holding back tasks", append(paneAttrs(p), "message", msg)...)
+	case !limited && d.limited[p.ID]:
+		delete(d.limited, p.ID)
+		d.logger.Info("pane no longer rate-limited", paneAttrs(p)...)
+	}
+}
+
+// tick runs one dispatch cycle: finish tasks whose panes went idle,
+// then hand queued tasks to idle panes.
+func (d *dispatcher) tick(store *taskStore) error {
+	if d.opts.From != "" {
+		if n, err := importTaskFile(d.opts.From, store); err != nil {
+			d.logger.Warn("reading task file failed", "file", d.opts.From, "err", err)
+		} else if n > 0 {
+			d.logger.Info("imported tasks", "file", d.opts.From, "count", n)
+		}
+	}
+
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return err
+	}
+	live := make(map[string]*paneInfo)
+	for i := range panes {
+		if output, err := capturePaneOutput(panes[i].ID, noticeCaptureLines); err == nil {
+			d.tracker.observe(&panes[i], output)
+			d.checkLimited(&panes[i], output)
+		}
+		live[panes[i].ID] = &panes[i]
+	}
+
+	busy := make(map[string]bool)
+	running := make(map[string]int)
+	total := 0
+	for _, t := range store.Tasks {
+		if t.Status != taskInProgress {
+			continue
+		}
+		p, ok := live[t.Pane]
+		if !ok {
+			d.finish(t, taskFailed, "pane closed")
+			continue
+		}
+		// A rate-limited agent stopped short of finishing its task.
+		if time.Since(t.StartedAt) >= d.opts.Idle && detectIdle(p, d.opts.Idle) && !d.limited[p.ID] {
+			d.complete(t)
+			continue
+		}
+		busy[t.Pane] = true
+		running[t.Agent]++
+		total++
+	}
+
+	d.logger.Debug("dispatch cycle", "panes", len(panes), "running", total, "queued", len(queuedTasks(store)))
+
+	for _, t := range store.Tasks {
+		if t.Status == taskTodo {
+			if id := failedDependency(store, t); id != 0 {
+				d.holdBack(t, fmt.Sprintf("depends on failed task #%d", id))
+			}
+		}
+	}
+
+	for _, p := range d.idleOrder(panes) {
+		if busy[p.ID] || d.limited[p.ID] || !detectIdle(p, d.opts.Idle) {
+			continue
+		}
+		t := nextTask(store, p.ID, live)
+		if t == nil {
+			continue
+		}
+		if ok, reason := d.allowed(p.Command, running, total); !ok {
+			d.holdBack(t, reason)
+			continue
+		}
+		if err := d.assign(t, p.ID, p.Command, p.Dir); err != nil {
+			continue
+		}
+		busy[p.ID] = true
+		running[p.Command]++
+		total++
+	}
+
+	if d.opts.Create {
+		paneCount := len(panes)
+		for t := nextTask(store, "", live); t != nil; t = nextTask(store, "", live) {
+			if ok, reason := d.allowed(activeAgent, running, total); !ok {
+				d.holdBack(t, reason)
+				return nil
+			}
+			if max := d.cfg.MaxConcurrentAgents; max > 0 && paneCount >= max {
+				d.holdBack(t, fmt.Sprintf("max_concurrent_agents (%d) panes exist", max))
+				return nil
+			}
+			paneID, dir, err := d.createPane(t)
+			if err != nil {
+				d.logger.Warn("creating pane failed", "task", t.ID, "err", err)
+				return nil
+			}
+			paneCount++
+			waitReady(paneID, activeAgent)
+			// The task is still todo after a failed send; stop here rather
+			// than create another pane for it, and drop the unused one.
+			if err := d.assign(t, paneID, activeAgent, dir); err != nil {
+				if err := killTmuxPane(paneID); err != nil {
+					d.logger.Warn("killing unused pane failed", "task", t.ID, "pane", paneID, "err", err)
+				}
+				return nil
+			}
+			running[activeAgent]++
+			total++
+		}
+	}
+	return nil
+}
+
+// idleOrder returns the panes in the order they are offered tasks. With
+// --prefer-headroom, panes of agents at their usage limit are left out and
+// the rest are sorted by remaining quota, most headroom first.
+func (d *dispatcher) idleOrder(panes []paneInfo) []*paneInfo {
+	var order []*paneInfo
+	if !d.opts.PreferHeadroom {
+		for i := range panes {
+			order = append(order, &panes[i])
+		}
+		return order
+	}
+	quota, err := loadQuota()
+	if err != nil {
+		d.logger.Warn("reading quota failed", "err", err)
+	}
+	now := time.Now()
+	headroom := func(agent string) int {
+		if q, ok := quota[agent]; ok {
+			return q.current(now).headroom()
+		}
+		return quotaStatus{State: quotaOK}.headroom()
+	}
+	for i := range panes {
+		if headroom(panes[i].Command) < 0 {
+			d.logger.Debug("skipping pane at usage limit", paneAttrs(&panes[i])...)
+			continue
+		}
+		order = append(order, &panes[i])
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return headroom(order[i].Command) > headroom(order[j].Command)
+	})
+	return order
+}
+
+// createPane creates a pane for a task, in a fresh worktree if configured.
+func (d *dispatcher) createPane(t *task) (string, string, error) {
+	dir := t.Dir
+	if d.opts.Repo != "" && dir == "" {
+		repoDir, err := ghqRepoDir(d.opts.Repo)
+		if err != nil {
+			return "", "", err
+		}
+		dir = repoDir
+		if d.opts.Workspace {
+			dir, err = addWorktree(repoDir, fmt.Sprintf("task-%d", t.ID))
+			if err != nil {
+				return "", "", err
+			}
+		}
+	}
+	command, err := d.cfg.launchCommand(activeAgent, "")
+	if err != nil {
+		return "", "", err
+	}
+	paneID, err := createTmuxPaneWithOpts(createPaneOpts{Command: command, Dir: dir})
+	if err != nil {
+		return "", "", err
+	}
+	renameTmuxPane(paneID, fmt.Sprintf("task-%d", t.ID))
+	d.logger.Info("created pane", "task", t.ID, "pane", paneID, "agent", activeAgent, "repo", shortDir(dir))
+	return paneID, dir, nil
+}
+
+// assign sends a task's prompt to a pane and marks it in progress. When the
+// send fails, the task is left as it was and the error returned.
+func (d *dispatcher) assign(t *task, paneID, agent, dir string) error {
+	prompt := t.Title
+	if t.LastError != "" {
+		prompt = retryPrompt(d.cfg.RetryPrompt, t)
+	}
+	if err := sendTmuxKeys(paneID, prompt); err != nil {
+		d.logger.Warn("sending task failed", "task", t.ID, "pane", paneID, "err", err)
+		return err
+	}
+	t.start(paneID)
+	delete(d.held, t.ID)
+	t.Agent = agent
+	if t.Dir == "" {
+		t.Dir = dir
+	}
+	if d.opts.From != "" && t.Source == taskSource(d.opts.From, t.Title) {
+		markTaskFileItem(d.opts.From, t.Title, '~')
+	}
+	// Treat the pane as active from now on so it is not immediately
+	// considered idle again before the agent starts producing output.
+	d.tracker.forget(paneID)
+	d.logger.Info("dispatched task", append(taskAttrs(t), "title", t.Title, "attempt", t.Attempts+1)...)
+	return nil
+}
+
+// complete finishes a task whose pane went idle, classifying the pane's
+// output with the configured success/error patterns. Failed tasks are
+// requeued on the same pane while retries remain.
+func (d *dispatcher) complete(t *task) {
+	rules, err := d.cfg.outcomeRules(t.Agent, t.Dir)
+	if err != nil {
+		d.logger.Warn("invalid outcome patterns", "err", err)
+		d.finish(t, taskDone, "")
+		return
+	}
+	output, err := capturePaneOutput(t.Pane, classifyLines)
+	if err != nil {
+		d.finish(t, taskDone, "")
+		return
+	}
+	status, excerpt := rules.classify(output)
+	if status != taskFailed {
+		d.finish(t, status, "")
+		return
+	}
+	t.LastError = excerpt
+	if t.Attempts < d.opts.Retries {
+		t.Attempts++
+		t.Status = taskTodo
+		d.logger.Info("retrying task", append(taskAttrs(t),
+			"attempt", t.Attempts+1, "max_attempts", d.opts.Retries+1, "error", firstLine(excerpt))...)
+		return
+	}
+	d.finish(t, taskFailed, firstLine(excerpt))
+	d.escalate(t)
+}
+
+// escalate reports a task that failed for good on the tmux status line.
+func (d *dispatcher) escalate(t *task) {
+	msg := fmt.Sprintf("tmux-agent: task #%d failed on pane %s after %d attempt(s): %s",
+		t.ID, t.Pane, t.Attempts+1, t.Outcome)
+	d.logger.Error("task failed", append(taskAttrs(t), "attempts", t.Attempts+1, "error", t.Outcome)...)
+	if err := displayTmuxMessage(msg); err != nil {
+		d.logger.Warn("notifying failed", "err", err)
+	}
+}
+
+// finish marks an in-progress task finished.
+func (d *dispatcher) finish(t *task, status, outcome string) {
+	t.finish(status, outcome)
+	if d.opts.From != "" && status == taskDone && t.Source == taskSource(d.opts.From, t.Title) {
+		markTaskFileItem(d.opts.From, t.Title, 'x')
+	}
+	d.logger.Info("task finished", append(taskAttrs(t), "status", status, "title", t.Title)...)
+}
+
+// snapshotTasks copies the tasks in store, by ID, so mergeTasks can tell
+// which of them a dispatch cycle changed.
+func snapshotTasks(store *taskStore) map[int]task {
+	before := make(map[int]task, len(store.Tasks))
+	for _, t := range store.Tasks {
+		before[t.ID] = *t
+	}
+	return before
+}
+
+// mergeTasks applies what a dispatch cycle did to its copy of the task
+// store (tick can take a while: it waits for new panes and sends prompts)
+// to the current store: tasks it changed replace theirs, unless they were
+// removed meanwhile, and tasks it imported are added, renumbered if other
+// commands have added tasks since. Everything else is left as the other
+// commands made it.
+func mergeTasks(current, dispatched *taskStore, before map[int]task) {
+	for _, t := range dispatched.Tasks {
+		old, ok := before[t.ID]
+		if !ok {
+			if _, err := current.get(t.ID); err == nil || t.ID < current.NextID {
+				t.ID = current.NextID
+			}
+			current.NextID = t.ID + 1
+			current.Tasks = append(current.Tasks, t)
+			continue
+		}
+		if reflect.DeepEqual(old, *t) {
+			continue
+		}
+		for i, c := range current.Tasks {
+			if c.ID == t.ID {
+				current.Tasks[i] = t
+				break
+			}
+		}
+	}
+}
+
+// runDispatch runs the dispatcher loop until interrupted.
+func runDispatch(args []string, w io.Writer) error {
+	opts := dispatchOpts{Idle: defaultDispatchIdle}
+	scanInterval := defaultScanInterval
+	once := false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--from":
+			if i+1 < len(args) {
+				i++
+				opts.From = args[i]
+			}
+		case "--scan":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil {
+					return fmt.Errorf("invalid --scan value: %s", args[i])
+				}
+				scanInterval = d
+			}
+		case "--idle":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil {
+					return fmt.Errorf("invalid --idle value: %s", args[i])
+				}
+				opts.Idle = d
+			}
+		case "--create":
+			opts.Create = true
+		case "--repo":
+			if i+1 < len(args) {
+				i++
+				opts.Repo = args[i]
+			}
+		case "--workspace":
+			opts.Workspace = true
+			opts.Create = true
+		case "--retries", "--retry":
+			// --retry is the name the flag had at first.
+			if i+1 < len(args) {
+				i++
+				n, err := strconv.Atoi(args[i])
+				if err != nil || n < 0 {
+					return fmt.Errorf("invalid %s value: %s", args[i-1], args[i])
+				}
+				opts.Retries = n
+			}
+		case "--prefer-headroom":
+			opts.PreferHeadroom = true
+		case "--once":
+			once = true
+		}
+	}
+	if opts.Workspace && opts.Repo == "" {
+		return fmt.Errorf("--workspace requires --repo")
+	}
+
+	logger := newLogger(w, "dispatch")
+	d := newDispatcher(opts, logger)
+
+	run := func() {
+		if panes, err := listTmuxPanes(); err == nil {
+			logReattach(panes, logger)
+		}
+		store, err := loadTasks()
+		if err != nil {
+			logger.Error("loading tasks failed", "err", err)
+			return
+		}
+		before := snapshotTasks(store)
+		if err := d.tick(store); err != nil {
+			logger.Warn("dispatch cycle failed", "err", err)
+		}
+		if err := updateTasks(func(current *taskStore) error {
+			mergeTasks(current, store, before)
+			return nil
+		}); err != nil {
+			logger.Error("saving tasks failed", "err", err)
+		}
+	}
+
+	if once {
+		run()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(scanInterval)
+	defer ticker.Stop()
+	logger.Info("dispatching tasks", "scan", scanInterval, "idle", opts.Idle)
+	run()
+	for {
+		select {
+		case <-ticker.C:
+			run()
+		case sig := <-sigCh:
+			logger.Info("shutting down", "signal", sig.String())
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestImportTaskFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.md")
+	os.WriteFile(path, []byte("# Backlog\n- [ ] implement parser\n- [x] already done\n* [ ] write tests\nnot a task\n"), 0644)
+
+	store := &taskStore{NextID: 1}
+	n, err := importTaskFile(path, store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 || len(store.Tasks) != 2 {
+		t.Fatalf("expected 2 imported tasks, got %d: %+v", n, store.Tasks)
+	}
+	if store.Tasks[0].Title != "implement parser" || store.Tasks[1].Title != "write tests" {
+		t.Errorf("unexpected titles: %+v", store.Tasks)
+	}
+
+	// Re-importing does not duplicate.
+	n, _ = importTaskFile(path, store)
+	if n != 0 {
+		t.Errorf("expected no new tasks on re-import, got %d", n)
+	}
+
+	if err := markTaskFileItem(path, "implement parser", 'x'); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "- [x] implement parser") {
+		t.Errorf("expected item to be checked, got:\n%s", string(data))
+	}
+}
+
+func TestDispatcherTick(t *testing.T) {
+	dir := t.TempDir()
+
+	argsFile := filepath.Join(dir, "tmux-args.txt")
+	tmuxScript := filepath.Join(dir, "tmux")
+	os.WriteFile(tmuxScript, []byte(`#!/bin/sh
+echo "$@" >> `+argsFile+`
+case "$1" in
+  list-panes)
+    printf "%%3\tclaude\t12345\t/work/a\n%%5\tcodex\t12346\t/work/b\n"
+    ;;
+  capture-pane)
+    echo "same output"
+    ;;
+esac
+`), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	store := &taskStore{NextID: 1}
+	store.add("first task")
+	store.add("second task")
+	running := store.add("running task")
+	running.start("%5")
+	running.StartedAt = time.Now().Add(-time.Hour)
+
+	var logs bytes.Buffer
+	d := newDispatcher(dispatchOpts{Idle: time.Minute}, newLogger(&logs, "test"))
+	// Both panes have shown the same output for an hour.
+	for _, id := range []string{"%3", "%5"} {
+		d.tracker.hashes[id] = outputHash("same output")
+		d.tracker.changed[id] = time.Now().Add(-time.Hour)
+	}
+
+	if err := d.tick(store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if running.Status != taskDone {
+		t.Errorf("expected running task on idle pane to be done, got %s", running.Status)
+	}
+	first, _ := store.get(1)
+	second, _ := store.get(2)
+	if first.Status != taskInProgress || first.Pane != "%3" || first.Agent != "claude" {
+		t.Errorf("expected first task on %%3, got %+v", first)
+	}
+	if second.Status != taskInProgress || second.Pane != "%5" {
+		t.Errorf("expected second task on freed pane %%5, got %+v", second)
+	}
+
+	data, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(data), "first task") || !strings.Contains(string(data), "second task") {
+		t.Errorf("expected both prompts sent, got: %s", string(data))
+	}
+	if !strings.Contains(logs.String(), `msg="dispatched task" component=test task=1 pane=%3`) {
+		t.Errorf("expected dispatch log, got: %s", logs.String())
+	}
+}
+
+func TestDispatcherTick_PaneClosed(t *testing.T) {
+	dir := t.TempDir()
+
+	tmuxScript := filepath.Join(dir, "tmux")
+	os.WriteFile(tmuxScript, []byte(`#!/bin/sh
+case "$1" in
+  list-panes)
+    printf "%%3\tclaude\t12345\n"
+    ;;
+esac
+`), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	store := &taskStore{NextID: 1}
+	orphan := store.add("orphaned")
+	orphan.start("%9")
+
+	var logs bytes.Buffer
+	d := newDispatcher(dispatchOpts{Idle: time.Minute}, newLogger(&logs, "test"))
+	if err := d.tick(store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if orphan.Status != taskFailed || orphan.Outcome != "pane closed" {
+		t.Errorf("expected task on vanished pane to fail, got %+v", orphan)
+	}
+}
+
+func TestRunDispatch_InvalidArgs(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runDispatch([]string{"--scan", "soon"}, &buf); err == nil {
+		t.Error("expected error for invalid --scan")
+	}
+	if err := runDispatch([]string{"--workspace"}, &buf); err == nil {
+		t.Error("expected error for --workspace without --repo")
+	}
+	if err := runDispatch([]string{"--retry", "-1"}, &buf); err == nil || err.Error() != "invalid --retry value: -1" {
+		t.Errorf("expected --retry to be parsed as --retries, got %v", err)
+	}
+}
+
+func TestDispatcherTick_ConcurrencyLimits(t *testing.T) {
+	dir := t.TempDir()
+
+	argsFile := filepath.Join(dir, "tmux-args.txt")
+	tmuxScript := filepath.Join(dir, "tmux")
+	os.WriteFile(tmuxScript, []byte(`#!/bin/sh
+echo "$@" >> `+argsFile+`
+case "$1" in
+  list-panes)
+    printf "%%3\tclaude\t1\n%%5\tclaude\t2\n%%7\tcodex\t3\n%%9\tcodex\t4\n"
+    ;;
+  capture-pane)
+    echo "same output"
+    ;;
+esac
+`), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	saveConfig(&agentConfig{
+		DefaultAgent:        "claude",
+		MaxConcurrentAgents: 2,
+		Agents:              map[string]*agentProfile{"claude": {MaxConcurrent: 1}},
+	})
+
+	store := &taskStore{NextID: 1}
+	for _, title := range []string{"one", "two", "three", "four"} {
+		store.add(title)
+	}
+
+	var logs bytes.Buffer
+	d := newDispatcher(dispatchOpts{Idle: time.Minute}, newLogger(&logs, "test"))
+	for _, id := range []string{"%3", "%5", "%7", "%9"} {
+		d.tracker.hashes[id] = outputHash("same output")
+		d.tracker.changed[id] = time.Now().Add(-time.Hour)
+	}
+	if err := d.tick(store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	running := map[string]int{}
+	for _, tk := range store.Tasks {
+		if tk.Status == taskInProgress {
+			running[tk.Agent]++
+		}
+	}
+	if running["claude"] != 1 || running["codex"] != 1 {
+		t.Errorf("expected one claude and one codex task running, got %v", running)
+	}
+	if strings.Count(logs.String(), `msg="task waiting" component=test task=3`) != 1 {
+		t.Errorf("expected task #3 to be held back once, got: %s", logs.String())
+	}
+
+	// A second scan does not repeat the hold-back message.
+	d.tick(store)
+	if strings.Count(logs.String(), `msg="task waiting" component=test task=3`) != 1 {
+		t.Errorf("expected hold-back message not to repeat, got: %s", logs.String())
+	}
+}
+
+func TestQueuedTasks(t *testing.T) {
+	store := &taskStore{NextID: 1}
+	impl := store.add("implement feature")
+	tests := store.add("write tests")
+	tests.DependsOn = []int{impl.ID}
+	store.add("low")
+	urgent := store.add("urgent")
+	urgent.Priority = 5
+
+	queue := queuedTasks(store)
+	var titles []string
+	for _, tk := range queue {
+		titles = append(titles, tk.Title)
+	}
+	if got := strings.Join(titles, ","); got != "urgent,implement feature,low" {
+		t.Errorf("unexpected queue order: %s", got)
+	}
+
+	impl.start("%3")
+	impl.Status = taskDone
+	if !store.ready(tests) {
+		t.Error("expected dependent task to be ready once its dependency is done")
+	}
+	if taskPane(store, tests) != "%3" {
+		t.Errorf("expected dependent task to follow its dependency's pane, got %q", taskPane(store, tests))
+	}
+
+	live := map[string]*paneInfo{"%3": {ID: "%3"}, "%5": {ID: "%5"}}
+	urgent.Status = taskDone
+	store.Tasks = store.Tasks[:2]
+	if next := nextTask(store, "%5", live); next != nil {
+		t.Errorf("expected no task for %%5, got %+v", next)
+	}
+	if next := nextTask(store, "%3", live); next != tests {
+		t.Errorf("expected dependent task on %%3, got %+v", next)
+	}
+	delete(live, "%3")
+	if next := nextTask(store, "%5", live); next != tests {
+		t.Errorf("expected dependent task to run anywhere once its pane is gone, got %+v", next)
+	}
+}
+
+func TestDispatcherTick_RetryOnError(t *testing.T) {
+	dir := t.TempDir()
+
+	argsFile := filepath.Join(dir, "tmux-args.txt")
+	tmuxScript := filepath.Join(dir, "tmux")
+	os.WriteFile(tmuxScript, []byte(`#!/bin/sh
+echo "$@" >> `+argsFile+`
+case "$1" in
+  list-panes)
+    printf "%%3\tclaude\t12345\t/work/a\n"
+    ;;
+  capture-pane)
+    echo "--- FAIL: TestParse"
+    ;;
+esac
+`), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	saveConfig(&agentConfig{
+		DefaultAgent: "claude",
+		Agents: map[string]*agentProfile{
+			"claude": {outcomePatterns: outcomePatterns{ErrorPatterns: []string{"FAIL"}}},
+		},
+	})
+
+	store := &taskStore{NextID: 1}
+	tk := store.add("fix the parser")
+	tk.Agent = "claude"
+	tk.start("%3")
+	tk.StartedAt = time.Now().Add(-time.Hour)
+
+	var logs bytes.Buffer
+	d := newDispatcher(dispatchOpts{Idle: time.Minute, Retries: 1}, newLogger(&logs, "test"))
+	idle := func() {
+		d.tracker.hashes["%3"] = outputHash("--- FAIL: TestParse")
+		d.tracker.changed["%3"] = time.Now().Add(-time.Hour)
+	}
+
+	idle()
+	d.tick(store)
+	if tk.Status != taskInProgress || tk.Attempts != 1 || tk.LastError != "--- FAIL: TestParse" {
+		t.Fatalf("expected task to be retried, got %+v", tk)
+	}
+	data, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(data), "previous attempt failed with: --- FAIL: TestParse") {
+		t.Errorf("expected augmented prompt, got: %s", string(data))
+	}
+
+	tk.StartedAt = time.Now().Add(-time.Hour)
+	idle()
+	d.tick(store)
+	if tk.Status != taskFailed || tk.Outcome != "--- FAIL: TestParse" {
+		t.Errorf("expected task to fail after retries, got %+v", tk)
+	}
+	if !strings.Contains(logs.String(), `level=ERROR msg="task failed" component=test task=1`) {
+		t.Errorf("expected escalation, got: %s", logs.String())
+	}
+	data, _ = os.ReadFile(argsFile)
+	if !strings.Contains(string(data), "display-message -- tmux-agent: task #1 failed on pane %3 after 2 attempt(s)") {
+		t.Errorf("expected status line notification, got: %s", string(data))
+	}
+}
+
+func TestDispatcherTick_RateLimited(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude", Output: "Claude usage limit reached. Your limit will reset at 3pm."},
+		&runner.FakePane{ID: "%5", Command: "codex", Output: "You've hit your usage limit. Try again in 2h."},
+	)
+
+	store := &taskStore{NextID: 1}
+	queued := store.add("new task")
+	running := store.add("running task")
+	running.start("%5")
+	running.StartedAt = time.Now().Add(-time.Hour)
+
+	var logs bytes.Buffer
+	d := newDispatcher(dispatchOpts{Idle: time.Minute}, newLogger(&logs, "test"))
+	for i := range 2 {
+		for _, p := range fake.Panes {
+			d.tracker.hashes[p.ID] = outputHash(p.Output)
+			d.tracker.changed[p.ID] = time.Now().Add(-time.Hour)
+		}
+		if err := d.tick(store); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && strings.Count(logs.String(), "pane rate-limited") != 2 {
+			t.Errorf("expected both panes logged as rate-limited, got: %s", logs.String())
+		}
+	}
+	if strings.Count(logs.String(), "pane rate-limited") != 2 {
+		t.Errorf("expected the rate limit logged once per pane, got: %s", logs.String())
+	}
+	if queued.Status != taskTodo {
+		t.Errorf("expected no task for rate-limited panes, got %+v", queued)
+	}
+	if running.Status != taskInProgress {
+		t.Errorf("expected the task on a rate-limited pane to keep running, got %s", running.Status)
+	}
+
+	fake.Pane("%3").Output = "ready"
+	d.tracker.hashes["%3"] = outputHash("ready")
+	if err := d.tick(store); err != nil {
+		t.Fatal(err)
+	}
+	if queued.Status != taskInProgress || queued.Pane != "%3" {
+		t.Errorf("expected the task on %%3 once its limit lifted, got %+v", queued)
+	}
+}
+
+func TestDispatcherTick_CreateSendFails(t *testing.T) {
+	fake := useFakeTmux(t)
+	t.Setenv("HOME", t.TempDir())
+	saveConfig(&agentConfig{BlockedPatterns: []string{`rm -rf`}})
+	orig := readyTimeout
+	readyTimeout = 0
+	defer func() { readyTimeout = orig }()
+
+	store := &taskStore{NextID: 1}
+	blocked := store.add("rm -rf the build dir")
+
+	var logs bytes.Buffer
+	d := newDispatcher(dispatchOpts{Idle: time.Minute, Create: true}, newLogger(&logs, "test"))
+	if err := d.tick(store); err != nil {
+		t.Fatal(err)
+	}
+	created := 0
+	for _, c := range fake.Calls {
+		if c[0] == "split-window" || c[0] == "new-window" {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("expected a single pane for the failed send, created %d", created)
+	}
+	if len(fake.Panes) != 0 {
+		t.Errorf("expected the unused pane to be killed, have %d panes", len(fake.Panes))
+	}
+	if blocked.Status != taskTodo {
+		t.Errorf("expected the task to stay todo, got %s", blocked.Status)
+	}
+}
+
+func TestMergeTasks(t *testing.T) {
+	dispatched := &taskStore{NextID: 3, Tasks: []*task{
+		{ID: 1, Title: "dispatched", Status: taskTodo},
+		{ID: 2, Title: "untouched", Status: taskTodo},
+	}}
+	before := snapshotTasks(dispatched)
+	dispatched.Tasks[0].start("%1")
+	dispatched.add("imported")
+
+	// Meanwhile, task #2 was finished and task #3 added by other commands.
+	current := &taskStore{NextID: 4, Tasks: []*task{
+		{ID: 1, Title: "dispatched", Status: taskTodo},
+		{ID: 2, Title: "untouched", Status: taskDone},
+		{ID: 3, Title: "added", Status: taskTodo},
+	}}
+	mergeTasks(current, dispatched, before)
+
+	want := map[int]string{1: taskInProgress, 2: taskDone, 3: taskTodo, 4: taskTodo}
+	if len(current.Tasks) != len(want) || current.NextID != 5 {
+		t.Fatalf("unexpected store: %+v", current)
+	}
+	for _, tk := range current.Tasks {
+		if tk.Status != want[tk.ID] {
+			t.Errorf("task #%d (%s): status %s, want %s", tk.ID, tk.Title, tk.Status, want[tk.ID])
+		}
+	}
+	if tk, _ := current.get(4); tk == nil || tk.Title != "imported" {
+		t.Errorf("expected the imported task to be renumbered to #4, got %+v", tk)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// menuSendBuffer is the tmux paste buffer the menu's send prompt stores its
+// text in. Going through a buffer keeps the text away from shell quoting.
+const menuSendBuffer = "tmux-agent-send"
+
+// menuKeys are the shortcut keys given to panes in the pane menu.
+const menuKeys = "123456789"
+
+// tmuxQuote quotes s as a single argument in a tmux command string.
+func tmuxQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(s) + `"`
+}
+
+// menuItem returns the display-menu arguments for one item. Disabled items
+// are shown greyed out.
+func menuItem(name, key, command string, disabled bool) []string {
+	name = strings.ReplaceAll(name, "#", "##")
+	if disabled {
+		name = "-" + name
+	}
+	return []string{name, key, command}
+}
+
+// buildPaneMenu returns the display-menu arguments listing panes. Choosing
+// a pane opens its action menu.
+func buildPaneMenu(panes []paneInfo, exe string) []string {
+	args := []string{"display-menu", "-T", "tmux-agent", "-x", "C", "-y", "C"}
+	if len(panes) == 0 {
+		return append(args, menuItem("No coding agent panes found", "", "", true)...)
+	}
+	for i, p := range panes {
+		key := ""
+		if i < len(menuKeys) {
+			key = menuKeys[i : i+1]
+		}
+		name := fmt.Sprintf("%s %s %s", p.ID, p.Command, shortDir(p.Dir))
+		if p.LastOutput != "" {
+			name += ": " + p.LastOutput
+		}
+		args = append(args, menuItem(name, key, "run-shell -b "+tmuxQuote(shellQuote(exe)+" menu --pane "+p.ID), false)...)
+	}
+	return args
+}
+
+// buildActionMenu returns the display-menu arguments for the actions on a
+// pane. In read-only mode, actions that change the pane are disabled.
+func buildActionMenu(p paneInfo, exe string, readOnly bool) []string {
+	self := shellQuote(exe)
+	capture := tmuxQuote(self + " capture " + p.ID + " --lines 1000 | less -R +G")
+	send := menuSendCommand(self, p.ID)
+	restart := "run-shell -b " + tmuxQuote(self+" restart "+p.ID)
+	// confirm-before has asked already; run-shell has no terminal for
+	// confirm_destructive to ask on.
+	kill := "run-shell -b " + tmuxQuote(self+" kill "+p.ID+" --yes")
+
+	args := []string{"display-menu", "-T", p.ID + " " + p.Command, "-x", "C", "-y", "C"}
+	args = append(args, menuItem("Go to pane", "g", "switch-client -t "+p.ID, false)...)
+	args = append(args, menuItem("Capture", "c", "display-popup -E -w 90% -h 90% "+capture, false)...)
+	args = append(args, menuItem("Send...", "s", "command-prompt -p "+tmuxQuote("send to "+p.ID+":")+" "+tmuxQuote(send), readOnly)...)
+	args = append(args, menuItem("Restart", "r", "confirm-before -p "+tmuxQuote("restart "+p.ID+"? (y/n)")+" "+tmuxQuote(restart), readOnly)...)
+	args = append(args, menuItem("Kill", "x", "confirm-before -p "+tmuxQuote("kill "+p.ID+"? (y/n)")+" "+tmuxQuote(kill), readOnly)...)
+	args = append(args, "", "", "")
+	args = append(args, menuItem("Back", "b", "run-shell -b "+tmuxQuote(self+" menu"), false)...)
+	return args
+}
+
+// menuSendCommand returns the command-prompt template for the send action.
+// "%%%" has command-prompt escape the response, so quotes, backslashes and
+// "$" in the prompt reach set-buffer as typed.
+func menuSendCommand(self, paneID string) string {
+	return "set-buffer -b " + menuSendBuffer + " -- \"%%%\" ; run-shell -b " +
+		tmuxQuote(self+" menu --send "+strings.TrimPrefix(paneID, "%"))
+}
+
+// menuSend sends the text the send prompt left in menuSendBuffer to a pane.
+// paneID may be given without its leading "%", which command-prompt would
+// otherwise treat as a placeholder.
+func menuSend(paneID string) error {
+	if !strings.HasPrefix(paneID, "%") {
+		paneID = "%" + paneID
+	}
+	out, err := tmuxRunner.Run("show-buffer", "-b", menuSendBuffer)
+	if err != nil {
+		return fmt.Errorf("tmux show-buffer: %w", err)
+	}
+	tmuxRunner.Run("delete-buffer", "-b", menuSendBuffer)
+	text := strings.TrimSpace(string(out))
+	if text == "" {
+		return nil
+	}
+	return sendTmuxKeys(paneID, text)
+}
+
+// runDisplayMenu opens a tmux display-menu listing agent panes, with a
+// submenu of actions for each.
+func runDisplayMenu(args []string, w io.Writer) error {
+	var pane, send string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--pane" && i+1 < len(args):
+			i++
+			pane = args[i]
+		case args[i] == "--send" && i+1 < len(args):
+			i++
+			send = args[i]
+		default:
+			return fmt.Errorf("usage: tmux-agent menu [--pane id]")
+		}
+	}
+	if send != "" {
+		return menuSend(send)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating tmux-agent: %w", err)
+	}
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return err
+	}
+	var menu []string
+	if pane != "" {
+		p := paneInfo{ID: pane}
+		for _, candidate := range panes {
+			if candidate.ID == pane {
+				p = candidate
+			}
+		}
+		menu = buildActionMenu(p, exe, readOnly)
+	} else {
+		for i := range panes {
+			if output, err := capturePaneOutput(panes[i].ID, 10); err == nil {
+				panes[i].LastOutput = truncateLastLine(strings.TrimRight(output, "\n"), 40)
+			}
+		}
+		menu = buildPaneMenu(panes, exe)
+	}
+	if _, err := tmuxRunner.Run(menu...); err != nil {
+		return fmt.Errorf("tmux display-menu: %w", err)
+	}
+	return nil
+}
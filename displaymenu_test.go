@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestBuildPaneMenu(t *testing.T) {
+	panes := []paneInfo{
+		{ID: "%1", Command: "claude", Dir: "/work/api", LastOutput: "fixed #42"},
+		{ID: "%12", Command: "codex", Dir: "/work/web"},
+	}
+	got := buildPaneMenu(panes, "/usr/bin/tmux-agent")
+	want := []string{
+		"display-menu", "-T", "tmux-agent", "-x", "C", "-y", "C",
+		"%1 claude api: fixed ##42", "1", `run-shell -b "'/usr/bin/tmux-agent' menu --pane %1"`,
+		"%12 codex web", "2", `run-shell -b "'/usr/bin/tmux-agent' menu --pane %12"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("buildPaneMenu =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestBuildActionMenu(t *testing.T) {
+	p := paneInfo{ID: "%12", Command: "claude"}
+	menu := strings.Join(buildActionMenu(p, "/bin/tmux-agent", false), "\n")
+	for _, want := range []string{
+		"switch-client -t %12",
+		`display-popup -E -w 90% -h 90% "'/bin/tmux-agent' capture %12 --lines 1000 | less -R +G"`,
+		`command-prompt -p "send to %12:" "set-buffer -b tmux-agent-send -- \"%%%\" ; run-shell -b \"'/bin/tmux-agent' menu --send 12\""`,
+		`confirm-before -p "kill %12? (y/n)" "run-shell -b \"'/bin/tmux-agent' kill %12 --yes\""`,
+	} {
+		if !strings.Contains(menu, want) {
+			t.Errorf("expected %s in menu:\n%s", want, menu)
+		}
+	}
+
+	readOnlyMenu := buildActionMenu(p, "/bin/tmux-agent", true)
+	for i := 7; i+2 < len(readOnlyMenu); i += 3 {
+		name := readOnlyMenu[i]
+		disabled := strings.HasPrefix(name, "-")
+		if mutating := name == "-Send..." || name == "-Restart" || name == "-Kill"; disabled != mutating {
+			t.Errorf("item %q: disabled = %v", name, disabled)
+		}
+	}
+}
+
+func TestMenuSendCommand_QuotedPrompt(t *testing.T) {
+	prompt := `say "hi" to $USER \ 50% off; ~ok`
+	// command-prompt replaces %%% with the response, escaped for the
+	// double quotes around it.
+	var escaped strings.Builder
+	for _, r := range prompt {
+		if strings.ContainsRune("\"\\$;~", r) {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	cmd := strings.Replace(menuSendCommand("'/bin/tmux-agent'", "%12"), "%%%", escaped.String(), 1)
+
+	// Read back the quoted set-buffer argument the way tmux parses it.
+	start := strings.Index(cmd, `-- "`)
+	if start < 0 {
+		t.Fatalf("no quoted set-buffer argument in %q", cmd)
+	}
+	var got strings.Builder
+	rest := cmd[start+len(`-- "`):]
+	for i := 0; i < len(rest) && rest[i] != '"'; i++ {
+		if rest[i] == '\\' {
+			i++
+		}
+		got.WriteByte(rest[i])
+	}
+	if got.String() != prompt {
+		t.Errorf("set-buffer got %q, want %q", got.String(), prompt)
+	}
+}
+
+func TestRunDisplayMenu(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Dir: "/work/api", Output: "done"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	if err := runDisplayMenu(nil, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	call := fake.Calls[len(fake.Calls)-1]
+	if call[0] != "display-menu" || call[7] != "%1 claude api: done" {
+		t.Errorf("unexpected menu call: %q", call)
+	}
+
+	if err := runDisplayMenu([]string{"--pane", "%1"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	call = fake.Calls[len(fake.Calls)-1]
+	if call[0] != "display-menu" || call[2] != "%1 claude" {
+		t.Errorf("unexpected action menu call: %q", call)
+	}
+
+	if err := runDisplayMenu([]string{"--bogus"}, io.Discard); err == nil {
+		t.Error("expected usage error")
+	}
+}
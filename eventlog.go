@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event log events besides the hook events. eventScan ends every scan;
+// eventPaneAdded reports a pane watch has not seen before, including every
+// pane on the first scan.
+const (
+	eventScan      = "scan"
+	eventPaneAdded = "pane_added"
+)
+
+// watchEvent is one line of the watch --event-log file.
+type watchEvent struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+	Pane  string    `json:"pane,omitempty"`
+	Agent string    `json:"agent,omitempty"`
+	Repo  string    `json:"repo,omitempty"`
+	Dir   string    `json:"dir,omitempty"`
+	State string    `json:"state,omitempty"`
+	// IdleSeconds is how long the pane's output has been unchanged.
+	IdleSeconds int64 `json:"idle_seconds,omitempty"`
+	// Panes and States count the panes of a scan, overall and by state.
+	Panes  int            `json:"panes,omitempty"`
+	States map[string]int `json:"states,omitempty"`
+}
+
+// newPaneEvent describes event on pane p, which is in state.
+func newPaneEvent(event string, p *paneInfo, state string) watchEvent {
+	e := watchEvent{
+		Time:  time.Now(),
+		Event: event,
+		Pane:  p.ID,
+		Agent: p.Command,
+		Repo:  shortDir(p.Dir),
+		Dir:   p.Dir,
+		State: state,
+	}
+	if event != eventPaneClosed {
+		e.IdleSeconds = idleSeconds(p)
+	}
+	return e
+}
+
+// logEvent appends e to the --event-log file, if one is open.
+func (wt *watcher) logEvent(e watchEvent) {
+	if wt.events == nil {
+		return
+	}
+	if err := json.NewEncoder(wt.events).Encode(e); err != nil {
+		wt.logger.Warn("writing event log failed", "event", e.Event, "err", err)
+	}
+}
+
+// logScan appends the scan event summarizing states, the state of each
+// live pane.
+func (wt *watcher) logScan(states map[string]string) {
+	counts := make(map[string]int)
+	for _, s := range states {
+		counts[s]++
+	}
+	wt.logEvent(watchEvent{Time: time.Now(), Event: eventScan, Panes: len(states), States: counts})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestWatcherScan_EventLog(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude", Dir: "/src/github.com/owner/repo", Output: "working"},
+		&runner.FakePane{ID: "%5", Command: "codex", Dir: "/work/b", Output: "working"},
+	)
+	var events bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(io.Discard, "test"))
+	wt.events = &events
+
+	wt.scan()
+	wt.tracker.changed["%3"] = time.Now().Add(-time.Hour)
+	wt.scan()
+	fake.Run("kill-pane", "-t", "%5")
+	wt.scan()
+
+	var got []watchEvent
+	dec := json.NewDecoder(&events)
+	for dec.More() {
+		var e watchEvent
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+	want := []string{
+		"pane_added %3", "pane_added %5", "scan ",
+		"pane_idle %3", "scan ",
+		"pane_closed %5", "scan ",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i, e := range got {
+		if e.Event+" "+e.Pane != want[i] {
+			t.Errorf("event %d = %q, want %q", i, e.Event+" "+e.Pane, want[i])
+		}
+	}
+	if e := got[0]; e.Agent != "claude" || e.Repo != "owner/repo" || e.State != stateActive {
+		t.Errorf("unexpected pane_added event: %+v", e)
+	}
+	if e := got[3]; e.State != stateIdle || e.IdleSeconds < 3600 {
+		t.Errorf("unexpected pane_idle event: %+v", e)
+	}
+	if e := got[4]; e.Panes != 2 || e.States[stateIdle] != 1 || e.States[stateActive] != 1 {
+		t.Errorf("unexpected scan event: %+v", e)
+	}
+	if e := got[6]; e.Panes != 1 {
+		t.Errorf("expected one pane after the close, got %+v", e)
+	}
+}
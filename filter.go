@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// paneFilter narrows a command down to some of the agent panes, e.g.
+// broadcast --agent codex --repo owner/name --idle-only.
+type paneFilter struct {
+	agent    string
+	repo     string
+	dir      string
+	exclude  []string
+	idleOnly bool
+	// idleFor, when set, is the idle threshold of idleOnly for every
+	// agent instead of the configured ones.
+	idleFor time.Duration
+}
+
+// parseFlag consumes the filter flag at args[i], if it is one, and returns
+// the index of its last argument.
+func (f *paneFilter) parseFlag(args []string, i int) (int, bool, error) {
+	switch args[i] {
+	case "--agent":
+		if i+1 < len(args) {
+			i++
+			if !isTargetCommand(args[i]) {
+				return i, true, fmt.Errorf("invalid --agent value: %s (want claude or codex)", args[i])
+			}
+			f.agent = args[i]
+		}
+	case "--repo":
+		if i+1 < len(args) {
+			i++
+			f.repo = strings.Trim(args[i], "/")
+		}
+	case "--dir":
+		if i+1 < len(args) {
+			i++
+			dir, err := filepath.Abs(expandHome(args[i]))
+			if err != nil {
+				return i, true, err
+			}
+			f.dir = dir
+		}
+	case "--exclude":
+		if i+1 < len(args) {
+			i++
+			f.exclude = append(f.exclude, strings.Split(args[i], ",")...)
+		}
+	case "--idle-only":
+		f.idleOnly = true
+	default:
+		return i, false, nil
+	}
+	return i, true, nil
+}
+
+// inRepo reports whether dir is the checkout of repo (owner/name), or a
+// directory inside it such as one of its worktrees.
+func inRepo(dir, repo string) bool {
+	short := shortDir(dir)
+	return short == repo || strings.HasPrefix(short, repo+"/")
+}
+
+// inDir reports whether dir is root or a directory below it.
+func inDir(dir, root string) bool {
+	return dir == root || strings.HasPrefix(dir, strings.TrimSuffix(root, "/")+"/")
+}
+
+// apply returns the panes that pass the filter. --idle-only classifies the
+// panes the way status does, with idleFor as the threshold when set.
+func (f *paneFilter) apply(panes []paneInfo) ([]paneInfo, error) {
+	var kept []paneInfo
+	for _, p := range panes {
+		if f.agent != "" && filepath.Base(p.Command) != f.agent {
+			continue
+		}
+		if f.repo != "" && !inRepo(p.Dir, f.repo) {
+			continue
+		}
+		if f.dir != "" && !inDir(p.Dir, f.dir) {
+			continue
+		}
+		if slices.Contains(f.exclude, p.ID) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	if !f.idleOnly || len(kept) == 0 {
+		return kept, nil
+	}
+
+	cfg := loadConfig()
+	threshold, err := cfg.idleThresholds(f.idleFor)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := cfg.idleBackend("")
+	if err != nil {
+		return nil, err
+	}
+	trackPanes(kept, false)
+	if backend == idleBackendTmux {
+		if err := applyPaneActivity(kept); err != nil {
+			return nil, err
+		}
+	}
+	busy := cfg.busyChecker()
+	idle := kept[:0]
+	for i := range kept {
+		if paneState(&kept[i], threshold(kept[i].Command), busy) == stateIdle {
+			idle = append(idle, kept[i])
+		}
+	}
+	return idle, nil
+}
+
+// active reports whether any filter is set.
+func (f *paneFilter) active() bool {
+	return f.agent != "" || f.repo != "" || f.dir != "" || len(f.exclude) > 0 || f.idleOnly
+}
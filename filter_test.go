@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestInRepo(t *testing.T) {
+	tests := []struct {
+		dir, repo string
+		want      bool
+	}{
+		{"/home/u/ghq/github.com/owner/name", "owner/name", true},
+		{"/home/u/ghq/github.com/owner/name/.worktrees/fix", "owner/name", true},
+		{"/home/u/ghq/github.com/owner/name-web", "owner/name", false},
+		{"/home/u/ghq/github.com/other/name", "owner/name", false},
+		{"", "owner/name", false},
+	}
+	for _, tt := range tests {
+		if got := inRepo(tt.dir, tt.repo); got != tt.want {
+			t.Errorf("inRepo(%q, %q) = %v, want %v", tt.dir, tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestInDir(t *testing.T) {
+	tests := []struct {
+		dir, root string
+		want      bool
+	}{
+		{"/src/work", "/src/work", true},
+		{"/src/work/api", "/src/work", true},
+		{"/src/work/api", "/src/work/", true},
+		{"/src/workshop", "/src/work", false},
+		{"/src", "/src/work", false},
+	}
+	for _, tt := range tests {
+		if got := inDir(tt.dir, tt.root); got != tt.want {
+			t.Errorf("inDir(%q, %q) = %v, want %v", tt.dir, tt.root, got, tt.want)
+		}
+	}
+}
+
+func TestRunPanes_Filters(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Dir: "/src/github.com/owner/name"},
+		&runner.FakePane{ID: "%2", Command: "codex", Dir: "/src/github.com/owner/name/.worktrees/fix"},
+		&runner.FakePane{ID: "%3", Command: "codex", Dir: "/src/github.com/owner/other"},
+		&runner.FakePane{ID: "%4", Command: "codex", Dir: "/work/scratch"},
+	)
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--agent", "codex"}, []string{"%2", "%3", "%4"}},
+		{[]string{"--repo", "owner/name"}, []string{"%1", "%2"}},
+		{[]string{"--agent", "codex", "--repo", "owner/name"}, []string{"%2"}},
+		{[]string{"--dir", "/src/github.com/owner"}, []string{"%1", "%2", "%3"}},
+		{[]string{"--dir", "/work"}, []string{"%4"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := runPanes(tt.args, &buf); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		for _, id := range []string{"%1", "%2", "%3", "%4"} {
+			listed := strings.Contains(buf.String(), id+" ")
+			if want := slices.Contains(tt.want, id); listed != want {
+				t.Errorf("%v: pane %s listed = %v, want %v", tt.args, id, listed, want)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := runPanes([]string{"--repo", "nobody/nothing"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "No coding agent panes match the filters") {
+		t.Errorf("expected no matching panes, got %q", buf.String())
+	}
+}
+
+func TestRunBroadcast_Filters(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Dir: "/src/github.com/owner/name", Output: "Done."},
+		&runner.FakePane{ID: "%2", Command: "codex", Dir: "/src/github.com/owner/name", Output: "Done."},
+		&runner.FakePane{ID: "%3", Command: "codex", Dir: "/src/github.com/owner/name/.worktrees/fix", Output: "Working"},
+		&runner.FakePane{ID: "%4", Command: "codex", Dir: "/src/github.com/owner/other", Output: "Done."},
+		&runner.FakePane{ID: "%5", Command: "codex", Dir: "/src/github.com/owner/name", Output: "Done."},
+	)
+	t.Setenv("HOME", t.TempDir())
+	hourAgo := time.Now().Add(-time.Hour)
+	saveState(paneStateFile, map[string]trackedOutput{
+		"%1": {Hash: outputHash("Done."), Changed: hourAgo},
+		"%2": {Hash: outputHash("Done."), Changed: hourAgo},
+		"%3": {Hash: outputHash("Thinking"), Changed: hourAgo},
+		"%4": {Hash: outputHash("Done."), Changed: hourAgo},
+		"%5": {Hash: outputHash("Done."), Changed: hourAgo},
+	})
+
+	var buf bytes.Buffer
+	args := []string{"--agent", "codex", "--repo", "owner/name", "--idle-only", "--exclude", "%5", "run", "the", "tests"}
+	if err := runBroadcast(args, &buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"%1", "%2", "%3", "%4", "%5"} {
+		sent := len(fake.Pane(id).Input) > 0
+		if want := id == "%2"; sent != want {
+			t.Errorf("pane %s: sent = %v, want %v", id, sent, want)
+		}
+	}
+	if in := fake.Pane("%2").Input; len(in) == 0 || in[0] != "run the tests" {
+		t.Errorf("expected the text without the filter flags, got %q", in)
+	}
+
+	buf.Reset()
+	if err := runBroadcast([]string{"--repo", "nobody/nothing", "hello"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "No coding agent panes match the filters") {
+		t.Errorf("expected no matching panes, got %q", buf.String())
+	}
+
+	if err := runBroadcast([]string{"--agent", "vim", "hello"}, &buf); err == nil {
+		t.Error("expected an error for an unknown agent")
+	}
+}
+
+func TestRunBroadcast_FlagsInText(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude"},
+		&runner.FakePane{ID: "%2", Command: "codex"},
+	)
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"check", "the", "--agent", "codex", "config"}, "check the --agent codex config"},
+		{[]string{"fix", "--idle-only", "handling"}, "fix --idle-only handling"},
+		{[]string{"--", "--stagger", "is", "broken"}, "--stagger is broken"},
+	}
+	for _, tt := range tests {
+		fake.Pane("%1").Input, fake.Pane("%2").Input = nil, nil
+		if err := runBroadcast(tt.args, &bytes.Buffer{}); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		for _, id := range []string{"%1", "%2"} {
+			if in := fake.Pane(id).Input; len(in) == 0 || in[0] != tt.want {
+				t.Errorf("%v: pane %s got %q, want %q", tt.args, id, in, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// templatePlaceholderRe matches {name} placeholders in broadcast templates.
+var templatePlaceholderRe = regexp.MustCompile(`\{\w+\}`)
+
+// sendGuard is the compiled form of blocked_patterns and broadcast_allowlist.
+type sendGuard struct {
+	blocked   []*regexp.Regexp
+	templates []*regexp.Regexp
+}
+
+// sendGuard compiles the config's send restrictions.
+func (c *agentConfig) sendGuard() (*sendGuard, error) {
+	g := &sendGuard{}
+	for _, s := range c.BlockedPatterns {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocked pattern %q: %w", s, err)
+		}
+		g.blocked = append(g.blocked, re)
+	}
+	for _, t := range c.BroadcastAllowlist {
+		g.templates = append(g.templates, templateRegexp(t))
+	}
+	return g, nil
+}
+
+// templateRegexp turns a prompt template into a regexp matching the whole
+// text, with each {name} placeholder standing for any non-empty text.
+func templateRegexp(template string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString(`^`)
+	last := 0
+	for _, loc := range templatePlaceholderRe.FindAllStringIndex(template, -1) {
+		b.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		b.WriteString(`.+`)
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(template[last:]))
+	b.WriteString(`$`)
+	return regexp.MustCompile(b.String())
+}
+
+// checkSend refuses text matching a blocked pattern.
+func (g *sendGuard) checkSend(text string) error {
+	for _, re := range g.blocked {
+		if re.MatchString(text) {
+			return fmt.Errorf("refusing to send text matching blocked pattern %q", re.String())
+		}
+	}
+	return nil
+}
+
+// checkBroadcast applies checkSend and, when broadcast_allowlist is set,
+// requires text to match one of its templates.
+func (g *sendGuard) checkBroadcast(text string) error {
+	if err := g.checkSend(text); err != nil {
+		return err
+	}
+	if len(g.templates) == 0 {
+		return nil
+	}
+	text = strings.TrimSpace(text)
+	for _, re := range g.templates {
+		if re.MatchString(text) {
+			return nil
+		}
+	}
+	return fmt.Errorf("refusing to broadcast text that matches no template in broadcast_allowlist")
+}
+
+// checkSendText applies the configured send guard to text.
+func checkSendText(text string) error {
+	g, err := loadConfig().sendGuard()
+	if err != nil {
+		return err
+	}
+	return g.checkSend(text)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestTemplateRegexp(t *testing.T) {
+	re := templateRegexp("Run the tests in {dir} (attempt {n})")
+	for text, want := range map[string]bool{
+		"Run the tests in ./pkg (attempt 2)": true,
+		"Run the tests in  (attempt 2)":      false,
+		"Run the tests in ./pkg":             false,
+		"Run the tests in x (attempt 1); rm": false,
+	} {
+		if got := re.MatchString(text); got != want {
+			t.Errorf("%q: got %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestSendGuard(t *testing.T) {
+	cfg := &agentConfig{
+		BlockedPatterns:    []string{`rm\s+-rf`, `git push\s.*(--force|-f\b)`},
+		BroadcastAllowlist: []string{"/compact", "Rebase onto {branch} and rerun the tests"},
+	}
+	g, err := cfg.sendGuard()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"then rm -rf build", "git push origin main --force"} {
+		if err := g.checkSend(text); err == nil {
+			t.Errorf("expected %q to be blocked", text)
+		}
+	}
+	if err := g.checkSend("git push origin main"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := g.checkBroadcast("Rebase onto main and rerun the tests"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := g.checkBroadcast("delete the repo"); err == nil {
+		t.Error("expected broadcast outside the allowlist to be refused")
+	}
+
+	if _, err := (&agentConfig{BlockedPatterns: []string{"("}}).sendGuard(); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestSendGuard_Enforced(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	saveConfig(&agentConfig{
+		DefaultAgent:       "claude",
+		BlockedPatterns:    []string{`rm\s+-rf`},
+		BroadcastAllowlist: []string{"/compact"},
+	})
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+
+	if err := runSend([]string{"%1", "rm", "-rf", "/"}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("expected blocked send, got %v", err)
+	}
+	if err := runBroadcast([]string{"hello"}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "broadcast_allowlist") {
+		t.Errorf("expected refused broadcast, got %v", err)
+	}
+	if len(fake.Pane("%1").Input) != 0 {
+		t.Errorf("keys reached the pane: %q", fake.Pane("%1").Input)
+	}
+	if err := runBroadcast([]string{"/compact"}, &bytes.Buffer{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := fake.Pane("%1").Input; len(got) == 0 || got[0] != "/compact" {
+		t.Errorf("expected /compact to be sent, got %q", got)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Hook events.
+const (
+	eventPaneClosed = "pane_closed"
+	// eventPaneIdle fires when a pane becomes idle, eventPaneActive when
+	// it becomes active again.
+	eventPaneIdle   = "pane_idle"
+	eventPaneActive = "pane_active"
+	// eventPaneError fires when a pane becomes idle with output matching
+	// its error_patterns.
+	eventPaneError = "pane_error"
+	// eventRateLimited and eventCompacting fire when a pane starts showing
+	// a rate-limit or context-compaction message.
+	eventRateLimited = "rate_limited"
+	eventCompacting  = "compacting"
+	// eventPaneWaiting fires when a pane starts showing a question or
+	// confirmation its agent is waiting on.
+	eventPaneWaiting = "pane_waiting"
+	// eventNeedsApproval fires when a pane starts showing a permission
+	// prompt.
+	eventNeedsApproval = "needs_approval"
+	// eventContextLow fires when the context an agent has left drops below
+	// context_warning.
+	eventContextLow = "context_low"
+)
+
+// runHook runs a configured hook command through sh, describing the event
+// and pane in TMUX_AGENT_* environment variables. message, if any, is the
+// agent's own text that triggered the event. An empty command is a no-op.
+func runHook(command, event string, p paneInfo, message string) error {
+	if command == "" {
+		return nil
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"TMUX_AGENT_EVENT="+event,
+		"TMUX_AGENT_PANE="+p.ID,
+		"TMUX_AGENT_AGENT="+p.Command,
+		"TMUX_AGENT_DIR="+p.Dir,
+	)
+	if message != "" {
+		cmd.Env = append(cmd.Env, "TMUX_AGENT_MESSAGE="+message)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w (output: %s)", command, err, string(output))
+	}
+	return nil
+}
+
+// runHook runs the hook configured for event, if any, in the background
+// (see deliver), logging failures.
+func (wt *watcher) runHook(event string, p *paneInfo, message string) {
+	command, pane := wt.hooks[event], *p
+	if command == "" {
+		return
+	}
+	wt.deliver(func() {
+		if err := runHook(command, event, pane, message); err != nil {
+			wt.logger.Warn("hook failed", append(paneAttrs(&pane), "event", event, "err", err)...)
+		}
+	})
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+)
+
+const identitiesFile = "identities.json"
+
+// detachedIdentityTTL is how long the identity of a pane lost in a tmux
+// restart is kept waiting for a matching pane to come back.
+const detachedIdentityTTL = 7 * 24 * time.Hour
+
+// identitySaveInterval is how often the identities are saved when nothing
+// but the panes' last-seen times changed.
+const identitySaveInterval = time.Hour
+
+// identityBranches caches the branches identify looks up, so that loops
+// calling reattachPanes on every scan do not run git for every pane.
+var identityBranches = newBranchCache(time.Minute)
+
+// paneIdentity is the logical identity of an agent pane: the repo, branch
+// and title it works in. Pane IDs are reassigned when the tmux server
+// restarts; the identity lets labels and tasks follow the recreated pane.
+type paneIdentity struct {
+	Pane   string `json:"pane"`
+	Repo   string `json:"repo"`
+	Branch string `json:"branch,omitempty"`
+	Title  string `json:"title,omitempty"`
+	// Label, Tasks and Queue hold the pane's state while it is detached.
+	Label    string         `json:"label,omitempty"`
+	Tasks    []int          `json:"tasks,omitempty"`
+	Queue    []queuedPrompt `json:"queue,omitempty"`
+	LastSeen time.Time      `json:"last_seen"`
+}
+
+// name renders the identity as "repo@branch".
+func (id *paneIdentity) name() string {
+	if id.Branch == "" {
+		return id.Repo
+	}
+	return id.Repo + "@" + id.Branch
+}
+
+// identityStore is the persisted identity state. Panes are the identities
+// of panes on the current server; Detached are those of panes lost in a
+// server restart that have not been matched yet.
+type identityStore struct {
+	Server   string          `json:"server"`
+	Panes    []*paneIdentity `json:"panes"`
+	Detached []*paneIdentity `json:"detached,omitempty"`
+	Saved    time.Time       `json:"saved"`
+}
+
+// reattachment records a detached identity matched to a new pane.
+type reattachment struct {
+	Identity *paneIdentity
+	OldPane  string
+}
+
+// identify returns the identity of a live pane.
+func identify(p paneInfo) *paneIdentity {
+	return &paneIdentity{
+		Pane:     p.ID,
+		Repo:     shortDir(p.Dir),
+		Branch:   identityBranches.get(p.Dir),
+		Title:    p.Title,
+		LastSeen: time.Now(),
+	}
+}
+
+// matchDetached finds the detached identity for id: an exact repo, branch
+// and title match, or else the only one with the same repo and branch.
+// Returns -1 if there is none.
+func matchDetached(detached []*paneIdentity, id *paneIdentity) int {
+	candidate, candidates := -1, 0
+	for i, d := range detached {
+		if d.Repo != id.Repo || d.Branch != id.Branch {
+			continue
+		}
+		if d.Title == id.Title {
+			return i
+		}
+		candidate = i
+		candidates++
+	}
+	if candidates == 1 {
+		return candidate
+	}
+	return -1
+}
+
+// sameIdentities reports whether a and b are the same panes with the same
+// identities, ignoring when they were last seen.
+func sameIdentities(a, b []*paneIdentity) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Pane != b[i].Pane || a[i].Repo != b[i].Repo || a[i].Branch != b[i].Branch || a[i].Title != b[i].Title {
+			return false
+		}
+	}
+	return true
+}
+
+// reattachPanes updates the identity store for the given live panes. When
+// the tmux server has restarted, the old panes' labels, active tasks and
+// queued prompts are detached, and handed to the new pane with the same
+// identity once it appears. Tasks that were in progress are queued again,
+// since the agent that was working on them is gone. The store is only
+// written when it changed, or every identitySaveInterval to keep the
+// last-seen times fresh.
+func reattachPanes(panes []paneInfo) ([]reattachment, error) {
+	server := tmuxServerID()
+	if server == "" {
+		return nil, nil
+	}
+	var reattached []reattachment
+	err := updateTasks(func(tasks *taskStore) error {
+		var err error
+		reattached, err = reattachIdentities(server, panes, tasks)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reattached, nil
+}
+
+// reattachIdentities does the work of reattachPanes, updating tasks in
+// place while reattachPanes holds the tasks lock.
+func reattachIdentities(server string, panes []paneInfo, tasks *taskStore) ([]reattachment, error) {
+	var store identityStore
+	if err := loadState(identitiesFile, &store); err != nil {
+		return nil, fmt.Errorf("reading identities: %w", err)
+	}
+	labels := loadLabels()
+
+	changed := false
+	if store.Server != server {
+		if store.Server != "" {
+			changed = true
+			for _, id := range store.Panes {
+				detachIdentity(id, labels, tasks)
+			}
+			err := updateQueue(func(queue map[string][]queuedPrompt) {
+				for _, id := range store.Panes {
+					id.Queue = queue[id.Pane]
+					delete(queue, id.Pane)
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+			store.Detached = append(store.Detached, store.Panes...)
+		}
+		store.Server = server
+		store.Panes = nil
+	}
+
+	known := make(map[string]*paneIdentity)
+	for _, id := range store.Panes {
+		known[id.Pane] = id
+	}
+	var current []*paneIdentity
+	var reattached []reattachment
+	queued := make(map[string][]queuedPrompt)
+	for _, p := range panes {
+		id := identify(p)
+		if _, ok := known[p.ID]; !ok {
+			if i := matchDetached(store.Detached, id); i >= 0 {
+				old := store.Detached[i]
+				store.Detached = append(store.Detached[:i], store.Detached[i+1:]...)
+				attachIdentity(old, p.ID, labels, tasks)
+				if len(old.Queue) > 0 {
+					queued[p.ID] = old.Queue
+				}
+				reattached = append(reattached, reattachment{Identity: id, OldPane: old.Pane})
+			}
+		}
+		current = append(current, id)
+	}
+	dirty := changed || len(reattached) > 0 || !sameIdentities(store.Panes, current)
+	store.Panes = current
+
+	var detached []*paneIdentity
+	for _, id := range store.Detached {
+		if time.Since(id.LastSeen) < detachedIdentityTTL {
+			detached = append(detached, id)
+		}
+	}
+	if len(detached) != len(store.Detached) {
+		dirty = true
+	}
+	store.Detached = detached
+
+	if len(queued) > 0 {
+		err := updateQueue(func(queue map[string][]queuedPrompt) {
+			for paneID, prompts := range queued {
+				queue[paneID] = append(queue[paneID], prompts...)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if dirty || time.Since(store.Saved) > identitySaveInterval {
+		store.Saved = time.Now()
+		if err := saveState(identitiesFile, &store); err != nil {
+			return nil, err
+		}
+	}
+	// Labels are only rewritten when they changed, so that concurrent
+	// edits by other commands are not clobbered on every scan; tasks are
+	// saved by updateTasks under its lock, and only when they changed.
+	if !changed && len(reattached) == 0 {
+		return nil, nil
+	}
+	if err := saveLabels(labels); err != nil {
+		return nil, err
+	}
+	return reattached, nil
+}
+
+// logReattach runs reattachPanes for a daemon loop and logs the result.
+func logReattach(panes []paneInfo, logger *slog.Logger) {
+	reattached, err := reattachPanes(panes)
+	if err != nil {
+		logger.Warn("reattaching panes failed", "err", err)
+		return
+	}
+	for _, r := range reattached {
+		logger.Info("reattached pane", "identity", r.Identity.name(), "old_pane", r.OldPane, "pane", r.Identity.Pane)
+	}
+}
+
+// detachIdentity moves a pane's label and active tasks into its identity.
+func detachIdentity(id *paneIdentity, labels map[string]string, tasks *taskStore) {
+	if label, ok := labels[id.Pane]; ok {
+		id.Label = label
+		delete(labels, id.Pane)
+	}
+	for _, t := range tasks.Tasks {
+		if t.Pane != id.Pane || (t.Status != taskTodo && t.Status != taskInProgress) {
+			continue
+		}
+		t.Status = taskTodo
+		t.Pane = ""
+		id.Tasks = append(id.Tasks, t.ID)
+	}
+}
+
+// attachIdentity gives a detached identity's label and tasks to paneID.
+func attachIdentity(id *paneIdentity, paneID string, labels map[string]string, tasks *taskStore) {
+	if id.Label != "" {
+		labels[paneID] = id.Label
+	}
+	for _, taskID := range id.Tasks {
+		if t, err := tasks.get(taskID); err == nil && t.Status == taskTodo && t.Pane == "" {
+			t.Pane = paneID
+		}
+	}
+}
+
+// runReattach re-associates panes recreated after a tmux restart with
+// their previous labels, tasks and queued prompts, and lists identities
+// still waiting.
+func runReattach(args []string, w io.Writer) error {
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return err
+	}
+	reattached, err := reattachPanes(panes)
+	if err != nil {
+		return err
+	}
+	for _, r := range reattached {
+		fmt.Fprintf(w, "Reattached %s: %s -> %s\n", r.Identity.name(), r.OldPane, r.Identity.Pane)
+	}
+
+	var store identityStore
+	loadState(identitiesFile, &store)
+	if len(store.Detached) == 0 {
+		if len(reattached) == 0 {
+			fmt.Fprintln(w, "No detached panes")
+		}
+		return nil
+	}
+	fmt.Fprintln(w, "Waiting for panes:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "OLD PANE\tIDENTITY\tTITLE\tTASK\tLAST SEEN")
+	for _, id := range store.Detached {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s ago\n", id.Pane, id.name(), id.Title, id.Label,
+			formatDuration(time.Since(id.LastSeen)))
+	}
+	tw.Flush()
+	return nil
+}
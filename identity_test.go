@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReattachPanes(t *testing.T) {
+	dir := t.TempDir()
+
+	serverFile := filepath.Join(dir, "server.txt")
+	panesFile := filepath.Join(dir, "panes.txt")
+	os.WriteFile(filepath.Join(dir, "tmux"), []byte(`#!/bin/sh
+case "$1" in
+  display-message)
+    cat `+serverFile+`
+    ;;
+  list-panes)
+    cat `+panesFile+`
+    ;;
+esac
+`), 0755)
+	os.WriteFile(filepath.Join(dir, "git"), []byte(`#!/bin/sh
+case "$2" in
+  /work/a) echo feature-a ;;
+  *) echo main ;;
+esac
+`), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	identityBranches = newBranchCache(time.Minute)
+
+	saveLabels(map[string]string{"%3": "auth refactor"})
+	store := &taskStore{NextID: 1}
+	running := store.add("running")
+	running.start("%3")
+	queued := store.add("queued")
+	queued.Pane = "%3"
+	other := store.add("other pane")
+	other.Pane = "%5"
+	done := store.add("finished")
+	done.start("%3")
+	done.Status = taskDone
+	saveTasks(store)
+	saveState(queueFile, map[string][]queuedPrompt{"%3": {{Text: "then update the docs"}}})
+
+	os.WriteFile(serverFile, []byte("100:1700000000\n"), 0644)
+	os.WriteFile(panesFile, []byte("%3\tclaude\t1\t/work/a\tauth\n%5\tcodex\t2\t/work/b\tb\n"), 0644)
+	var buf bytes.Buffer
+	if err := runReattach(nil, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No detached panes") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+	// Nothing changed, so the identities are not written again.
+	var before, after identityStore
+	loadState(identitiesFile, &before)
+	runReattach(nil, io.Discard)
+	loadState(identitiesFile, &after)
+	if !after.Saved.Equal(before.Saved) {
+		t.Error("expected an unchanged store not to be saved again")
+	}
+
+	// The server restarts and only the /work/b pane is back so far.
+	os.WriteFile(serverFile, []byte("200:1700001000\n"), 0644)
+	os.WriteFile(panesFile, []byte("%0\tcodex\t3\t/work/b\tb\n"), 0644)
+	buf.Reset()
+	if err := runReattach(nil, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Reattached b@main: %5 -> %0") {
+		t.Errorf("expected %%5 to be reattached to %%0, got: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "%3") || !strings.Contains(buf.String(), "a@feature-a") {
+		t.Errorf("expected %%3 to be listed as waiting, got: %s", buf.String())
+	}
+	store, _ = loadTasks()
+	if tk, _ := store.get(3); tk.Pane != "%0" {
+		t.Errorf("expected task #3 on %%0, got %+v", tk)
+	}
+	if tk, _ := store.get(1); tk.Status != taskTodo || tk.Pane != "" {
+		t.Errorf("expected interrupted task to be queued and detached, got %+v", tk)
+	}
+	if _, ok := loadLabels()["%3"]; ok {
+		t.Error("expected label of the old %3 to be detached")
+	}
+
+	// The /work/a pane comes back with a different title.
+	os.WriteFile(panesFile, []byte("%0\tcodex\t3\t/work/b\tb\n%1\tclaude\t4\t/work/a\tnew title\n"), 0644)
+	buf.Reset()
+	runReattach(nil, &buf)
+	if !strings.Contains(buf.String(), "Reattached a@feature-a: %3 -> %1") {
+		t.Errorf("expected %%3 to be reattached to %%1, got: %s", buf.String())
+	}
+	if loadLabels()["%1"] != "auth refactor" {
+		t.Errorf("expected label on %%1, got %v", loadLabels())
+	}
+	if queue, _ := loadQueue(); len(queue["%1"]) != 1 || len(queue["%3"]) != 0 {
+		t.Errorf("expected the queued prompt to move to %%1, got %v", queue)
+	}
+	store, _ = loadTasks()
+	for _, id := range []int{1, 2} {
+		if tk, _ := store.get(id); tk.Pane != "%1" || tk.Status != taskTodo {
+			t.Errorf("expected task #%d queued on %%1, got %+v", id, tk)
+		}
+	}
+	if tk, _ := store.get(4); tk.Pane != "%3" {
+		t.Errorf("expected finished task to keep its pane, got %+v", tk)
+	}
+}
+
+func TestMatchDetached(t *testing.T) {
+	detached := []*paneIdentity{
+		{Pane: "%1", Repo: "owner/repo", Branch: "main", Title: "one"},
+		{Pane: "%2", Repo: "owner/repo", Branch: "main", Title: "two"},
+		{Pane: "%3", Repo: "owner/other", Branch: "main"},
+	}
+	if i := matchDetached(detached, &paneIdentity{Repo: "owner/repo", Branch: "main", Title: "two"}); i != 1 {
+		t.Errorf("expected exact title match, got %d", i)
+	}
+	if i := matchDetached(detached, &paneIdentity{Repo: "owner/repo", Branch: "main", Title: "three"}); i != -1 {
+		t.Errorf("expected ambiguous match to fail, got %d", i)
+	}
+	if i := matchDetached(detached, &paneIdentity{Repo: "owner/other", Branch: "main", Title: "x"}); i != 2 {
+		t.Errorf("expected unique repo/branch match, got %d", i)
+	}
+}
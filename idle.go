@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Idle detection backends.
+const (
+	// idleBackendOutput compares captured pane output between scans.
+	idleBackendOutput = "output"
+	// idleBackendTmux uses the last-activity timestamps tmux keeps for each
+	// pane, so a single call already knows how long a pane has been quiet.
+	idleBackendTmux = "tmux"
+)
+
+// idleBackend returns the backend chosen by an --idle-backend flag value,
+// else the config's idle_backend, else idleBackendOutput.
+func (c *agentConfig) idleBackend(flag string) (string, error) {
+	backend := flag
+	if backend == "" {
+		backend = c.IdleBackend
+	}
+	switch backend {
+	case "":
+		return idleBackendOutput, nil
+	case idleBackendOutput, idleBackendTmux:
+		return backend, nil
+	}
+	return "", fmt.Errorf("invalid idle backend: %s (want %s or %s)", backend, idleBackendOutput, idleBackendTmux)
+}
+
+// paneActivity returns when each pane last produced output, according to
+// tmux. #{pane_activity} is preferred; tmux versions without it fall back
+// to #{window_activity}.
+func paneActivity() (map[string]time.Time, error) {
+	output, err := tmuxRunner.Run("list-panes", "-a", "-F", "#{pane_id}\t#{pane_activity}\t#{window_activity}")
+	if err != nil {
+		return nil, fmt.Errorf("tmux list-panes: %w", err)
+	}
+	activity := make(map[string]time.Time)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		for _, f := range fields[1:] {
+			if sec, err := strconv.ParseInt(f, 10, 64); err == nil && sec > 0 {
+				activity[fields[0]] = time.Unix(sec, 0)
+				break
+			}
+		}
+	}
+	return activity, nil
+}
+
+// applyPaneActivity sets LastChangeAt on each pane from tmux's activity
+// timestamps.
+func applyPaneActivity(panes []paneInfo) error {
+	activity, err := paneActivity()
+	if err != nil {
+		return err
+	}
+	for i := range panes {
+		if t, ok := activity[panes[i].ID]; ok {
+			panes[i].LastChangeAt = t
+		}
+	}
+	return nil
+}
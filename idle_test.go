@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestIdleBackend(t *testing.T) {
+	cfg := &agentConfig{}
+	if b, _ := cfg.idleBackend(""); b != idleBackendOutput {
+		t.Errorf("default: got %q", b)
+	}
+	cfg.IdleBackend = idleBackendTmux
+	if b, _ := cfg.idleBackend(""); b != idleBackendTmux {
+		t.Errorf("config: got %q", b)
+	}
+	if b, _ := cfg.idleBackend(idleBackendOutput); b != idleBackendOutput {
+		t.Errorf("flag should win, got %q", b)
+	}
+	if _, err := cfg.idleBackend("hash"); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+func TestApplyPaneActivity(t *testing.T) {
+	quiet := time.Now().Add(-time.Hour).Truncate(time.Second)
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Activity: quiet},
+		&runner.FakePane{ID: "%2", Command: "codex"},
+	)
+	panes := []paneInfo{{ID: "%1"}, {ID: "%2"}, {ID: "%9"}}
+	if err := applyPaneActivity(panes); err != nil {
+		t.Fatal(err)
+	}
+	if !panes[0].LastChangeAt.Equal(quiet) {
+		t.Errorf("%%1: got %v, want %v", panes[0].LastChangeAt, quiet)
+	}
+	if time.Since(panes[1].LastChangeAt) > time.Minute {
+		t.Errorf("%%2 should be recent, got %v", panes[1].LastChangeAt)
+	}
+	if !panes[2].LastChangeAt.IsZero() {
+		t.Errorf("unknown pane should be left alone, got %v", panes[2].LastChangeAt)
+	}
+}
+
+func TestRunStatus_TmuxBackend(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Activity: time.Now().Add(-time.Hour)},
+		&runner.FakePane{ID: "%2", Command: "codex"},
+	)
+
+	var buf bytes.Buffer
+	if err := runStatus([]string{"--short", "--idle-backend", "tmux"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "1 active, 1 idle") {
+		t.Errorf("unexpected status: %s", buf.String())
+	}
+
+	buf.Reset()
+	runStatus([]string{"--short"}, &buf)
+	if !strings.Contains(buf.String(), "2 active, 0 idle") {
+		t.Errorf("output backend cannot see idle time in one call: %s", buf.String())
+	}
+}
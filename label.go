@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const labelsFile = "labels.json"
+
+// loadLabels returns the persisted pane ID -> task label mapping.
+func loadLabels() map[string]string {
+	labels := make(map[string]string)
+	loadState(labelsFile, &labels)
+	return labels
+}
+
+// saveLabels persists the pane ID -> task label mapping.
+func saveLabels(labels map[string]string) error {
+	return saveState(labelsFile, labels)
+}
+
+// runLabel sets, clears, or lists task labels for panes.
+func runLabel(args []string, w io.Writer) error {
+	labels := loadLabels()
+
+	if len(args) == 0 {
+		if jsonOutput {
+			return writeJSON(w, labels)
+		}
+		if len(labels) == 0 {
+			fmt.Fprintln(w, "No pane labels set")
+			return nil
+		}
+		ids := make([]string, 0, len(labels))
+		for id := range labels {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "PANE\tTASK")
+		for _, id := range ids {
+			fmt.Fprintf(tw, "%s\t%s\n", id, labels[id])
+		}
+		tw.Flush()
+		return nil
+	}
+
+	paneID := args[0]
+	if len(args) < 2 {
+		if label, ok := labels[paneID]; ok {
+			fmt.Fprintln(w, label)
+			return nil
+		}
+		return fmt.Errorf("no label set for pane %s", paneID)
+	}
+
+	if args[1] == "--clear" {
+		delete(labels, paneID)
+		if err := saveLabels(labels); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Cleared label for pane %s\n", paneID)
+		return nil
+	}
+
+	label := strings.Join(args[1:], " ")
+	labels[paneID] = label
+	if err := saveLabels(labels); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Labeled pane %s: %s\n", paneID, label)
+	return nil
+}
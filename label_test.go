@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLabel(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	var buf bytes.Buffer
+	if err := runLabel([]string{"%5", "refactor", "auth"}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Labeled pane %5: refactor auth") {
+		t.Errorf("expected label message, got: %s", buf.String())
+	}
+	if got := loadLabels()["%5"]; got != "refactor auth" {
+		t.Errorf("expected persisted label, got %q", got)
+	}
+
+	buf.Reset()
+	if err := runLabel(nil, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "refactor auth") {
+		t.Errorf("expected label in list, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := runLabel([]string{"%5", "--clear"}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := loadLabels()["%5"]; ok {
+		t.Error("expected label to be cleared")
+	}
+	if err := runLabel([]string{"%5"}, &buf); err == nil {
+		t.Error("expected error for pane without label")
+	}
+}
+
+func TestRunPanes_ShowsLabel(t *testing.T) {
+	dir := t.TempDir()
+
+	tmuxScript := filepath.Join(dir, "tmux")
+	os.WriteFile(tmuxScript, []byte(`#!/bin/sh
+case "$1" in
+  list-panes)
+    printf "%%3\tclaude\t12345\t/tmp/work\n"
+    ;;
+esac
+`), 0755)
+	gitScript := filepath.Join(dir, "git")
+	os.WriteFile(gitScript, []byte("#!/bin/sh\necho main\n"), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	saveLabels(map[string]string{"%3": "write migration"})
+
+	var buf bytes.Buffer
+	if err := runPanes(nil, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "TASK") || !strings.Contains(buf.String(), "write migration") {
+		t.Errorf("expected TASK column with label, got: %s", buf.String())
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logLevel is the minimum level logged, set by --log-level.
+var logLevel = new(slog.LevelVar)
+
+// parseLogLevel parses a --log-level value: debug, info, warn or error.
+func parseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid --log-level value: %s (want debug, info, warn or error)", s)
+	}
+}
+
+// newLogger returns a structured logger writing key=value lines to w,
+// tagged with the component (e.g. "watch") that produced them.
+func newLogger(w io.Writer, component string) *slog.Logger {
+	h := slog.NewTextHandler(w, &slog.HandlerOptions{Level: logLevel})
+	return slog.New(h).With("component", component)
+}
+
+// init routes warnings from one-shot commands to stderr.
+func init() {
+	slog.SetDefault(newLogger(os.Stderr, "cli"))
+}
+
+// paneAttrs returns the standard log fields for a pane.
+func paneAttrs(p *paneInfo) []any {
+	return []any{"pane", p.ID, "agent", p.Command, "repo", shortDir(p.Dir)}
+}
+
+// taskAttrs returns the standard log fields for a task.
+func taskAttrs(t *task) []any {
+	return []any{"task", t.ID, "pane", t.Pane, "agent", t.Agent, "repo", shortDir(t.Dir)}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		got, err := parseLogLevel(in)
+		if err != nil || got != want {
+			t.Errorf("parseLogLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseLogLevel("loud"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	defer logLevel.Set(slog.LevelInfo)
+
+	var buf bytes.Buffer
+	logger := newLogger(&buf, "watch")
+	p := &paneInfo{ID: "%5", Command: "claude", Dir: "/home/user/ghq/github.com/owner/repo"}
+	logger.Info("pane idle", paneAttrs(p)...)
+	logger.Debug("scan complete")
+	if !strings.Contains(buf.String(), `level=INFO msg="pane idle" component=watch pane=%5 agent=claude repo=owner/repo`) {
+		t.Errorf("unexpected log line: %s", buf.String())
+	}
+	if strings.Contains(buf.String(), "scan complete") {
+		t.Error("expected debug message to be filtered at info level")
+	}
+
+	logLevel.Set(slog.LevelDebug)
+	logger.Debug("scan complete")
+	if !strings.Contains(buf.String(), "level=DEBUG") {
+		t.Errorf("expected debug message, got: %s", buf.String())
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 )
@@ -29,7 +30,20 @@ func main() {
 	}
 
 	if err := runSubcommand(args); err != nil {
+		var code exitCodeError
+		if errors.As(err, &code) {
+			os.Exit(int(code))
+		}
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// exitCodeError makes main exit with its value, without printing an error,
+// for subcommands whose exit code is part of their output.
+type exitCodeError int
+
+// Error implements error.
+func (e exitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
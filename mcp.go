@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// mcpProtocolVersion is the Model Context Protocol revision the server speaks.
+const mcpProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes.
+const (
+	mcpParseError     = -32700
+	mcpInvalidRequest = -32600
+	mcpMethodNotFound = -32601
+	mcpInvalidParams  = -32602
+)
+
+// mcpRequest is a JSON-RPC request or, when ID is empty, a notification.
+type mcpRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// mcpResponse is a JSON-RPC response.
+type mcpResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *mcpError       `json:"error,omitempty"`
+}
+
+// mcpError is a JSON-RPC error object.
+type mcpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpProperty describes one tool argument.
+type mcpProperty struct {
+	// Type is "string", "integer" or "boolean".
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// mcpTool is a tmux-agent subcommand exposed as an MCP tool.
+type mcpTool struct {
+	Name        string
+	Description string
+	Properties  map[string]mcpProperty
+	Required    []string
+	// Command is the subcommand the tool runs, checked against
+	// mutatingCommands in read-only mode.
+	Command string
+	Run     func(args []string, w io.Writer) error
+	// Args turns validated arguments into the subcommand's arguments.
+	Args func(a mcpArgs) []string
+}
+
+// mcpArgs are the validated arguments of a tool call.
+type mcpArgs map[string]any
+
+// str returns a string argument, or "" when it was not given.
+func (a mcpArgs) str(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// flag appends "--flag value" to args when the string argument name is set.
+func (a mcpArgs) flag(args []string, flag, name string) []string {
+	if s := a.str(name); s != "" {
+		return append(args, flag, s)
+	}
+	return args
+}
+
+// mcpTools are the tools the server offers.
+var mcpTools = []*mcpTool{
+	{
+		Name:        "list_panes",
+		Description: "List coding agent panes with their state (active, idle, waiting, ...) and last output line.",
+		Command:     "status",
+		Run:         runStatus,
+		Args:        func(mcpArgs) []string { return nil },
+	},
+	{
+		Name:        "capture_pane",
+		Description: "Return the recent output of a pane.",
+		Properties: map[string]mcpProperty{
+			"pane":  {Type: "string", Description: "Pane ID, e.g. %3"},
+			"lines": {Type: "integer", Description: "Number of lines to capture (default 10)"},
+		},
+		Required: []string{"pane"},
+		Command:  "capture",
+		Run:      runCapture,
+		Args: func(a mcpArgs) []string {
+			args := []string{a.str("pane")}
+			if n, ok := a["lines"].(float64); ok {
+				args = append(args, "--lines", strconv.Itoa(int(n)))
+			}
+			return args
+		},
+	},
+	{
+		Name:        "send_keys",
+		Description: "Type text into a pane and press Enter.",
+		Properties: map[string]mcpProperty{
+			"pane": {Type: "string", Description: "Pane ID, e.g. %3"},
+			"text": {Type: "string", Description: "Text to send"},
+		},
+		Required: []string{"pane", "text"},
+		Command:  "send",
+		Run:      runSend,
+		// "--" keeps text such as "-" or "--file x" from being read as
+		// send's flags.
+		Args: func(a mcpArgs) []string { return []string{a.str("pane"), "--", a.str("text")} },
+	},
+	{
+		Name:        "create_pane",
+		Description: "Start a coding agent in a new pane.",
+		Properties: map[string]mcpProperty{
+			"agent":      {Type: "string", Description: "Agent command to run (default: the active agent)"},
+			"model":      {Type: "string", Description: "Model to start the agent with"},
+			"preset":     {Type: "string", Description: "Create preset from config.json"},
+			"prompt":     {Type: "string", Description: "Text to send once the agent has started"},
+			"split":      {Type: "string", Description: "Split direction: h or v"},
+			"session":    {Type: "string", Description: "tmux session to create the pane in"},
+			"new_window": {Type: "boolean", Description: "Create a new window instead of splitting"},
+		},
+		Command: "create",
+		Run:     runCreate,
+		Args: func(a mcpArgs) []string {
+			var args []string
+			args = a.flag(args, "--preset", "preset")
+			args = a.flag(args, "--command", "agent")
+			args = a.flag(args, "--model", "model")
+			args = a.flag(args, "--keys", "prompt")
+			args = a.flag(args, "--split", "split")
+			args = a.flag(args, "--session", "session")
+			if b, _ := a["new_window"].(bool); b {
+				args = append(args, "--new-window")
+			}
+			return args
+		},
+	},
+	{
+		Name:        "kill_pane",
+		Description: "Kill a pane.",
+		Properties: map[string]mcpProperty{
+			"pane": {Type: "string", Description: "Pane ID, e.g. %3"},
+		},
+		Required: []string{"pane"},
+		Command:  "kill",
+		Run:      runKill,
+		// There is no one to answer confirm_destructive's question here.
+		Args: func(a mcpArgs) []string { return []string{a.str("pane"), "--yes"} },
+	},
+	{
+		Name:        "create_workspace",
+		Description: "Create a git worktree for a ghq-managed repository and start an agent in it.",
+		Properties: map[string]mcpProperty{
+			"repo":   {Type: "string", Description: "Repository as owner/repo"},
+			"issue":  {Type: "string", Description: "Issue number; the agent is asked to work on it"},
+			"branch": {Type: "string", Description: "Branch name (default issue-N)"},
+		},
+		Required: []string{"repo"},
+		Command:  "workspace",
+		Run:      runWorkspace,
+		Args: func(a mcpArgs) []string {
+			args := []string{"--repo", a.str("repo")}
+			args = a.flag(args, "--issue", "issue")
+			return a.flag(args, "--branch", "branch")
+		},
+	},
+}
+
+// MarshalJSON encodes the tool as it is listed by tools/list.
+func (t *mcpTool) MarshalJSON() ([]byte, error) {
+	props := t.Properties
+	if props == nil {
+		props = map[string]mcpProperty{}
+	}
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(t.Required) > 0 {
+		schema["required"] = t.Required
+	}
+	return json.Marshal(map[string]any{
+		"name":        t.Name,
+		"description": t.Description,
+		"inputSchema": schema,
+	})
+}
+
+// validate checks call arguments against the tool's schema.
+func (t *mcpTool) validate(a mcpArgs) error {
+	for _, name := range t.Required {
+		if v, ok := a[name]; !ok || v == "" {
+			return fmt.Errorf("%s: missing required argument %q", t.Name, name)
+		}
+	}
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop, ok := t.Properties[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown argument %q", t.Name, name)
+		}
+		valid := false
+		switch v := a[name].(type) {
+		case string:
+			valid = prop.Type == "string"
+		case bool:
+			valid = prop.Type == "boolean"
+		case float64:
+			valid = prop.Type == "integer" && v == float64(int(v)) && v > 0
+		}
+		if !valid && prop.Type == "integer" {
+			return fmt.Errorf("%s: argument %q must be a positive integer", t.Name, name)
+		}
+		if !valid {
+			return fmt.Errorf("%s: argument %q must be a %s", t.Name, name, prop.Type)
+		}
+		// A pane is passed on as a command-line argument; anything but a
+		// pane ID could be taken for a flag.
+		if name == "pane" && !paneIDRe.MatchString(a.str(name)) {
+			return fmt.Errorf("%s: argument %q must be a pane ID such as %%3", t.Name, name)
+		}
+	}
+	return nil
+}
+
+// findMCPTool returns the tool with the given name, or nil.
+func findMCPTool(name string) *mcpTool {
+	for _, t := range mcpTools {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// callMCPTool runs a tool and returns its tools/call result. Failures of
+// the command itself are reported in the result, not as protocol errors,
+// so the calling agent can see and react to them.
+func callMCPTool(params json.RawMessage) (any, *mcpError) {
+	var call struct {
+		Name      string  `json:"name"`
+		Arguments mcpArgs `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &mcpError{mcpInvalidParams, "invalid tools/call params: " + err.Error()}
+	}
+	t := findMCPTool(call.Name)
+	if t == nil {
+		return nil, &mcpError{mcpInvalidParams, fmt.Sprintf("unknown tool %q", call.Name)}
+	}
+	if err := t.validate(call.Arguments); err != nil {
+		return nil, &mcpError{mcpInvalidParams, err.Error()}
+	}
+
+	var out bytes.Buffer
+	var err error
+	if readOnly && mutatingCommands[t.Command] {
+		err = fmt.Errorf("%s is disabled in read-only mode", t.Name)
+	} else {
+		err = t.Run(t.Args(call.Arguments), &out)
+	}
+	text := out.String()
+	if err != nil {
+		text = err.Error()
+	}
+	return map[string]any{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": err != nil,
+	}, nil
+}
+
+// handleMCPRequest returns the result or error for one request.
+func handleMCPRequest(req *mcpRequest) (any, *mcpError) {
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": mcpProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": "tmux-agent", "version": version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": mcpTools}, nil
+	case "tools/call":
+		return callMCPTool(req.Params)
+	}
+	return nil, &mcpError{mcpMethodNotFound, "method not found: " + req.Method}
+}
+
+// serveMCP reads newline-delimited JSON-RPC messages from r and writes
+// responses to w until r is exhausted.
+func serveMCP(r io.Reader, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var req mcpRequest
+		resp := mcpResponse{JSONRPC: "2.0", ID: json.RawMessage("null")}
+		if err := json.Unmarshal(line, &req); err != nil {
+			resp.Error = &mcpError{mcpParseError, "parse error: " + err.Error()}
+		} else if req.JSONRPC != "2.0" || req.Method == "" {
+			resp.ID = orNull(req.ID)
+			resp.Error = &mcpError{mcpInvalidRequest, "invalid request"}
+		} else if len(req.ID) == 0 {
+			// Notifications, such as notifications/initialized, get no reply.
+			continue
+		} else {
+			resp.ID = req.ID
+			resp.Result, resp.Error = handleMCPRequest(&req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// orNull returns id, or a JSON null when it is empty.
+func orNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
+
+// runMCP serves the pane operations as MCP tools over stdio, so an agent
+// can drive its sibling panes. Tool results are the commands' JSON output.
+func runMCP(args []string, w io.Writer) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: tmux-agent mcp")
+	}
+	jsonOutput = true
+	return serveMCP(os.Stdin, w)
+}
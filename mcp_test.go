@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+// mcpExchange sends requests to serveMCP and returns the decoded responses.
+func mcpExchange(t *testing.T, requests ...string) []map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	if err := serveMCP(strings.NewReader(strings.Join(requests, "\n")), &out); err != nil {
+		t.Fatal(err)
+	}
+	var responses []map[string]any
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp map[string]any
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+// mcpResultText returns the text content of a tools/call response.
+func mcpResultText(t *testing.T, resp map[string]any) (string, bool) {
+	t.Helper()
+	result, ok := resp["result"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected a result, got %v", resp)
+	}
+	content := result["content"].([]any)[0].(map[string]any)
+	return content["text"].(string), result["isError"].(bool)
+}
+
+func TestServeMCP_Handshake(t *testing.T) {
+	responses := mcpExchange(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"resources/list"}`,
+		`not json`,
+	)
+	if len(responses) != 4 {
+		t.Fatalf("expected 4 responses (none for the notification), got %d: %v", len(responses), responses)
+	}
+	info := responses[0]["result"].(map[string]any)["serverInfo"].(map[string]any)
+	if info["name"] != "tmux-agent" {
+		t.Errorf("unexpected serverInfo: %v", info)
+	}
+
+	tools := responses[1]["result"].(map[string]any)["tools"].([]any)
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.(map[string]any)["name"].(string))
+	}
+	if got := strings.Join(names, ","); got != "list_panes,capture_pane,send_keys,create_pane,kill_pane,create_workspace" {
+		t.Errorf("unexpected tools: %s", got)
+	}
+
+	if code := responses[2]["error"].(map[string]any)["code"].(float64); code != mcpMethodNotFound {
+		t.Errorf("expected method not found, got %v", responses[2])
+	}
+	if code := responses[3]["error"].(map[string]any)["code"].(float64); code != mcpParseError || responses[3]["id"] != nil {
+		t.Errorf("expected parse error with null id, got %v", responses[3])
+	}
+}
+
+func TestServeMCP_ToolCalls(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "line1\nAll tests pass"})
+	useJSONOutput(t)
+
+	responses := mcpExchange(t,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"capture_pane","arguments":{"pane":"%1","lines":5}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"send_keys","arguments":{"pane":"%1","text":"run the linter"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"kill_pane","arguments":{"pane":"%9"}}}`,
+	)
+
+	text, isError := mcpResultText(t, responses[0])
+	var capture actionJSON
+	if err := json.Unmarshal([]byte(text), &capture); err != nil || isError || !strings.Contains(capture.Output, "All tests pass") {
+		t.Errorf("unexpected capture result: %q (err %v)", text, err)
+	}
+
+	if _, isError := mcpResultText(t, responses[1]); isError {
+		t.Errorf("send failed: %v", responses[1])
+	}
+	if got := fake.Panes[0].Input; len(got) == 0 || !strings.Contains(strings.Join(got, ""), "run the linter") {
+		t.Errorf("expected text to be sent, got %q", got)
+	}
+
+	// Text that looks like send's flags is typed as it is.
+	for i, text := range []string{"-", "--file /etc/hosts", "--edit"} {
+		resp := mcpExchange(t, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"send_keys","arguments":{"pane":"%%1","text":%q}}}`, i, text))[0]
+		if _, isError := mcpResultText(t, resp); isError {
+			t.Errorf("send %q failed: %v", text, resp)
+		}
+		if got := fake.Panes[0].Input; !strings.Contains(strings.Join(got, "|"), text) {
+			t.Errorf("expected %q to be typed, got %q", text, got)
+		}
+	}
+
+	// Command failures are tool results, not protocol errors.
+	if _, isError := mcpResultText(t, responses[2]); !isError {
+		t.Errorf("expected killing a missing pane to fail, got %v", responses[2])
+	}
+}
+
+func TestServeMCP_Validation(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+	useJSONOutput(t)
+
+	for _, tt := range []struct {
+		name, params, want string
+	}{
+		{"missing", `{"name":"send_keys","arguments":{"pane":"%1"}}`, `missing required argument "text"`},
+		{"unknown argument", `{"name":"kill_pane","arguments":{"pane":"%1","force":true}}`, `unknown argument "force"`},
+		{"wrong type", `{"name":"capture_pane","arguments":{"pane":"%1","lines":"ten"}}`, `"lines" must be a positive integer`},
+		{"fraction", `{"name":"capture_pane","arguments":{"pane":"%1","lines":2.5}}`, `"lines" must be a positive integer`},
+		{"unknown tool", `{"name":"rm_rf","arguments":{}}`, `unknown tool "rm_rf"`},
+		{"pane flag", `{"name":"send_keys","arguments":{"pane":"--file","text":"/etc/passwd"}}`, `"pane" must be a pane ID`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := mcpExchange(t, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":`+tt.params+`}`)[0]
+			e, ok := resp["error"].(map[string]any)
+			if !ok || e["code"].(float64) != mcpInvalidParams || !strings.Contains(e["message"].(string), tt.want) {
+				t.Errorf("expected invalid params error containing %q, got %v", tt.want, resp)
+			}
+		})
+	}
+	if len(fake.Panes[0].Input) != 0 {
+		t.Errorf("invalid calls should not reach tmux, got input %q", fake.Panes[0].Input)
+	}
+}
+
+func TestServeMCP_ReadOnly(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+	useJSONOutput(t)
+	readOnly = true
+	defer func() { readOnly = false }()
+
+	resp := mcpExchange(t, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"send_keys","arguments":{"pane":"%1","text":"hi"}}}`)[0]
+	if text, isError := mcpResultText(t, resp); !isError || !strings.Contains(text, "read-only") {
+		t.Errorf("expected send to be refused, got %q", text)
+	}
+	if len(fake.Panes[0].Input) != 0 {
+		t.Errorf("expected nothing sent, got %q", fake.Panes[0].Input)
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// menuRefreshInterval is how often the menu re-reads the pane list.
+const menuRefreshInterval = 2 * time.Second
+
+// paneKeysHelp is the footer of the menu and the dashboard, which share
+// their keys (see runPaneList).
+const paneKeysHelp = "↑↓ select  c capture  g go to  s send  p saved prompt  r restart  x kill  q quit"
+
+// renderMenu draws the pane list with the selected pane highlighted. The
+// last line shows message, or the key help when there is none.
+func renderMenu(panes []paneInfo, sel, width, height int, message string) string {
+	var sb strings.Builder
+	sb.WriteString(ansiClear)
+	sb.WriteString(ansiBold + fitString("tmux-agent", width) + ansiReset + "\n")
+	rows := max(height-3, 1)
+	start := 0
+	if sel >= rows {
+		start = sel - rows + 1
+	}
+	for i := start; i < len(panes) && i < start+rows; i++ {
+		p := panes[i]
+		line := fitString(fmt.Sprintf("%-5s %-7s %-20s %s", p.ID, p.Command, truncateWidth(shortDir(p.Dir), 20), p.LastOutput), width)
+		if i == sel {
+			line = ansiReverse + line + ansiReset
+		}
+		sb.WriteString(line + "\n")
+	}
+	if len(panes) == 0 {
+		sb.WriteString("No coding agent panes found\n")
+	}
+	footer := paneKeysHelp
+	if message != "" {
+		footer = message
+	}
+	sb.WriteString(fitString(footer, width))
+	return sb.String()
+}
+
+// menuPrompt reads a line of text from keys, echoing it after prompt on the
+// last line. It returns false if the prompt was cancelled with Escape.
+func menuPrompt(w io.Writer, keys <-chan string, prompt string) (string, bool) {
+	width, height := terminalSize()
+	var text []rune
+	for {
+		fmt.Fprintf(w, "\x1b[%d;1H%s", height, fitString(prompt+string(text), width))
+		k, ok := <-keys
+		if !ok {
+			return "", false
+		}
+		switch k {
+		case "enter":
+			return string(text), true
+		case "esc", "ctrl-c":
+			return "", false
+		case "backspace":
+			if len(text) > 0 {
+				text = text[:len(text)-1]
+			}
+		default:
+			if r := []rune(k); len(r) == 1 {
+				text = append(text, r[0])
+			}
+		}
+	}
+}
+
+// menuCapture shows the end of a pane's output until a key is pressed.
+func menuCapture(w io.Writer, keys <-chan string, p paneInfo) {
+	width, height := terminalSize()
+	output, err := capturePaneOutput(p.ID, height-1)
+	if err != nil {
+		output = err.Error()
+	}
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > height-1 {
+		lines = lines[len(lines)-(height-1):]
+	}
+	var sb strings.Builder
+	sb.WriteString(ansiClear)
+	for _, line := range lines {
+		sb.WriteString(truncateWidth(line, width) + "\n")
+	}
+	sb.WriteString(ansiBold + fitString(p.ID+" "+p.Command+"  (any key to go back)", width) + ansiReset)
+	fmt.Fprint(w, rawLines(sb.String()))
+	<-keys
+}
+
+// menuAction runs the action bound to key on pane p and returns a status
+// message for the menu, and whether the action was carried out (rather than
+// cancelled, failed, or only looked at the pane).
+func menuAction(w io.Writer, keys <-chan string, key string, p paneInfo) (string, bool) {
+	if readOnly && key != "c" && key != "enter" && key != "g" {
+		return "disabled in read-only mode", false
+	}
+	switch key {
+	case "c", "enter":
+		menuCapture(w, keys, p)
+		return "", false
+	case "g":
+		if _, err := tmuxRunner.Run("switch-client", "-t", p.ID); err != nil {
+			return err.Error(), false
+		}
+		return "", true
+	case "s":
+		text, ok := menuPrompt(w, keys, "send to "+p.ID+": ")
+		if !ok || strings.TrimSpace(text) == "" {
+			return "", false
+		}
+		if err := sendTmuxKeys(p.ID, text); err != nil {
+			return err.Error(), false
+		}
+		return "sent to " + p.ID, true
+	case "p":
+		return menuSendPrompt(w, keys, p)
+	case "r":
+		if answer, _ := menuPrompt(w, keys, "restart "+p.ID+"? [y/N] "); answer != "y" {
+			return "", false
+		}
+		if _, err := restartAgent(p.ID, restartOpts{}); err != nil {
+			return err.Error(), false
+		}
+		return "restarted " + p.ID, true
+	case "x":
+		if answer, _ := menuPrompt(w, keys, "kill "+p.ID+"? [y/N] "); answer != "y" {
+			return "", false
+		}
+		if _, err := stopPane(p.ID, false); err != nil {
+			return err.Error(), false
+		}
+		return "killed " + p.ID, true
+	}
+	return "", false
+}
+
+// menuSendPrompt asks for the name of a saved prompt (see `prompt save`)
+// and sends it to pane p, filled in for the pane.
+func menuSendPrompt(w io.Writer, keys <-chan string, p paneInfo) (string, bool) {
+	names, err := promptNames()
+	if err != nil {
+		return err.Error(), false
+	}
+	if len(names) == 0 {
+		return "no saved prompts (see tmux-agent prompt save)", false
+	}
+	name, ok := menuPrompt(w, keys, "prompt for "+p.ID+" ("+strings.Join(names, ", ")+"): ")
+	if name = strings.TrimSpace(name); !ok || name == "" {
+		return "", false
+	}
+	text, err := loadPrompt(name)
+	if err == nil {
+		text, err = expandPrompt(text, p, nil)
+	}
+	if err == nil {
+		err = sendTmuxText(p.ID, text, enterDefault, false)
+	}
+	if err != nil {
+		return err.Error(), false
+	}
+	return "sent " + name + " to " + p.ID, true
+}
+
+// runMenu shows the interactive pane menu in the current terminal. With
+// quick set it is a one-shot palette: it exits once an action is done.
+func runMenu(w io.Writer, quick bool) error {
+	load := func() ([]paneInfo, error) {
+		panes, err := listTmuxPanes()
+		for i := range panes {
+			if output, err := capturePaneOutput(panes[i].ID, 10); err == nil {
+				panes[i].LastOutput = truncateLastLine(strings.TrimRight(output, "\n"), maxLastOutputWidth)
+			}
+		}
+		return panes, err
+	}
+	return runPaneList(w, menuRefreshInterval, quick, load, renderMenu)
+}
+
+// runPaneList runs the key loop of the menu and the dashboard in raw mode:
+// every interval, and after each key, it calls load for the panes and draws
+// them with render, with the selected one highlighted; the keys in
+// paneKeysHelp move the selection and act on the selected pane (see
+// menuAction). With quick set it exits once an action is done.
+func runPaneList(w io.Writer, interval time.Duration, quick bool,
+	load func() ([]paneInfo, error),
+	render func(panes []paneInfo, sel, width, height int, message string) string) error {
+	restore, err := enterRawMode()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	keys, stop := readKeys()
+	defer stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	sel := 0
+	message := ""
+	for {
+		panes, err := load()
+		if err != nil {
+			message = err.Error()
+		}
+		sel = min(sel, max(len(panes)-1, 0))
+
+		width, height := terminalSize()
+		fmt.Fprint(w, rawLines(render(panes, sel, width, height, message)))
+
+		select {
+		case <-ticker.C:
+			continue
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			message = ""
+			switch k {
+			case "q", "esc", "ctrl-c":
+				fmt.Fprint(w, ansiClear)
+				return nil
+			case "up", "k":
+				sel = max(sel-1, 0)
+			case "down", "j":
+				sel++
+			case "c", "enter", "g", "s", "p", "r", "x":
+				if sel < len(panes) {
+					var done bool
+					message, done = menuAction(w, keys, k, panes[sel])
+					if quick && done {
+						fmt.Fprint(w, ansiClear)
+						return nil
+					}
+				}
+			}
+		}
+	}
+}
+
+// runMenuPopup opens the pane menu in a tmux popup, or with --inline runs
+// it in the current terminal (which is what the popup itself does). With
+// --quick (`popup`) the popup closes as soon as an action is done.
+func runMenuPopup(args []string, w io.Writer) error {
+	inline, quick := false, false
+	width, height := "80%", "60%"
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--inline":
+			inline = true
+		case "--quick":
+			quick = true
+		case "--width", "--height":
+			if i+1 >= len(args) {
+				return fmt.Errorf("usage: tmux-agent menu-popup [--width size] [--height size] [--quick] [--inline]")
+			}
+			if args[i] == "--width" {
+				width = args[i+1]
+			} else {
+				height = args[i+1]
+			}
+			i++
+		default:
+			return fmt.Errorf("usage: tmux-agent menu-popup [--width size] [--height size] [--quick] [--inline]")
+		}
+	}
+	if inline {
+		return runMenu(w, quick)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating tmux-agent: %w", err)
+	}
+	command := shellQuote(exe)
+	if readOnly {
+		command += " --read-only"
+	}
+	command += " menu-popup --inline"
+	if quick {
+		command += " --quick"
+	}
+	if _, err := tmuxRunner.Run("display-popup", "-E", "-w", width, "-h", height, command); err != nil {
+		return fmt.Errorf("tmux display-popup: %w", err)
+	}
+	return nil
+}
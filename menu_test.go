@@ -0,0 +1,131 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRenderMenu(t *testing.T) {
+	panes := []paneInfo{
+		{ID: "%1", Command: "claude", Dir: "/work/api", LastOutput: "All tests pass"},
+		{ID: "%2", Command: "codex", Dir: "/work/web", LastOutput: "Thinking..."},
+		{ID: "%3", Command: "claude", Dir: "/work/cli"},
+	}
+	out := renderMenu(panes, 1, 60, 10, "sent to %1")
+	if !strings.Contains(out, ansiReverse+"%2    codex") {
+		t.Errorf("expected selected pane to be highlighted, got:\n%s", out)
+	}
+	if !strings.Contains(out, "All tests pass") || !strings.HasSuffix(strings.TrimRight(out, " "), "sent to %1") {
+		t.Errorf("unexpected menu:\n%s", out)
+	}
+
+	// Only the rows that fit are shown, scrolled to keep the selection visible.
+	out = renderMenu(panes, 2, 60, 5, "")
+	if strings.Contains(out, "%1 ") || !strings.Contains(out, "%3 ") {
+		t.Errorf("expected the list to scroll to the selection, got:\n%s", out)
+	}
+}
+
+func TestMenuAction_Send(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+	t.Setenv("HOME", t.TempDir())
+
+	keys := make(chan string, 8)
+	for _, k := range []string{"h", "i", "x", "backspace", "enter"} {
+		keys <- k
+	}
+	msg, done := menuAction(io.Discard, keys, "s", paneInfo{ID: "%1", Command: "claude"})
+	if msg != "sent to %1" || !done {
+		t.Errorf("unexpected message %q, done %v", msg, done)
+	}
+	if input := strings.Join(fake.Panes[0].Input, "\n"); !strings.Contains(input, "hi") || strings.Contains(input, "hix") {
+		t.Errorf("unexpected input %q", input)
+	}
+
+	// Cancelling a confirmation leaves the pane alone.
+	keys <- "n"
+	keys <- "enter"
+	if msg, done := menuAction(io.Discard, keys, "x", paneInfo{ID: "%1"}); msg != "" || done || len(fake.Panes) != 1 {
+		t.Errorf("expected kill to be cancelled, got %q", msg)
+	}
+}
+
+func TestMenuAction_KillGraceful(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+	t.Setenv("HOME", t.TempDir())
+	origDelay, origLookup, origGrace := restartDelay, agentCommandFn, killGracePeriod
+	restartDelay, killGracePeriod = 0, 50*time.Millisecond
+	// The agent exits once it has been sent its exit command.
+	agentCommandFn = func(paneID string) string {
+		p := fake.Pane(paneID)
+		if p == nil || strings.Contains(strings.Join(p.Input, " "), "Enter") {
+			return ""
+		}
+		return p.Command
+	}
+	defer func() { restartDelay, agentCommandFn, killGracePeriod = origDelay, origLookup, origGrace }()
+
+	keys := make(chan string, 2)
+	keys <- "y"
+	keys <- "enter"
+	p := fake.Pane("%1")
+	if msg, done := menuAction(io.Discard, keys, "x", paneInfo{ID: "%1"}); msg != "killed %1" || !done {
+		t.Fatalf("unexpected message %q, done %v", msg, done)
+	}
+	if got := strings.Join(p.Input, " | "); got != "C-c | /exit Enter" {
+		t.Errorf("expected claude to be asked to exit, got %q", got)
+	}
+	if fake.Pane("%1") != nil {
+		t.Error("expected the pane to be killed")
+	}
+}
+
+func TestRunMenuPopup(t *testing.T) {
+	fake := useFakeTmux(t)
+	if err := runMenuPopup([]string{"--width", "100"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	call := fake.Calls[len(fake.Calls)-1]
+	got := strings.Join(call, " ")
+	if call[0] != "display-popup" || !strings.Contains(got, "-w 100 -h 60%") || !strings.HasSuffix(got, "' menu-popup --inline") {
+		t.Errorf("unexpected popup call: %q", call)
+	}
+	if err := runMenuPopup([]string{"--bogus"}, io.Discard); err == nil {
+		t.Error("expected usage error")
+	}
+}
+
+func TestMenuAction_SavedPrompt(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Dir: "/src/github.com/owner/api"})
+	t.Setenv("HOME", t.TempDir())
+
+	keys := make(chan string, 16)
+	if msg, done := menuAction(io.Discard, keys, "p", paneInfo{ID: "%1"}); done || !strings.Contains(msg, "no saved prompts") {
+		t.Errorf("expected no saved prompts, got %q", msg)
+	}
+	runPrompt([]string{"save", "ci", "Fix", "CI", "in", "{{.Repo}}"}, io.Discard)
+	for _, k := range []string{"c", "i", "enter"} {
+		keys <- k
+	}
+	p := paneInfo{ID: "%1", Command: "claude", Dir: "/src/github.com/owner/api"}
+	if msg, done := menuAction(io.Discard, keys, "p", p); msg != "sent ci to %1" || !done {
+		t.Errorf("unexpected message %q, done %v", msg, done)
+	}
+	if in := fake.Pane("%1").Input; len(in) == 0 || in[0] != "Fix CI in owner/api" {
+		t.Errorf("unexpected input %q", in)
+	}
+}
+
+func TestRunMenuPopup_Quick(t *testing.T) {
+	fake := useFakeTmux(t)
+	if err := runMenuPopup([]string{"--quick"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fake.Calls[len(fake.Calls)-1], " "); !strings.HasSuffix(got, "' menu-popup --inline --quick") {
+		t.Errorf("unexpected popup call: %q", got)
+	}
+}
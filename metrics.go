@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+// countingRunner wraps a runner and counts the tmux commands that fail, for
+// the tmux_agent_tmux_errors_total metric.
+type countingRunner struct {
+	runner.TmuxRunner
+	errors *atomic.Int64
+}
+
+// Run runs args with the wrapped runner, counting failures.
+func (r countingRunner) Run(args ...string) ([]byte, error) {
+	out, err := r.TmuxRunner.Run(args...)
+	if err != nil {
+		r.errors.Add(1)
+	}
+	return out, err
+}
+
+// Unwrap returns the wrapped runner.
+func (r countingRunner) Unwrap() runner.TmuxRunner {
+	return r.TmuxRunner
+}
+
+// paneMetric is what watch's last scan saw of one pane.
+type paneMetric struct {
+	pane, agent, repo, state string
+	lastChange               time.Time
+}
+
+// watchMetrics holds the fleet state watch exposes with --metrics-listen.
+// scan updates it; the HTTP handler reads it.
+type watchMetrics struct {
+	mu    sync.Mutex
+	panes []paneMetric
+	scans int64
+	// tmuxErrors counts failed tmux commands, see countingRunner.
+	tmuxErrors atomic.Int64
+}
+
+// update records the panes of a scan and their states.
+func (m *watchMetrics) update(panes []paneInfo, states map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.panes = m.panes[:0]
+	for _, p := range panes {
+		m.panes = append(m.panes, paneMetric{
+			pane:       p.ID,
+			agent:      p.Command,
+			repo:       shortDir(p.Dir),
+			state:      states[p.ID],
+			lastChange: p.LastChangeAt,
+		})
+	}
+	m.scans++
+}
+
+// write prints the metrics in the Prometheus text format. Idle seconds are
+// measured at now; prompts is the number of prompts in the sent log.
+func (m *watchMetrics) write(w io.Writer, now time.Time, prompts int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byState := make(map[string]int)
+	for _, p := range m.panes {
+		byState[p.state]++
+	}
+	states := make([]string, 0, len(byState))
+	for s := range byState {
+		states = append(states, s)
+	}
+	sort.Strings(states)
+
+	writeMetricHeader(w, "tmux_agent_panes", "gauge", "Coding agent panes found by the last scan.")
+	fmt.Fprintf(w, "tmux_agent_panes %d\n", len(m.panes))
+	writeMetricHeader(w, "tmux_agent_panes_idle", "gauge", "Coding agent panes that are idle.")
+	fmt.Fprintf(w, "tmux_agent_panes_idle %d\n", byState[stateIdle])
+	writeMetricHeader(w, "tmux_agent_panes_by_state", "gauge", "Coding agent panes per state.")
+	for _, s := range states {
+		fmt.Fprintf(w, "tmux_agent_panes_by_state{state=%s} %d\n", metricLabel(s), byState[s])
+	}
+	writeMetricHeader(w, "tmux_agent_pane_idle_seconds", "gauge", "Seconds since the pane's output last changed.")
+	for _, p := range m.panes {
+		idle := 0.0
+		if !p.lastChange.IsZero() {
+			idle = now.Sub(p.lastChange).Seconds()
+		}
+		fmt.Fprintf(w, "tmux_agent_pane_idle_seconds{pane=%s,agent=%s,repo=%s} %.0f\n",
+			metricLabel(p.pane), metricLabel(p.agent), metricLabel(p.repo), idle)
+	}
+	writeMetricHeader(w, "tmux_agent_prompts_sent_total", "counter", "Prompts sent to panes, from the sent log.")
+	fmt.Fprintf(w, "tmux_agent_prompts_sent_total %d\n", prompts)
+	writeMetricHeader(w, "tmux_agent_tmux_errors_total", "counter", "tmux commands that failed.")
+	fmt.Fprintf(w, "tmux_agent_tmux_errors_total %d\n", m.tmuxErrors.Load())
+	writeMetricHeader(w, "tmux_agent_scans_total", "counter", "Scans watch has completed.")
+	fmt.Fprintf(w, "tmux_agent_scans_total %d\n", m.scans)
+}
+
+// writeMetricHeader prints a metric's HELP and TYPE lines.
+func writeMetricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// metricLabelEscaper escapes a Prometheus label value.
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricLabel quotes a label value.
+func metricLabel(s string) string {
+	return `"` + metricLabelEscaper.Replace(s) + `"`
+}
+
+// handler serves the metrics at /metrics.
+func (m *watchMetrics) handler(logger *slog.Logger) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		sent, err := loadSent()
+		if err != nil {
+			logger.Warn("reading sent log failed", "err", err)
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.write(w, time.Now(), len(sent))
+	})
+	return mux
+}
+
+// serveMetrics starts serving m on addr and returns the server to close.
+// Listening happens before it returns, so a bad address fails watch at
+// once rather than in the background.
+func serveMetrics(addr string, m *watchMetrics, logger *slog.Logger) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening for metrics: %w", err)
+	}
+	srv := &http.Server{Handler: m.handler(logger), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Warn("metrics server failed", "err", err)
+		}
+	}()
+	return srv, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestWatchMetrics_Write(t *testing.T) {
+	now := time.Now()
+	m := &watchMetrics{}
+	m.update([]paneInfo{
+		{ID: "%1", Command: "claude", Dir: "/src/github.com/owner/a", LastChangeAt: now.Add(-90 * time.Second)},
+		{ID: "%2", Command: "codex", Dir: "/src/github.com/owner/b", LastChangeAt: now.Add(-10 * time.Second)},
+		{ID: "%3", Command: "claude", Dir: "/src/github.com/owner/c", LastChangeAt: now.Add(-5 * time.Minute)},
+	}, map[string]string{"%1": stateIdle, "%2": stateActive, "%3": stateIdle})
+	m.tmuxErrors.Add(2)
+
+	var buf strings.Builder
+	m.write(&buf, now, 7)
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE tmux_agent_panes gauge\ntmux_agent_panes 3\n",
+		"tmux_agent_panes_idle 2\n",
+		`tmux_agent_panes_by_state{state="active"} 1`,
+		`tmux_agent_panes_by_state{state="idle"} 2`,
+		`tmux_agent_pane_idle_seconds{pane="%1",agent="claude",repo="owner/a"} 90`,
+		`tmux_agent_pane_idle_seconds{pane="%3",agent="claude",repo="owner/c"} 300`,
+		"# TYPE tmux_agent_prompts_sent_total counter\ntmux_agent_prompts_sent_total 7\n",
+		"tmux_agent_tmux_errors_total 2\n",
+		"tmux_agent_scans_total 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in metrics, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestMetricLabel(t *testing.T) {
+	if got := metricLabel(`a"b\c` + "\n"); got != `"a\"b\\c\n"` {
+		t.Errorf("metricLabel = %s", got)
+	}
+}
+
+func TestWatchMetrics_Handler(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := &watchMetrics{}
+	srv := httptest.NewServer(m.handler(newLogger(&strings.Builder{}, "watch")))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
+
+// failingRunner fails every command.
+type failingRunner struct{}
+
+func (failingRunner) Run(args ...string) ([]byte, error) { return nil, errors.New("no server") }
+
+func TestCountingRunner(t *testing.T) {
+	var n atomic.Int64
+	var r runner.TmuxRunner = countingRunner{TmuxRunner: failingRunner{}, errors: &n}
+	r.Run("list-panes")
+	r.Run("capture-pane")
+	if n.Load() != 2 {
+		t.Errorf("expected 2 errors counted, got %d", n.Load())
+	}
+}
+
+func TestCountingRunner_Unwrap(t *testing.T) {
+	var n atomic.Int64
+	d := runner.Docker{Container: "sandbox"}
+	r := countingRunner{TmuxRunner: runner.ReadOnly{Runner: d}, errors: &n}
+	if got, ok := runner.Unwrap(r).(runner.Docker); !ok || got != d {
+		t.Errorf("expected the docker runner under the wrappers, got %#v", got)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// defaultModelFlags are the flags each agent CLI takes to select a model.
+// Override or add agents with "model_flag" in config.json.
+var defaultModelFlags = map[string]string{
+	"claude": "--model",
+	"codex":  "-m",
+}
+
+// launchCommand returns command with the flag that selects model appended.
+// An empty model falls back to the agent's configured "model"; with
+// neither, command is returned unchanged.
+func (c *agentConfig) launchCommand(command, model string) (string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return command, nil
+	}
+	agent := filepath.Base(fields[0])
+	profile := c.agent(agent)
+	if model == "" {
+		model = profile.Model
+	}
+	if model == "" {
+		return command, nil
+	}
+	flag := profile.ModelFlag
+	if flag == "" {
+		flag = defaultModelFlags[agent]
+	}
+	if flag == "" {
+		return "", fmt.Errorf("don't know how to select a model for %s; set agents.%s.model_flag", agent, agent)
+	}
+	return command + " " + flag + " " + shellQuote(model), nil
+}
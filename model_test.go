@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestLaunchCommand(t *testing.T) {
+	cfg := &agentConfig{Agents: map[string]*agentProfile{
+		"claude": {Model: "sonnet"},
+		"aider":  {Model: "gpt-4o", ModelFlag: "--model"},
+	}}
+	tests := []struct {
+		command, model, want string
+	}{
+		{"claude", "", "claude --model 'sonnet'"},
+		{"claude", "opus", "claude --model 'opus'"},
+		{"/usr/local/bin/codex", "o3", "/usr/local/bin/codex -m 'o3'"},
+		{"codex", "", "codex"},
+		{"aider", "", "aider --model 'gpt-4o'"},
+	}
+	for _, tt := range tests {
+		got, err := cfg.launchCommand(tt.command, tt.model)
+		if err != nil || got != tt.want {
+			t.Errorf("launchCommand(%q, %q) = %q, %v; want %q", tt.command, tt.model, got, err, tt.want)
+		}
+	}
+	if _, err := cfg.launchCommand("gemini", "pro"); err == nil || !strings.Contains(err.Error(), "model_flag") {
+		t.Errorf("expected unknown model flag error, got %v", err)
+	}
+}
+
+func TestRunCreate_Model(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "zsh", Dir: "/work/a"})
+	t.Setenv("HOME", t.TempDir())
+	origAgent := activeAgent
+	activeAgent = "codex"
+	defer func() { activeAgent = origAgent }()
+
+	if err := runCreate([]string{"--model", "o3"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	var split []string
+	for _, call := range fake.Calls {
+		if call[0] == "split-window" {
+			split = call
+		}
+	}
+	if len(split) == 0 || split[len(split)-1] != "codex -m 'o3'" {
+		t.Errorf("unexpected split-window call: %q", split)
+	}
+}
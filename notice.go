@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pane states recognized from agent messages. They take precedence over
+// active/idle in status and watch. stateWaiting (see activity.go) is a
+// pane showing a question or a choice that the agent is waiting on;
+// stateNeedsApproval one asking permission to run a command or make an
+// edit.
+const (
+	stateRateLimited   = "rate-limited"
+	stateCompacting    = "compacting"
+	stateNeedsApproval = "needs-approval"
+)
+
+// noticeLines is how many trailing non-empty lines of a capture are
+// searched for rate-limit, compaction, approval and waiting messages.
+// Agent TUIs print these just above the input box, so older occurrences in
+// the scrollback are not mistaken for the current state.
+const noticeLines = 8
+
+// noticeCaptureLines is how many lines status and watch capture, enough to
+// hold noticeLines non-empty lines of an agent TUI padded with blank lines.
+const noticeCaptureLines = 20
+
+// defaultRateLimitPatterns match claude and codex rate-limit and usage-limit
+// messages, including ones with a reset countdown.
+var defaultRateLimitPatterns = []string{
+	`(?i)usage limit`,
+	`(?i)\blimit reached\b`,
+	`(?i)rate[ -]limit(ed|s)?\b.*(reached|exceeded|hit|try again|retry)`,
+	`(?i)too many requests|api error:? 429`,
+	`(?i)try again (in|at) \d`,
+}
+
+// defaultCompactionPatterns match in-progress context compaction notices.
+var defaultCompactionPatterns = []string{
+	`(?i)compacting (the )?(conversation|context)`,
+	`(?i)auto-compacting`,
+}
+
+// defaultApprovalPatterns match claude and codex permission prompts for
+// commands, edits and tool calls.
+var defaultApprovalPatterns = []string{
+	`(?i)\bdo you want to\b.*\?`,
+	`(?i)^\W*allow\b.*\?`,
+	`(?i)\bwould you like to (run|make|apply) the following\b`,
+	`(?i)\bapproval (required|needed)\b`,
+}
+
+// defaultWaitingPatterns match claude and codex questions, selection menus
+// and confirmations that block until someone answers.
+var defaultWaitingPatterns = []string{
+	`(?i)\b(enter|↵) to (select|confirm|submit)\b`,
+	`(?i)[(\[]y/n[)\]]`,
+	`^\s*[❯›]\s*1\.\s`,
+}
+
+// noticePatterns extend the built-in rate-limit, compaction, approval and
+// waiting patterns for an agent.
+type noticePatterns struct {
+	RateLimitPatterns  []string `json:"rate_limit_patterns,omitempty"`
+	CompactionPatterns []string `json:"compaction_patterns,omitempty"`
+	ApprovalPatterns   []string `json:"approval_patterns,omitempty"`
+	WaitingPatterns    []string `json:"waiting_patterns,omitempty"`
+}
+
+// noticeRules is the compiled set of notice patterns for one agent.
+type noticeRules struct {
+	rateLimit  []*regexp.Regexp
+	compaction []*regexp.Regexp
+	approval   []*regexp.Regexp
+	waiting    []*regexp.Regexp
+}
+
+// noticeRules compiles the built-in and configured notice patterns for agent.
+func (c *agentConfig) noticeRules(agent string) (*noticeRules, error) {
+	extra := c.agent(agent).noticePatterns
+	rules := &noticeRules{}
+	for _, set := range []struct {
+		dst      *[]*regexp.Regexp
+		patterns []string
+	}{
+		{&rules.rateLimit, append(append([]string(nil), defaultRateLimitPatterns...), extra.RateLimitPatterns...)},
+		{&rules.compaction, append(append([]string(nil), defaultCompactionPatterns...), extra.CompactionPatterns...)},
+		{&rules.approval, append(append([]string(nil), defaultApprovalPatterns...), extra.ApprovalPatterns...)},
+		{&rules.waiting, append(append([]string(nil), defaultWaitingPatterns...), extra.WaitingPatterns...)},
+	} {
+		for _, s := range set.patterns {
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid notice pattern %q: %w", s, err)
+			}
+			*set.dst = append(*set.dst, re)
+		}
+	}
+	return rules, nil
+}
+
+// detect looks for a rate-limit, compaction, approval or waiting message
+// near the end of output. It returns the state and the matching line, or ""
+// if none. A permission prompt is a menu as well, so approval wins over
+// waiting anywhere in the lines checked.
+func (r *noticeRules) detect(output string) (string, string) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	checked := 0
+	waiting := ""
+	for i := len(lines) - 1; i >= 0 && checked < noticeLines; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		checked++
+		if matchAny(r.rateLimit, line) {
+			return stateRateLimited, line
+		}
+		if matchAny(r.compaction, line) {
+			return stateCompacting, line
+		}
+		if matchAny(r.approval, line) {
+			return stateNeedsApproval, line
+		}
+		if waiting == "" && matchAny(r.waiting, line) {
+			waiting = line
+		}
+	}
+	if waiting != "" {
+		return stateWaiting, waiting
+	}
+	return "", ""
+}
+
+// noticeDetector caches compiled notice rules per agent.
+type noticeDetector struct {
+	cfg   *agentConfig
+	rules map[string]*noticeRules
+}
+
+// newNoticeDetector returns a detector using cfg's patterns.
+func newNoticeDetector(cfg *agentConfig) *noticeDetector {
+	return &noticeDetector{cfg: cfg, rules: make(map[string]*noticeRules)}
+}
+
+// detect returns the notice state and message for a pane's output.
+func (d *noticeDetector) detect(p *paneInfo, output string) (string, string, error) {
+	rules, ok := d.rules[p.Command]
+	if !ok {
+		var err error
+		if rules, err = d.cfg.noticeRules(p.Command); err != nil {
+			return "", "", err
+		}
+		d.rules[p.Command] = rules
+	}
+	state, msg := rules.detect(output)
+	return state, msg, nil
+}
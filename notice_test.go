@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestNoticeRulesDetect(t *testing.T) {
+	rules, err := (&agentConfig{}).noticeRules("claude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		output    string
+		wantState string
+		wantMsg   string
+	}{
+		{"working", "Running tests...\n\n> ", "", ""},
+		{"claude usage limit", "⎿ Claude usage limit reached. Your limit will reset at 3pm (Europe/Berlin).\n\n> \n", stateRateLimited, "⎿ Claude usage limit reached. Your limit will reset at 3pm (Europe/Berlin)."},
+		{"codex usage limit", "■ You've hit your usage limit. Try again in 2 hours 13 minutes.\n› ", stateRateLimited, "■ You've hit your usage limit. Try again in 2 hours 13 minutes."},
+		{"api 429", "API Error: 429 {\"type\":\"rate_limit_error\"}", stateRateLimited, `API Error: 429 {"type":"rate_limit_error"}`},
+		{"compacting", "✻ Compacting conversation… (esc to interrupt)\n\n>", stateCompacting, "✻ Compacting conversation… (esc to interrupt)"},
+		{"claude question", "Which database should I use?\n❯ 1. Postgres\n  2. SQLite\n\nEnter to select · ↑/↓ to navigate · Esc to cancel\n", stateWaiting, "Enter to select · ↑/↓ to navigate · Esc to cancel"},
+		{"confirmation", "Overwrite the existing config? (y/n)", stateWaiting, "Overwrite the existing config? (y/n)"},
+		{"claude permission", " Bash command\n   npm test\n Do you want to proceed?\n ❯ 1. Yes\n   2. Yes, and don't ask again for npm test commands\n   3. No, and tell Claude what to do differently (esc)\n", stateNeedsApproval, "Do you want to proceed?"},
+		{"codex permission", "Would you like to run the following command?\n  $ rm -rf build\n› 1. Yes, proceed\n  2. No, and tell Codex what to do differently", stateNeedsApproval, "Would you like to run the following command?"},
+		{"allow prompt", "Allow this command? (y/n)", stateNeedsApproval, "Allow this command? (y/n)"},
+		{"prompt box", "Done. Tests pass.\n\n› 1 file changed", "", ""},
+		{"old notice", "Claude usage limit reached.\n1\n2\n3\n4\n5\n6\n7\n8\n", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, msg := rules.detect(tt.output)
+			if state != tt.wantState || msg != tt.wantMsg {
+				t.Errorf("got (%q, %q), want (%q, %q)", state, msg, tt.wantState, tt.wantMsg)
+			}
+		})
+	}
+
+	custom, err := (&agentConfig{Agents: map[string]*agentProfile{
+		"codex": {noticePatterns: noticePatterns{RateLimitPatterns: []string{"quota exhausted"}}},
+	}}).noticeRules("codex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := custom.detect("error: quota exhausted"); state != stateRateLimited {
+		t.Errorf("custom pattern: got %q", state)
+	}
+	if _, err := (&agentConfig{Agents: map[string]*agentProfile{
+		"codex": {noticePatterns: noticePatterns{CompactionPatterns: []string{"("}}},
+	}}).noticeRules("codex"); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestWatcherScan_RateLimited(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Dir: "/work/a", Output: "working"})
+
+	hookOut := filepath.Join(dir, "hook.txt")
+	saveConfig(&agentConfig{
+		DefaultAgent: "claude",
+		Hooks:        map[string]string{"rate_limited": `echo "$TMUX_AGENT_PANE $TMUX_AGENT_MESSAGE" >> ` + hookOut},
+	})
+	var logs bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(&logs, "test"))
+	wt.scan()
+
+	fake.Pane("%1").Output = "Claude usage limit reached. Your limit will reset at 3pm."
+	wt.scan()
+	wt.scan()
+	wt.flush()
+
+	if strings.Count(logs.String(), `msg="pane rate-limited"`) != 1 {
+		t.Errorf("expected one rate-limited event, got: %s", logs.String())
+	}
+	data, _ := os.ReadFile(hookOut)
+	if strings.TrimSpace(string(data)) != "%1 Claude usage limit reached. Your limit will reset at 3pm." {
+		t.Errorf("unexpected hook output: %q", string(data))
+	}
+	var notified bool
+	for _, call := range fake.Calls {
+		if call[0] == "display-message" && strings.Contains(strings.Join(call, " "), `"Claude usage limit reached`) {
+			notified = true
+		}
+	}
+	if !notified {
+		t.Error("expected a status line message quoting the limit")
+	}
+	data, _ = os.ReadFile(activityFilePath())
+	if !strings.Contains(string(data), `"state":"rate-limited"`) {
+		t.Errorf("expected rate-limited samples, got:\n%s", data)
+	}
+
+	fake.Pane("%1").Output = "Resuming work"
+	wt.scan()
+	if !strings.Contains(logs.String(), `msg="pane resumed"`) {
+		t.Errorf("expected pane resumed, got: %s", logs.String())
+	}
+}
+
+func TestRunStatus_Notices(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Output: "✻ Compacting conversation…\n\n>"},
+		&runner.FakePane{ID: "%2", Command: "codex", Output: "You've hit your usage limit. Try again in 2h.\n\n› "},
+		&runner.FakePane{ID: "%3", Command: "claude", Output: "editing main.go"},
+		&runner.FakePane{ID: "%4", Command: "claude", Output: "Which database should I use?\n❯ 1. Postgres\n  2. SQLite"},
+		&runner.FakePane{ID: "%5", Command: "claude", Output: "Do you want to create hello.go?\n❯ 1. Yes\n  2. No"},
+	)
+
+	var buf bytes.Buffer
+	if err := runStatus([]string{"--width", "0"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "compacting") || !strings.Contains(out, "rate-limited") || !strings.Contains(out, "waiting") ||
+		!strings.Contains(out, "needs-approval") {
+		t.Errorf("expected notice states:\n%s", out)
+	}
+	if !strings.Contains(out, "Try again in 2h.") {
+		t.Errorf("expected the limit message as last output:\n%s", out)
+	}
+	buf.Reset()
+	runStatus([]string{"--short"}, &buf)
+	if !strings.Contains(buf.String(), "1 active, 1 compacting, 1 waiting, 1 needs-approval, 1 rate-limited, 0 idle") {
+		t.Errorf("unexpected summary: %s", buf.String())
+	}
+}
+
+func TestWatcherScan_Waiting(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Dir: "/work/a", Output: "working"})
+	hookOut := filepath.Join(dir, "hook.txt")
+	saveConfig(&agentConfig{
+		DefaultAgent: "claude",
+		Hooks:        map[string]string{"pane_waiting": `echo "$TMUX_AGENT_PANE $TMUX_AGENT_MESSAGE" >> ` + hookOut},
+	})
+	var events bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(io.Discard, "test"))
+	wt.events = &events
+	wt.scan()
+
+	fake.Pane("%1").Output = "Apply this change? (y/n)"
+	wt.scan()
+	wt.scan()
+	wt.flush()
+
+	if n := strings.Count(events.String(), `"event":"pane_waiting"`); n != 1 {
+		t.Errorf("expected one pane_waiting event, got %d:\n%s", n, events.String())
+	}
+	if wt.states["%1"] != stateWaiting {
+		t.Errorf("state = %q, want %q", wt.states["%1"], stateWaiting)
+	}
+	data, _ := os.ReadFile(hookOut)
+	if strings.TrimSpace(string(data)) != "%1 Apply this change? (y/n)" {
+		t.Errorf("unexpected hook output: %q", string(data))
+	}
+}
+
+func TestWatcherScan_NeedsApproval(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "codex", Dir: "/work/a", Output: "working"})
+	var notified []string
+	orig := desktopNotifyFn
+	desktopNotifyFn = func(title, body string) error {
+		notified = append(notified, title+": "+body)
+		return nil
+	}
+	defer func() { desktopNotifyFn = orig }()
+	var logs bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Hour }, newLogger(&logs, "test"))
+	wt.notify = notifyDesktop
+	wt.scan()
+
+	fake.Pane("%1").Output = "Would you like to run the following command?\n  $ make deploy\n› 1. Yes, proceed"
+	wt.scan()
+	wt.scan()
+
+	if wt.states["%1"] != stateNeedsApproval {
+		t.Errorf("state = %q, want %q", wt.states["%1"], stateNeedsApproval)
+	}
+	if len(notified) != 1 || !strings.Contains(notified[0], "%1 (codex) needs approval") {
+		t.Errorf("expected one desktop notification, got %q", notified)
+	}
+	var shown int
+	for _, call := range fake.Calls {
+		if call[0] == "display-message" && strings.Contains(strings.Join(call, " "), "needs approval") {
+			shown++
+		}
+	}
+	if shown != 1 {
+		t.Errorf("expected one status line message, got %d", shown)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// notifiedFile records when each pane event was last posted to chat, so
+// throttling holds across scans and invocations.
+const notifiedFile = "notified.json"
+
+// defaultNotifyThrottle is how often the same event on the same pane may be
+// posted when a notifier sets no throttle.
+const defaultNotifyThrottle = 30 * time.Minute
+
+// Notifier types.
+const (
+	notifierSlack   = "slack"
+	notifierDiscord = "discord"
+)
+
+// notifyTemplates are the default messages per event. {pane}, {agent},
+// {repo}, {idle} and {message} are substituted.
+var notifyTemplates = map[string]string{
+	eventPaneIdle:      "pane {pane} ({agent}, {repo}) has been idle for {idle}",
+	eventPaneClosed:    "pane {pane} ({agent}, {repo}) closed",
+	eventRateLimited:   "pane {pane} ({agent}, {repo}) is rate-limited: {message}",
+	eventCompacting:    "pane {pane} ({agent}, {repo}) is compacting its context",
+	eventPaneWaiting:   "pane {pane} ({agent}, {repo}) is waiting for input: {message}",
+	eventNeedsApproval: "pane {pane} ({agent}, {repo}) needs approval: {message}",
+	eventContextLow:    "pane {pane} ({agent}, {repo}) is running out of context: {message}",
+}
+
+// notifierConfig is a chat webhook that events are posted to.
+type notifierConfig struct {
+	// Type is "slack" or "discord".
+	Type string `json:"type"`
+	URL  string `json:"url"`
+	// Channel overrides the Slack webhook's default channel.
+	Channel string `json:"channel,omitempty"`
+	// Template replaces the default message for every event.
+	Template string `json:"template,omitempty"`
+	// Events lists the events to post; empty means pane_idle only.
+	Events []string `json:"events,omitempty"`
+	// Throttle is the minimum time between posts of the same event for
+	// the same pane, e.g. "1h".
+	Throttle string `json:"throttle,omitempty"`
+}
+
+// wants reports whether the notifier posts event.
+func (n *notifierConfig) wants(event string) bool {
+	if len(n.Events) == 0 {
+		return event == eventPaneIdle
+	}
+	return slices.Contains(n.Events, event)
+}
+
+// throttle returns the notifier's throttle interval.
+func (n *notifierConfig) throttle() (time.Duration, error) {
+	if n.Throttle == "" {
+		return defaultNotifyThrottle, nil
+	}
+	d, err := time.ParseDuration(n.Throttle)
+	if err != nil {
+		return 0, fmt.Errorf("invalid notifier throttle: %s", n.Throttle)
+	}
+	return d, nil
+}
+
+// message returns the text posted for event on pane p.
+func (n *notifierConfig) message(event string, p *paneInfo, detail string) string {
+	tmpl := n.Template
+	if tmpl == "" {
+		tmpl = notifyTemplates[event]
+	}
+	repo := shortDir(p.Dir)
+	if repo == "" {
+		repo = "-"
+	}
+	return strings.NewReplacer(
+		"{event}", event,
+		"{pane}", p.ID,
+		"{agent}", p.Command,
+		"{repo}", repo,
+		"{idle}", formatDuration(time.Since(p.LastChangeAt)),
+		"{message}", detail,
+	).Replace(tmpl)
+}
+
+// payload returns the JSON body the notifier's service expects for text.
+func (n *notifierConfig) payload(text string) (any, error) {
+	switch n.Type {
+	case notifierSlack:
+		body := map[string]string{"text": text}
+		if n.Channel != "" {
+			body["channel"] = n.Channel
+		}
+		return body, nil
+	case notifierDiscord:
+		return map[string]string{"content": text, "username": "tmux-agent"}, nil
+	}
+	return nil, fmt.Errorf("invalid notifier type: %q (want %s or %s)", n.Type, notifierSlack, notifierDiscord)
+}
+
+// notifyChat posts event on pane p to every notifier that wants it, unless
+// the same event for the pane was posted within the notifier's throttle.
+// detail is the agent's own message, if any.
+func notifyChat(notifiers []*notifierConfig, event string, p *paneInfo, detail string) error {
+	if len(notifiers) == 0 {
+		return nil
+	}
+	sent := make(map[string]time.Time)
+	if err := loadState(notifiedFile, &sent); err != nil {
+		return err
+	}
+	var errs []error
+	posted := false
+	for i, n := range notifiers {
+		if n == nil || !n.wants(event) {
+			continue
+		}
+		throttle, err := n.throttle()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		key := fmt.Sprintf("%d|%s|%s", i, p.ID, event)
+		if time.Since(sent[key]) < throttle {
+			continue
+		}
+		body, err := n.payload(n.message(event, p, detail))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := postJSON(n.URL, body); err != nil {
+			errs = append(errs, fmt.Errorf("%s notifier: %w", n.Type, err))
+			continue
+		}
+		sent[key] = time.Now()
+		posted = true
+	}
+	if posted {
+		if err := saveState(notifiedFile, sent); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notifyChat posts a watch event to the configured notifiers in the
+// background (see deliver), logging failures.
+func (wt *watcher) notifyChat(event string, p *paneInfo, detail string) {
+	if len(wt.notifiers) == 0 {
+		return
+	}
+	pane := *p
+	wt.deliver(func() {
+		if err := notifyChat(wt.notifiers, event, &pane, detail); err != nil {
+			wt.logger.Warn("chat notification failed", append(paneAttrs(&pane), "event", event, "err", err)...)
+		}
+	})
+}
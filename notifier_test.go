@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chatServer records the JSON bodies posted to it.
+func chatServer(t *testing.T) (*httptest.Server, *[]map[string]string) {
+	t.Helper()
+	var bodies []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+func TestNotifyChat(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv, bodies := chatServer(t)
+	notifiers := []*notifierConfig{
+		{Type: notifierSlack, URL: srv.URL, Channel: "#agents"},
+		{Type: notifierDiscord, URL: srv.URL, Events: []string{eventRateLimited}, Template: "{agent} in {repo}: {message}"},
+	}
+	p := &paneInfo{ID: "%3", Command: "claude", Dir: "/src/github.com/owner/repo", LastChangeAt: time.Now().Add(-12 * time.Minute)}
+
+	if err := notifyChat(notifiers, eventPaneIdle, p, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(*bodies) != 1 {
+		t.Fatalf("expected only the slack notifier to post, got %v", *bodies)
+	}
+	if got := (*bodies)[0]; got["text"] != "pane %3 (claude, owner/repo) has been idle for 12m00s" || got["channel"] != "#agents" {
+		t.Errorf("unexpected slack body: %v", got)
+	}
+
+	// A stuck pane is not posted again within the throttle, even by a
+	// later invocation.
+	if err := notifyChat(notifiers, eventPaneIdle, p, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(*bodies) != 1 {
+		t.Errorf("expected the repeat to be throttled, got %v", *bodies)
+	}
+	// Other panes and events are throttled separately.
+	notifyChat(notifiers, eventPaneIdle, &paneInfo{ID: "%4", Command: "codex"}, "")
+	notifyChat(notifiers, eventRateLimited, p, "limit reached")
+	if len(*bodies) != 3 || (*bodies)[2]["content"] != "claude in owner/repo: limit reached" {
+		t.Errorf("unexpected bodies: %v", *bodies)
+	}
+}
+
+func TestNotifyChat_Errors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	p := &paneInfo{ID: "%1", Command: "claude"}
+	err := notifyChat([]*notifierConfig{
+		{Type: "teams", URL: srv.URL},
+		{Type: notifierSlack, URL: srv.URL + "/services/SECRET"},
+		{Type: notifierSlack, URL: srv.URL, Throttle: "soon"},
+	}, eventPaneIdle, p, "")
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	msg := err.Error()
+	for _, want := range []string{`invalid notifier type: "teams"`, "slack notifier: POST: 403", "invalid notifier throttle: soon"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in %q", want, msg)
+		}
+	}
+	if strings.Contains(msg, "SECRET") {
+		t.Errorf("error leaks the webhook URL: %s", msg)
+	}
+}
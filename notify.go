@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// notifyDesktop is the --notify value for native desktop notifications.
+const notifyDesktop = "desktop"
+
+// desktopNotifyFn shows a desktop notification. Tests replace it.
+var desktopNotifyFn = desktopNotify
+
+// desktopNotify shows a native notification: osascript on macOS,
+// notify-send elsewhere.
+func desktopNotify(title, body string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptQuote(body), appleScriptQuote(title))
+		cmd = exec.Command("osascript", "-e", script)
+	} else {
+		cmd = exec.Command("notify-send", "--", title, body)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w (output: %s)", cmd.Args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// appleScriptQuote quotes s as an AppleScript string literal.
+func appleScriptQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// idleTransition returns the notification for a pane whose state went from
+// prev to state: becoming idle, or becoming active again after being idle.
+// ok is false for every other change.
+func idleTransition(p *paneInfo, prev, state string) (title, body string, ok bool) {
+	switch {
+	case state == stateIdle && prev != stateIdle:
+		title = fmt.Sprintf("%s (%s) is idle", p.ID, p.Command)
+	case state == stateActive && prev == stateIdle:
+		title = fmt.Sprintf("%s (%s) is active again", p.ID, p.Command)
+	default:
+		return "", "", false
+	}
+	body = shortDir(p.Dir)
+	if last := lastLines(p.LastOutput, 1); len(last) > 0 {
+		body += ": " + truncateWidth(last[0], maxLastOutputWidth)
+	}
+	return title, body, true
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestWatcherScan_NotifiesIdleTransitions(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%3", Command: "claude", Dir: "/work/api", Output: "Running tests"})
+	type note struct{ title, body string }
+	var notes []note
+	orig := desktopNotifyFn
+	desktopNotifyFn = func(title, body string) error {
+		notes = append(notes, note{title, body})
+		return nil
+	}
+	defer func() { desktopNotifyFn = orig }()
+
+	var logs bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(&logs, "test"))
+	wt.notify = notifyDesktop
+
+	wt.scan()
+	if len(notes) != 0 {
+		t.Fatalf("expected no notification on the first scan, got %v", notes)
+	}
+
+	wt.tracker.changed["%3"] = time.Now().Add(-time.Hour)
+	fake.Panes[0].Output = "Running tests"
+	wt.scan()
+	wt.scan()
+	if len(notes) != 1 || notes[0].title != "tmux-agent: %3 (claude) is idle" || notes[0].body != "api: Running tests" {
+		t.Fatalf("expected one idle notification, got %v", notes)
+	}
+
+	fake.Panes[0].Output = "All tests pass"
+	wt.scan()
+	if len(notes) != 2 || notes[1].title != "tmux-agent: %3 (claude) is active again" {
+		t.Errorf("expected an active notification, got %v", notes)
+	}
+}
+
+func TestIdleTransition(t *testing.T) {
+	p := &paneInfo{ID: "%1", Command: "codex", Dir: "/work/web"}
+	tests := []struct {
+		prev, state string
+		ok          bool
+	}{
+		{stateActive, stateIdle, true},
+		{stateBusyCPU, stateIdle, true},
+		{stateIdle, stateActive, true},
+		{stateIdle, stateIdle, false},
+		{stateActive, stateBusyCPU, false},
+		{stateIdle, stateRateLimited, false},
+	}
+	for _, tt := range tests {
+		if _, _, ok := idleTransition(p, tt.prev, tt.state); ok != tt.ok {
+			t.Errorf("%s -> %s: ok = %v, want %v", tt.prev, tt.state, ok, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"time"
+)
+
+// Defaults for watch --nudge.
+const (
+	defaultNudgeAfter = 15 * time.Minute
+	defaultNudgeLimit = 3
+)
+
+// nudger sends a prompt to panes that have been idle too long, so agents
+// that stopped to wait after a step keep going during unattended runs.
+type nudger struct {
+	prompt string
+	// after is how long a pane must be idle, and how long after the
+	// previous nudge, before it is nudged.
+	after time.Duration
+	// limit caps the nudges per pane; 0 means no limit.
+	limit  int
+	counts map[string]int
+	last   map[string]time.Time
+}
+
+// newNudger returns a nudger sending prompt.
+func newNudger(prompt string, after time.Duration, limit int) *nudger {
+	return &nudger{
+		prompt: prompt,
+		after:  after,
+		limit:  limit,
+		counts: make(map[string]int),
+		last:   make(map[string]time.Time),
+	}
+}
+
+// due reports whether pane p, in the given state, should be nudged now.
+func (n *nudger) due(p *paneInfo, state string) bool {
+	if state != stateIdle || time.Since(p.LastChangeAt) < n.after {
+		return false
+	}
+	if n.limit > 0 && n.counts[p.ID] >= n.limit {
+		return false
+	}
+	// Output normally changes once the prompt is typed, but an agent that
+	// does not echo it must not be nudged on every scan.
+	return time.Since(n.last[p.ID]) >= n.after
+}
+
+// forget drops the nudge history of a closed pane.
+func (n *nudger) forget(paneID string) {
+	delete(n.counts, paneID)
+	delete(n.last, paneID)
+}
+
+// checkNudge nudges pane p if it has been idle past the --nudge-after
+// threshold and is still under the --nudge-limit.
+func (wt *watcher) checkNudge(p *paneInfo, state string) {
+	n := wt.nudge
+	if n == nil || !n.due(p, state) {
+		return
+	}
+	n.last[p.ID] = time.Now()
+	if err := sendTmuxKeys(p.ID, n.prompt); err != nil {
+		wt.logger.Warn("nudge failed", append(paneAttrs(p), "err", err)...)
+		return
+	}
+	n.counts[p.ID]++
+	attrs := append(paneAttrs(p), "idle", time.Since(p.LastChangeAt).Truncate(time.Second), "nudges", n.counts[p.ID])
+	wt.logger.Info("pane nudged", attrs...)
+	if n.limit > 0 && n.counts[p.ID] == n.limit {
+		wt.logger.Info("nudge limit reached", append(paneAttrs(p), "limit", n.limit)...)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestWatcherScan_Nudge(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude", Output: "Step 1 done. Shall I continue?"},
+		&runner.FakePane{ID: "%5", Command: "codex", Output: "Compiling"},
+	)
+
+	var logs bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(&logs, "test"))
+	wt.nudge = newNudger("continue", 15*time.Minute, 2)
+
+	wt.scan()
+	wt.tracker.changed["%3"] = time.Now().Add(-20 * time.Minute)
+	wt.tracker.changed["%5"] = time.Now().Add(-5 * time.Minute)
+	wt.scan()
+	nudges := func() int { return strings.Count(strings.Join(fake.Panes[0].Input, "\n"), "continue") }
+	if nudges() != 1 {
+		t.Fatalf("expected the idle pane to be nudged once, got %q", fake.Panes[0].Input)
+	}
+	if len(fake.Panes[1].Input) != 0 {
+		t.Errorf("pane idle for less than --nudge-after was nudged: %q", fake.Panes[1].Input)
+	}
+
+	// The agent did not react, so its output is unchanged: no second nudge
+	// until another --nudge-after has passed.
+	wt.scan()
+	if nudges() != 1 {
+		t.Fatalf("expected no repeat nudge, got %q", fake.Panes[0].Input)
+	}
+	for range 3 {
+		wt.nudge.last["%3"] = time.Now().Add(-time.Hour)
+		wt.scan()
+	}
+	if nudges() != 2 {
+		t.Errorf("expected the nudge limit of 2 to hold, got %q", fake.Panes[0].Input)
+	}
+	if !strings.Contains(logs.String(), `msg="pane nudged"`) || !strings.Contains(logs.String(), `msg="nudge limit reached"`) {
+		t.Errorf("expected nudges to be logged, got: %s", logs.String())
+	}
+}
+
+func TestNudgerDue(t *testing.T) {
+	n := newNudger("continue", 10*time.Minute, 0)
+	idle := &paneInfo{ID: "%1", LastChangeAt: time.Now().Add(-time.Hour)}
+	for _, state := range []string{stateActive, stateBusyCPU, stateRateLimited} {
+		if n.due(idle, state) {
+			t.Errorf("%s pane should not be nudged", state)
+		}
+	}
+	n.counts["%1"] = 100
+	if !n.due(idle, stateIdle) {
+		t.Error("expected no limit with --nudge-limit 0")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// jsonOutput is set by the global --json flag: commands print JSON instead
+// of text, for scripts and other tools driving tmux-agent.
+var jsonOutput bool
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// paneJSON is the JSON form of a pane in panes and status.
+type paneJSON struct {
+	ID      string `json:"id"`
+	Agent   string `json:"agent"`
+	PID     int    `json:"pid,omitempty"`
+	Session string `json:"session,omitempty"`
+	Window  string `json:"window,omitempty"`
+	Dir     string `json:"dir,omitempty"`
+	Repo    string `json:"repo,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Label   string `json:"label,omitempty"`
+	// IdleSeconds is how long the pane's output has not changed.
+	IdleSeconds int64 `json:"idle_seconds"`
+	// State is set by panes and status; LastLine, Message and
+	// ContextLeft (the percentage the agent reports) only by status.
+	State       string `json:"state,omitempty"`
+	LastLine    string `json:"last_line,omitempty"`
+	Message     string `json:"message,omitempty"`
+	ContextLeft *int   `json:"context_left,omitempty"`
+}
+
+// idleSeconds returns how long p's output has not changed, in whole seconds.
+func idleSeconds(p *paneInfo) int64 {
+	return int64(time.Since(p.LastChangeAt) / time.Second)
+}
+
+// newPaneJSON returns the JSON form of p.
+func newPaneJSON(p *paneInfo, label string) paneJSON {
+	pid, _ := strconv.Atoi(p.PID)
+	return paneJSON{ID: p.ID, Agent: p.Command, PID: pid, Session: p.Session, Window: p.Window,
+		Dir: p.Dir, Repo: shortDir(p.Dir), Title: p.Title, Label: label}
+}
+
+// actionJSON is the JSON result of a command that acted on one pane.
+type actionJSON struct {
+	Pane    string `json:"pane"`
+	Command string `json:"command,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Output  string `json:"output,omitempty"`
+	Dir     string `json:"dir,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+	Issue   string `json:"issue,omitempty"`
+	// Archive is where kill saved the pane's output.
+	Archive string `json:"archive,omitempty"`
+}
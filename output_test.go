@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+// useJSONOutput turns on --json for one test.
+func useJSONOutput(t *testing.T) {
+	t.Helper()
+	jsonOutput = true
+	t.Cleanup(func() { jsonOutput = false })
+}
+
+func TestJSONOutput_Panes(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude", PID: 4242, Session: "dev", Window: 2, Dir: "/work/api", Title: "impl"},
+		&runner.FakePane{ID: "%4", Command: "zsh"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	saveLabels(map[string]string{"%3": "add rate limiting"})
+	useJSONOutput(t)
+
+	var buf bytes.Buffer
+	if err := runPanes(nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var panes []paneJSON
+	if err := json.Unmarshal(buf.Bytes(), &panes); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	want := paneJSON{ID: "%3", Agent: "claude", PID: 4242, Session: "dev", Window: "2", Dir: "/work/api", Repo: "api",
+		Title: "impl", Label: "add rate limiting", State: stateActive}
+	if len(panes) != 1 || panes[0] != want {
+		t.Errorf("got %+v, want %+v", panes, want)
+	}
+}
+
+func TestJSONOutput_PanesFlag(t *testing.T) {
+	useFakeTmux(t, &runner.FakePane{ID: "%3", Command: "claude"})
+	t.Setenv("HOME", t.TempDir())
+
+	var buf bytes.Buffer
+	if err := runPanes([]string{"--json"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var panes []paneJSON
+	if err := json.Unmarshal(buf.Bytes(), &panes); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if len(panes) != 1 || panes[0].ID != "%3" {
+		t.Errorf("unexpected panes: %+v", panes)
+	}
+}
+
+func TestJSONOutput_Status(t *testing.T) {
+	useFakeTmux(t, &runner.FakePane{ID: "%3", Command: "claude", Output: "Running tests\nAll 42 passed\n\n"})
+	t.Setenv("HOME", t.TempDir())
+	useJSONOutput(t)
+
+	var buf bytes.Buffer
+	if err := runStatus(nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var panes []paneJSON
+	if err := json.Unmarshal(buf.Bytes(), &panes); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if len(panes) != 1 || panes[0].State != stateActive || panes[0].LastLine != "All 42 passed" {
+		t.Errorf("unexpected status %+v", panes)
+	}
+
+	buf.Reset()
+	if err := runStatus([]string{"--short"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var counts map[string]int
+	if err := json.Unmarshal(buf.Bytes(), &counts); err != nil || counts[stateActive] != 1 {
+		t.Errorf("unexpected short status %q, %v", buf.String(), err)
+	}
+}
+
+func TestJSONOutput_Actions(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude", Output: "hello"},
+		&runner.FakePane{ID: "%5", Command: "codex"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	useJSONOutput(t)
+
+	var buf bytes.Buffer
+	if err := runCapture([]string{"%3"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var action actionJSON
+	if err := json.Unmarshal(buf.Bytes(), &action); err != nil || action != (actionJSON{Pane: "%3", Output: "hello"}) {
+		t.Errorf("capture: got %q, %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	action = actionJSON{}
+	if err := runSend([]string{"%3", "run", "the", "tests"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &action); err != nil || action != (actionJSON{Pane: "%3", Text: "run the tests"}) {
+		t.Errorf("send: got %q, %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := runBroadcast([]string{"commit"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var results []struct{ Pane, Agent, Error string }
+	if err := json.Unmarshal(buf.Bytes(), &results); err != nil || len(results) != 2 || results[1].Pane != "%5" {
+		t.Errorf("broadcast: got %q, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// paneNames looks up panes by title (set with rename) or task label. The
+// panes are listed on first use.
+type paneNames struct {
+	panes  []paneInfo
+	labels map[string]string
+	loaded bool
+}
+
+// find returns the ID of the pane called name. ok is false when no pane has
+// that name, and it is an error when more than one does.
+func (n *paneNames) find(name string) (id string, ok bool, err error) {
+	if !n.loaded {
+		// Without a tmux server nothing has a name; the command itself
+		// reports the failure.
+		n.panes, _ = listTmuxPanesOpts("", true)
+		n.labels = loadLabels()
+		n.loaded = true
+	}
+	var matches []string
+	for _, p := range n.panes {
+		if p.Title == name || n.labels[p.ID] == name {
+			matches = append(matches, p.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", false, nil
+	case 1:
+		return matches[0], true, nil
+	}
+	sort.Strings(matches)
+	return "", false, fmt.Errorf("pane name %q is ambiguous: it could be %s", name, strings.Join(matches, ", "))
+}
+
+// resolve returns the pane ID ref refers to: ref itself if it is a pane ID
+// (or names no pane), else the pane of that title or label. "@name" always
+// means a name, and it is an error when no pane has it. A --panes style
+// comma-separated list is resolved item by item.
+func (n *paneNames) resolve(ref string) (string, error) {
+	if strings.Contains(ref, ",") {
+		items := strings.Split(ref, ",")
+		for i := range items {
+			var err error
+			if items[i], err = n.resolve(items[i]); err != nil {
+				return "", err
+			}
+		}
+		return strings.Join(items, ","), nil
+	}
+	if name, ok := strings.CutPrefix(ref, "@"); ok && name != "" {
+		id, ok, err := n.find(name)
+		if err == nil && !ok {
+			err = fmt.Errorf("no pane is named %q", name)
+		}
+		return id, err
+	}
+	if ref == "" || strings.HasPrefix(ref, "%") || strings.HasPrefix(ref, "-") {
+		return ref, nil
+	}
+	id, ok, err := n.find(ref)
+	if err != nil || !ok {
+		return ref, err
+	}
+	return id, nil
+}
+
+// sendTargetsBeforeDashes resolves the targets of `send a b -- text`, which
+// may be titles or labels. It does so only when every argument before the
+// "--" is a send flag or names a pane; otherwise the "--" is part of the
+// text and ok is false.
+func (n *paneNames) sendTargetsBeforeDashes(args []string) (out []string, ok bool, err error) {
+	out = append([]string(nil), args...)
+	var opts sendOptions
+	for i := 1; i < len(out); i++ {
+		if out[i] == "--" {
+			return out, i > 1, nil
+		}
+		if out[i] == "--panes" && i+1 < len(out) {
+			i++
+		} else if next, isFlag, _ := opts.parseFlag(out, i); isFlag {
+			i = next
+			continue
+		}
+		if out[i], err = n.resolve(out[i]); err != nil {
+			return nil, false, err
+		}
+		for _, id := range strings.Split(out[i], ",") {
+			if !paneIDRe.MatchString(id) {
+				return nil, false, nil
+			}
+		}
+	}
+	return nil, false, nil
+}
+
+// paneRefPositions returns the indexes of args (a full command line) that
+// name panes, for the commands that take pane arguments.
+func paneRefPositions(args []string) []int {
+	if len(args) < 2 {
+		return nil
+	}
+	switch args[0] {
+	case "send":
+		targets, _, _, _ := parseSendArgs(args[1:])
+		pos := make([]int, len(targets))
+		for i, t := range targets {
+			pos[i] = t + 1
+		}
+		return pos
+	case "diff":
+		if len(args) > 2 {
+			return []int{1, 2}
+		}
+		return []int{1}
+	case "pipe":
+		var pos []int
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "--lines", "--prefix", "--idle", "--timeout":
+				i++
+			case "|":
+			default:
+				pos = append(pos, i)
+			}
+		}
+		return pos
+	case "queue":
+		if args[1] == "list" || args[1] == "ls" || args[1] == "clear" {
+			if len(args) > 2 {
+				return []int{2}
+			}
+			return nil
+		}
+		return []int{1}
+	case "label", "primary":
+		return []int{1}
+	}
+	if paneArgCommands[args[0]] {
+		return []int{1}
+	}
+	return nil
+}
+
+// resolvePaneArgs replaces pane titles and labels in a command line's pane
+// arguments with the pane IDs, so `capture auth-refactor` works after
+// `rename %5 auth-refactor`. Arguments that name no pane are left alone.
+// When the window has a primary pane, the first argument of a command that
+// can leave out its pane may be the start of the text instead (`send fix the
+// tests`), so it is only taken as a name in the @name form.
+func resolvePaneArgs(args []string) ([]string, error) {
+	pos := paneRefPositions(args)
+	if len(pos) == 0 {
+		return args, nil
+	}
+	var names paneNames
+	if args[0] == "send" {
+		if out, ok, err := names.sendTargetsBeforeDashes(args); ok || err != nil {
+			return out, err
+		}
+	}
+	out := append([]string(nil), args...)
+	for _, i := range pos {
+		if i >= len(out) {
+			continue
+		}
+		if i == 1 && paneArgCommands[args[0]] && !strings.HasPrefix(out[i], "@") && currentPrimary() != "" {
+			continue
+		}
+		id, err := names.resolve(out[i])
+		if err != nil {
+			return nil, err
+		}
+		out[i] = id
+	}
+	return out, nil
+}
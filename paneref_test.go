@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestResolvePaneArgs(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude", Title: "auth-refactor"},
+		&runner.FakePane{ID: "%5", Command: "codex", Title: "docs"},
+		&runner.FakePane{ID: "%7", Command: "codex", Title: "dup"},
+		&runner.FakePane{ID: "%8", Command: "claude", Title: "dup"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	saveLabels(map[string]string{"%5": "write-docs"})
+
+	tests := []struct{ args, want string }{
+		{"capture auth-refactor --lines 5", "capture %3 --lines 5"},
+		{"send write-docs update the README", "send %5 update the README"},
+		{"send auth-refactor docs -- docs are stale", "send %3 %5 -- docs are stale"},
+		{"send --panes auth-refactor,%9 hi", "send --panes %3,%9 hi"},
+		{"send hello world", "send hello world"},
+		{"send auth-refactor run git log -- README.md", "send %3 run git log -- README.md"},
+		{"send docs rename docs -- now", "send %5 rename docs -- now"},
+		{"diff auth-refactor docs", "diff %3 %5"},
+		{"pipe auth-refactor | docs --prefix docs", "pipe %3 | %5 --prefix docs"},
+		{"queue list docs", "queue list %5"},
+		{"wait docs --idle 1m", "wait %5 --idle 1m"},
+		{"panes docs", "panes docs"},
+		{"capture @docs", "capture %5"},
+		{"send --panes @docs,@auth-refactor hi", "send --panes %5,%3 hi"},
+	}
+	for _, tt := range tests {
+		got, err := resolvePaneArgs(strings.Fields(tt.args))
+		if err != nil {
+			t.Errorf("resolvePaneArgs(%s): %v", tt.args, err)
+			continue
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("resolvePaneArgs(%s) = %s, want %s", tt.args, strings.Join(got, " "), tt.want)
+		}
+	}
+
+	_, err := resolvePaneArgs([]string{"kill", "dup"})
+	if err == nil || !strings.Contains(err.Error(), "%7, %8") {
+		t.Errorf("expected an ambiguity error listing the panes, got %v", err)
+	}
+	_, err = resolvePaneArgs([]string{"capture", "@missing"})
+	if err == nil || !strings.Contains(err.Error(), `no pane is named "missing"`) {
+		t.Errorf("expected an error for an unknown @name, got %v", err)
+	}
+}
+
+func TestResolvePaneArgs_PrimaryPane(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Window: 1, Command: "zsh"},
+		&runner.FakePane{ID: "%2", Window: 1, Command: "claude"},
+		&runner.FakePane{ID: "%3", Window: 1, Command: "codex", Title: "fix"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TMUX_PANE", "%1")
+	if err := runPrimary([]string{"%2"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	// With a primary pane, the first word may start the text.
+	tests := []struct{ args, want string }{
+		{"send fix the tests", "send fix the tests"},
+		{"send @fix the tests", "send %3 the tests"},
+		{"send fix -- the tests", "send %3 -- the tests"},
+		{"diff fix %2", "diff %3 %2"},
+	}
+	for _, tt := range tests {
+		got, err := resolvePaneArgs(strings.Fields(tt.args))
+		if err != nil {
+			t.Errorf("resolvePaneArgs(%s): %v", tt.args, err)
+			continue
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("resolvePaneArgs(%s) = %s, want %s", tt.args, strings.Join(got, " "), tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// --sort values of status and panes.
+const (
+	sortIdle  = "idle"
+	sortRepo  = "repo"
+	sortAgent = "agent"
+	sortPane  = "pane"
+)
+
+// validSort checks a --sort value.
+func validSort(key string) error {
+	switch key {
+	case sortIdle, sortRepo, sortAgent, sortPane:
+		return nil
+	}
+	return fmt.Errorf("invalid --sort value: %s (want idle, repo, agent or pane)", key)
+}
+
+// paneNumber returns the number of a pane ID such as "%12", or -1.
+func paneNumber(id string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, "%"))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// sortPanes orders panes by key: idle puts the pane whose output changed
+// longest ago first, repo and agent group panes by repository or agent, and
+// pane is pane ID order. Ties keep pane ID order.
+func sortPanes(panes []paneInfo, key string) {
+	byPane := func(a, b paneInfo) int { return paneNumber(a.ID) - paneNumber(b.ID) }
+	slices.SortStableFunc(panes, func(a, b paneInfo) int {
+		var c int
+		switch key {
+		case sortIdle:
+			c = a.LastChangeAt.Compare(b.LastChangeAt)
+		case sortRepo:
+			c = strings.Compare(shortDir(a.Dir), shortDir(b.Dir))
+		case sortAgent:
+			c = strings.Compare(a.Command, b.Command)
+		}
+		if c != 0 {
+			return c
+		}
+		return byPane(a, b)
+	})
+}
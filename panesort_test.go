@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestSortPanes(t *testing.T) {
+	now := time.Now()
+	panes := func() []paneInfo {
+		return []paneInfo{
+			{ID: "%10", Command: "codex", Dir: "/src/github.com/owner/b", LastChangeAt: now.Add(-time.Minute)},
+			{ID: "%2", Command: "claude", Dir: "/src/github.com/owner/c", LastChangeAt: now.Add(-time.Hour)},
+			{ID: "%3", Command: "claude", Dir: "/src/github.com/owner/a", LastChangeAt: now},
+		}
+	}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{sortIdle, "%2 %10 %3"},
+		{sortRepo, "%3 %10 %2"},
+		{sortAgent, "%2 %3 %10"},
+		{sortPane, "%2 %3 %10"},
+	}
+	for _, tt := range tests {
+		p := panes()
+		sortPanes(p, tt.key)
+		var ids []string
+		for _, pane := range p {
+			ids = append(ids, pane.ID)
+		}
+		if got := strings.Join(ids, " "); got != tt.want {
+			t.Errorf("sortPanes(%s) = %s, want %s", tt.key, got, tt.want)
+		}
+	}
+	if err := validSort("state"); err == nil {
+		t.Error("expected invalid --sort value to fail")
+	}
+}
+
+func TestRunPanes_Sort(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "codex", Dir: "/src/github.com/owner/zeta"},
+		&runner.FakePane{ID: "%2", Command: "claude", Dir: "/src/github.com/owner/alpha"},
+	)
+
+	var buf bytes.Buffer
+	if err := runPanes([]string{"--sort", "repo"}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	if strings.Index(out, "%2") > strings.Index(out, "%1") {
+		t.Errorf("expected owner/alpha before owner/zeta, got:\n%s", out)
+	}
+	if err := runPanes([]string{"--sort", "bogus"}, &buf); err == nil {
+		t.Error("expected invalid --sort value to fail")
+	}
+}
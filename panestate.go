@@ -0,0 +1,92 @@
+package main
+
+import (
+	"time"
+)
+
+// paneStateFile is the state file holding each pane's output hash and when
+// it last changed. watch, status and panes share it, so idle time is known
+// across invocations instead of starting over at every run.
+const paneStateFile = "panestate.json"
+
+// trackedOutput is the persisted tracker state of one pane.
+type trackedOutput struct {
+	Hash    string    `json:"hash"`
+	Changed time.Time `json:"changed"`
+}
+
+// loadOutputTracker returns a tracker seeded from the pane state file. An
+// unreadable file gives an empty tracker.
+func loadOutputTracker() *outputTracker {
+	t := newOutputTracker()
+	var saved map[string]trackedOutput
+	if err := loadState(paneStateFile, &saved); err != nil {
+		return t
+	}
+	for id, s := range saved {
+		t.hashes[id] = s.Hash
+		t.changed[id] = s.Changed
+	}
+	return t
+}
+
+// save writes the tracker to the pane state file.
+func (t *outputTracker) save() error {
+	saved := make(map[string]trackedOutput, len(t.hashes))
+	for id, hash := range t.hashes {
+		saved[id] = trackedOutput{Hash: hash, Changed: t.changed[id]}
+	}
+	return saveState(paneStateFile, saved)
+}
+
+// prune forgets every pane not in panes.
+func (t *outputTracker) prune(panes []paneInfo) {
+	live := make(map[string]bool, len(panes))
+	for _, p := range panes {
+		live[p.ID] = true
+	}
+	for id := range t.hashes {
+		if !live[id] {
+			t.forget(id)
+		}
+	}
+}
+
+// trackPanes captures each pane's output and sets LastOutput and
+// LastChangeAt from the shared pane state, which it then updates. When
+// prune is set, panes is taken to be every agent pane and state for other
+// panes is dropped.
+func trackPanes(panes []paneInfo, prune bool) {
+	t := loadOutputTracker()
+	for i := range panes {
+		if output, err := capturePaneOutput(panes[i].ID, noticeCaptureLines); err == nil {
+			t.observe(&panes[i], output)
+		}
+	}
+	if prune {
+		t.prune(panes)
+	}
+	// The state only improves later idle estimates; failing to save it
+	// should not fail the command.
+	t.save()
+}
+
+// touchPane records that the panes paneIDs changed just now, e.g. because
+// input was sent to them, so they are not taken for idle before the agent
+// reacts. Touch several panes in one call rather than concurrently: each
+// call rewrites the whole state file.
+func touchPane(paneIDs ...string) error {
+	t := loadOutputTracker()
+	touched := false
+	for _, id := range paneIDs {
+		// Untracked panes count as changed on their first capture anyway.
+		if _, ok := t.hashes[id]; ok {
+			t.changed[id] = time.Now()
+			touched = true
+		}
+	}
+	if !touched {
+		return nil
+	}
+	return t.save()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestTrackPanes_PersistsAcrossRuns(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Output: "Done."},
+		&runner.FakePane{ID: "%2", Command: "codex", Output: "Working"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	hourAgo := time.Now().Add(-time.Hour).Truncate(time.Second)
+	saveState(paneStateFile, map[string]trackedOutput{
+		"%1": {Hash: outputHash("Done."), Changed: hourAgo},
+		"%2": {Hash: outputHash("Thinking"), Changed: hourAgo},
+		"%9": {Hash: outputHash("gone"), Changed: hourAgo},
+	})
+
+	panes, err := listTmuxPanes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackPanes(panes, true)
+	if !panes[0].LastChangeAt.Equal(hourAgo) {
+		t.Errorf("expected unchanged output to keep its change time, got %v", panes[0].LastChangeAt)
+	}
+	if time.Since(panes[1].LastChangeAt) > time.Minute {
+		t.Errorf("expected changed output to reset the change time, got %v", panes[1].LastChangeAt)
+	}
+
+	tracker := loadOutputTracker()
+	if _, ok := tracker.hashes["%9"]; ok {
+		t.Error("expected state for closed panes to be pruned")
+	}
+	if tracker.hashes["%2"] != outputHash("Working") {
+		t.Error("expected the new output hash to be saved")
+	}
+}
+
+func TestRunStatus_ReportsPersistedIdle(t *testing.T) {
+	useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "Done."})
+	t.Setenv("HOME", t.TempDir())
+	saveState(paneStateFile, map[string]trackedOutput{
+		"%1": {Hash: outputHash("Done."), Changed: time.Now().Add(-90 * time.Minute)},
+	})
+
+	var buf bytes.Buffer
+	if err := runStatus([]string{"--idle", "5m"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "idle 1h30m") {
+		t.Errorf("expected the idle time from the state file, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := runPanes(nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "IDLE") || !strings.Contains(buf.String(), "1h30m") {
+		t.Errorf("expected panes to show the idle time, got:\n%s", buf.String())
+	}
+}
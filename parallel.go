@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+)
+
+// paneWorkers bounds how many panes broadcast and kill-all act on at once.
+const paneWorkers = 8
+
+// paneResult is the outcome of an operation on one pane.
+type paneResult struct {
+	Pane paneInfo
+	Err  error
+}
+
+// forEachPane runs fn for every pane, at most workers at a time, and
+// returns the results in pane order. fn also receives the pane's index.
+func forEachPane(panes []paneInfo, workers int, fn func(i int, p paneInfo) error) []paneResult {
+	results := make([]paneResult, len(panes))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(workers, 1), len(panes)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = paneResult{Pane: panes[i], Err: fn(i, panes[i])}
+			}
+		}()
+	}
+	for i := range panes {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// writePaneResults prints one row per pane with done or the error (or a
+// JSON array with --json), and returns an error if any pane failed.
+func writePaneResults(w io.Writer, results []paneResult, done string) error {
+	if err := printPaneResults(w, results, done); err != nil {
+		return err
+	}
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d panes failed", failed, len(results))
+	}
+	return nil
+}
+
+// printPaneResults writes the results table or JSON array.
+func printPaneResults(w io.Writer, results []paneResult, done string) error {
+	if jsonOutput {
+		type resultJSON struct {
+			Pane  string `json:"pane"`
+			Agent string `json:"agent"`
+			Error string `json:"error,omitempty"`
+		}
+		out := make([]resultJSON, len(results))
+		for i, r := range results {
+			out[i] = resultJSON{Pane: r.Pane.ID, Agent: r.Pane.Command}
+			if r.Err != nil {
+				out[i].Error = r.Err.Error()
+			}
+		}
+		return writeJSON(w, out)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PANE\tAGENT\tRESULT")
+	for _, r := range results {
+		result := done
+		if r.Err != nil {
+			result = "error: " + r.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Pane.ID, r.Pane.Command, result)
+	}
+	return tw.Flush()
+}
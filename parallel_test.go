@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEachPane(t *testing.T) {
+	var panes []paneInfo
+	for i := range 20 {
+		panes = append(panes, paneInfo{ID: fmt.Sprintf("%%%d", i), Command: "claude"})
+	}
+	var running, peak atomic.Int32
+	results := forEachPane(panes, 4, func(i int, p paneInfo) error {
+		n := running.Add(1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		if i%7 == 0 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	if peak.Load() > 4 || peak.Load() < 2 {
+		t.Errorf("expected up to 4 panes at once, peak was %d", peak.Load())
+	}
+	for i, r := range results {
+		if r.Pane.ID != panes[i].ID || (r.Err != nil) != (i%7 == 0) {
+			t.Errorf("result %d out of order or wrong: %+v", i, r)
+		}
+	}
+}
+
+func TestWritePaneResults(t *testing.T) {
+	results := []paneResult{
+		{Pane: paneInfo{ID: "%3", Command: "claude"}},
+		{Pane: paneInfo{ID: "%5", Command: "codex"}, Err: errors.New("can't find pane: %5")},
+	}
+	var buf bytes.Buffer
+	err := writePaneResults(&buf, results, "sent")
+	if err == nil || err.Error() != "1 of 2 panes failed" {
+		t.Errorf("unexpected error %v", err)
+	}
+	want := "PANE  AGENT   RESULT\n%3    claude  sent\n%5    codex   error: can't find pane: %5\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if strings.Contains(buf.String(), "\t") {
+		t.Error("expected aligned columns")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// defaultPipeLines is how many lines of output pipe forwards by default.
+const defaultPipeLines = 50
+
+// pipeJSON is one hop of `pipe --json`.
+type pipeJSON struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Lines int    `json:"lines"`
+}
+
+// pipeMessage returns the prompt forwarding output, under prefix if set.
+func pipeMessage(prefix, output string) string {
+	if prefix == "" {
+		return output
+	}
+	return prefix + "\n\n" + output
+}
+
+// runPipe sends the recent output of one pane to the next as a prompt.
+// With more than two panes it forms a chain: each destination is waited
+// for until its agent is done, and its output goes on to the next pane.
+func runPipe(args []string, w io.Writer) error {
+	lines, err := parseIntFlag(args, "--lines", defaultPipeLines)
+	if err != nil {
+		return err
+	}
+	if lines <= 0 {
+		return fmt.Errorf("invalid --lines value: %d", lines)
+	}
+	idle := defaultRunIdle
+	var timeout time.Duration
+	var prefix string
+	var panes []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--lines":
+			i++
+		case "--prefix":
+			if i+1 < len(args) {
+				i++
+				prefix = args[i]
+			}
+		case "--idle":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil || d <= 0 {
+					return fmt.Errorf("invalid --idle value: %s", args[i])
+				}
+				idle = d
+			}
+		case "--timeout":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil || d <= 0 {
+					return fmt.Errorf("invalid --timeout value: %s", args[i])
+				}
+				timeout = d
+			}
+		case "|":
+			// Allows the quoted chain form: pipe %1 '|' %2 '|' %3.
+		default:
+			panes = append(panes, args[i])
+		}
+	}
+	if len(panes) < 2 {
+		return fmt.Errorf("usage: tmux-agent pipe <src_pane> <dst_pane> [dst_pane...] [--lines N] [--prefix text]")
+	}
+
+	var hops []pipeJSON
+	for i := 0; i+1 < len(panes); i++ {
+		src, dst := panes[i], panes[i+1]
+		if i > 0 {
+			// The previous destination is the source now: let it finish
+			// with what it was sent first.
+			if !jsonOutput {
+				fmt.Fprintf(w, "Waiting for pane %s\n", src)
+			}
+			if _, _, err := awaitReply(src, "", "", idle, timeout, nil); err != nil {
+				return err
+			}
+		}
+		output, err := capturePaneOutput(src, lines)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(output) == "" {
+			return fmt.Errorf("pane %s has no output to pipe", src)
+		}
+		if err := pasteTmuxText(dst, pipeMessage(prefix, output)); err != nil {
+			return err
+		}
+		touchPane(dst)
+		n := len(strings.Split(output, "\n"))
+		hops = append(hops, pipeJSON{From: src, To: dst, Lines: n})
+		if !jsonOutput {
+			fmt.Fprintf(w, "Piped %d lines from pane %s to pane %s\n", n, src, dst)
+		}
+	}
+	if jsonOutput {
+		return writeJSON(w, hops)
+	}
+	return nil
+}
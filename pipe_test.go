@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRunPipe(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Output: "old\n+func Export()\n+// done"},
+		&runner.FakePane{ID: "%2", Command: "codex", Output: "LGTM, one nit"},
+		&runner.FakePane{ID: "%3", Command: "claude"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	orig := waitPollInterval
+	waitPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { waitPollInterval = orig })
+
+	var buf bytes.Buffer
+	if err := runPipe([]string{"%1", "%2", "--lines", "2", "--prefix", "Review this:"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Pane("%2").Input; len(got) == 0 || got[0] != "Review this:\n\n+func Export()\n+// done" {
+		t.Errorf("unexpected input to %%2: %q", got)
+	}
+	if buf.String() != "Piped 2 lines from pane %1 to pane %2\n" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := runPipe([]string{"%1", "|", "%2", "|", "%3", "--idle", "30ms", "--timeout", "5s"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Pane("%3").Input; len(got) == 0 || got[0] != "LGTM, one nit" {
+		t.Errorf("expected %%2's output to reach %%3, got %q", got)
+	}
+	if !strings.Contains(buf.String(), "Waiting for pane %2") {
+		t.Errorf("expected the chain to wait for %%2, got:\n%s", buf.String())
+	}
+
+	if err := runPipe([]string{"%3", "%1"}, &buf); err == nil || !strings.Contains(err.Error(), "no output") {
+		t.Errorf("expected an error for an empty source, got %v", err)
+	}
+	if err := runPipe([]string{"%1"}, &buf); err == nil {
+		t.Error("expected a usage error")
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// playbook is a multi-step agent workflow run by `play`: the panes it
+// works in, and the steps to take in order.
+type playbook struct {
+	Name  string                   `json:"name,omitempty"`
+	Panes map[string]*playbookPane `json:"panes"`
+	Steps []*playbookStep          `json:"steps"`
+}
+
+// playbookPane is a pane a playbook refers to by name: an existing pane,
+// or one to create.
+type playbookPane struct {
+	// Pane is the ID of an existing pane to use instead of creating one.
+	Pane  string `json:"pane,omitempty"`
+	Agent string `json:"agent,omitempty"`
+	Model string `json:"model,omitempty"`
+	Dir   string `json:"dir,omitempty"`
+	// Repo is an owner/repo checked out with ghq; with Branch, the pane
+	// gets a fresh worktree on that branch, as with `workspace`.
+	Repo   string `json:"repo,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	Title  string `json:"title,omitempty"`
+}
+
+// playbookStep is one step of a playbook. A step that sends a prompt waits
+// until the agent is done with it, unless NoWait is set.
+type playbookStep struct {
+	Name string `json:"name,omitempty"`
+	// Pane is a name from the playbook's panes, or a pane ID. It may be
+	// left out when the playbook has a single pane.
+	Pane   string        `json:"pane,omitempty"`
+	Send   string        `json:"send,omitempty"`
+	Wait   *playbookWait `json:"wait,omitempty"`
+	NoWait bool          `json:"no_wait,omitempty"`
+	// Capture is a file to save the output the step produced to; for a
+	// step that sends nothing, the pane's whole scrollback.
+	Capture string `json:"capture,omitempty"`
+
+	idle, timeout time.Duration
+	match         *regexp.Regexp
+}
+
+// playbookWait is the condition a step waits for, as with `run`.
+type playbookWait struct {
+	Idle    string `json:"idle,omitempty"`
+	Match   string `json:"match,omitempty"`
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// playStepJSON is the result of one step in `play --json`.
+type playStepJSON struct {
+	Step    string `json:"step"`
+	Pane    string `json:"pane"`
+	Reason  string `json:"reason,omitempty"`
+	Capture string `json:"capture,omitempty"`
+}
+
+// loadPlaybook reads and checks a playbook. Files are YAML (see parseYAML
+// for the supported subset) or, with a .json extension, JSON.
+func loadPlaybook(path string) (*playbook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		v, err := parseYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var pb playbook
+	if err := dec.Decode(&pb); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := pb.check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &pb, nil
+}
+
+// check validates the playbook and fills in each step's pane and parsed
+// wait condition.
+func (pb *playbook) check() error {
+	if len(pb.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+	for name, p := range pb.Panes {
+		if p == nil {
+			pb.Panes[name] = &playbookPane{}
+			continue
+		}
+		if p.Pane != "" && (p.Repo != "" || p.Dir != "" || p.Agent != "" || p.Model != "") {
+			return fmt.Errorf("pane %s: pane cannot be combined with repo, dir, agent or model", name)
+		}
+		if p.Branch != "" && p.Repo == "" {
+			return fmt.Errorf("pane %s: branch needs repo", name)
+		}
+	}
+	for i, s := range pb.Steps {
+		if s == nil {
+			return fmt.Errorf("step %d is empty", i+1)
+		}
+		label := pb.stepLabel(i)
+		if s.Pane == "" && len(pb.Panes) == 1 {
+			for name := range pb.Panes {
+				s.Pane = name
+			}
+		}
+		if _, ok := pb.Panes[s.Pane]; !ok && !strings.HasPrefix(s.Pane, "%") {
+			if s.Pane == "" {
+				return fmt.Errorf("%s: no pane", label)
+			}
+			return fmt.Errorf("%s: unknown pane %q", label, s.Pane)
+		}
+		if s.Send == "" && s.Wait == nil && s.Capture == "" {
+			return fmt.Errorf("%s: nothing to do (want send, wait or capture)", label)
+		}
+		if s.NoWait && s.Wait != nil {
+			return fmt.Errorf("%s: no_wait cannot be combined with wait", label)
+		}
+		s.idle = defaultRunIdle
+		if w := s.Wait; w != nil {
+			var err error
+			if w.Idle != "" {
+				if s.idle, err = time.ParseDuration(w.Idle); err != nil || s.idle <= 0 {
+					return fmt.Errorf("%s: invalid wait idle: %s", label, w.Idle)
+				}
+			}
+			if w.Timeout != "" {
+				if s.timeout, err = time.ParseDuration(w.Timeout); err != nil || s.timeout <= 0 {
+					return fmt.Errorf("%s: invalid wait timeout: %s", label, w.Timeout)
+				}
+			}
+			if w.Match != "" {
+				if s.match, err = regexp.Compile(w.Match); err != nil {
+					return fmt.Errorf("%s: invalid wait match: %w", label, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// stepLabel names step i in messages, e.g. "step 2 (implement)".
+func (pb *playbook) stepLabel(i int) string {
+	label := fmt.Sprintf("step %d", i+1)
+	if name := pb.Steps[i].Name; name != "" {
+		label += " (" + name + ")"
+	}
+	return label
+}
+
+// openPane returns the ID of the playbook pane p, named name, creating the
+// pane (and its worktree) unless it names an existing one.
+func openPane(name string, p *playbookPane) (string, error) {
+	if p.Pane != "" {
+		if _, ok := lookupPane(p.Pane); !ok {
+			return "", fmt.Errorf("pane %s (%s) not found", name, p.Pane)
+		}
+		return p.Pane, nil
+	}
+	dir := expandHome(p.Dir)
+	if p.Repo != "" {
+		repoDir, err := ghqRepoDir(p.Repo)
+		if err != nil {
+			return "", err
+		}
+		dir = repoDir
+		if p.Branch != "" {
+			if dir, err = addWorktree(repoDir, p.Branch); err != nil {
+				return "", err
+			}
+		}
+	}
+	agent := p.Agent
+	if agent == "" {
+		agent = activeAgent
+	}
+	command, err := loadConfig().launchCommand(agent, p.Model)
+	if err != nil {
+		return "", err
+	}
+	paneID, err := createTmuxPaneInDir(command, dir)
+	if err != nil {
+		return "", fmt.Errorf("creating pane %s: %w", name, err)
+	}
+	title := p.Title
+	if title == "" {
+		title = name
+	}
+	if err := renameTmuxPane(paneID, title); err != nil {
+		return "", fmt.Errorf("naming pane %s: %w", name, err)
+	}
+	return paneID, nil
+}
+
+// runStep takes one step in pane paneID and returns why its wait ended,
+// if it waited.
+func runStep(s *playbookStep, paneID string) (string, error) {
+	before, err := captureScrollback(paneID)
+	if err != nil {
+		return "", err
+	}
+	if s.Send != "" {
+		if err := sendTmuxKeys(paneID, s.Send); err != nil {
+			return "", err
+		}
+		if err := touchPane(paneID); err != nil {
+			return "", err
+		}
+	}
+	reason := ""
+	if s.Wait != nil || (s.Send != "" && !s.NoWait) {
+		if reason, _, err = awaitReply(paneID, s.Send, before, s.idle, s.timeout, s.match); err != nil {
+			return "", err
+		}
+	}
+	if s.Capture == "" {
+		return reason, nil
+	}
+	output, err := captureScrollback(paneID)
+	if err != nil {
+		return "", err
+	}
+	if s.Send != "" {
+		output = newOutput(before, output)
+	}
+	path := expandHome(s.Capture)
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+	}
+	return reason, os.WriteFile(path, []byte(output+"\n"), 0644)
+}
+
+// runPlay runs a playbook: it creates the panes the playbook declares, then
+// takes its steps in order, stopping at the first that fails.
+func runPlay(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: tmux-agent play <playbook.yaml>")
+	}
+	pb, err := loadPlaybook(args[0])
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(pb.Panes))
+	for name := range pb.Panes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ids := make(map[string]string)
+	// started holds the agent of each pane created here, to wait for.
+	started := make(map[string]string)
+	for _, name := range names {
+		id, err := openPane(name, pb.Panes[name])
+		if err != nil {
+			return err
+		}
+		ids[name] = id
+		if pb.Panes[name].Pane == "" {
+			started[id] = pb.Panes[name].Agent
+			if started[id] == "" {
+				started[id] = activeAgent
+			}
+			if !jsonOutput {
+				fmt.Fprintf(w, "Created pane %s for %s\n", id, name)
+			}
+		}
+	}
+	for id, agent := range started {
+		waitReady(id, agent)
+	}
+
+	var results []playStepJSON
+	for i, s := range pb.Steps {
+		label := pb.stepLabel(i)
+		paneID := s.Pane
+		if id, ok := ids[s.Pane]; ok {
+			paneID = id
+		}
+		if !jsonOutput {
+			fmt.Fprintf(w, "[%d/%d] %s: pane %s\n", i+1, len(pb.Steps), label, paneID)
+		}
+		reason, err := runStep(s, paneID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		results = append(results, playStepJSON{Step: label, Pane: paneID, Reason: reason, Capture: s.Capture})
+		if jsonOutput {
+			continue
+		}
+		if reason != "" {
+			fmt.Fprintf(w, "[%d/%d] done (%s)\n", i+1, len(pb.Steps), reason)
+		}
+		if s.Capture != "" {
+			fmt.Fprintf(w, "[%d/%d] saved output to %s\n", i+1, len(pb.Steps), s.Capture)
+		}
+	}
+	if jsonOutput {
+		return writeJSON(w, results)
+	}
+	fmt.Fprintf(w, "Finished %d steps\n", len(pb.Steps))
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRunPlay(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "review notes"})
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	origDelay, origPoll := readyTimeout, waitPollInterval
+	readyTimeout, waitPollInterval = 0, 10*time.Millisecond
+	t.Cleanup(func() { readyTimeout, waitPollInterval = origDelay, origPoll })
+
+	path := filepath.Join(dir, "feature.yaml")
+	os.WriteFile(path, []byte(`name: feature
+panes:
+  impl:
+    dir: ~/work
+  reviewer:
+    pane: "%1"
+steps:
+  - name: scaffold
+    pane: impl
+    send: Scaffold the package
+    wait: {idle: 30ms, timeout: 5s}
+  - pane: impl
+    send: Now implement it
+    no_wait: true
+  - name: notes
+    pane: reviewer
+    capture: ~/out/notes.txt
+`), 0644)
+
+	var buf bytes.Buffer
+	if err := runPlay([]string{path}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	impl := fake.Pane("%2")
+	if impl == nil || impl.Dir != filepath.Join(dir, "work") || impl.Title != "impl" {
+		t.Fatalf("expected pane impl to be created in ~/work, got %+v", impl)
+	}
+	if got := strings.Join(impl.Input, "|"); got != "Scaffold the package|C-m|C-m|Now implement it|C-m|C-m" {
+		t.Errorf("unexpected input to impl: %q", got)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "out/notes.txt")); string(data) != "review notes\n" {
+		t.Errorf("unexpected capture %q", data)
+	}
+	for _, want := range []string{"Created pane %2 for impl", "[1/3] step 1 (scaffold): pane %2", "[1/3] done (idle)", "[3/3] saved output to ~/out/notes.txt", "Finished 3 steps"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in output:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestLoadPlaybook_Errors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct{ doc, want string }{
+		{"steps: []", "no steps"},
+		{"steps:\n  - send: hi\n    pane: impl", `step 1: unknown pane "impl"`},
+		{"panes:\n  a:\n  b:\nsteps:\n  - send: hi", "step 1: no pane"},
+		{"panes:\n  a:\nsteps:\n  - name: x\n    send: hi\n    wait: {idle: soon}", "step 1 (x): invalid wait idle: soon"},
+		{"panes:\n  a:\nsteps:\n  - send: hi\n    sned: typo", `unknown field "sned"`},
+		{"panes:\n  a: {branch: b}\nsteps:\n  - send: hi", "branch needs repo"},
+		{"steps:\n  - send: hi\n   pane: x", "line 3: unexpected indentation"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "p.yaml")
+		os.WriteFile(path, []byte(tt.doc), 0644)
+		if _, err := loadPlaybook(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("loadPlaybook(%q) error = %v, want %q", tt.doc, err, tt.want)
+		}
+	}
+
+	path := filepath.Join(dir, "p.json")
+	os.WriteFile(path, []byte(`{"steps": [{"pane": "%1", "send": "hi"}]}`), 0644)
+	if pb, err := loadPlaybook(path); err != nil || pb.Steps[0].idle != defaultRunIdle {
+		t.Errorf("expected a JSON playbook with the default idle, got %+v, %v", pb, err)
+	}
+}
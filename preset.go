@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultTeamLayout is the tmux layout team and create --count arrange
+// their panes in.
+const defaultTeamLayout = "tiled"
+
+// createPreset is a named set of create options.
+type createPreset struct {
+	// Agent is the command to run; empty means the active agent.
+	Agent string `json:"agent,omitempty"`
+	// Model overrides the agent's configured model.
+	Model string `json:"model,omitempty"`
+	// Dir is the working directory; "~" is expanded.
+	Dir string `json:"dir,omitempty"`
+	// Title is set as the pane title.
+	Title string `json:"title,omitempty"`
+	// Prompt is sent once the agent has started.
+	Prompt    string `json:"prompt,omitempty"`
+	Split     string `json:"split,omitempty"`
+	NewWindow bool   `json:"new_window,omitempty"`
+}
+
+// teamConfig is a set of presets launched together in one window.
+type teamConfig struct {
+	Presets []string `json:"presets"`
+	// Layout is a tmux layout name such as "tiled" or "main-vertical".
+	Layout string `json:"layout,omitempty"`
+}
+
+// expandHome replaces a leading "~" in path with the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// preset returns the named preset.
+func (c *agentConfig) preset(name string) (*createPreset, error) {
+	p, ok := c.Presets[name]
+	if !ok || p == nil {
+		return nil, fmt.Errorf("unknown preset %q (define it under \"presets\" in config.json)", name)
+	}
+	return p, nil
+}
+
+// paneOpts returns the create options for a preset.
+func (p *createPreset) paneOpts(cfg *agentConfig) (createPaneOpts, error) {
+	agent := p.Agent
+	if agent == "" {
+		agent = activeAgent
+	}
+	command, err := cfg.launchCommand(agent, p.Model)
+	if err != nil {
+		return createPaneOpts{}, err
+	}
+	return createPaneOpts{
+		Command:   command,
+		Dir:       expandHome(p.Dir),
+		Split:     p.Split,
+		NewWindow: p.NewWindow,
+	}, nil
+}
+
+// runTeam launches every preset of a team in a new window and arranges the
+// panes with the team's layout.
+func runTeam(args []string, w io.Writer) error {
+	var name, session string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--session" && i+1 < len(args):
+			i++
+			session = args[i]
+		case name == "" && !strings.HasPrefix(args[i], "-"):
+			name = args[i]
+		default:
+			return fmt.Errorf("usage: tmux-agent team <name> [--session name]")
+		}
+	}
+	if name == "" {
+		return fmt.Errorf("usage: tmux-agent team <name> [--session name]")
+	}
+	cfg := loadConfig()
+	team, ok := cfg.Teams[name]
+	if !ok || team == nil || len(team.Presets) == 0 {
+		return fmt.Errorf("unknown team %q (define it under \"teams\" in config.json)", name)
+	}
+	presets := make([]*createPreset, len(team.Presets))
+	for i, pn := range team.Presets {
+		p, err := cfg.preset(pn)
+		if err != nil {
+			return err
+		}
+		presets[i] = p
+	}
+
+	type prompt struct{ pane, agent, text string }
+	var first string
+	var prompts []prompt
+	var created []actionJSON
+	for i, p := range presets {
+		opts, err := p.paneOpts(cfg)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			opts.NewWindow, opts.Session = true, session
+		} else {
+			opts.NewWindow, opts.Target = false, first
+		}
+		paneID, err := createTmuxPaneWithOpts(opts)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			first = paneID
+			claimPrimary(paneID)
+		}
+		if p.Title != "" {
+			renameTmuxPane(paneID, p.Title)
+		}
+		if p.Prompt != "" {
+			prompts = append(prompts, prompt{paneID, commandAgent(opts.Command), p.Prompt})
+		}
+		created = append(created, actionJSON{Pane: paneID, Command: opts.Command, Title: p.Title, Text: p.Prompt})
+		if !jsonOutput {
+			fmt.Fprintf(w, "Created pane %s (%s)\n", paneID, team.Presets[i])
+		}
+	}
+
+	layout := team.Layout
+	if layout == "" {
+		layout = defaultTeamLayout
+	}
+	if err := selectTmuxLayout(first, layout); err != nil {
+		return err
+	}
+
+	for _, p := range prompts {
+		waitReady(p.pane, p.agent)
+		if err := sendTmuxKeys(p.pane, p.text); err != nil {
+			return fmt.Errorf("failed to send prompt to pane %s: %w", p.pane, err)
+		}
+		if !jsonOutput {
+			fmt.Fprintf(w, "Sent to pane %s: %s\n", p.pane, p.text)
+		}
+	}
+	if jsonOutput {
+		return writeJSON(w, created)
+	}
+	return nil
+}
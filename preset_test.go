@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func presetTestConfig() *agentConfig {
+	return &agentConfig{
+		DefaultAgent: "claude",
+		Presets: map[string]*createPreset{
+			"impl":   {Agent: "claude", Dir: "~/src/api", Title: "impl"},
+			"review": {Agent: "claude", Model: "opus", Title: "review", Prompt: "review the diff", Split: "v"},
+			"tests":  {Agent: "codex", Title: "tests", Prompt: "run the tests"},
+		},
+		Teams: map[string]*teamConfig{
+			"review": {Presets: []string{"impl", "review", "tests"}, Layout: "main-vertical"},
+			"broken": {Presets: []string{"impl", "missing"}},
+		},
+	}
+}
+
+func TestRunTeam(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "zsh"})
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	saveConfig(presetTestConfig())
+	origDelay := readyTimeout
+	readyTimeout = 0
+	defer func() { readyTimeout = origDelay }()
+
+	var buf bytes.Buffer
+	if err := runTeam([]string{"review"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	for _, c := range fake.Calls {
+		switch c[0] {
+		case "new-window", "split-window", "select-layout":
+			calls = append(calls, strings.Join(c, " "))
+		}
+	}
+	want := []string{
+		"new-window -P -F #{pane_id} -c " + filepath.Join(home, "src/api") + " claude",
+		"split-window -v -t %2 -P -F #{pane_id} claude --model 'opus'",
+		"split-window -h -t %2 -P -F #{pane_id} codex",
+		"select-layout -t %2 main-vertical",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected tmux calls:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+	if title := fake.Pane("%3").Title; title != "review" {
+		t.Errorf("expected pane title review, got %q", title)
+	}
+	if input := fake.Pane("%4").Input; len(input) == 0 || input[0] != "run the tests" {
+		t.Errorf("expected prompt to be sent, got %q", input)
+	}
+	if input := fake.Pane("%2").Input; len(input) != 0 {
+		t.Errorf("expected no prompt for a preset without one, got %q", input)
+	}
+
+	if err := runTeam([]string{"broken"}, &buf); err == nil || !strings.Contains(err.Error(), `unknown preset "missing"`) {
+		t.Errorf("expected unknown preset error, got %v", err)
+	}
+	if err := runTeam([]string{"nope"}, &buf); err == nil {
+		t.Error("expected unknown team error")
+	}
+}
+
+func TestRunCreate_Preset(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "zsh"})
+	t.Setenv("HOME", t.TempDir())
+	saveConfig(presetTestConfig())
+	origDelay := readyTimeout
+	readyTimeout = 0
+	defer func() { readyTimeout = origDelay }()
+
+	// Flags override the preset.
+	if err := runCreate([]string{"--preset", "review", "--model", "sonnet"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	last := fake.Panes[len(fake.Panes)-1]
+	if last.Title != "review" || len(last.Input) == 0 || last.Input[0] != "review the diff" {
+		t.Errorf("unexpected pane %+v", last)
+	}
+	for _, c := range fake.Calls {
+		if c[0] == "split-window" && (c[1] != "-v" || c[len(c)-1] != "claude --model 'sonnet'") {
+			t.Errorf("unexpected split-window call %q", c)
+		}
+	}
+
+	if err := runCreate([]string{"--preset", "nope"}, &bytes.Buffer{}); err == nil {
+		t.Error("expected unknown preset error")
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	for in, want := range map[string]string{
+		"~":        home,
+		"~/src":    filepath.Join(home, "src"),
+		"/tmp/x":   "/tmp/x",
+		"~other/x": "~other/x",
+	} {
+		if got := expandHome(in); got != want {
+			t.Errorf("expandHome(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
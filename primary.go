@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const primaryFile = "primary.json"
+
+// paneArgCommands are the subcommands whose first argument is a pane ID.
+// Run from a tmux window without one, they use the window's primary pane.
+var paneArgCommands = map[string]bool{
+	"capture": true,
+	"history": true,
+	"send":    true,
+	"kill":    true,
+	"restart": true,
+	"rename":  true,
+	"logs":    true,
+	"again":   true,
+	"wait":    true,
+	"run":     true,
+	"approve": true,
+	"deny":    true,
+	"compact": true,
+}
+
+// loadPrimaries returns the persisted window ID -> primary pane ID mapping.
+func loadPrimaries() map[string]string {
+	primaries := make(map[string]string)
+	loadState(primaryFile, &primaries)
+	return primaries
+}
+
+// paneWindow returns the ID of the window a pane is in.
+func paneWindow(paneID string) (string, error) {
+	out, err := tmuxRunner.Run("display-message", "-p", "-t", paneID, "#{window_id}")
+	if err != nil {
+		return "", fmt.Errorf("tmux display-message %s: %w", paneID, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// setPrimary makes paneID the primary pane of its window.
+func setPrimary(paneID string) (string, error) {
+	window, err := paneWindow(paneID)
+	if err != nil {
+		return "", err
+	}
+	primaries := loadPrimaries()
+	primaries[window] = paneID
+	return window, saveState(primaryFile, primaries)
+}
+
+// claimPrimary makes a newly created pane the primary pane of its window
+// unless the window already has one.
+func claimPrimary(paneID string) {
+	window, err := paneWindow(paneID)
+	if err != nil || window == "" {
+		return
+	}
+	primaries := loadPrimaries()
+	if current := primaries[window]; current != "" {
+		if w, err := paneWindow(current); err == nil && w == window {
+			return
+		}
+	}
+	primaries[window] = paneID
+	saveState(primaryFile, primaries)
+}
+
+// currentPrimary returns the primary pane of the window this command runs
+// in, or "" if there is none. A primary that was closed or moved to another
+// window no longer counts.
+func currentPrimary() string {
+	self := os.Getenv("TMUX_PANE")
+	if self == "" {
+		return ""
+	}
+	window, err := paneWindow(self)
+	if err != nil {
+		return ""
+	}
+	paneID := loadPrimaries()[window]
+	if paneID == "" {
+		return ""
+	}
+	if w, err := paneWindow(paneID); err != nil || w != window {
+		return ""
+	}
+	return paneID
+}
+
+// withPrimaryPane inserts the current window's primary pane into args for
+// commands that take a pane ID, when none was given.
+func withPrimaryPane(args []string) []string {
+	if len(args) == 0 || !paneArgCommands[args[0]] {
+		return args
+	}
+	if len(args) > 1 && (strings.HasPrefix(args[1], "%") || args[1] == "--panes") {
+		return args
+	}
+	if args[0] == "kill" && killFilterArgs(args[1:]) {
+		return args
+	}
+	paneID := currentPrimary()
+	if paneID == "" {
+		return args
+	}
+	return append([]string{args[0], paneID}, args[1:]...)
+}
+
+// runPrimary shows, sets, or clears the primary pane of the current window.
+func runPrimary(args []string, w io.Writer) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: tmux-agent primary [pane_id|--clear]")
+	}
+	if len(args) == 1 && args[0] != "--clear" {
+		window, err := setPrimary(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Pane %s is now the primary pane of window %s\n", args[0], window)
+		return nil
+	}
+
+	self := os.Getenv("TMUX_PANE")
+	if self == "" {
+		return fmt.Errorf("$TMUX_PANE not set; not running inside tmux")
+	}
+	window, err := paneWindow(self)
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		primaries := loadPrimaries()
+		delete(primaries, window)
+		if err := saveState(primaryFile, primaries); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Cleared the primary pane of window %s\n", window)
+		return nil
+	}
+	paneID := currentPrimary()
+	if paneID == "" {
+		return fmt.Errorf("no primary pane set for window %s", window)
+	}
+	fmt.Fprintln(w, paneID)
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestWithPrimaryPane(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Window: 1, Command: "zsh"},
+		&runner.FakePane{ID: "%2", Window: 1, Command: "claude"},
+		&runner.FakePane{ID: "%3", Window: 2, Command: "codex"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TMUX_PANE", "%1")
+
+	// Without a primary, arguments are left alone.
+	if got := withPrimaryPane([]string{"send", "hello"}); strings.Join(got, " ") != "send hello" {
+		t.Errorf("unexpected args %q", got)
+	}
+
+	if err := runPrimary([]string{"%2"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ args, want string }{
+		{"send run the tests", "send %2 run the tests"},
+		{"capture --lines 20", "capture %2 --lines 20"},
+		{"restart", "restart %2"},
+		{"send %3 hello", "send %3 hello"},
+		{"kill --force", "kill %2 --force"},
+		{"kill --idle 30m --agent codex", "kill --idle 30m --agent codex"},
+		{"status", "status"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(withPrimaryPane(strings.Fields(tt.args)), " "); got != tt.want {
+			t.Errorf("withPrimaryPane(%s) = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+
+	// Another window has its own primary.
+	t.Setenv("TMUX_PANE", "%3")
+	if got := withPrimaryPane([]string{"restart"}); len(got) != 1 {
+		t.Errorf("expected no primary in window @2, got %q", got)
+	}
+}
+
+func TestRunPrimary(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Window: 1, Command: "zsh"},
+		&runner.FakePane{ID: "%2", Window: 1, Command: "claude"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TMUX_PANE", "%1")
+
+	var buf bytes.Buffer
+	if err := runPrimary(nil, &buf); err == nil {
+		t.Error("expected error without a primary pane")
+	}
+	runPrimary([]string{"%2"}, &buf)
+	buf.Reset()
+	if err := runPrimary(nil, &buf); err != nil || buf.String() != "%2\n" {
+		t.Errorf("runPrimary = %q, %v", buf.String(), err)
+	}
+
+	// A closed primary no longer counts.
+	fake.Run("kill-pane", "-t", "%2")
+	if got := currentPrimary(); got != "" {
+		t.Errorf("expected closed primary to be ignored, got %s", got)
+	}
+
+	runPrimary([]string{"%1"}, io.Discard)
+	if err := runPrimary([]string{"--clear"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentPrimary(); got != "" {
+		t.Errorf("expected primary to be cleared, got %s", got)
+	}
+}
+
+func TestClaimPrimary(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Window: 1, Command: "claude"},
+		&runner.FakePane{ID: "%2", Window: 1, Command: "claude"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TMUX_PANE", "%2")
+
+	claimPrimary("%1")
+	claimPrimary("%2")
+	if got := currentPrimary(); got != "%1" {
+		t.Errorf("expected the first created pane to stay primary, got %s", got)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// promptNameRe matches the names prompts can be saved under.
+var promptNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// promptsDir is the directory holding the saved prompts, one Markdown file
+// per prompt.
+func promptsDir() string {
+	return filepath.Join(configDir(), "prompts")
+}
+
+// promptPath returns the file of the prompt called name.
+func promptPath(name string) (string, error) {
+	if !promptNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid prompt name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return filepath.Join(promptsDir(), name+".md"), nil
+}
+
+// loadPrompt returns the text of the saved prompt called name.
+func loadPrompt(name string) (string, error) {
+	path, err := promptPath(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no saved prompt %q (see tmux-agent prompt list)", name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// promptNames returns the names of the saved prompts, sorted.
+func promptNames() ([]string, error) {
+	entries, err := os.ReadDir(promptsDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".md"); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// repoName returns the "owner/name" of the ghq checkout dir is in (its
+// worktrees and subdirectories included), or else dir's last component.
+func repoName(dir string) string {
+	name := shortDir(dir)
+	if strings.Contains(dir, "/github.com/") {
+		parts := strings.SplitN(name, "/", 3)
+		if len(parts) >= 2 {
+			return parts[0] + "/" + parts[1]
+		}
+	}
+	return name
+}
+
+// promptData is what a prompt template's placeholders are filled from: the
+// --var values, as {{.name}}, and the target pane's Pane, Agent, Dir,
+// Repo, Branch and Title. Branch is only looked up when text uses it.
+func promptData(text string, p paneInfo, vars map[string]string) map[string]string {
+	data := map[string]string{
+		"Pane":  p.ID,
+		"Agent": p.Command,
+		"Dir":   p.Dir,
+		"Repo":  repoName(p.Dir),
+		"Title": p.Title,
+	}
+	if strings.Contains(text, ".Branch") && p.Dir != "" {
+		data["Branch"] = gitBranch(p.Dir)
+	}
+	for k, v := range vars {
+		data[k] = v
+	}
+	return data
+}
+
+// expandPrompt fills in the {{...}} placeholders (Go template syntax) of a
+// prompt for pane p. Text without placeholders is returned as is; a
+// placeholder without a value is an error.
+func expandPrompt(text string, p paneInfo, vars map[string]string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, promptData(text, p, vars)); err != nil {
+		return "", fmt.Errorf("filling in prompt template: %w (set it with --var name=value)", err)
+	}
+	return b.String(), nil
+}
+
+// promptJSON is a saved prompt in `prompt list --json`.
+type promptJSON struct {
+	Name string `json:"name"`
+	Text string `json:"text"`
+}
+
+// runPrompt manages the saved prompts that `send --prompt` sends.
+func runPrompt(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: tmux-agent prompt save <name> [text...] | list | show <name> | rm <name>")
+	}
+	switch args[0] {
+	case "save":
+		return runPromptSave(args[1:], w)
+	case "list", "ls":
+		return runPromptList(w)
+	case "show":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: tmux-agent prompt show <name>")
+		}
+		text, err := loadPrompt(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, text)
+		return nil
+	case "rm", "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: tmux-agent prompt rm <name>")
+		}
+		path, err := promptPath(args[1])
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); os.IsNotExist(err) {
+			return fmt.Errorf("no saved prompt %q", args[1])
+		} else if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Removed prompt %s\n", args[1])
+		return nil
+	}
+	return fmt.Errorf("unknown prompt command: %s", args[0])
+}
+
+// runPromptSave saves a prompt under a name, replacing any prompt of that
+// name. Without text it is read from stdin or written in $EDITOR, as for
+// send.
+func runPromptSave(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: tmux-agent prompt save <name> [text...]")
+	}
+	name := args[0]
+	path, err := promptPath(name)
+	if err != nil {
+		return err
+	}
+	text := strings.TrimSpace(strings.Join(args[1:], " "))
+	if text == "" {
+		if text, err = composeMessage("prompt " + name); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(promptsDir(), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(text+"\n"), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Saved prompt %s\n", name)
+	return nil
+}
+
+// runPromptList shows the saved prompts with their first line.
+func runPromptList(w io.Writer) error {
+	names, err := promptNames()
+	if err != nil {
+		return err
+	}
+	items := make([]promptJSON, 0, len(names))
+	for _, name := range names {
+		text, err := loadPrompt(name)
+		if err != nil {
+			return err
+		}
+		items = append(items, promptJSON{Name: name, Text: text})
+	}
+	if jsonOutput {
+		return writeJSON(w, items)
+	}
+	if len(items) == 0 {
+		fmt.Fprintf(w, "No saved prompts (add one with tmux-agent prompt save <name>)\n")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tPROMPT")
+	for _, it := range items {
+		first, _, more := strings.Cut(it.Text, "\n")
+		if more {
+			first += " ..."
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", it.Name, truncateWidth(first, 80))
+	}
+	return tw.Flush()
+}
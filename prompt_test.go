@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRunPrompt(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "claude"})
+	t.Setenv("HOME", t.TempDir())
+
+	var buf bytes.Buffer
+	if err := runPrompt([]string{"save", "fix-tests", "Run", "the", "tests", "and", "fix", "failures."}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	useMessageStdin(t, "Write a PR description.\nKeep it short.\n")
+	if err := runPrompt([]string{"save", "pr"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(promptsDir(), "pr.md")); string(data) != "Write a PR description.\nKeep it short.\n" {
+		t.Errorf("unexpected prompt file %q", data)
+	}
+
+	buf.Reset()
+	if err := runPrompt([]string{"list"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "fix-tests  Run the tests and fix failures.") || !strings.Contains(out, "pr         Write a PR description. ...") {
+		t.Errorf("unexpected list:\n%s", out)
+	}
+
+	if err := runSend([]string{"%5", "--prompt", "fix-tests"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if in := fake.Pane("%5").Input; len(in) == 0 || in[0] != "Run the tests and fix failures." {
+		t.Errorf("expected the saved prompt to be sent, got %q", in)
+	}
+	if err := runSend([]string{"%5", "--prompt", "nope"}, &buf); err == nil || !strings.Contains(err.Error(), "no saved prompt") {
+		t.Errorf("expected an unknown prompt error, got %v", err)
+	}
+
+	if err := runPrompt([]string{"rm", "pr"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := promptNames(); len(names) != 1 || names[0] != "fix-tests" {
+		t.Errorf("expected only fix-tests left, got %q", names)
+	}
+	if err := runPrompt([]string{"save", "../evil", "x"}, &buf); err == nil {
+		t.Error("expected an invalid name to be refused")
+	}
+}
+
+func TestExpandPrompt(t *testing.T) {
+	p := paneInfo{ID: "%3", Command: "codex", Dir: "/src/github.com/owner/name/.worktrees/fix"}
+	tests := []struct {
+		text string
+		vars map[string]string
+		want string
+	}{
+		{"no placeholders {x}", nil, "no placeholders {x}"},
+		{"Fix #{{.issue}} in {{.Repo}} ({{.Agent}} in {{.Pane}})", map[string]string{"issue": "123"}, "Fix #123 in owner/name (codex in %3)"},
+		{"{{if .issue}}#{{.issue}}{{else}}none{{end}}", map[string]string{"issue": ""}, "none"},
+	}
+	for _, tt := range tests {
+		got, err := expandPrompt(tt.text, p, tt.vars)
+		if err != nil || got != tt.want {
+			t.Errorf("expandPrompt(%q) = %q, %v; want %q", tt.text, got, err, tt.want)
+		}
+	}
+	if _, err := expandPrompt("Fix #{{.issue}}", p, nil); err == nil || !strings.Contains(err.Error(), "--var") {
+		t.Errorf("expected a missing variable error, got %v", err)
+	}
+	if _, err := expandPrompt("{{.issue", p, nil); err == nil {
+		t.Error("expected an invalid template error")
+	}
+}
+
+func TestRunSend_PromptTemplate(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude", Dir: "/src/github.com/owner/api"},
+		&runner.FakePane{ID: "%5", Command: "codex", Dir: "/src/github.com/owner/web"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	if err := runPrompt([]string{"save", "fix-issue", "Fix", "issue", "#{{.issue}}", "in", "{{.Repo}}."}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runSend([]string{"%3", "%5", "--prompt", "fix-issue", "--var", "issue=42"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if in := fake.Pane("%3").Input; len(in) == 0 || in[0] != "Fix issue #42 in owner/api." {
+		t.Errorf("unexpected input for %%3: %q", in)
+	}
+	if in := fake.Pane("%5").Input; len(in) == 0 || in[0] != "Fix issue #42 in owner/web." {
+		t.Errorf("unexpected input for %%5: %q", in)
+	}
+	if err := runSend([]string{"%3", "--prompt", "fix-issue"}, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for a missing --var")
+	}
+	if err := runSend([]string{"%3", "--var", "issue"}, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for --var without a value")
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queueFile holds the prompts waiting to be sent to each pane.
+const queueFile = "queue.json"
+
+// queuedPrompt is a prompt waiting for its pane to become idle.
+type queuedPrompt struct {
+	Text  string    `json:"text"`
+	Added time.Time `json:"added"`
+}
+
+// queueJSON is one queued prompt in `queue list --json`.
+type queueJSON struct {
+	Pane     string    `json:"pane"`
+	Position int       `json:"position"`
+	Text     string    `json:"text"`
+	Added    time.Time `json:"added"`
+}
+
+// loadQueue returns the queued prompts by pane ID, oldest first.
+func loadQueue() (map[string][]queuedPrompt, error) {
+	queue := make(map[string][]queuedPrompt)
+	if err := loadState(queueFile, &queue); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+// updateQueue loads the queue, lets fn change it, and saves it, holding the
+// queue lock throughout so concurrent updates aren't lost.
+func updateQueue(fn func(queue map[string][]queuedPrompt)) error {
+	return withStateLock(queueFile, func() error {
+		queue, err := loadQueue()
+		if err != nil {
+			return err
+		}
+		fn(queue)
+		return saveState(queueFile, queue)
+	})
+}
+
+// peekQueue returns the next prompt queued for paneID without removing it.
+func peekQueue(paneID string) (queuedPrompt, bool, error) {
+	queue, err := loadQueue()
+	if err != nil || len(queue[paneID]) == 0 {
+		return queuedPrompt{}, false, err
+	}
+	return queue[paneID][0], true, nil
+}
+
+// dropQueued removes prompt q from paneID's queue once it has been sent.
+// Prompts queued or cleared in the meantime are left as they are.
+func dropQueued(paneID string, q queuedPrompt) error {
+	return updateQueue(func(queue map[string][]queuedPrompt) {
+		for i, p := range queue[paneID] {
+			if p.Text == q.Text && p.Added.Equal(q.Added) {
+				queue[paneID] = append(queue[paneID][:i], queue[paneID][i+1:]...)
+				break
+			}
+		}
+		if len(queue[paneID]) == 0 {
+			delete(queue, paneID)
+		}
+	})
+}
+
+// checkQueue sends pane p the next prompt queued for it once it is idle,
+// and reports whether it did. The prompt leaves the queue only once it has
+// been sent, so a failed send is retried on a later scan. The send counts
+// as a change, so the pane gets a full idle threshold to pick the prompt up
+// before the one after is sent. Queued prompts go to a pane that is already
+// running, so dispatch's max_concurrent limits, which count tasks, do not
+// hold them back.
+func (wt *watcher) checkQueue(p *paneInfo, state string) bool {
+	if state != stateIdle {
+		return false
+	}
+	next, ok, err := peekQueue(p.ID)
+	if err != nil {
+		wt.logger.Warn("reading prompt queue failed", append(paneAttrs(p), "err", err)...)
+	}
+	if !ok {
+		return false
+	}
+	if err := sendTmuxKeys(p.ID, next.Text); err != nil {
+		wt.logger.Warn("sending queued prompt failed", append(paneAttrs(p), "err", err)...)
+		return false
+	}
+	if err := dropQueued(p.ID, next); err != nil {
+		wt.logger.Warn("removing sent prompt from queue failed", append(paneAttrs(p), "err", err)...)
+	}
+	p.LastChangeAt = time.Now()
+	wt.tracker.changed[p.ID] = p.LastChangeAt
+	wt.logger.Info("queued prompt sent", append(paneAttrs(p), "text", next.Text,
+		"waited", time.Since(next.Added).Truncate(time.Second))...)
+	return true
+}
+
+// runQueue adds a prompt to a pane's queue, lists the queues, or clears
+// them. watch sends each pane its queued prompts one at a time, whenever
+// the pane goes idle.
+func runQueue(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: tmux-agent queue <pane_id> <text...> | list [pane_id] | clear [pane_id]")
+	}
+	switch args[0] {
+	case "list", "ls":
+		return runQueueList(args[1:], w)
+	case "clear":
+		return runQueueClear(args[1:], w)
+	}
+
+	paneID := args[0]
+	text := strings.Join(args[1:], " ")
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("usage: tmux-agent queue <pane_id> <text...>")
+	}
+	if _, ok := lookupPane(paneID); !ok {
+		return fmt.Errorf("pane %s not found", paneID)
+	}
+	q := queuedPrompt{Text: text, Added: time.Now()}
+	position := 0
+	err := updateQueue(func(queue map[string][]queuedPrompt) {
+		queue[paneID] = append(queue[paneID], q)
+		position = len(queue[paneID])
+	})
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return writeJSON(w, queueJSON{Pane: paneID, Position: position, Text: text, Added: q.Added})
+	}
+	fmt.Fprintf(w, "Queued for pane %s (#%d): %s\n", paneID, position, text)
+	return nil
+}
+
+// queueColumns are the columns of `queue list`.
+var queueColumns = []tableColumn{
+	{Title: "PANE"},
+	{Title: "#"},
+	{Title: "QUEUED", Optional: true},
+	{Title: "TEXT", Min: 20},
+}
+
+// runQueueList shows the queued prompts, of one pane or of all.
+func runQueueList(args []string, w io.Writer) error {
+	pane := ""
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--width" {
+			i++
+		} else if pane == "" {
+			pane = args[i]
+		}
+	}
+	width, err := tableWidth(args)
+	if err != nil {
+		return err
+	}
+	queue, err := loadQueue()
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for id := range queue {
+		if pane == "" || id == pane {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var items []queueJSON
+	for _, id := range ids {
+		for i, q := range queue[id] {
+			items = append(items, queueJSON{Pane: id, Position: i + 1, Text: q.Text, Added: q.Added})
+		}
+	}
+	if jsonOutput {
+		if items == nil {
+			items = []queueJSON{}
+		}
+		return writeJSON(w, items)
+	}
+	if len(items) == 0 {
+		fmt.Fprintln(w, "No queued prompts")
+		return nil
+	}
+	var rows [][]string
+	for _, it := range items {
+		rows = append(rows, []string{it.Pane, strconv.Itoa(it.Position),
+			formatDuration(time.Since(it.Added)) + " ago", it.Text})
+	}
+	renderTable(w, width, queueColumns, rows)
+	return nil
+}
+
+// runQueueClear drops the queued prompts of one pane, or of all panes.
+func runQueueClear(args []string, w io.Writer) error {
+	n := 0
+	err := updateQueue(func(queue map[string][]queuedPrompt) {
+		for id, prompts := range queue {
+			if len(args) == 0 || id == args[0] {
+				n += len(prompts)
+				delete(queue, id)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Cleared %d queued prompts\n", n)
+	return nil
+}
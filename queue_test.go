@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRunQueue(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude"},
+		&runner.FakePane{ID: "%2", Command: "codex"},
+	)
+	t.Setenv("HOME", t.TempDir())
+
+	var buf bytes.Buffer
+	for _, args := range [][]string{
+		{"%1", "fix", "the", "tests"},
+		{"%1", "update the changelog"},
+		{"%2", "review the diff"},
+	} {
+		if err := runQueue(args, &buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.Contains(buf.String(), "Queued for pane %1 (#2): update the changelog") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+	if err := runQueue([]string{"%9", "hello"}, &buf); err == nil {
+		t.Error("expected an error for a missing pane")
+	}
+
+	buf.Reset()
+	if err := runQueue([]string{"list", "%1"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[1], "fix the tests") || !strings.HasSuffix(lines[2], "update the changelog") {
+		t.Errorf("unexpected list:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := runQueue([]string{"clear", "%1"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	queue, _ := loadQueue()
+	if len(queue["%1"]) != 0 || len(queue["%2"]) != 1 || buf.String() != "Cleared 2 queued prompts\n" {
+		t.Errorf("expected only %%1's prompts to be cleared, got %v (%q)", queue, buf.String())
+	}
+}
+
+func TestWatcherScan_DeliversQueue(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "Done."})
+	t.Setenv("HOME", t.TempDir())
+	runQueue([]string{"%1", "first"}, io.Discard)
+	runQueue([]string{"%1", "second"}, io.Discard)
+
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(io.Discard, "test"))
+	wt.scan()
+	wt.tracker.changed["%1"] = time.Now().Add(-time.Hour)
+	wt.scan()
+	// The pane has not reacted yet, but the send restarted its idle time.
+	wt.scan()
+
+	if got := strings.Join(fake.Pane("%1").Input, "|"); got != "first|C-m|C-m" {
+		t.Errorf("expected only the first prompt to be sent, got %q", got)
+	}
+	if wt.states["%1"] != stateActive {
+		t.Errorf("expected the pane to count as active after the send, got %s", wt.states["%1"])
+	}
+
+	wt.tracker.changed["%1"] = time.Now().Add(-time.Hour)
+	wt.scan()
+	queue, _ := loadQueue()
+	if got := strings.Join(fake.Pane("%1").Input, "|"); !strings.HasSuffix(got, "second|C-m|C-m") || len(queue) != 0 {
+		t.Errorf("expected the second prompt once idle again, got %q (queue %v)", got, queue)
+	}
+}
+
+func TestCheckQueue_KeepsPromptWhenSendFails(t *testing.T) {
+	useFakeTmux(t)
+	t.Setenv("HOME", t.TempDir())
+	updateQueue(func(queue map[string][]queuedPrompt) {
+		queue["%9"] = []queuedPrompt{{Text: "first", Added: time.Now()}}
+	})
+
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(io.Discard, "test"))
+	if wt.checkQueue(&paneInfo{ID: "%9"}, stateIdle) {
+		t.Fatal("expected the send to a missing pane to fail")
+	}
+	queue, _ := loadQueue()
+	if len(queue["%9"]) != 1 {
+		t.Errorf("expected the prompt to stay queued after a failed send, got %v", queue)
+	}
+}
+
+func TestRunQueue_ConcurrentAdds(t *testing.T) {
+	useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+	t.Setenv("HOME", t.TempDir())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := runQueue([]string{"%1", fmt.Sprintf("prompt %d", i)}, io.Discard); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	queue, _ := loadQueue()
+	if len(queue["%1"]) != 20 {
+		t.Errorf("expected all 20 prompts queued, got %d", len(queue["%1"]))
+	}
+}
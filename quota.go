@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const quotaFile = "quota.json"
+
+// Quota states, from most to least headroom.
+const (
+	quotaOK      = "ok"
+	quotaNear    = "near"
+	quotaLimited = "limited"
+)
+
+// quotaStaleAfter is how long a usage warning without a reset time is
+// trusted; usage windows are typically a few hours.
+const quotaStaleAfter = 5 * time.Hour
+
+// usageWarningRes match messages about approaching a usage limit. The first
+// group, when present, is the percentage used.
+var usageWarningRes = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(\d{1,3})%\s+of\s+(?:your\s+)?(?:[\w-]+\s+){0,3}?(?:limit|quota|usage)`),
+	regexp.MustCompile(`(?i)approaching\s+(?:[\w-]+\s+){0,2}?(?:usage\s+)?limit`),
+}
+
+// quotaStatus is the last usage information seen for an agent account.
+type quotaStatus struct {
+	Agent       string    `json:"agent"`
+	State       string    `json:"state"`
+	UsedPercent int       `json:"used_percent,omitempty"`
+	ResetAt     time.Time `json:"reset_at,omitzero"`
+	Message     string    `json:"message,omitempty"`
+	SeenAt      time.Time `json:"seen_at"`
+}
+
+// current returns the status as of now: limits whose reset time has passed
+// and stale warnings count as ok again.
+func (q quotaStatus) current(now time.Time) quotaStatus {
+	if q.State == quotaOK {
+		return q
+	}
+	if !q.ResetAt.IsZero() && now.After(q.ResetAt) ||
+		q.ResetAt.IsZero() && q.State == quotaNear && now.Sub(q.SeenAt) > quotaStaleAfter {
+		q.State = quotaOK
+		q.UsedPercent = 0
+	}
+	return q
+}
+
+// headroom ranks a status for the dispatcher: higher is better.
+func (q quotaStatus) headroom() int {
+	switch q.State {
+	case quotaLimited:
+		return -1
+	case quotaNear:
+		if q.UsedPercent > 0 {
+			return 100 - q.UsedPercent
+		}
+		return 10
+	}
+	return 100
+}
+
+// detectUsageWarning looks for a usage warning near the end of output and
+// returns the percentage used (0 if not stated) and the matching line.
+func detectUsageWarning(output string) (int, string, bool) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	checked := 0
+	for i := len(lines) - 1; i >= 0 && checked < noticeLines; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		checked++
+		for _, re := range usageWarningRes {
+			if m := re.FindStringSubmatch(line); m != nil {
+				pct := 0
+				if len(m) > 1 {
+					pct, _ = strconv.Atoi(m[1])
+				}
+				return pct, line, true
+			}
+		}
+	}
+	return 0, "", false
+}
+
+// loadQuota reads the recorded quota status per agent.
+func loadQuota() (map[string]quotaStatus, error) {
+	quota := make(map[string]quotaStatus)
+	if err := loadState(quotaFile, &quota); err != nil {
+		return nil, err
+	}
+	return quota, nil
+}
+
+// recordQuota stores the latest quota status for an agent.
+func recordQuota(q quotaStatus) error {
+	quota, err := loadQuota()
+	if err != nil {
+		return err
+	}
+	quota[q.Agent] = q
+	return saveState(quotaFile, quota)
+}
+
+// observeQuota records what a pane's output says about its agent's quota:
+// a rate-limit notice marks the account limited until its reset time, and
+// a usage warning marks it near its limit.
+func observeQuota(p *paneInfo, notice, msg, output string) (quotaStatus, bool) {
+	now := time.Now()
+	if notice == stateRateLimited {
+		q := quotaStatus{Agent: p.Command, State: quotaLimited, UsedPercent: 100, Message: msg, SeenAt: now}
+		if reset, ok := parseResetTime(msg, now); ok {
+			q.ResetAt = reset
+		}
+		return q, true
+	}
+	if pct, line, ok := detectUsageWarning(output); ok {
+		q := quotaStatus{Agent: p.Command, State: quotaNear, UsedPercent: pct, Message: line, SeenAt: now}
+		if reset, ok := parseResetTime(line, now); ok {
+			q.ResetAt = reset
+		}
+		return q, true
+	}
+	return quotaStatus{}, false
+}
+
+// runQuota shows how close each agent account is to its usage limits,
+// as last seen by watch.
+func runQuota(args []string, w io.Writer) error {
+	asJSON := jsonOutput
+	for _, a := range args {
+		switch a {
+		case "--json":
+			asJSON = true
+		default:
+			return fmt.Errorf("usage: tmux-agent quota [--json]")
+		}
+	}
+	quota, err := loadQuota()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", quotaFile, err)
+	}
+	now := time.Now()
+	var rows []quotaStatus
+	for _, q := range quota {
+		rows = append(rows, q.current(now))
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Agent < rows[j].Agent })
+
+	if asJSON {
+		return writeJSON(w, rows)
+	}
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "No usage information recorded (run tmux-agent watch to collect it)")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGENT\tSTATUS\tUSED\tRESETS\tSEEN\tMESSAGE")
+	for _, q := range rows {
+		used, resets := "-", "-"
+		if q.UsedPercent > 0 {
+			used = fmt.Sprintf("%d%%", q.UsedPercent)
+		}
+		if !q.ResetAt.IsZero() && q.ResetAt.After(now) {
+			resets = "in " + formatDuration(q.ResetAt.Sub(now))
+		}
+		seen := formatDuration(now.Sub(q.SeenAt)) + " ago"
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", q.Agent, q.State, used, resets, seen, truncateWidth(q.Message, 60))
+	}
+	tw.Flush()
+	return nil
+}
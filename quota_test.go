@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestDetectUsageWarning(t *testing.T) {
+	tests := []struct {
+		output  string
+		wantPct int
+		wantOK  bool
+	}{
+		{"You've used 90% of your weekly limit · resets Mon 9am\n> ", 90, true},
+		{"⚠ You've used 75% of your 5h limit", 75, true},
+		{"Approaching Opus usage limit", 0, true},
+		{"Context left until auto-compact: 5%", 0, false},
+		{"all tests passed", 0, false},
+	}
+	for _, tt := range tests {
+		pct, _, ok := detectUsageWarning(tt.output)
+		if pct != tt.wantPct || ok != tt.wantOK {
+			t.Errorf("%q: got (%d, %v), want (%d, %v)", tt.output, pct, ok, tt.wantPct, tt.wantOK)
+		}
+	}
+}
+
+func TestQuotaStatusCurrent(t *testing.T) {
+	now := time.Now()
+	limited := quotaStatus{State: quotaLimited, UsedPercent: 100, ResetAt: now.Add(-time.Minute), SeenAt: now.Add(-time.Hour)}
+	if got := limited.current(now); got.State != quotaOK {
+		t.Errorf("expected limit past its reset to be ok, got %s", got.State)
+	}
+	limited.ResetAt = now.Add(time.Hour)
+	if got := limited.current(now); got.State != quotaLimited || got.headroom() >= 0 {
+		t.Errorf("expected limited, got %+v", got)
+	}
+	near := quotaStatus{State: quotaNear, UsedPercent: 80, SeenAt: now.Add(-6 * time.Hour)}
+	if got := near.current(now); got.State != quotaOK {
+		t.Errorf("expected stale warning to be ok, got %s", got.State)
+	}
+	near.SeenAt = now
+	if got := near.current(now).headroom(); got != 20 {
+		t.Errorf("headroom = %d, want 20", got)
+	}
+}
+
+func TestWatcherScan_Quota(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "codex", Output: "You've hit your usage limit. Try again in 2 hours."},
+		&runner.FakePane{ID: "%2", Command: "claude", Output: "You've used 85% of your weekly limit\n> "},
+	)
+	var logs bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(&logs, "test"))
+	wt.scan()
+
+	var buf bytes.Buffer
+	if err := runQuota(nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"AGENT", "codex   limited  100%  in ", "claude  near     85%"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	buf.Reset()
+	runQuota([]string{"--json"}, &buf)
+	if !strings.Contains(buf.String(), `"state": "limited"`) {
+		t.Errorf("unexpected JSON: %s", buf.String())
+	}
+}
+
+func TestRunQuota_Empty(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	var buf bytes.Buffer
+	if err := runQuota(nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "No usage information") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+	if err := runQuota([]string{"--bogus"}, &buf); err == nil {
+		t.Error("expected usage error")
+	}
+}
+
+func TestDispatcherIdleOrder_PreferHeadroom(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	now := time.Now()
+	recordQuota(quotaStatus{Agent: "codex", State: quotaLimited, ResetAt: now.Add(time.Hour), SeenAt: now})
+	recordQuota(quotaStatus{Agent: "claude", State: quotaNear, UsedPercent: 90, SeenAt: now})
+
+	panes := []paneInfo{{ID: "%1", Command: "claude"}, {ID: "%2", Command: "codex"}, {ID: "%3", Command: "gemini"}}
+	var logs bytes.Buffer
+	d := newDispatcher(dispatchOpts{Idle: time.Minute, PreferHeadroom: true}, newLogger(&logs, "test"))
+	var ids []string
+	for _, p := range d.idleOrder(panes) {
+		ids = append(ids, p.ID)
+	}
+	if strings.Join(ids, ",") != "%3,%1" {
+		t.Errorf("order = %v, want [%%3 %%1]", ids)
+	}
+
+	d.opts.PreferHeadroom = false
+	if got := d.idleOrder(panes); len(got) != 3 || got[0].ID != "%1" {
+		t.Errorf("without --prefer-headroom the order should be unchanged, got %d panes", len(got))
+	}
+}
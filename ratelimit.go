@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const sendsFile = "sends.json"
+
+// rateLimitSleep waits out send rate limits; replaced in tests.
+var rateLimitSleep = time.Sleep
+
+// sendRateLimit spaces out text sent to panes. Values are Go durations
+// such as "10s"; empty means no limit.
+type sendRateLimit struct {
+	// PerPane is the minimum time between two sends to the same pane.
+	PerPane string `json:"per_pane,omitempty"`
+	// Global is the minimum time between any two sends.
+	Global string `json:"global,omitempty"`
+}
+
+// sendLog records recent sends so limits hold across invocations.
+type sendLog struct {
+	Last  time.Time            `json:"last"`
+	Panes map[string]time.Time `json:"panes,omitempty"`
+}
+
+// intervals parses the configured limits.
+func (l sendRateLimit) intervals() (perPane, global time.Duration, err error) {
+	if l.PerPane != "" {
+		if perPane, err = time.ParseDuration(l.PerPane); err != nil {
+			return 0, 0, fmt.Errorf("invalid send_rate_limit.per_pane: %s", l.PerPane)
+		}
+	}
+	if l.Global != "" {
+		if global, err = time.ParseDuration(l.Global); err != nil {
+			return 0, 0, fmt.Errorf("invalid send_rate_limit.global: %s", l.Global)
+		}
+	}
+	return perPane, global, nil
+}
+
+// sendSlotMu serializes waitForSendSlot within one process, so concurrent
+// sends (e.g. from broadcast) each see the others' recorded times.
+var sendSlotMu sync.Mutex
+
+// waitForSendSlot blocks until a send to paneID is allowed by the
+// configured rate limits, then records the send. The slot is reserved in
+// sends.json under its state lock, and only then waited for, so sends from
+// other processes (watch, dispatch, the CLI) queue up behind it instead of
+// all reading the same last send and going out together.
+func waitForSendSlot(paneID string) error {
+	perPane, global, err := loadConfig().SendRateLimit.intervals()
+	if err != nil {
+		return err
+	}
+	if perPane <= 0 && global <= 0 {
+		return nil
+	}
+	sendSlotMu.Lock()
+	defer sendSlotMu.Unlock()
+
+	var wait time.Duration
+	err = withStateLock(sendsFile, func() error {
+		var log sendLog
+		if err := loadState(sendsFile, &log); err != nil {
+			return err
+		}
+		now := time.Now()
+		wait = log.Last.Add(global).Sub(now)
+		if d := log.Panes[paneID].Add(perPane).Sub(now); d > wait {
+			wait = d
+		}
+		if wait > 0 {
+			now = now.Add(wait)
+		}
+
+		if log.Panes == nil {
+			log.Panes = make(map[string]time.Time)
+		}
+		for id, t := range log.Panes {
+			if now.Sub(t) > perPane {
+				delete(log.Panes, id)
+			}
+		}
+		log.Last = now
+		log.Panes[paneID] = now
+		return saveState(sendsFile, log)
+	})
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		rateLimitSleep(wait)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+// recordSleeps replaces rateLimitSleep with one that records its waits.
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	orig := rateLimitSleep
+	rateLimitSleep = func(d time.Duration) { waits = append(waits, d) }
+	t.Cleanup(func() { rateLimitSleep = orig })
+	return &waits
+}
+
+func TestWaitForSendSlot(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	waits := recordSleeps(t)
+
+	// No limits configured: no waiting and no state written.
+	if err := waitForSendSlot("%1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(statePath(sendsFile)); !os.IsNotExist(err) {
+		t.Errorf("expected no send log without limits, got %v", err)
+	}
+
+	saveConfig(&agentConfig{DefaultAgent: "claude", SendRateLimit: sendRateLimit{PerPane: "1h", Global: "1m"}})
+	waitForSendSlot("%1")
+	if len(*waits) != 0 {
+		t.Fatalf("first send should not wait, got %v", *waits)
+	}
+	waitForSendSlot("%2")
+	if len(*waits) != 1 || (*waits)[0] <= 59*time.Second || (*waits)[0] > time.Minute {
+		t.Errorf("expected a global wait of about 1m, got %v", *waits)
+	}
+	waitForSendSlot("%1")
+	if len(*waits) != 2 || (*waits)[1] <= 58*time.Minute {
+		t.Errorf("expected a per-pane wait of about 1h, got %v", *waits)
+	}
+
+	// Another process holding sends.json's lock keeps the slot from being taken.
+	origWait := lockWait
+	lockWait = 50 * time.Millisecond
+	defer func() { lockWait = origWait }()
+	os.WriteFile(statePath(sendsFile+".lock"), nil, 0644)
+	if err := waitForSendSlot("%3"); err == nil {
+		t.Error("expected an error while sends.json is locked")
+	}
+	os.Remove(statePath(sendsFile + ".lock"))
+
+	saveConfig(&agentConfig{DefaultAgent: "claude", SendRateLimit: sendRateLimit{Global: "soon"}})
+	if err := waitForSendSlot("%1"); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+func TestRunBroadcast_Stagger(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	waits := recordSleeps(t)
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude"},
+		&runner.FakePane{ID: "%2", Command: "claude"},
+		&runner.FakePane{ID: "%3", Command: "codex"},
+	)
+
+	var buf bytes.Buffer
+	if err := runBroadcast([]string{"--stagger", "2s", "pull", "main"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(*waits) != 2 || (*waits)[0] != 2*time.Second {
+		t.Errorf("expected two 2s waits, got %v", *waits)
+	}
+	if err := runBroadcast([]string{"--stagger", "x", "hi"}, &buf); err == nil {
+		t.Error("expected error for invalid --stagger")
+	}
+	useMessageStdin(t, "")
+	if err := runBroadcast([]string{"--stagger", "2s"}, &buf); err == nil {
+		t.Error("expected error without text")
+	}
+}
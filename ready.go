@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+// readyTimeout is how long a starting agent gets to show its input prompt
+// before keys are sent to it anyway, unless the agent's ready_timeout says
+// otherwise. Tests shorten it.
+var readyTimeout = 30 * time.Second
+
+// readyPollInterval is how often a starting pane's screen is checked.
+var readyPollInterval = 250 * time.Millisecond
+
+// readyFallbackDelay is how long to wait for an agent without ready
+// patterns, which cannot be watched for its prompt.
+const readyFallbackDelay = 5 * time.Second
+
+// defaultReadyPatterns match the input prompt claude and codex show once
+// they accept keys: the footer hints and claude's "> " input box.
+var defaultReadyPatterns = map[string][]string{
+	"claude": {`\? for shortcuts`, `^\s*│ > `, `^> `},
+	"codex":  {`\? for shortcuts`, `⏎ send`, `(?i)\d+% context left`, `^▌ `},
+}
+
+// readyProbe is an agent's compiled ready patterns and timeout.
+type readyProbe struct {
+	patterns []*regexp.Regexp
+	timeout  time.Duration
+}
+
+// readyProbe returns how to tell that agent is ready: its built-in and
+// configured ready_patterns, and its ready_timeout or readyTimeout.
+func (c *agentConfig) readyProbe(agent string) (*readyProbe, error) {
+	profile := c.agent(agent)
+	probe := &readyProbe{timeout: readyTimeout}
+	if profile.ReadyTimeout != "" {
+		d, err := time.ParseDuration(profile.ReadyTimeout)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid agents.%s.ready_timeout: %q", agent, profile.ReadyTimeout)
+		}
+		probe.timeout = d
+	}
+	for _, s := range append(append([]string(nil), defaultReadyPatterns[agent]...), profile.ReadyPatterns...) {
+		re, err := regexp.Compile("(?m)" + s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ready pattern %q: %w", s, err)
+		}
+		probe.patterns = append(probe.patterns, re)
+	}
+	return probe, nil
+}
+
+// ready reports whether output shows the agent's input prompt.
+func (p *readyProbe) ready(output string) bool {
+	for _, re := range p.patterns {
+		if re.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitReady waits until the agent starting in a pane shows its input
+// prompt, so keys sent next are not lost while it boots. It gives up after
+// the agent's ready timeout, and for agents without ready patterns simply
+// waits readyFallbackDelay (or the timeout, if shorter), as it does when
+// the patterns are invalid, after logging why. It reports whether the
+// prompt was seen; callers send their keys either way.
+func waitReady(paneID, agent string) bool {
+	return waitReadySince(paneID, agent, "")
+}
+
+// waitReadySince is waitReady for an agent restarted in a pane, before
+// being the pane's scrollback captured just ahead of the restart. Only
+// output that follows it counts, since an agent that draws inline leaves
+// its old prompt on screen.
+func waitReadySince(paneID, agent, before string) bool {
+	probe, err := loadConfig().readyProbe(agent)
+	if err != nil {
+		slog.Warn("ignoring ready config", "pane", paneID, "agent", agent, "err", err)
+		probe = &readyProbe{timeout: readyTimeout}
+	}
+	if len(probe.patterns) == 0 {
+		time.Sleep(min(readyFallbackDelay, probe.timeout))
+		return false
+	}
+	capture := func() (string, error) {
+		if before == "" {
+			return capturePaneOutput(paneID, noticeCaptureLines)
+		}
+		after, err := captureScrollback(paneID)
+		return newOutput(before, after), err
+	}
+	deadline := time.Now().Add(probe.timeout)
+	for {
+		if output, err := capture(); err == nil && probe.ready(output) {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(min(readyPollInterval, time.Until(deadline)))
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestReadyProbe(t *testing.T) {
+	cfg := &agentConfig{Agents: map[string]*agentProfile{
+		"aider": {ReadyPatterns: []string{`^aider> `}, ReadyTimeout: "1m"},
+		"bad":   {ReadyTimeout: "later"},
+	}}
+	tests := []struct {
+		agent, output string
+		want          bool
+	}{
+		{"claude", "╭────╮\n│ > \n╰────╯\n  ? for shortcuts", true},
+		{"claude", "Welcome to Claude Code!\n  cwd: /work/a", false},
+		{"codex", "▌ Ask Codex to do anything\n ⏎ send   ⌃J newline   ⌃C quit", true},
+		{"codex", "  100% context left", true},
+		{"codex", "loading...", false},
+		{"aider", "Aider v0.80\naider> ", true},
+		{"aider", "Aider v0.80", false},
+	}
+	for _, tt := range tests {
+		probe, err := cfg.readyProbe(tt.agent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := probe.ready(tt.output); got != tt.want {
+			t.Errorf("%s ready(%q) = %v, want %v", tt.agent, tt.output, got, tt.want)
+		}
+	}
+	if probe, _ := cfg.readyProbe("aider"); probe.timeout != time.Minute {
+		t.Errorf("expected the configured timeout, got %s", probe.timeout)
+	}
+	if _, err := cfg.readyProbe("bad"); err == nil {
+		t.Error("expected invalid ready_timeout to fail")
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "Welcome to Claude Code!"})
+	t.Setenv("HOME", t.TempDir())
+	origTimeout, origPoll := readyTimeout, readyPollInterval
+	readyTimeout, readyPollInterval = 2*time.Second, 5*time.Millisecond
+	defer func() { readyTimeout, readyPollInterval = origTimeout, origPoll }()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		fake.Print("%1", "\n> \n  ? for shortcuts")
+	}()
+	start := time.Now()
+	if !waitReady("%1", "claude") {
+		t.Fatal("expected the pane to become ready")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to stop waiting once ready, took %s", elapsed)
+	}
+
+	readyTimeout = 20 * time.Millisecond
+	fake.Pane("%1").Output = "still loading"
+	if waitReady("%1", "claude") {
+		t.Error("expected a timeout while the prompt is not shown")
+	}
+}
+
+func TestWaitReadySince(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "╭────╮\n│ > \n╰────╯\n  ? for shortcuts"})
+	t.Setenv("HOME", t.TempDir())
+	origTimeout, origPoll := readyTimeout, readyPollInterval
+	readyTimeout, readyPollInterval = 20*time.Millisecond, 5*time.Millisecond
+	defer func() { readyTimeout, readyPollInterval = origTimeout, origPoll }()
+
+	before, _ := captureScrollback("%1")
+	fake.Print("%1", "\n$ claude\nWelcome to Claude Code!")
+	if waitReadySince("%1", "claude", before) {
+		t.Error("expected the prompt left over from before the restart not to count")
+	}
+
+	readyTimeout = 2 * time.Second
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		fake.Print("%1", "\n╭────╮\n│ > \n╰────╯\n  ? for shortcuts")
+	}()
+	if !waitReadySince("%1", "claude", before) {
+		t.Error("expected the new prompt to count")
+	}
+}
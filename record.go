@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// recordingDir returns the directory where recordings are stored by default.
+func recordingDir() string {
+	return filepath.Join(configDir(), "recordings")
+}
+
+// paneSize returns the width and height of a tmux pane.
+func paneSize(paneID string) (int, int, error) {
+	output, err := tmuxRunner.Run("display-message", "-p", "-t", paneID, "#{pane_width} #{pane_height}")
+	if err != nil {
+		return 0, 0, fmt.Errorf("tmux display-message %s: %w", paneID, err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected pane size output: %q", string(output))
+	}
+	width, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid pane width: %s", fields[0])
+	}
+	height, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid pane height: %s", fields[1])
+	}
+	return width, height, nil
+}
+
+// pipeTmuxPane pipes pane output to a shell command. An empty command
+// stops any existing pipe.
+func pipeTmuxPane(paneID, command string) error {
+	args := []string{"pipe-pane", "-t", paneID}
+	if command != "" {
+		args = append(args, command)
+	}
+	if _, err := tmuxRunner.Run(args...); err != nil {
+		return fmt.Errorf("tmux pipe-pane %s: %w", paneID, err)
+	}
+	return nil
+}
+
+// runRecord starts or stops recording a pane in asciicast v2 format.
+func runRecord(args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: tmux-agent record <pane_id> [--out file.cast] | record stop <pane_id>")
+	}
+	if args[0] == "stop" {
+		if len(args) < 2 {
+			return fmt.Errorf("usage: tmux-agent record stop <pane_id>")
+		}
+		if err := pipeTmuxPane(args[1], ""); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Stopped recording pane %s\n", args[1])
+		return nil
+	}
+
+	paneID := args[0]
+	out := ""
+	for i := 1; i < len(args); i++ {
+		if args[i] == "--out" && i+1 < len(args) {
+			i++
+			out = args[i]
+		}
+	}
+	width, height, err := paneSize(paneID)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating tmux-agent executable: %w", err)
+	}
+
+	reserved := out == ""
+	if reserved {
+		// The file is created here, so that a recording started in the
+		// same second as another gets a name of its own; record-stream
+		// then writes to it.
+		dir := recordingDir()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		f, err := createNewFile(filepath.Join(dir, fmt.Sprintf("%s-%s.cast",
+			strings.TrimPrefix(paneID, "%"),
+			time.Now().Format("20060102-150405"))))
+		if err != nil {
+			return err
+		}
+		out = f.Name()
+		f.Close()
+	}
+	if out, err = filepath.Abs(out); err != nil {
+		return err
+	}
+
+	stream := fmt.Sprintf("%s record-stream --out %s --width %d --height %d --title %s",
+		shellQuote(exe), shellQuote(out), width, height, shellQuote("pane "+paneID))
+	if err := pipeTmuxPane(paneID, stream); err != nil {
+		if reserved {
+			os.Remove(out)
+		}
+		return err
+	}
+	fmt.Fprintf(w, "Recording pane %s to %s (stop with: tmux-agent record stop %s)\n", paneID, out, paneID)
+	return nil
+}
+
+// runRecordStream is the pipe-pane side of record. It reads raw pane output
+// from stdin and appends timed asciicast events to the output file.
+func runRecordStream(args []string) error {
+	var out, title string
+	width, err := parseIntFlag(args, "--width", 80)
+	if err != nil {
+		return err
+	}
+	height, err := parseIntFlag(args, "--height", 24)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--out":
+			if i+1 < len(args) {
+				i++
+				out = args[i]
+			}
+		case "--title":
+			if i+1 < len(args) {
+				i++
+				title = args[i]
+			}
+		}
+	}
+	if out == "" {
+		return fmt.Errorf("usage: tmux-agent record-stream --out <file>")
+	}
+
+	f, err := os.OpenFile(out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("opening recording: %w", err)
+	}
+	defer f.Close()
+
+	start := time.Now()
+	header := castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+	}
+	return writeCastStream(os.Stdin, f, header, func() time.Duration { return time.Since(start) })
+}
+
+// writeCastStream writes an asciicast header followed by one output event
+// per chunk read from r. Multibyte characters split across reads are held
+// back until complete so every event contains valid UTF-8.
+func writeCastStream(r io.Reader, w io.Writer, header castHeader, elapsed func() time.Duration) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4096)
+	var pending []byte
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			pending = append(pending, buf[:n]...)
+			cut := validUTF8Prefix(pending)
+			if cut > 0 {
+				event := []any{elapsed().Seconds(), "o", string(pending[:cut])}
+				if err := enc.Encode(event); err != nil {
+					return err
+				}
+				if err := bw.Flush(); err != nil {
+					return err
+				}
+				pending = append(pending[:0], pending[cut:]...)
+			}
+		}
+		if err == io.EOF {
+			if len(pending) > 0 {
+				enc.Encode([]any{elapsed().Seconds(), "o", string(pending)})
+			}
+			return bw.Flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// validUTF8Prefix returns the length of b excluding a trailing incomplete
+// UTF-8 sequence.
+func validUTF8Prefix(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(b[i]) {
+			continue
+		}
+		if !utf8.FullRune(b[i:]) {
+			return i
+		}
+		break
+	}
+	return len(b)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteCastStream(t *testing.T) {
+	input := "hello \xe4\xb8\x96\xe7\x95\x8c\n"
+	// Split the input in the middle of a multibyte character.
+	r := &chunkReader{chunks: []string{input[:8], input[8:]}}
+
+	var out bytes.Buffer
+	tick := 0
+	elapsed := func() time.Duration {
+		tick++
+		return time.Duration(tick) * 500 * time.Millisecond
+	}
+	header := castHeader{Version: 2, Width: 120, Height: 40, Timestamp: 1700000000}
+	if err := writeCastStream(r, &out, header, elapsed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header + 2 events, got %d: %v", len(lines), lines)
+	}
+
+	var h castHeader
+	if err := json.Unmarshal([]byte(lines[0]), &h); err != nil {
+		t.Fatalf("invalid header: %v", err)
+	}
+	if h.Version != 2 || h.Width != 120 || h.Height != 40 {
+		t.Errorf("unexpected header: %+v", h)
+	}
+
+	var got string
+	for _, line := range lines[1:] {
+		var ev []any
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		if len(ev) != 3 || ev[1] != "o" {
+			t.Fatalf("unexpected event: %v", ev)
+		}
+		got += ev[2].(string)
+	}
+	if got != input {
+		t.Errorf("recorded output = %q, want %q", got, input)
+	}
+}
+
+func TestRunRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	argsFile := filepath.Join(dir, "tmux-args.txt")
+	tmuxScript := filepath.Join(dir, "tmux")
+	os.WriteFile(tmuxScript, []byte(`#!/bin/sh
+echo "$@" >> `+argsFile+`
+case "$1" in
+  display-message)
+    echo "120 40"
+    ;;
+esac
+`), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+
+	out := filepath.Join(dir, "session.cast")
+	var buf bytes.Buffer
+	if err := runRecord([]string{"%5", "--out", out}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Recording pane %5 to "+out) {
+		t.Errorf("expected recording message, got: %s", buf.String())
+	}
+
+	data, _ := os.ReadFile(argsFile)
+	args := string(data)
+	if !strings.Contains(args, "pipe-pane -t %5") {
+		t.Errorf("expected pipe-pane in tmux args, got: %s", args)
+	}
+	if !strings.Contains(args, "record-stream") || !strings.Contains(args, "--width 120 --height 40") {
+		t.Errorf("expected record-stream with pane size, got: %s", args)
+	}
+
+	// Default names stay distinct when recordings start in the same second.
+	t.Setenv("HOME", dir)
+	runRecord([]string{"%5"}, &buf)
+	runRecord([]string{"%5"}, &buf)
+	entries, _ := os.ReadDir(recordingDir())
+	if len(entries) != 2 {
+		t.Errorf("expected two recording files, got %v", entries)
+	}
+
+	buf.Reset()
+	if err := runRecord([]string{"stop", "%5"}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Stopped recording pane %5") {
+		t.Errorf("expected stop message, got: %s", buf.String())
+	}
+}
+
+func TestRunRecord_MissingArgs(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runRecord(nil, &buf); err == nil {
+		t.Fatal("expected error for missing pane ID")
+	}
+	if err := runRecord([]string{"stop"}, &buf); err == nil {
+		t.Fatal("expected error for missing pane ID to stop")
+	}
+}
+
+// chunkReader returns one chunk per Read call.
+type chunkReader struct {
+	chunks []string
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
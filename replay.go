@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// replaySleep is the function used to wait between replayed events.
+// It can be replaced in tests.
+var replaySleep = time.Sleep
+
+// parseSpeed parses a playback speed like "2", "2x", or "0.5x".
+func parseSpeed(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(s), "x"), 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid --speed value: %s", s)
+	}
+	return v, nil
+}
+
+// playCast writes the output events of an asciicast v2 stream to w,
+// waiting between events according to their timestamps. Delays are divided
+// by speed and, when maxIdle is positive, capped at maxIdle.
+func playCast(r io.Reader, w io.Writer, speed float64, maxIdle time.Duration) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("empty recording")
+	}
+	var header castHeader
+	if err := json.Unmarshal(sc.Bytes(), &header); err != nil {
+		return fmt.Errorf("invalid asciicast header: %w", err)
+	}
+	if header.Version != 2 {
+		return fmt.Errorf("unsupported asciicast version: %d", header.Version)
+	}
+
+	prev := 0.0
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var ev []any
+		if err := json.Unmarshal([]byte(line), &ev); err != nil || len(ev) < 3 {
+			return fmt.Errorf("invalid asciicast event: %s", line)
+		}
+		at, ok1 := ev[0].(float64)
+		kind, ok2 := ev[1].(string)
+		data, ok3 := ev[2].(string)
+		if !ok1 || !ok2 || !ok3 {
+			return fmt.Errorf("invalid asciicast event: %s", line)
+		}
+		if kind != "o" {
+			continue
+		}
+
+		delay := time.Duration((at - prev) / speed * float64(time.Second))
+		if maxIdle > 0 && delay > maxIdle {
+			delay = maxIdle
+		}
+		if delay > 0 {
+			replaySleep(delay)
+		}
+		prev = at
+
+		if _, err := io.WriteString(w, data); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// runReplay plays back a recording inline or in a new pane.
+func runReplay(args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: tmux-agent replay <file.cast> [--speed 2x] [--max-idle duration] [--new-pane]")
+	}
+	file := args[0]
+	speedArg := "1"
+	maxIdleArg := ""
+	newPane := false
+	opts := createPaneOpts{}
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--speed":
+			if i+1 < len(args) {
+				i++
+				speedArg = args[i]
+			}
+		case "--max-idle":
+			if i+1 < len(args) {
+				i++
+				maxIdleArg = args[i]
+			}
+		case "--new-pane":
+			newPane = true
+		case "--new-window":
+			newPane = true
+			opts.NewWindow = true
+		case "--split":
+			if i+1 < len(args) {
+				i++
+				opts.Split = args[i]
+			}
+		}
+	}
+
+	speed, err := parseSpeed(speedArg)
+	if err != nil {
+		return err
+	}
+	var maxIdle time.Duration
+	if maxIdleArg != "" {
+		maxIdle, err = time.ParseDuration(maxIdleArg)
+		if err != nil {
+			return fmt.Errorf("invalid --max-idle value: %s", maxIdleArg)
+		}
+	}
+
+	if newPane {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("recording not found: %s", path)
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("locating tmux-agent executable: %w", err)
+		}
+		replayCmd := fmt.Sprintf("%s replay %s --speed %s", shellQuote(exe), shellQuote(path), shellQuote(speedArg))
+		if maxIdleArg != "" {
+			replayCmd += " --max-idle " + shellQuote(maxIdleArg)
+		}
+		opts.Command = replayCmd + "; printf '\\n[replay finished; press enter to close]'; read _"
+		paneID, err := createTmuxPaneWithOpts(opts)
+		if err != nil {
+			return err
+		}
+		renameTmuxPane(paneID, "replay:"+filepath.Base(path))
+		fmt.Fprintf(w, "Replaying %s in pane %s\n", filepath.Base(path), paneID)
+		return nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("opening recording: %w", err)
+	}
+	defer f.Close()
+	return playCast(f, w, speed, maxIdle)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCast = `{"version":2,"width":80,"height":24,"timestamp":1700000000}
+[0.5,"o","hello "]
+[1.5,"i","ignored input"]
+[2.5,"o","world\r\n"]
+[3602.5,"o","after a long pause"]
+`
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"2x", 2, false},
+		{"2", 2, false},
+		{"0.5X", 0.5, false},
+		{"0", 0, true},
+		{"fast", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSpeed(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSpeed(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("parseSpeed(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPlayCast(t *testing.T) {
+	var delays []time.Duration
+	origSleep := replaySleep
+	replaySleep = func(d time.Duration) { delays = append(delays, d) }
+	defer func() { replaySleep = origSleep }()
+
+	var buf bytes.Buffer
+	if err := playCast(strings.NewReader(testCast), &buf, 2, 5*time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "hello world\r\nafter a long pause" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+
+	want := []time.Duration{250 * time.Millisecond, time.Second, 5 * time.Second}
+	if len(delays) != len(want) {
+		t.Fatalf("expected %d delays, got %v", len(want), delays)
+	}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Errorf("delay %d = %s, want %s", i, delays[i], want[i])
+		}
+	}
+}
+
+func TestPlayCast_InvalidHeader(t *testing.T) {
+	var buf bytes.Buffer
+	if err := playCast(strings.NewReader(`{"version":1}`+"\n"), &buf, 1, 0); err == nil {
+		t.Fatal("expected error for unsupported version")
+	}
+	if err := playCast(strings.NewReader(""), &buf, 1, 0); err == nil {
+		t.Fatal("expected error for empty recording")
+	}
+}
+
+func TestRunReplay_NewPane(t *testing.T) {
+	dir := t.TempDir()
+
+	argsFile := filepath.Join(dir, "tmux-args.txt")
+	tmuxScript := filepath.Join(dir, "tmux")
+	os.WriteFile(tmuxScript, []byte(`#!/bin/sh
+echo "$@" >> `+argsFile+`
+case "$1" in
+  split-window)
+    echo "%77"
+    ;;
+esac
+`), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+
+	cast := filepath.Join(dir, "session.cast")
+	os.WriteFile(cast, []byte(testCast), 0644)
+
+	var buf bytes.Buffer
+	if err := runReplay([]string{cast, "--speed", "4x", "--new-pane"}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "in pane %77") {
+		t.Errorf("expected pane ID in output, got: %s", buf.String())
+	}
+
+	data, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(data), "replay '"+cast+"' --speed '4x'") {
+		t.Errorf("expected replay command in tmux args, got: %s", string(data))
+	}
+}
+
+func TestRunReplay_MissingArgs(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runReplay(nil, &buf); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+	if err := runReplay([]string{"x.cast", "--speed", "bad"}, &buf); err == nil {
+		t.Fatal("expected error for invalid speed")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+const defaultReportWindow = 24 * time.Hour
+
+// utilization holds aggregated pane time for one agent or repo.
+type utilization struct {
+	Name     string  `json:"name"`
+	Busy     float64 `json:"busy_seconds"`
+	Idle     float64 `json:"idle_seconds"`
+	Waiting  float64 `json:"waiting_seconds"`
+	Restarts int     `json:"restarts"`
+	Done     int     `json:"tasks_done"`
+	Failed   int     `json:"tasks_failed"`
+}
+
+// utilizationReport is the JSON shape of the report subcommand.
+type utilizationReport struct {
+	Since  time.Time     `json:"since"`
+	Agents []utilization `json:"agents"`
+	Repos  []utilization `json:"repos"`
+}
+
+// aggregateUtilization sums sample spans and restart counts per key.
+// Results are sorted by busy time, most busy first.
+func aggregateUtilization(records []activityRecord, key func(activityRecord) string) []utilization {
+	byName := make(map[string]*utilization)
+	for _, r := range records {
+		name := key(r)
+		if name == "" {
+			name = "-"
+		}
+		u, ok := byName[name]
+		if !ok {
+			u = &utilization{Name: name}
+			byName[name] = u
+		}
+		switch r.Kind {
+		case activitySample:
+			switch r.State {
+			case stateActive, stateBusyCPU, stateCompacting:
+				u.Busy += r.Span
+			case stateIdle:
+				u.Idle += r.Span
+			case stateWaiting, stateNeedsApproval, stateRateLimited:
+				u.Waiting += r.Span
+			}
+		case activityRestart:
+			u.Restarts++
+		case activityTask:
+			switch r.State {
+			case taskDone:
+				u.Done++
+			case taskFailed:
+				u.Failed++
+			}
+		}
+	}
+
+	result := make([]utilization, 0, len(byName))
+	for _, u := range byName {
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Busy != result[j].Busy {
+			return result[i].Busy > result[j].Busy
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// runReport prints busy/idle/waiting totals, restarts, and finished tasks
+// per agent and per repo.
+func runReport(args []string, w io.Writer) error {
+	window := defaultReportWindow
+	asJSON := jsonOutput
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil {
+					return fmt.Errorf("invalid --since value: %s", args[i])
+				}
+				window = d
+			}
+		case "--json":
+			asJSON = true
+		}
+	}
+
+	since := time.Now().Add(-window)
+	records, err := loadActivity(since)
+	if err != nil {
+		return fmt.Errorf("reading activity log: %w", err)
+	}
+
+	report := utilizationReport{
+		Since:  since,
+		Agents: aggregateUtilization(records, func(r activityRecord) string { return r.Agent }),
+		Repos:  aggregateUtilization(records, func(r activityRecord) string { return r.Repo }),
+	}
+
+	if asJSON {
+		return writeJSON(w, report)
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(w, "No activity recorded (run tmux-agent watch to collect data)")
+		return nil
+	}
+
+	writeUtilizationTable(w, "AGENT", report.Agents)
+	fmt.Fprintln(w)
+	writeUtilizationTable(w, "REPO", report.Repos)
+	return nil
+}
+
+// writeUtilizationTable prints one utilization table with the given name column.
+func writeUtilizationTable(w io.Writer, column string, rows []utilization) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tBUSY\tIDLE\tWAITING\tUTIL\tRESTARTS\tDONE\tFAILED\n", column)
+	for _, u := range rows {
+		util := "-"
+		if total := u.Busy + u.Idle + u.Waiting; total > 0 {
+			util = fmt.Sprintf("%.0f%%", u.Busy/total*100)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\n", u.Name,
+			formatDuration(seconds(u.Busy)),
+			formatDuration(seconds(u.Idle)),
+			formatDuration(seconds(u.Waiting)),
+			util, u.Restarts, u.Done, u.Failed)
+	}
+	tw.Flush()
+}
+
+// seconds converts a float number of seconds to a time.Duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregateUtilization(t *testing.T) {
+	records := []activityRecord{
+		{Kind: activitySample, Agent: "claude", Repo: "owner/a", State: stateActive, Span: 60},
+		{Kind: activitySample, Agent: "claude", Repo: "owner/a", State: stateIdle, Span: 30},
+		{Kind: activitySample, Agent: "codex", Repo: "owner/b", State: stateActive, Span: 120},
+		{Kind: activitySample, Agent: "codex", Repo: "owner/b", State: stateWaiting, Span: 10},
+		{Kind: activityRestart, Agent: "codex"},
+	}
+
+	got := aggregateUtilization(records, func(r activityRecord) string { return r.Agent })
+	if len(got) != 2 {
+		t.Fatalf("expected 2 agents, got %+v", got)
+	}
+	if got[0].Name != "codex" || got[0].Busy != 120 || got[0].Waiting != 10 || got[0].Restarts != 1 {
+		t.Errorf("unexpected codex totals: %+v", got[0])
+	}
+	if got[1].Name != "claude" || got[1].Busy != 60 || got[1].Idle != 30 {
+		t.Errorf("unexpected claude totals: %+v", got[1])
+	}
+
+	byRepo := aggregateUtilization(records, func(r activityRecord) string { return r.Repo })
+	if len(byRepo) != 3 {
+		t.Fatalf("expected 2 repos plus unknown, got %+v", byRepo)
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	now := time.Now()
+	appendActivity(
+		activityRecord{Time: now.Add(-48 * time.Hour), Kind: activitySample, Agent: "old", State: stateActive, Span: 10},
+		activityRecord{Time: now.Add(-time.Hour), Kind: activitySample, Agent: "claude", Repo: "owner/repo", State: stateActive, Span: 600},
+		activityRecord{Time: now.Add(-time.Hour), Kind: activitySample, Agent: "claude", Repo: "owner/repo", State: stateIdle, Span: 600},
+	)
+
+	var buf bytes.Buffer
+	if err := runReport([]string{"--since", "24h"}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "claude") || !strings.Contains(output, "owner/repo") {
+		t.Errorf("expected agent and repo rows, got: %s", output)
+	}
+	if !strings.Contains(output, "50%") {
+		t.Errorf("expected 50%% utilization, got: %s", output)
+	}
+	if strings.Contains(output, "old") {
+		t.Errorf("expected records outside window to be excluded, got: %s", output)
+	}
+
+	buf.Reset()
+	if err := runReport([]string{"--json"}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var report utilizationReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(report.Agents) != 1 || report.Agents[0].Busy != 600 {
+		t.Errorf("unexpected JSON report: %+v", report)
+	}
+}
+
+func TestRunReport_NoActivity(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	var buf bytes.Buffer
+	if err := runReport(nil, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No activity recorded") {
+		t.Errorf("expected empty message, got: %s", buf.String())
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{45 * time.Second, "45s"},
+		{12*time.Minute + 30*time.Second, "12m30s"},
+		{2*time.Hour + 5*time.Minute, "2h05m"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// restartDelay is the default wait after each restart step.
+var restartDelay = 500 * time.Millisecond
+
+// restartSequence is how restart stops an agent before starting it again:
+// the keys that interrupt its current turn, the command that quits it, and
+// how long to wait after each. Command, if set, replaces the command the
+// agent is relaunched with.
+type restartSequence struct {
+	InterruptKeys  []string `json:"interrupt_keys,omitempty"`
+	ExitCommand    string   `json:"exit_command,omitempty"`
+	Command        string   `json:"command,omitempty"`
+	InterruptDelay string   `json:"interrupt_delay,omitempty"`
+	ExitDelay      string   `json:"exit_delay,omitempty"`
+}
+
+// defaultRestartSequences stop claude and codex; other agents get
+// fallbackRestartSequence.
+var defaultRestartSequences = map[string]restartSequence{
+	"claude": {InterruptKeys: []string{"C-c"}, ExitCommand: "/exit"},
+	"codex":  {InterruptKeys: []string{"C-c"}, ExitCommand: "/quit"},
+}
+
+// fallbackRestartSequence stops an agent without a built-in sequence.
+var fallbackRestartSequence = restartSequence{InterruptKeys: []string{"C-c"}, ExitCommand: "/exit"}
+
+// restartSteps is a restart sequence with its defaults filled in.
+type restartSteps struct {
+	interrupt      []string
+	exit           string
+	command        string
+	interruptDelay time.Duration
+	exitDelay      time.Duration
+}
+
+// restartSteps returns agent's restart sequence: its "restart" config,
+// falling back to the built-in sequence per field and to restartDelay for
+// the delays.
+func (c *agentConfig) restartSteps(agent string) (restartSteps, error) {
+	seq, ok := defaultRestartSequences[agent]
+	if !ok {
+		seq = fallbackRestartSequence
+	}
+	if custom := c.agent(agent).Restart; custom != nil {
+		if len(custom.InterruptKeys) > 0 {
+			seq.InterruptKeys = custom.InterruptKeys
+		}
+		if custom.ExitCommand != "" {
+			seq.ExitCommand = custom.ExitCommand
+		}
+		seq.Command = custom.Command
+		seq.InterruptDelay, seq.ExitDelay = custom.InterruptDelay, custom.ExitDelay
+	}
+	steps := restartSteps{
+		interrupt:      seq.InterruptKeys,
+		exit:           seq.ExitCommand,
+		command:        seq.Command,
+		interruptDelay: restartDelay,
+		exitDelay:      restartDelay,
+	}
+	for _, d := range []struct {
+		name, value string
+		dst         *time.Duration
+	}{{"interrupt_delay", seq.InterruptDelay, &steps.interruptDelay}, {"exit_delay", seq.ExitDelay, &steps.exitDelay}} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil || v < 0 {
+			return restartSteps{}, fmt.Errorf("invalid agents.%s.restart.%s: %q", agent, d.name, d.value)
+		}
+		*d.dst = v
+	}
+	return steps, nil
+}
+
+// commandAgent returns the agent a command line starts, e.g. "codex" for
+// "/usr/local/bin/codex -m o3".
+func commandAgent(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+	return filepath.Base(fields[0])
+}
+
+// restartOpts are the restart and restart-all flags.
+type restartOpts struct {
+	// resume starts the agent on its latest session.
+	resume bool
+	// respawn replaces the pane's processes instead of quitting the agent
+	// with keystrokes.
+	respawn bool
+}
+
+// killGracePeriod is how long kill waits for an agent to exit before it
+// kills the pane anyway.
+var killGracePeriod = 5 * time.Second
+
+// quitAgent asks the agent in a pane to exit with the interrupt keys and
+// exit command of its restart sequence, and waits up to killGracePeriod for
+// it to go, so it can save its session. It reports whether the agent
+// exited; a pane with no agent running reports true at once.
+func quitAgent(paneID string) (bool, error) {
+	command := agentCommandFn(paneID)
+	if command == "" {
+		return true, nil
+	}
+	steps, err := loadConfig().restartSteps(commandAgent(command))
+	if err != nil {
+		return false, err
+	}
+	if len(steps.interrupt) > 0 {
+		if err := sendRawTmuxKeys(paneID, steps.interrupt...); err != nil {
+			return false, err
+		}
+		time.Sleep(steps.interruptDelay)
+	}
+	if steps.exit != "" {
+		if err := sendRawTmuxKeys(paneID, steps.exit, "Enter"); err != nil {
+			return false, err
+		}
+	}
+	deadline := time.Now().Add(killGracePeriod)
+	for agentCommandFn(paneID) != "" {
+		if !time.Now().Before(deadline) {
+			return false, nil
+		}
+		time.Sleep(min(readyPollInterval, time.Until(deadline)))
+	}
+	return true, nil
+}
+
+// stopPane kills a pane, asking its agent to exit first unless force is
+// set. An agent that does not exit in time is killed with the pane. Unless
+// archive_on_kill is false, the pane's output is saved to the logs
+// directory first; stopPane returns that file. A failed save is logged and
+// does not keep the pane alive.
+func stopPane(paneID string, force bool) (string, error) {
+	if !force {
+		if _, err := quitAgent(paneID); err != nil {
+			return "", err
+		}
+	}
+	archive := ""
+	if loadConfig().archiveOnKill() {
+		var err error
+		if archive, err = archivePane(paneID); err != nil {
+			slog.Warn("archiving pane failed, killing it anyway", "pane", paneID, "err", err)
+		}
+	}
+	return archive, killTmuxPane(paneID)
+}
+
+// restartAgent restarts the agent in a pane with the command it runs: its
+// configured restart command, else the running command with its original
+// flags when ps shows them, else the active agent. With resume, the agent
+// is instead started on its latest session in the pane's directory. When
+// the agent has exited (the pane is back at a shell prompt, or dead), or
+// with respawn, the pane is respawned rather than sent the restart
+// sequence; an agent that merely could not be detected is not. It returns
+// the command.
+func restartAgent(paneID string, opts restartOpts) (string, error) {
+	detected := agentCommandFn(paneID)
+	command := detected
+	if command == "" {
+		command = activeAgent
+	}
+	respawn := opts.respawn || detected == "" && paneAtShell(paneID)
+	cfg := loadConfig()
+	agent := commandAgent(command)
+	steps, err := cfg.restartSteps(agent)
+	if err != nil {
+		return "", err
+	}
+	dir := ""
+	if opts.resume || respawn {
+		dir = paneCurrentPath(paneID)
+	}
+	switch {
+	case opts.resume:
+		command, _ = cfg.resumeSessionCommand(agent, dir)
+	case steps.command != "":
+		command = steps.command
+	}
+	if respawn {
+		return command, respawnAgent(paneID, dir, command)
+	}
+	return command, runRestartSteps(paneID, steps, command)
+}
+
+// respawnAgent replaces everything running in a pane with a fresh shell in
+// dir and starts command in it, so the pane goes back to a shell prompt when
+// the agent exits, as after a keystroke restart.
+func respawnAgent(paneID, dir, command string) error {
+	if err := respawnTmuxPane(paneID, dir); err != nil {
+		return err
+	}
+	if err := sendRawTmuxKeys(paneID, command, "Enter"); err != nil {
+		return err
+	}
+	recordRestart(paneID, commandAgent(command))
+	return nil
+}
+
+// restartPane interrupts and exits the agent in a pane with the restart
+// sequence of the agent command starts, then starts command.
+func restartPane(paneID, command string) error {
+	steps, err := loadConfig().restartSteps(commandAgent(command))
+	if err != nil {
+		return err
+	}
+	return runRestartSteps(paneID, steps, command)
+}
+
+// runRestartSteps sends a pane the interrupt keys and the exit command,
+// waiting after each, then starts command.
+func runRestartSteps(paneID string, steps restartSteps, command string) error {
+	if len(steps.interrupt) > 0 {
+		if err := sendRawTmuxKeys(paneID, steps.interrupt...); err != nil {
+			return err
+		}
+		time.Sleep(steps.interruptDelay)
+	}
+	if steps.exit != "" {
+		if err := sendRawTmuxKeys(paneID, steps.exit, "Enter"); err != nil {
+			return err
+		}
+		time.Sleep(steps.exitDelay)
+	}
+	if err := sendRawTmuxKeys(paneID, command, "Enter"); err != nil {
+		return err
+	}
+	recordRestart(paneID, commandAgent(command))
+	return nil
+}
+
+// runRestartAll restarts the agent in every (matching) coding agent pane,
+// reporting each pane's result once the agents are ready.
+func runRestartAll(args []string, w io.Writer) error {
+	var filter paneFilter
+	var opts restartOpts
+	confirm := loadConfig().ConfirmDestructive
+	for i := 0; i < len(args); i++ {
+		next, ok, err := filter.parseFlag(args, i)
+		if err != nil {
+			return err
+		}
+		if ok {
+			i = next
+			continue
+		}
+		switch args[i] {
+		case "--resume":
+			opts.resume = true
+		case "--respawn":
+			opts.respawn = true
+		default:
+			if !parseConfirmFlag(args[i], &confirm) {
+				return fmt.Errorf("usage: tmux-agent restart-all [--resume] [--respawn] [--agent claude|codex] [--repo owner/name] [--dir path] [--exclude %%id] [--idle-only] [--confirm|--yes]")
+			}
+		}
+	}
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return err
+	}
+	if panes, err = filter.apply(panes); err != nil {
+		return err
+	}
+	if len(panes) == 0 && !jsonOutput {
+		if filter.active() {
+			fmt.Fprintln(w, "No coding agent panes match the filters")
+		} else {
+			fmt.Fprintln(w, "No coding agent panes found")
+		}
+		return nil
+	}
+	if confirm && len(panes) > 0 {
+		if err := confirmPanes("Restart", panes); err != nil {
+			return err
+		}
+	}
+
+	results := forEachPane(panes, paneWorkers, func(_ int, p paneInfo) error {
+		before, err := captureScrollback(p.ID)
+		if err != nil {
+			return err
+		}
+		command, err := restartAgent(p.ID, opts)
+		if err == nil {
+			waitReadySince(p.ID, commandAgent(command), before)
+		}
+		return err
+	})
+	return writePaneResults(w, results, "restarted")
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRestartSteps(t *testing.T) {
+	cfg := &agentConfig{Agents: map[string]*agentProfile{
+		"aider": {Restart: &restartSequence{ExitCommand: "/quit", Command: "aider --no-auto-commits", ExitDelay: "2s"}},
+		"bad":   {Restart: &restartSequence{InterruptDelay: "soon"}},
+	}}
+
+	codex, err := cfg.restartSteps("codex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(codex.interrupt, " ") != "C-c" || codex.exit != "/quit" || codex.command != "" || codex.exitDelay != restartDelay {
+		t.Errorf("unexpected codex steps: %+v", codex)
+	}
+
+	aider, err := cfg.restartSteps("aider")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(aider.interrupt, " ") != "C-c" || aider.exit != "/quit" || aider.command != "aider --no-auto-commits" ||
+		aider.exitDelay != 2*time.Second || aider.interruptDelay != restartDelay {
+		t.Errorf("unexpected aider steps: %+v", aider)
+	}
+
+	if _, err := cfg.restartSteps("bad"); err == nil {
+		t.Error("expected invalid interrupt_delay to fail")
+	}
+}
+
+func TestCommandAgent(t *testing.T) {
+	for command, want := range map[string]string{
+		"claude":                      "claude",
+		"/usr/local/bin/codex -m o3":  "codex",
+		"claude --continue --verbose": "claude",
+		"":                            "",
+	} {
+		if got := commandAgent(command); got != want {
+			t.Errorf("commandAgent(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+func TestRunRestart_CodexSequence(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "codex", Dir: "/work/a"})
+	t.Setenv("HOME", t.TempDir())
+	origDelay, origLookup, origReady := restartDelay, agentCommandFn, readyTimeout
+	restartDelay, readyTimeout = 0, 0
+	agentCommandFn = func(paneID string) string { return "codex" }
+	defer func() { restartDelay, agentCommandFn, readyTimeout = origDelay, origLookup, origReady }()
+
+	if err := runRestart([]string{"%5"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(fake.Panes[0].Input, " | ")
+	if got != "C-c | /quit Enter | codex Enter" {
+		t.Errorf("unexpected restart keys: %s", got)
+	}
+}
+
+func TestRunRestart_ConfiguredCommand(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "aider", Dir: "/work/a"})
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".config", "tmux-agent")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"agents": {"aider": {"restart": {
+		"interrupt_keys": ["Escape", "C-c"], "exit_command": "/quit", "command": "aider --yes", "exit_delay": "0s"}}}}`), 0644)
+	origDelay, origLookup, origReady := restartDelay, agentCommandFn, readyTimeout
+	restartDelay, readyTimeout = 0, 0
+	agentCommandFn = func(paneID string) string { return "aider" }
+	defer func() { restartDelay, agentCommandFn, readyTimeout = origDelay, origLookup, origReady }()
+
+	if err := runRestart([]string{"%5"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(fake.Panes[0].Input, " | ")
+	if got != "Escape C-c | /quit Enter | aider --yes Enter" {
+		t.Errorf("unexpected restart keys: %s", got)
+	}
+}
+
+func TestRunRestartAll(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude"},
+		&runner.FakePane{ID: "%2", Command: "codex"},
+		&runner.FakePane{ID: "%3", Command: "claude"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	origDelay, origLookup, origReady := restartDelay, agentCommandFn, readyTimeout
+	restartDelay, readyTimeout = 0, 0
+	agentCommandFn = func(paneID string) string { return fake.Pane(paneID).Command }
+	defer func() { restartDelay, agentCommandFn, readyTimeout = origDelay, origLookup, origReady }()
+
+	var buf strings.Builder
+	if err := runRestartAll([]string{"--agent", "claude"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"%1": "C-c | /exit Enter | claude Enter", "%2": "", "%3": "C-c | /exit Enter | claude Enter"} {
+		if got := strings.Join(fake.Pane(id).Input, " | "); got != want {
+			t.Errorf("pane %s: sent %q, want %q", id, got, want)
+		}
+	}
+	if !strings.Contains(buf.String(), "restarted") {
+		t.Errorf("expected a results table, got %q", buf.String())
+	}
+	if err := runRestartAll([]string{"--bogus"}, io.Discard); err == nil {
+		t.Error("expected usage error for unknown flag")
+	}
+}
+
+func TestRunRestart_RespawnsExitedAgent(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "zsh", Dir: "/work/a"})
+	t.Setenv("HOME", t.TempDir())
+	origDelay, origLookup, origReady := restartDelay, agentCommandFn, readyTimeout
+	restartDelay, readyTimeout = 0, 0
+	agentCommandFn = func(paneID string) string { return "" }
+	defer func() { restartDelay, agentCommandFn, readyTimeout = origDelay, origLookup, origReady }()
+
+	if err := runRestart([]string{"%5"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	p := fake.Pane("%5")
+	if p.Respawns != 1 || p.Dir != "/work/a" {
+		t.Errorf("expected the pane to be respawned in its directory, got %+v", p)
+	}
+	if got := strings.Join(p.Input, " | "); got != "claude Enter" {
+		t.Errorf("expected only the agent to be started, got %q", got)
+	}
+}
+
+func TestRunRestart_UndetectedAgentNotRespawned(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "node", Dir: "/work/a"})
+	t.Setenv("HOME", t.TempDir())
+	origDelay, origLookup, origReady := restartDelay, agentCommandFn, readyTimeout
+	restartDelay, readyTimeout = 0, 0
+	// ps failed, or ran where the agent is not.
+	agentCommandFn = func(paneID string) string { return "" }
+	defer func() { restartDelay, agentCommandFn, readyTimeout = origDelay, origLookup, origReady }()
+
+	if err := runRestart([]string{"%5"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	p := fake.Pane("%5")
+	if got := strings.Join(p.Input, " | "); p.Respawns != 0 || got != "C-c | /exit Enter | claude Enter" {
+		t.Errorf("expected the restart keys rather than a respawn, got %d respawns, %q", p.Respawns, got)
+	}
+}
+
+func TestRunRestart_Respawn(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "codex", Dir: "/work/a"})
+	t.Setenv("HOME", t.TempDir())
+	origDelay, origLookup, origReady := restartDelay, agentCommandFn, readyTimeout
+	restartDelay, readyTimeout = 0, 0
+	agentCommandFn = func(paneID string) string { return "codex -m o3" }
+	defer func() { restartDelay, agentCommandFn, readyTimeout = origDelay, origLookup, origReady }()
+
+	if err := runRestart([]string{"%5", "--respawn"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	p := fake.Pane("%5")
+	if got := strings.Join(p.Input, " | "); p.Respawns != 1 || got != "codex -m o3 Enter" {
+		t.Errorf("expected a respawn and the agent relaunched with its flags, got %d respawns, %q", p.Respawns, got)
+	}
+}
+
+func TestRunKill_ExitsAgentFirst(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "codex"},
+		&runner.FakePane{ID: "%2", Command: "claude"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	origDelay, origLookup, origGrace := restartDelay, agentCommandFn, killGracePeriod
+	restartDelay, killGracePeriod = 0, 50*time.Millisecond
+	// The agent exits once it has been sent its exit command.
+	agentCommandFn = func(paneID string) string {
+		p := fake.Pane(paneID)
+		if p == nil || strings.Contains(strings.Join(p.Input, " "), "Enter") {
+			return ""
+		}
+		return p.Command
+	}
+	defer func() { restartDelay, agentCommandFn, killGracePeriod = origDelay, origLookup, origGrace }()
+
+	p1 := fake.Pane("%1")
+	if err := runKill([]string{"%1"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(p1.Input, " | "); got != "C-c | /quit Enter" {
+		t.Errorf("expected codex to be asked to quit, got %q", got)
+	}
+	if fake.Pane("%1") != nil {
+		t.Error("expected the pane to be killed")
+	}
+
+	p2 := fake.Pane("%2")
+	if err := runKill([]string{"%2", "--force"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if len(p2.Input) != 0 || fake.Pane("%2") != nil {
+		t.Errorf("expected --force to kill at once, sent %q", p2.Input)
+	}
+}
+
+func TestQuitAgent_Timeout(t *testing.T) {
+	useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+	t.Setenv("HOME", t.TempDir())
+	origDelay, origLookup, origGrace := restartDelay, agentCommandFn, killGracePeriod
+	restartDelay, killGracePeriod = 0, 20*time.Millisecond
+	agentCommandFn = func(paneID string) string { return "claude" }
+	defer func() { restartDelay, agentCommandFn, killGracePeriod = origDelay, origLookup, origGrace }()
+
+	if exited, err := quitAgent("%1"); err != nil || exited {
+		t.Errorf("expected a hung agent not to exit, got %v, %v", exited, err)
+	}
+}
+
+func TestRunKill_Filters(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "codex", Output: "Done."},
+		&runner.FakePane{ID: "%2", Command: "codex", Output: "Done."},
+		&runner.FakePane{ID: "%3", Command: "claude", Output: "Done."},
+	)
+	t.Setenv("HOME", t.TempDir())
+	saveState(paneStateFile, map[string]trackedOutput{
+		"%1": {Hash: outputHash("Done."), Changed: time.Now().Add(-time.Hour)},
+		"%2": {Hash: outputHash("Done."), Changed: time.Now().Add(-10 * time.Minute)},
+		"%3": {Hash: outputHash("Done."), Changed: time.Now().Add(-time.Hour)},
+	})
+
+	var buf bytes.Buffer
+	args := []string{"--idle", "30m", "--agent", "codex", "--force"}
+	if err := runKill(append(args, "--dry-run"), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "%1") || strings.Contains(out, "%2") || strings.Contains(out, "%3") {
+		t.Errorf("expected only %%1 to be listed, got %q", out)
+	}
+	if fake.Pane("%1") == nil {
+		t.Fatal("expected --dry-run not to kill")
+	}
+
+	if err := runKill(args, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"%1", "%2", "%3"} {
+		if killed := fake.Pane(id) == nil; killed != (id == "%1") {
+			t.Errorf("pane %s: killed = %v", id, killed)
+		}
+	}
+
+	for _, args := range [][]string{nil, {"--dry-run"}, {"--idle", "soon"}} {
+		if err := runKill(args, io.Discard); err == nil {
+			t.Errorf("expected an error for %q", args)
+		}
+	}
+}
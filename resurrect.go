@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const resurrectFile = "resurrect.json"
+
+// defaultResumeCommands continue an agent's most recent conversation in the
+// current directory. Override per agent with "resume_command" in config.json.
+var defaultResumeCommands = map[string]string{
+	"claude": "claude --continue",
+	"codex":  "codex resume --last",
+}
+
+// resurrectConfig is the tmux.conf snippet printed by `resurrect-hook`.
+const resurrectConfig = `# Save agent panes along with the tmux-resurrect snapshot, and relaunch
+# them in their worktrees after a restore. Leave claude/codex out of
+# @resurrect-processes so resurrect does not start fresh sessions itself.
+set -g @resurrect-hook-post-save-all 'tmux-agent resurrect-hook save'
+set -g @resurrect-hook-post-restore-all 'tmux-agent resurrect-hook restore'`
+
+// resurrectPane is an agent pane saved for tmux-resurrect. Target is the
+// session:window.pane position, which resurrect restores unchanged.
+type resurrectPane struct {
+	Target string `json:"target"`
+	Pane   string `json:"pane"`
+	Agent  string `json:"agent"`
+	Dir    string `json:"dir"`
+	Title  string `json:"title,omitempty"`
+}
+
+// resumeCommand returns the command that resumes the named agent.
+func (c *agentConfig) resumeCommand(agent string) string {
+	if cmd := c.agent(agent).ResumeCommand; cmd != "" {
+		return cmd
+	}
+	if cmd, ok := defaultResumeCommands[agent]; ok {
+		return cmd
+	}
+	return agent
+}
+
+// listPaneTargets returns pane ID -> session:window.pane for every pane.
+func listPaneTargets() (map[string]string, error) {
+	output, err := tmuxRunner.Run("list-panes", "-a", "-F", "#{pane_id}\t#{session_name}:#{window_index}.#{pane_index}")
+	if err != nil {
+		return nil, fmt.Errorf("tmux list-panes: %w", err)
+	}
+	targets := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if id, target, ok := strings.Cut(line, "\t"); ok {
+			targets[id] = target
+		}
+	}
+	return targets, nil
+}
+
+// runResurrectHook is called by tmux-resurrect after saving and after
+// restoring. Without arguments it prints the tmux.conf lines to install it.
+func runResurrectHook(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintln(w, resurrectConfig)
+		return nil
+	}
+	switch args[0] {
+	case "save":
+		return resurrectSave(w)
+	case "restore":
+		return resurrectRestore(w)
+	default:
+		return fmt.Errorf("usage: tmux-agent resurrect-hook [save|restore]")
+	}
+}
+
+// resurrectSave records the agent panes and their identities.
+func resurrectSave(w io.Writer) error {
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return err
+	}
+	targets, err := listPaneTargets()
+	if err != nil {
+		return err
+	}
+	var saved []resurrectPane
+	for _, p := range panes {
+		saved = append(saved, resurrectPane{
+			Target: targets[p.ID],
+			Pane:   p.ID,
+			Agent:  p.Command,
+			Dir:    p.Dir,
+			Title:  p.Title,
+		})
+	}
+	if err := saveState(resurrectFile, saved); err != nil {
+		return err
+	}
+	if _, err := reattachPanes(panes); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Saved %d agent panes\n", len(saved))
+	return nil
+}
+
+// resurrectRestore relaunches saved agents in the restored panes and hands
+// the new panes their labels and tasks.
+func resurrectRestore(w io.Writer) error {
+	var saved []resurrectPane
+	if err := loadState(resurrectFile, &saved); err != nil {
+		return fmt.Errorf("reading %s: %w", resurrectFile, err)
+	}
+	targets, err := listPaneTargets()
+	if err != nil {
+		return err
+	}
+	byTarget := make(map[string]string)
+	for id, target := range targets {
+		byTarget[target] = id
+	}
+	running := make(map[string]bool)
+	if panes, err := listTmuxPanes(); err == nil {
+		for _, p := range panes {
+			running[p.ID] = true
+		}
+	}
+
+	cfg := loadConfig()
+	var restored []paneInfo
+	for _, s := range saved {
+		paneID, ok := byTarget[s.Target]
+		if !ok {
+			fmt.Fprintf(w, "No pane at %s for %s (%s)\n", s.Target, s.Agent, shortDir(s.Dir))
+			continue
+		}
+		if !running[paneID] {
+			line := fmt.Sprintf("cd %s && %s", shellQuote(s.Dir), cfg.resumeCommand(s.Agent))
+			if err := sendRawTmuxKeys(paneID, line, "Enter"); err != nil {
+				fmt.Fprintf(w, "Failed to relaunch %s in %s: %v\n", s.Agent, paneID, err)
+				continue
+			}
+		}
+		restored = append(restored, paneInfo{ID: paneID, Command: s.Agent, Dir: s.Dir, Title: s.Title})
+		fmt.Fprintf(w, "Resumed %s in pane %s (%s)\n", s.Agent, paneID, shortDir(s.Dir))
+	}
+
+	reattached, err := reattachPanes(restored)
+	if err != nil {
+		return err
+	}
+	for _, r := range reattached {
+		fmt.Fprintf(w, "Reattached %s: %s -> %s\n", r.Identity.name(), r.OldPane, r.Identity.Pane)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResurrectHook_SaveAndRestore(t *testing.T) {
+	dir := t.TempDir()
+
+	argsFile := filepath.Join(dir, "tmux-args.txt")
+	serverFile := filepath.Join(dir, "server.txt")
+	panesFile := filepath.Join(dir, "panes.txt")
+	targetsFile := filepath.Join(dir, "targets.txt")
+	os.WriteFile(filepath.Join(dir, "tmux"), []byte(`#!/bin/sh
+echo "$@" >> `+argsFile+`
+case "$1" in
+  display-message)
+    cat `+serverFile+`
+    ;;
+  list-panes)
+    case "$*" in
+      *session_name}:*) cat `+targetsFile+` ;;
+      *) cat `+panesFile+` ;;
+    esac
+    ;;
+esac
+`), 0755)
+	os.WriteFile(filepath.Join(dir, "git"), []byte("#!/bin/sh\necho main\n"), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	origLookup := childLookupFn
+	childLookupFn = func(string) string { return "" }
+	defer func() { childLookupFn = origLookup }()
+
+	saveLabels(map[string]string{"%3": "auth refactor"})
+	os.WriteFile(serverFile, []byte("100:1700000000\n"), 0644)
+	os.WriteFile(panesFile, []byte("%3\tclaude\t1\t/work/a\tauth\n%4\tzsh\t2\t/work/b\tshell\n"), 0644)
+	os.WriteFile(targetsFile, []byte("%3\tmain:1.0\n%4\tmain:1.1\n"), 0644)
+
+	var buf bytes.Buffer
+	if err := runResurrectHook([]string{"save"}, &buf); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Saved 1 agent panes") {
+		t.Errorf("unexpected save output: %s", buf.String())
+	}
+
+	// After a reboot resurrect recreates the layout with shells in new panes.
+	os.WriteFile(serverFile, []byte("200:1700009000\n"), 0644)
+	os.WriteFile(panesFile, []byte("%0\tzsh\t3\t/work/a\tauth\n%1\tzsh\t4\t/work/b\tshell\n"), 0644)
+	os.WriteFile(targetsFile, []byte("%0\tmain:1.0\n%1\tmain:1.1\n"), 0644)
+	os.Remove(argsFile)
+
+	buf.Reset()
+	if err := runResurrectHook([]string{"restore"}, &buf); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Resumed claude in pane %0") {
+		t.Errorf("unexpected restore output: %s", buf.String())
+	}
+	data, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(data), "send-keys -t %0 cd '/work/a' && claude --continue Enter") {
+		t.Errorf("expected agent relaunch in %%0, got: %s", string(data))
+	}
+	if strings.Contains(string(data), "send-keys -t %1") {
+		t.Errorf("expected non-agent pane to be left alone, got: %s", string(data))
+	}
+	if loadLabels()["%0"] != "auth refactor" {
+		t.Errorf("expected label to follow the pane, got %v", loadLabels())
+	}
+}
+
+func TestRunResurrectHook_PrintsConfig(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runResurrectHook(nil, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "@resurrect-hook-post-restore-all 'tmux-agent resurrect-hook restore'") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+	if err := runResurrectHook([]string{"bogus"}, &buf); err == nil {
+		t.Error("expected error for unknown action")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultRunIdle is how long a pane's output must stay unchanged before run
+// takes the agent to be done, unless --idle is given.
+const defaultRunIdle = time.Minute
+
+// runJSON is the JSON result of run.
+type runJSON struct {
+	Pane   string `json:"pane"`
+	Text   string `json:"text"`
+	Reason string `json:"reason"`
+	Output string `json:"output"`
+	// Outcome is "done" or "failed" by the pane's error_patterns and
+	// success_patterns, if it has any; Error is the failing output.
+	Outcome string `json:"outcome,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// newOutput returns the lines of after that follow what before already
+// held, both being captures of the same pane's scrollback. Lines at the top
+// of the history may have been dropped in between (history-limit), and the
+// agent may have redrawn the bottom of the screen, so before is aligned by
+// its first line still present rather than compared as a plain prefix.
+func newOutput(before, after string) string {
+	b := strings.Split(before, "\n")
+	a := strings.Split(after, "\n")
+	for shift := range b {
+		n := 0
+		for n < len(a) && shift+n < len(b) && a[n] == b[shift+n] {
+			n++
+		}
+		// A short match far from the end of before is a coincidence, e.g.
+		// a blank line.
+		if n > 0 && (n >= 3 || shift+n == len(b)) {
+			return strings.Trim(strings.Join(a[n:], "\n"), "\n")
+		}
+	}
+	return strings.Trim(after, "\n")
+}
+
+// matchesReply reports whether a line of output matches re, leaving out
+// the echo of the prompt text (if any), which may well contain the pattern
+// itself.
+func matchesReply(output, text string, re *regexp.Regexp) bool {
+	for _, line := range strings.Split(output, "\n") {
+		if (text == "" || !strings.Contains(line, text)) && re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// runCommandUsage is the usage of run.
+const runCommandUsage = "usage: tmux-agent run <pane_id> [--idle duration] [--match regex] [--timeout duration] [--] <prompt...>"
+
+// runOptions are run's flags.
+type runOptions struct {
+	idle    time.Duration
+	timeout time.Duration
+	match   *regexp.Regexp
+}
+
+// parseRunArgs parses run's flags from args, the arguments after the pane
+// ID, and returns the index where the prompt starts. As with send, flags
+// come before the prompt and "--" ends them, so the prompt may mention
+// them.
+func parseRunArgs(args []string) (opts runOptions, text int, err error) {
+	opts.idle = defaultRunIdle
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			return opts, i + 1, nil
+		}
+		if n := globalFlagWidth(args[i]); n > 0 {
+			i += n - 1
+			continue
+		}
+		if i+1 == len(args) {
+			return opts, i, nil
+		}
+		switch args[i] {
+		case "--idle":
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d <= 0 {
+				return opts, 0, fmt.Errorf("invalid --idle value: %s", args[i])
+			}
+			opts.idle = d
+		case "--timeout":
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d <= 0 {
+				return opts, 0, fmt.Errorf("invalid --timeout value: %s", args[i])
+			}
+			opts.timeout = d
+		case "--match":
+			i++
+			if opts.match, err = regexp.Compile(args[i]); err != nil {
+				return opts, 0, fmt.Errorf("invalid --match pattern: %w", err)
+			}
+		default:
+			return opts, i, nil
+		}
+	}
+	return opts, len(args), nil
+}
+
+// runRun sends a prompt to a pane, waits until the agent is done (idle, or
+// its new output matches --match) and prints the output produced since. It
+// fails when that output ends in a match of the pane's error_patterns.
+func runRun(args []string, w io.Writer) error {
+	if len(args) < 1 || !strings.HasPrefix(args[0], "%") {
+		return fmt.Errorf(runCommandUsage)
+	}
+	paneID := args[0]
+	opts, start, err := parseRunArgs(args[1:])
+	if err != nil {
+		return err
+	}
+	text := strings.Join(args[1+start:], " ")
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf(runCommandUsage)
+	}
+
+	before, err := captureScrollback(paneID)
+	if err != nil {
+		return err
+	}
+	if err := sendTmuxKeys(paneID, text); err != nil {
+		return err
+	}
+	touchPane(paneID)
+	reason, output, err := awaitReply(paneID, text, before, opts.idle, opts.timeout, opts.match)
+	if err != nil {
+		return err
+	}
+	outcome, excerpt, err := paneOutcome(paneID, output)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		err = writeJSON(w, runJSON{Pane: paneID, Text: text, Reason: reason, Output: output, Outcome: outcome, Error: excerpt})
+	} else if output != "" {
+		fmt.Fprintln(w, output)
+	}
+	if err == nil && outcome == taskFailed {
+		err = taskFailedError(paneID, excerpt)
+	}
+	return err
+}
+
+// awaitReply waits until the agent in paneID is done with text, sent after
+// before was captured: until its output has been still for idle, or a new
+// line matches re (when set). It returns why it stopped, waitIdle or
+// waitMatch, and the output since before.
+func awaitReply(paneID, text, before string, idle, timeout time.Duration, re *regexp.Regexp) (string, string, error) {
+	cfg := loadConfig()
+	backend, err := cfg.idleBackend("")
+	if err != nil {
+		return "", "", err
+	}
+	busy := cfg.busyChecker()
+	threshold := func(string) time.Duration { return idle }
+
+	var output string
+	res, err := pollUntil(timeout, "pane "+paneID, func() (waitJSON, error) {
+		res, err := checkWait(paneID, threshold, backend, busy.fresh(), nil, "")
+		if err != nil || re == nil {
+			return res, err
+		}
+		after, err := captureScrollback(paneID)
+		if err != nil {
+			return waitJSON{}, err
+		}
+		output = newOutput(before, after)
+		if res.Reason == "" && matchesReply(output, text, re) {
+			res.Reason = waitMatch
+		}
+		return res, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if re == nil {
+		after, err := captureScrollback(paneID)
+		if err != nil {
+			return "", "", err
+		}
+		output = newOutput(before, after)
+	}
+	return res.Reason, output, nil
+}
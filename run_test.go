@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestNewOutput(t *testing.T) {
+	tests := []struct {
+		name, before, after, want string
+	}{
+		{"appended", "a\nb\nc", "a\nb\nc\nd\ne", "d\ne"},
+		{"redrawn prompt", "a\nb\nc\n> ", "a\nb\nc\n> fix it\nfixed\n> ", "> fix it\nfixed\n> "},
+		{"history trimmed", "a\nb\nc\nd\ne", "c\nd\ne\nf", "f"},
+		{"blank line is no anchor", "a\n\nb\nc\nd", "\nx\ny", "x\ny"},
+		{"cleared", "a\nb", "x\ny", "x\ny"},
+		{"unchanged", "a\nb", "a\nb", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newOutput(tt.before, tt.after); got != tt.want {
+				t.Errorf("newOutput = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunRun(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "earlier work\nDone.\n"})
+	t.Setenv("HOME", t.TempDir())
+	orig := waitPollInterval
+	waitPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { waitPollInterval = orig })
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		fake.Print("%1", "> say Done. when done\nworking\nDone.\n")
+	}()
+	var buf bytes.Buffer
+	err := runRun([]string{"%1", "--match", `^Done\.`, "--idle", "1h", "--timeout", "5s", "say", "Done.", "when", "done"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "> say Done. when done\nworking\nDone.\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if in := fake.Pane("%1").Input; len(in) == 0 || in[0] != "say Done. when done" {
+		t.Errorf("expected the prompt to be sent, got %q", in)
+	}
+
+	// Without --match, run returns once the output has been still for --idle.
+	buf.Reset()
+	if err := runRun([]string{"%1", "--idle", "50ms", "--timeout", "5s", "continue"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no new output, got %q", buf.String())
+	}
+
+	if err := runRun([]string{"%1"}, &buf); err == nil {
+		t.Error("expected a usage error without a prompt")
+	}
+}
+
+func TestParseRunArgs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		text  int
+		match string
+	}{
+		{[]string{"explain", "the", "--match", "flag"}, 0, ""},
+		{[]string{"--match", "^ok", "explain", "--idle"}, 2, "^ok"},
+		{[]string{"--idle", "2m", "--", "--match", "is", "broken"}, 3, ""},
+	}
+	for _, tt := range tests {
+		opts, text, err := parseRunArgs(tt.args)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.args, err)
+		}
+		match := ""
+		if opts.match != nil {
+			match = opts.match.String()
+		}
+		if text != tt.text || match != tt.match {
+			t.Errorf("%q: text at %d, match %q; want %d, %q", tt.args, text, match, tt.text, tt.match)
+		}
+	}
+	if _, _, err := parseRunArgs([]string{"--idle", "soon", "go"}); err == nil {
+		t.Error("expected an error for an invalid --idle")
+	}
+}
+
+func TestRunRun_ErrorPatterns(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude", Output: "ready"})
+	t.Setenv("HOME", t.TempDir())
+	saveConfig(&agentConfig{Agents: map[string]*agentProfile{
+		"claude": {outcomePatterns: outcomePatterns{ErrorPatterns: []string{"^--- FAIL"}}},
+	}})
+	orig := waitPollInterval
+	waitPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { waitPollInterval = orig })
+	useJSONOutput(t)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		fake.Print("%1", "\n--- FAIL: TestParse\nDone.")
+	}()
+	var buf bytes.Buffer
+	err := runRun([]string{"%1", "--match", `^Done\.`, "--timeout", "5s", "run the tests"}, &buf)
+	if err == nil || err.Error() != "pane %1 failed: --- FAIL: TestParse" {
+		t.Errorf("expected the failure to be reported, got %v", err)
+	}
+	var res runJSON
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil || res.Outcome != taskFailed || !strings.HasPrefix(res.Error, "--- FAIL") {
+		t.Errorf("expected a failed outcome in the JSON, got %+v (%v)", res, err)
+	}
+}
@@ -0,0 +1,246 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// controlReady is printed once the control client is attached, so the
+// replies to anything tmux sends on attach can be skipped.
+const controlReady = "tmux-agent-ready"
+
+// controlCommands are the commands Control sends over its connection. They
+// must name their target with -t (or list everything with -a): without
+// one, tmux would pick the control client's session instead of the
+// caller's pane. Commands that act on the current client (display-popup,
+// switch-client, ...) are never sent.
+var controlCommands = map[string]bool{
+	"list-panes":      true,
+	"list-windows":    true,
+	"capture-pane":    true,
+	"send-keys":       true,
+	"display-message": true,
+	"select-pane":     true,
+	"kill-pane":       true,
+	"respawn-pane":    true,
+	"pipe-pane":       true,
+	"paste-buffer":    true,
+}
+
+// controlPlainRe matches arguments that need no quoting in a tmux command.
+var controlPlainRe = regexp.MustCompile(`^[A-Za-z0-9_,.:/@%+=-]+$`)
+
+// Control runs commands over a single tmux control-mode client (tmux -C)
+// instead of starting a tmux process for each one, which matters when
+// status and watch query many panes every few seconds. Commands it cannot
+// send, and every command once the connection fails, go to Fallback.
+type Control struct {
+	// Socket selects a tmux server by socket name (tmux -L).
+	Socket string
+	// Fallback runs the commands Control does not.
+	Fallback TmuxRunner
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	failed bool
+}
+
+// NewControl returns a Control for the given socket that falls back to
+// Exec. The connection is opened by the first command that uses it.
+func NewControl(socket string) *Control {
+	return &Control{Socket: socket, Fallback: Exec{Socket: socket}}
+}
+
+// Run sends args over the control connection when possible, and runs them
+// with Fallback otherwise.
+func (c *Control) Run(args ...string) ([]byte, error) {
+	if !controlSafe(args) {
+		return c.Fallback.Run(args...)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failed {
+		return c.Fallback.Run(args...)
+	}
+	if c.cmd == nil {
+		if err := c.start(); err != nil {
+			c.failed = true
+			return c.Fallback.Run(args...)
+		}
+	}
+	out, ok, err := c.send(args)
+	if !ok {
+		c.close()
+		c.failed = true
+		return c.Fallback.Run(args...)
+	}
+	return out, err
+}
+
+// Close ends the control connection.
+func (c *Control) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.close()
+}
+
+// controlSafe reports whether args can be sent over the control connection.
+func controlSafe(args []string) bool {
+	if len(args) == 0 || !controlCommands[args[0]] {
+		return false
+	}
+	for _, a := range args[1:] {
+		if a == "--" {
+			break
+		}
+		if a == "-t" || a == "-a" {
+			return true
+		}
+	}
+	return false
+}
+
+// start attaches the control client. Output notifications are turned off
+// and the client is ignored when sizing windows; tmux versions without
+// these attach flags fail here and Fallback is used instead.
+func (c *Control) start() error {
+	cmd := exec.Command("tmux", withSocket(c.Socket, []string{"-C", "attach-session", "-f", "no-output,ignore-size"})...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	c.cmd, c.stdin, c.stdout = cmd, stdin, bufio.NewReader(stdout)
+
+	if _, err := io.WriteString(c.stdin, "display-message -p "+controlReady+"\n"); err != nil {
+		c.close()
+		return err
+	}
+	for {
+		lines, _, err := c.readBlock()
+		if err != nil {
+			c.close()
+			return err
+		}
+		if len(lines) == 1 && lines[0] == controlReady {
+			return nil
+		}
+	}
+}
+
+// send writes one command and reads its reply. ok is false if the
+// connection failed; err is the command's own error.
+func (c *Control) send(args []string) (out []byte, ok bool, err error) {
+	if _, err := io.WriteString(c.stdin, controlCommand(args)+"\n"); err != nil {
+		return nil, false, nil
+	}
+	lines, failed, err := c.readBlock()
+	if err != nil {
+		return nil, false, nil
+	}
+	if failed {
+		return nil, true, fmt.Errorf("tmux %s: %s", args[0], strings.Join(lines, "; "))
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	return []byte(b.String()), true, nil
+}
+
+// readBlock reads the reply to the next command this client sent, skipping
+// notifications and replies to commands tmux ran itself (such as the
+// attach), which have 0 rather than 1 as their flags. failed reports an
+// %error reply.
+func (c *Control) readBlock() (lines []string, failed bool, err error) {
+	guard := ""
+	for {
+		line, err := c.stdout.ReadString('\n')
+		if err != nil {
+			return nil, false, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if guard == "" {
+			if rest, ok := strings.CutPrefix(line, "%begin "); ok {
+				guard, lines = rest, nil
+			} else if strings.HasPrefix(line, "%exit") {
+				return nil, false, io.EOF
+			}
+			continue
+		}
+		// The closing line repeats the time, number and flags of %begin,
+		// which output lines that merely start with %end will not.
+		switch line {
+		case "%end " + guard, "%error " + guard:
+			if strings.HasSuffix(guard, " 1") {
+				return lines, strings.HasPrefix(line, "%error"), nil
+			}
+			guard = ""
+			continue
+		}
+		lines = append(lines, line)
+	}
+}
+
+// close stops the control client, if any.
+func (c *Control) close() error {
+	if c.cmd == nil {
+		return nil
+	}
+	c.stdin.Close()
+	err := c.cmd.Wait()
+	c.cmd, c.stdin, c.stdout = nil, nil, nil
+	return err
+}
+
+// controlCommand formats args as a tmux command line.
+func controlCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = controlQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// controlQuote quotes one argument for tmux's command parser. Double
+// quotes are used with "$" escaped, so nothing is expanded, and control
+// characters escaped, so the command stays on one line.
+func controlQuote(s string) string {
+	if controlPlainRe.MatchString(s) {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '"' || r == '$':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\%03o`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeControlTmux installs a tmux script that, in control mode, echoes each
+// command back in a reply block, and otherwise prints "exec" and its
+// arguments.
+func fakeControlTmux(t *testing.T, attach string) {
+	t.Helper()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "tmux"), []byte(`#!/bin/sh
+if [ "$1" != "-C" ]; then
+  echo "exec $@"
+  exit 0
+fi
+`+attach+`
+printf '%%begin 1 1 0\n%%end 1 1 0\n'
+n=2
+while IFS= read -r line; do
+  case "$line" in
+  "display-message -p tmux-agent-ready")
+    printf '%%begin 1 %d 1\ntmux-agent-ready\n%%end 1 %d 1\n' $n $n ;;
+  *missing*)
+    printf '%%output %%1 noise\n%%begin 1 %d 1\ncan'"'"'t find pane: %%9\n%%error 1 %d 1\n' $n $n ;;
+  *)
+    printf '%%window-add @1\n%%begin 1 %d 1\n%%end of line\n%s\n%%end 1 %d 1\n' $n "$line" $n ;;
+  esac
+  n=$((n+1))
+done
+`), 0755)
+	t.Setenv("PATH", dir+":"+os.Getenv("PATH"))
+}
+
+func TestControl(t *testing.T) {
+	fakeControlTmux(t, "")
+	c := NewControl("")
+	defer c.Close()
+
+	out, err := c.Run("send-keys", "-t", "%1", "-l", "--", "say \"hi\" to $USER\nnow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "%end of line\nsend-keys -t %1 -l -- \"say \\\"hi\\\" to \\$USER\\nnow\"\n"
+	if string(out) != want {
+		t.Errorf("Run = %q, want %q", out, want)
+	}
+
+	if _, err := c.Run("capture-pane", "-p", "-t", "%missing"); err == nil || !strings.Contains(err.Error(), "can't find pane") {
+		t.Errorf("expected the %%error reply as an error, got %v", err)
+	}
+
+	// Commands without an explicit target, or that act on the current
+	// client, are run directly.
+	for _, args := range [][]string{{"display-message", "-p", "#{pane_id}"}, {"display-popup", "-E", "top"}} {
+		if out, _ := c.Run(args...); !strings.HasPrefix(string(out), "exec ") {
+			t.Errorf("%v: expected exec fallback, got %q", args, out)
+		}
+	}
+}
+
+func TestControl_FallsBackWhenAttachFails(t *testing.T) {
+	fakeControlTmux(t, "echo 'no sessions' >&2; exit 1")
+	c := NewControl("")
+	defer c.Close()
+
+	for range 2 {
+		out, err := c.Run("list-panes", "-a")
+		if err != nil || string(out) != "exec list-panes -a\n" {
+			t.Errorf("Run = %q, %v; want exec fallback", out, err)
+		}
+	}
+}
+
+func TestControlQuote(t *testing.T) {
+	tests := map[string]string{
+		"%1":              "%1",
+		"-t":              "-t",
+		"":                `""`,
+		"#{pane_id}":      `"#{pane_id}"`,
+		"a b;c":           `"a b;c"`,
+		"tab\there\x1b[A": `"tab\there\033[A"`,
+		`back\slash`:      `"back\\slash"`,
+		"~/src":           `"~/src"`,
+	}
+	for in, want := range tests {
+		if got := controlQuote(in); got != want {
+			t.Errorf("controlQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakePane is a pane in a Fake tmux server.
+type FakePane struct {
+	ID      string
+	Session string
+	Window  int
+	Index   int
+	Command string
+	PID     int
+	Dir     string
+	Title   string
+	Width   int
+	Height  int
+	// Output is the pane's screen and scrollback, returned by capture-pane.
+	Output string
+	// Activity is when the pane last produced output, reported as
+	// #{pane_activity} and #{window_activity}. Defaults to when it was added.
+	Activity time.Time
+	// Input collects text sent with send-keys or paste-buffer, one entry
+	// per call.
+	Input []string
+	// Respawns counts respawn-pane calls.
+	Respawns int
+}
+
+// Fake is an in-memory tmux server. It understands the subset of tmux
+// commands tmux-agent uses and records every call.
+type Fake struct {
+	mu     sync.Mutex
+	Panes  []*FakePane
+	Calls  [][]string
+	nextID int
+	// ServerPID is reported as #{pid}.
+	ServerPID int
+	// SocketPath is reported as #{socket_path}.
+	SocketPath string
+	// Buffers holds paste buffers by name.
+	Buffers map[string]string
+}
+
+// NewFake returns a fake server with the given panes.
+func NewFake(panes ...*FakePane) *Fake {
+	f := &Fake{ServerPID: 1}
+	for _, p := range panes {
+		f.AddPane(p)
+	}
+	return f
+}
+
+// AddPane adds a pane, filling in defaults for unset fields.
+func (f *Fake) AddPane(p *FakePane) *FakePane {
+	if p.ID == "" {
+		p.ID = fmt.Sprintf("%%%d", f.nextID)
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(p.ID, "%")); err == nil && n >= f.nextID {
+		f.nextID = n + 1
+	}
+	if p.Session == "" {
+		p.Session = "main"
+	}
+	if p.Activity.IsZero() {
+		p.Activity = time.Now()
+	}
+	if p.PID == 0 {
+		p.PID = 1000 + f.nextID
+	}
+	if p.Width == 0 {
+		p.Width, p.Height = 80, 24
+	}
+	f.Panes = append(f.Panes, p)
+	return p
+}
+
+// Pane returns the pane with the given ID, or nil.
+func (f *Fake) Pane(id string) *FakePane {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pane(id)
+}
+
+// Print appends text to a pane's output, as if its program wrote it. Unlike
+// setting Output directly, it is safe while commands are running.
+func (f *Fake) Print(id, text string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p := f.pane(id); p != nil {
+		p.Output += text
+	}
+}
+
+func (f *Fake) pane(id string) *FakePane {
+	for _, p := range f.Panes {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// Run interprets a tmux command against the in-memory panes.
+func (f *Fake) Run(args ...string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Calls = append(f.Calls, append([]string(nil), args...))
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no command")
+	}
+
+	flags, rest := parseFlags(args[1:])
+	target := flags["t"]
+	switch args[0] {
+	case "list-panes":
+		var lines []string
+		for _, p := range f.Panes {
+			if _, ok := flags["s"]; ok && p.Session != target {
+				continue
+			}
+			lines = append(lines, f.expand(flags["F"], p))
+		}
+		return output(lines), nil
+
+	case "capture-pane":
+		p, err := f.target(target)
+		if err != nil {
+			return nil, err
+		}
+		lines := strings.Split(strings.TrimRight(p.Output, "\n"), "\n")
+		if start := flags["S"]; start != "" && start != "-" {
+			if n, err := strconv.Atoi(strings.TrimPrefix(start, "-")); err == nil && n < len(lines) {
+				lines = lines[len(lines)-n:]
+			}
+		}
+		return output(lines), nil
+
+	case "send-keys":
+		p, err := f.target(target)
+		if err != nil {
+			return nil, err
+		}
+		p.Input = append(p.Input, strings.Join(rest, " "))
+		return nil, nil
+
+	case "split-window", "new-window":
+		p := f.AddPane(&FakePane{Dir: flags["c"], Command: "zsh"})
+		if len(rest) > 0 {
+			p.Command = strings.Fields(rest[0])[0]
+		}
+		if parent := f.pane(target); parent != nil {
+			p.Session = parent.Session
+		} else if target != "" {
+			p.Session = strings.TrimSuffix(target, ":")
+		}
+		if _, ok := flags["P"]; ok {
+			return output([]string{f.expand(flags["F"], p)}), nil
+		}
+		return nil, nil
+
+	case "set-buffer":
+		if f.Buffers == nil {
+			f.Buffers = make(map[string]string)
+		}
+		f.Buffers[flags["b"]] = strings.Join(rest, " ")
+		return nil, nil
+
+	case "show-buffer":
+		data, ok := f.Buffers[flags["b"]]
+		if !ok {
+			return nil, fmt.Errorf("no buffer %s", flags["b"])
+		}
+		return []byte(data), nil
+
+	case "paste-buffer":
+		p, err := f.target(target)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := f.Buffers[flags["b"]]
+		if !ok {
+			return nil, fmt.Errorf("no buffer %s", flags["b"])
+		}
+		// Pasted text is recorded like send-keys input.
+		p.Input = append(p.Input, data)
+		if _, ok := flags["d"]; ok {
+			delete(f.Buffers, flags["b"])
+		}
+		return nil, nil
+
+	case "respawn-pane":
+		p, err := f.target(target)
+		if err != nil {
+			return nil, err
+		}
+		p.Command, p.Output = "zsh", ""
+		if len(rest) > 0 {
+			p.Command = strings.Fields(rest[0])[0]
+		}
+		if dir, ok := flags["c"]; ok {
+			p.Dir = dir
+		}
+		p.Respawns++
+		return nil, nil
+
+	case "kill-pane":
+		for i, p := range f.Panes {
+			if p.ID == target {
+				f.Panes = append(f.Panes[:i], f.Panes[i+1:]...)
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("can't find pane: %s", target)
+
+	case "select-pane":
+		p, err := f.target(target)
+		if err != nil {
+			return nil, err
+		}
+		if title, ok := flags["T"]; ok {
+			p.Title = title
+		}
+		return nil, nil
+
+	case "display-message":
+		if _, ok := flags["p"]; !ok {
+			return nil, nil
+		}
+		var p *FakePane
+		if target != "" {
+			var err error
+			if p, err = f.target(target); err != nil {
+				return nil, err
+			}
+		} else if len(f.Panes) > 0 {
+			p = f.Panes[0]
+		}
+		format := ""
+		if len(rest) > 0 {
+			format = rest[0]
+		}
+		return output([]string{f.expand(format, p)}), nil
+	}
+	return nil, nil
+}
+
+// target returns the pane a -t flag refers to.
+func (f *Fake) target(id string) (*FakePane, error) {
+	if p := f.pane(id); p != nil {
+		return p, nil
+	}
+	return nil, fmt.Errorf("can't find pane: %s", id)
+}
+
+// expand fills in the #{...} variables tmux-agent uses.
+func (f *Fake) expand(format string, p *FakePane) string {
+	vars := []string{
+		"#{pid}", strconv.Itoa(f.ServerPID),
+		"#{start_time}", "0",
+		"#{socket_path}", f.SocketPath,
+	}
+	if p != nil {
+		vars = append(vars,
+			"#{pane_id}", p.ID,
+			"#{pane_current_command}", p.Command,
+			"#{pane_pid}", strconv.Itoa(p.PID),
+			"#{pane_current_path}", p.Dir,
+			"#{pane_title}", p.Title,
+			"#{session_name}", p.Session,
+			"#{window_index}", strconv.Itoa(p.Window),
+			"#{window_id}", "@"+strconv.Itoa(p.Window),
+			"#{pane_index}", strconv.Itoa(p.Index),
+			"#{pane_width}", strconv.Itoa(p.Width),
+			"#{pane_height}", strconv.Itoa(p.Height),
+			"#{pane_activity}", strconv.FormatInt(p.Activity.Unix(), 10),
+			"#{window_activity}", strconv.FormatInt(p.Activity.Unix(), 10),
+		)
+	}
+	return strings.NewReplacer(vars...).Replace(format)
+}
+
+// flagsWithValue are the tmux flags that take an argument.
+const flagsWithValue = "tFScTb"
+
+// parseFlags splits tmux arguments into flags and positional arguments.
+// Boolean flags map to "". Everything after "--" is positional.
+func parseFlags(args []string) (map[string]string, []string) {
+	flags := make(map[string]string)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			rest = append(rest, args[i+1:]...)
+			break
+		}
+		if len(a) != 2 || a[0] != '-' {
+			rest = append(rest, a)
+			continue
+		}
+		name := a[1:]
+		if strings.Contains(flagsWithValue, name) && i+1 < len(args) {
+			i++
+			flags[name] = args[i]
+		} else {
+			flags[name] = ""
+		}
+	}
+	return flags, rest
+}
+
+// output joins lines the way tmux prints them.
+func output(lines []string) []byte {
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
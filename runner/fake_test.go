@@ -0,0 +1,60 @@
+package runner
+
+import "testing"
+
+func TestFake(t *testing.T) {
+	f := NewFake(
+		&FakePane{ID: "%3", Command: "claude", Dir: "/work/a", Output: "one\ntwo\nthree\n"},
+		&FakePane{ID: "%5", Session: "other", Command: "codex", Dir: "/work/b"},
+	)
+
+	out, err := f.Run("list-panes", "-a", "-F", "#{pane_id}\t#{pane_current_command}\t#{pane_current_path}")
+	if err != nil || string(out) != "%3\tclaude\t/work/a\n%5\tcodex\t/work/b\n" {
+		t.Errorf("list-panes -a = %q, %v", out, err)
+	}
+	out, _ = f.Run("list-panes", "-s", "-t", "other", "-F", "#{pane_id}")
+	if string(out) != "%5\n" {
+		t.Errorf("list-panes -s -t other = %q", out)
+	}
+
+	out, _ = f.Run("capture-pane", "-p", "-t", "%3", "-S", "-2")
+	if string(out) != "two\nthree\n" {
+		t.Errorf("capture-pane -S -2 = %q", out)
+	}
+	if _, err := f.Run("capture-pane", "-p", "-t", "%9"); err == nil {
+		t.Error("expected error for unknown pane")
+	}
+
+	f.Run("send-keys", "-t", "%3", "-l", "--", "fix -t the tests")
+	f.Run("send-keys", "-t", "%3", "C-m")
+	if p := f.Pane("%3"); len(p.Input) != 2 || p.Input[0] != "fix -t the tests" || p.Input[1] != "C-m" {
+		t.Errorf("unexpected input: %q", p.Input)
+	}
+
+	out, err = f.Run("split-window", "-h", "-t", "%5", "-P", "-F", "#{pane_id}", "-c", "/work/c", "claude --continue")
+	if err != nil || string(out) != "%6\n" {
+		t.Errorf("split-window = %q, %v", out, err)
+	}
+	if p := f.Pane("%6"); p == nil || p.Command != "claude" || p.Dir != "/work/c" || p.Session != "other" {
+		t.Errorf("unexpected new pane: %+v", p)
+	}
+
+	f.Run("select-pane", "-t", "%6", "-T", "auth")
+	out, _ = f.Run("display-message", "-p", "-t", "%6", "#{pane_title} #{pane_width}x#{pane_height}")
+	if string(out) != "auth 80x24\n" {
+		t.Errorf("display-message = %q", out)
+	}
+
+	f.Run("respawn-pane", "-k", "-t", "%6", "-c", "/work/d")
+	if p := f.Pane("%6"); p.Command != "zsh" || p.Dir != "/work/d" || p.Respawns != 1 {
+		t.Errorf("unexpected respawned pane: %+v", p)
+	}
+
+	f.Run("kill-pane", "-t", "%6")
+	if f.Pane("%6") != nil {
+		t.Error("expected pane to be killed")
+	}
+	if len(f.Calls) != 11 || f.Calls[0][0] != "list-panes" {
+		t.Errorf("unexpected calls: %v", f.Calls)
+	}
+}
@@ -0,0 +1,127 @@
+// Package runner abstracts the tmux commands tmux-agent runs, so they can
+// be replaced with an in-memory fake in tests or when embedding.
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// TmuxRunner runs one tmux command (list-panes, capture-pane, send-keys,
+// split-window, ...) and returns its standard output.
+type TmuxRunner interface {
+	Run(args ...string) ([]byte, error)
+}
+
+// Exec runs commands with the tmux binary on PATH.
+type Exec struct {
+	// Socket selects a tmux server by socket name (tmux -L). Empty means
+	// the default server.
+	Socket string
+}
+
+// Run executes tmux with args. On failure the error includes stderr.
+func (e Exec) Run(args ...string) ([]byte, error) {
+	return run("tmux", withSocket(e.Socket, args)...)
+}
+
+// Docker runs commands with the tmux server inside a container, via
+// docker exec, so sandboxed agents can be managed from the host.
+type Docker struct {
+	Container string
+	// Socket selects a tmux server inside the container (tmux -L).
+	Socket string
+}
+
+// Run executes tmux with args inside the container.
+func (d Docker) Run(args ...string) ([]byte, error) {
+	return run("docker", append([]string{"exec", d.Container, "tmux"}, withSocket(d.Socket, args)...)...)
+}
+
+// withSocket prefixes args with -L socket when a socket is set.
+func withSocket(socket string, args []string) []string {
+	if socket == "" {
+		return args
+	}
+	return append([]string{"-L", socket}, args...)
+}
+
+// run executes a command and returns its stdout. On failure the error
+// includes stderr.
+func run(name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("%w (output: %s)", err, msg)
+		}
+		return out, err
+	}
+	return out, nil
+}
+
+// ErrReadOnly is returned by ReadOnly for commands that would change panes.
+var ErrReadOnly = errors.New("disabled in read-only mode")
+
+// readOnlyBlocked lists the tmux commands ReadOnly refuses to run: those
+// that type into, create, kill or rearrange panes, and the paste buffers
+// text is typed through.
+var readOnlyBlocked = map[string]bool{
+	"send-keys":      true,
+	"paste-buffer":   true,
+	"set-buffer":     true,
+	"load-buffer":    true,
+	"kill-pane":      true,
+	"kill-window":    true,
+	"kill-session":   true,
+	"kill-server":    true,
+	"split-window":   true,
+	"new-window":     true,
+	"new-session":    true,
+	"respawn-pane":   true,
+	"respawn-window": true,
+	"select-pane":    true,
+	"select-layout":  true,
+	"resize-pane":    true,
+	"swap-pane":      true,
+	"join-pane":      true,
+	"break-pane":     true,
+	"move-pane":      true,
+	"pipe-pane":      true,
+}
+
+// ReadOnly wraps a runner and refuses commands that send input to, create,
+// kill or otherwise change panes, so observers cannot disturb agents.
+type ReadOnly struct {
+	Runner TmuxRunner
+}
+
+// Run runs args with the wrapped runner unless the command is blocked.
+func (r ReadOnly) Run(args ...string) ([]byte, error) {
+	if len(args) > 0 && readOnlyBlocked[args[0]] {
+		return nil, fmt.Errorf("tmux %s: %w", args[0], ErrReadOnly)
+	}
+	return r.Runner.Run(args...)
+}
+
+// Unwrap returns the wrapped runner.
+func (r ReadOnly) Unwrap() TmuxRunner {
+	return r.Runner
+}
+
+// Unwrap returns the runner r wraps, through any number of wrappers such as
+// ReadOnly, so callers can check which runner actually runs tmux.
+func Unwrap(r TmuxRunner) TmuxRunner {
+	for {
+		w, ok := r.(interface{ Unwrap() TmuxRunner })
+		if !ok {
+			return r
+		}
+		r = w.Unwrap()
+	}
+}
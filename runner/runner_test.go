@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExec(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "tmux"), []byte(`#!/bin/sh
+if [ "$3" = "fail" ]; then
+  echo "no server running" >&2
+  exit 1
+fi
+echo "$@"
+`), 0755)
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+
+	out, err := Exec{Socket: "test"}.Run("list-panes", "-a")
+	if err != nil || string(out) != "-L test list-panes -a\n" {
+		t.Errorf("Run = %q, %v", out, err)
+	}
+	_, err = Exec{Socket: "test"}.Run("fail")
+	if err == nil || !strings.Contains(err.Error(), "no server running") {
+		t.Errorf("expected stderr in error, got %v", err)
+	}
+}
+
+func TestDocker(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "docker"), []byte("#!/bin/sh\necho \"$@\"\n"), 0755)
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+
+	out, err := Docker{Container: "sandbox"}.Run("capture-pane", "-p", "-t", "%1")
+	if err != nil || string(out) != "exec sandbox tmux capture-pane -p -t %1\n" {
+		t.Errorf("Run = %q, %v", out, err)
+	}
+	out, _ = Docker{Container: "sandbox", Socket: "agents"}.Run("list-panes")
+	if string(out) != "exec sandbox tmux -L agents list-panes\n" {
+		t.Errorf("Run with socket = %q", out)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	fake := NewFake(&FakePane{ID: "%1", Command: "claude", Output: "done"})
+	r := ReadOnly{Runner: fake}
+
+	if out, err := r.Run("capture-pane", "-p", "-t", "%1"); err != nil || !strings.Contains(string(out), "done") {
+		t.Errorf("capture-pane = %q, %v", out, err)
+	}
+	for _, cmd := range []string{"send-keys", "paste-buffer", "set-buffer", "kill-pane", "split-window", "respawn-pane", "respawn-window", "select-layout"} {
+		if _, err := r.Run(cmd, "-t", "%1"); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: expected ErrReadOnly, got %v", cmd, err)
+		}
+	}
+	if len(fake.Pane("%1").Input) != 0 {
+		t.Errorf("keys reached the pane: %q", fake.Pane("%1").Input)
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	d := Docker{Container: "sandbox"}
+	if got, ok := Unwrap(ReadOnly{Runner: d}).(Docker); !ok || got != d {
+		t.Errorf("expected the docker runner under ReadOnly, got %#v", got)
+	}
+	if got, ok := Unwrap(d).(Docker); !ok || got != d {
+		t.Errorf("expected an unwrapped runner to be returned as is, got %#v", got)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// sentRecord is one line of the sent-prompt log.
+type sentRecord struct {
+	Time time.Time `json:"time"`
+	Pane string    `json:"pane"`
+	Repo string    `json:"repo,omitempty"`
+	Text string    `json:"text"`
+}
+
+// sentLogPath returns the path to the log of text sent to panes.
+func sentLogPath() string {
+	return filepath.Join(configDir(), "sent.jsonl")
+}
+
+// recordSent appends text sent to a pane to the sent-prompt log.
+func recordSent(paneID, text string) error {
+	f, err := openLog(sentLogPath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(sentRecord{
+		Time: time.Now(),
+		Pane: paneID,
+		Repo: shortDir(paneCurrentPath(paneID)),
+		Text: text,
+	})
+}
+
+// loadSent reads the sent-prompt log, oldest first. Malformed lines are
+// skipped.
+func loadSent() ([]sentRecord, error) {
+	var records []sentRecord
+	err := readLog(sentLogPath(), func(line []byte) {
+		var r sentRecord
+		if err := json.Unmarshal(line, &r); err == nil {
+			records = append(records, r)
+		}
+	})
+	return records, err
+}
+
+// runSentLog shows what was sent to panes, optionally only to one pane or
+// matching a regex.
+func runSentLog(args []string, w io.Writer) error {
+	var pane string
+	var re *regexp.Regexp
+	asJSON := jsonOutput
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--pane" && i+1 < len(args):
+			i++
+			pane = args[i]
+		case args[i] == "--grep" && i+1 < len(args):
+			i++
+			var err error
+			if re, err = regexp.Compile(args[i]); err != nil {
+				return fmt.Errorf("invalid --grep pattern: %w", err)
+			}
+		case args[i] == "--json":
+			asJSON = true
+		default:
+			return fmt.Errorf("usage: tmux-agent sent-log [--pane id] [--grep regex] [--json]")
+		}
+	}
+
+	records, err := loadSent()
+	if err != nil {
+		return err
+	}
+	var matched []sentRecord
+	for _, r := range records {
+		if pane != "" && r.Pane != pane || re != nil && !re.MatchString(r.Text) {
+			continue
+		}
+		matched = append(matched, r)
+	}
+
+	if asJSON {
+		return writeJSON(w, matched)
+	}
+	if len(matched) == 0 {
+		fmt.Fprintln(w, "Nothing sent yet")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tPANE\tREPO\tTEXT")
+	for _, r := range matched {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Time.Local().Format("2006-01-02 15:04:05"), r.Pane, r.Repo, r.Text)
+	}
+	tw.Flush()
+	return nil
+}
+
+// runAgain re-sends the last prompt sent to a pane, with any further
+// arguments appended for this send only. Without a pane, the last prompt sent to any pane is
+// sent to that pane again.
+func runAgain(args []string, w io.Writer) error {
+	var pane string
+	if len(args) > 0 && strings.HasPrefix(args[0], "%") {
+		pane, args = args[0], args[1:]
+	}
+	records, err := loadSent()
+	if err != nil {
+		return err
+	}
+	var last *sentRecord
+	for i := len(records) - 1; i >= 0 && last == nil; i-- {
+		if pane == "" || records[i].Pane == pane {
+			last = &records[i]
+		}
+	}
+	if last == nil {
+		if pane != "" {
+			return fmt.Errorf("nothing has been sent to pane %s", pane)
+		}
+		return fmt.Errorf("nothing has been sent yet")
+	}
+
+	text := last.Text
+	if len(args) > 0 {
+		text += " " + strings.Join(args, " ")
+	}
+	if _, err := typeTmuxKeys(last.Pane, text, enterDefault); err != nil {
+		return err
+	}
+	// The prompt is logged without the appended words, so the next again
+	// doesn't stack them.
+	recordSent(last.Pane, last.Text)
+	if jsonOutput {
+		return writeJSON(w, actionJSON{Pane: last.Pane, Text: text})
+	}
+	fmt.Fprintf(w, "Sent to pane %s: %s\n", last.Pane, text)
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRunSentLog(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude", Dir: "/work/api"},
+		&runner.FakePane{ID: "%5", Command: "codex", Dir: "/work/web"},
+	)
+	t.Setenv("HOME", t.TempDir())
+
+	var buf bytes.Buffer
+	if err := runSentLog(nil, &buf); err != nil || !strings.Contains(buf.String(), "Nothing sent yet") {
+		t.Errorf("expected empty log, got %q, %v", buf.String(), err)
+	}
+
+	sendTmuxKeys("%3", "write the migration")
+	sendTmuxKeys("%5", "fix the CSS")
+	if err := runBroadcast([]string{"commit", "your", "work"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	if err := runSentLog([]string{"--pane", "%3"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "%3    api   write the migration") || !strings.Contains(out, "commit your work") || strings.Contains(out, "fix the CSS") {
+		t.Errorf("unexpected log for %%3:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := runSentLog([]string{"--grep", "(?i)css|commit", "--json"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var records []sentRecord
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0].Text != "fix the CSS" || records[0].Repo != "web" {
+		t.Errorf("unexpected records: %+v", records)
+	}
+
+	if err := runSentLog([]string{"--grep", "("}, &buf); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+}
+
+func TestRunAgain(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude"},
+		&runner.FakePane{ID: "%5", Command: "codex"},
+	)
+	t.Setenv("HOME", t.TempDir())
+
+	if err := runAgain([]string{"%3"}, &bytes.Buffer{}); err == nil {
+		t.Error("expected error when nothing was sent")
+	}
+
+	sendTmuxKeys("%3", "write the migration")
+	sendTmuxKeys("%5", "fix the CSS")
+
+	var buf bytes.Buffer
+	if err := runAgain([]string{"%3", "continue", "where", "you", "left", "off"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	input := fake.Pane("%3").Input
+	if got := input[len(input)-3]; got != "write the migration continue where you left off" {
+		t.Errorf("unexpected resend %q", got)
+	}
+	// The appended words don't stack up on the next again.
+	if err := runAgain([]string{"%3", "and", "add", "tests"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	input = fake.Pane("%3").Input
+	if got := input[len(input)-3]; got != "write the migration and add tests" {
+		t.Errorf("unexpected resend %q", got)
+	}
+
+	// Without a pane, the last prompt goes back to where it was sent.
+	if err := runAgain(nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	input = fake.Pane("%5").Input
+	if got := input[len(input)-3]; got != "fix the CSS" {
+		t.Errorf("unexpected resend %q", got)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultServeAddr is where serve listens without --listen. Binding to
+// loopback keeps the API off the network unless asked for.
+const defaultServeAddr = "127.0.0.1:7070"
+
+// serveTokenEnv is the environment variable serve reads its token from.
+const serveTokenEnv = "TMUX_AGENT_TOKEN"
+
+// maxRequestBody limits the size of a request body.
+const maxRequestBody = 1 << 20
+
+// sendRequest is the body of POST /panes/{id}/send.
+type sendRequest struct {
+	Text string `json:"text"`
+}
+
+// createRequest is the body of POST /panes. Every field is optional.
+type createRequest struct {
+	Agent     string `json:"agent,omitempty"`
+	Model     string `json:"model,omitempty"`
+	Preset    string `json:"preset,omitempty"`
+	Prompt    string `json:"prompt,omitempty"`
+	Split     string `json:"split,omitempty"`
+	Session   string `json:"session,omitempty"`
+	NewWindow bool   `json:"new_window,omitempty"`
+}
+
+// args returns the create arguments for the request.
+func (r *createRequest) args() []string {
+	var args []string
+	for _, f := range []struct{ flag, value string }{
+		{"--preset", r.Preset}, {"--command", r.Agent}, {"--model", r.Model},
+		{"--keys", r.Prompt}, {"--split", r.Split}, {"--session", r.Session},
+	} {
+		if f.value != "" {
+			args = append(args, f.flag, f.value)
+		}
+	}
+	if r.NewWindow {
+		args = append(args, "--new-window")
+	}
+	return args
+}
+
+// validate returns an error if the request names an agent or preset that
+// config.json does not know. The agent becomes the pane's command line, so
+// an arbitrary one would let API clients run any shell command.
+func (r *createRequest) validate(cfg *agentConfig) error {
+	if r.Preset != "" {
+		if _, err := cfg.preset(r.Preset); err != nil {
+			return err
+		}
+	}
+	if r.Agent == "" || r.Agent == cfg.DefaultAgent {
+		return nil
+	}
+	if _, ok := defaultModelFlags[r.Agent]; ok {
+		return nil
+	}
+	if p, ok := cfg.Agents[r.Agent]; ok && p != nil {
+		return nil
+	}
+	return fmt.Errorf("unknown agent %q", r.Agent)
+}
+
+// errorJSON is the body of an error response.
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+// apiError writes an error response.
+func apiError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, errorJSON{Error: msg})
+}
+
+// apiRun runs a subcommand and writes its JSON output as the response.
+func apiRun(w http.ResponseWriter, command string, run func([]string, io.Writer) error, args []string) {
+	if readOnly && mutatingCommands[command] {
+		apiError(w, http.StatusForbidden, command+" is disabled in read-only mode")
+		return
+	}
+	var out bytes.Buffer
+	if err := run(args, &out); err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out.Bytes())
+}
+
+// decodeRequest reads a JSON request body into v, writing an error response
+// and returning false if it is malformed.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		apiError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// pathPane returns the pane ID in the request path, writing an error
+// response and returning false if it is not one. The leading "%" may be
+// left out, since it would otherwise have to be escaped as %25.
+func pathPane(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.PathValue("id")
+	if !strings.HasPrefix(id, "%") {
+		id = "%" + id
+	}
+	if !paneIDRe.MatchString(id) {
+		apiError(w, http.StatusBadRequest, "invalid pane ID: "+r.PathValue("id"))
+		return "", false
+	}
+	return id, true
+}
+
+// requireToken wraps h so that requests without "Authorization: Bearer
+// <token>" are rejected.
+func requireToken(token string, h http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			apiError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// newAPIHandler returns the HTTP API. Responses are the JSON output of the
+// matching subcommands.
+func newAPIHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /panes", func(w http.ResponseWriter, r *http.Request) {
+		apiRun(w, "panes", runPanes, nil)
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		apiRun(w, "status", runStatus, nil)
+	})
+	mux.HandleFunc("GET /panes/{id}/capture", func(w http.ResponseWriter, r *http.Request) {
+		paneID, ok := pathPane(w, r)
+		if !ok {
+			return
+		}
+		args := []string{paneID}
+		if lines := r.URL.Query().Get("lines"); lines != "" {
+			if n, err := strconv.Atoi(lines); err != nil || n <= 0 {
+				apiError(w, http.StatusBadRequest, "lines must be a positive integer")
+				return
+			}
+			args = append(args, "--lines", lines)
+		}
+		apiRun(w, "capture", runCapture, args)
+	})
+	mux.HandleFunc("POST /panes/{id}/send", func(w http.ResponseWriter, r *http.Request) {
+		paneID, ok := pathPane(w, r)
+		if !ok {
+			return
+		}
+		var req sendRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		if strings.TrimSpace(req.Text) == "" {
+			apiError(w, http.StatusBadRequest, "text is required")
+			return
+		}
+		// "--" keeps text such as "-" or "--file x" from being read as
+		// send's flags.
+		apiRun(w, "send", runSend, []string{paneID, "--", req.Text})
+	})
+	mux.HandleFunc("POST /panes", func(w http.ResponseWriter, r *http.Request) {
+		var req createRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		if err := req.validate(loadConfig()); err != nil {
+			apiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		apiRun(w, "create", runCreate, req.args())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		apiError(w, http.StatusNotFound, "not found")
+	})
+	return requireToken(token, mux)
+}
+
+// newServeToken returns a random API token.
+func newServeToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// runServe serves the HTTP API until it fails. The token comes from
+// --token, else $TMUX_AGENT_TOKEN, else is generated and printed.
+func runServe(args []string, w io.Writer) error {
+	addr, token := defaultServeAddr, os.Getenv(serveTokenEnv)
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--listen" && i+1 < len(args):
+			i++
+			addr = args[i]
+		case args[i] == "--token" && i+1 < len(args):
+			i++
+			token = args[i]
+		default:
+			return fmt.Errorf("usage: tmux-agent serve [--listen addr] [--token token]")
+		}
+	}
+	if token == "" {
+		var err error
+		if token, err = newServeToken(); err != nil {
+			return fmt.Errorf("generating token: %w", err)
+		}
+		fmt.Fprintf(w, "Token: %s\n", token)
+	}
+	jsonOutput = true
+	fmt.Fprintf(w, "Listening on http://%s\n", addr)
+	return http.ListenAndServe(addr, newAPIHandler(token))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+// apiRequest sends a request to the API handler with the test token.
+func apiRequest(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPI_RequiresToken(t *testing.T) {
+	useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+	h := newAPIHandler("secret")
+
+	for _, auth := range []string{"", "Bearer wrong", "secret"} {
+		req := httptest.NewRequest("GET", "/panes", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: expected 401, got %d", auth, rec.Code)
+		}
+	}
+}
+
+func TestAPI_PanesAndCapture(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Dir: "/work/api", Output: "line1\nAll tests pass"},
+		&runner.FakePane{ID: "%2", Command: "zsh"},
+	)
+	useJSONOutput(t)
+	h := newAPIHandler("secret")
+
+	rec := apiRequest(t, h, "GET", "/panes", "")
+	var panes []paneJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &panes); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body)
+	}
+	if len(panes) != 1 || panes[0].ID != "%1" || panes[0].Agent != "claude" {
+		t.Errorf("unexpected panes: %+v", panes)
+	}
+
+	rec = apiRequest(t, h, "GET", "/panes/1/capture?lines=5", "")
+	var capture actionJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &capture); err != nil || capture.Pane != "%1" || !strings.Contains(capture.Output, "All tests pass") {
+		t.Errorf("unexpected capture %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := apiRequest(t, h, "GET", "/panes/1/capture?lines=x", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for bad lines, got %d", rec.Code)
+	}
+	if rec := apiRequest(t, h, "GET", "/panes/9/capture", ""); rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"error"`) {
+		t.Errorf("expected an error for a missing pane, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := apiRequest(t, h, "DELETE", "/panes", ""); rec.Code == http.StatusOK {
+		t.Errorf("expected DELETE /panes to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestAPI_Send(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+	useJSONOutput(t)
+	h := newAPIHandler("secret")
+
+	rec := apiRequest(t, h, "POST", "/panes/%251/send", `{"text":"run the linter"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body)
+	}
+	if got := strings.Join(fake.Panes[0].Input, ""); !strings.Contains(got, "run the linter") {
+		t.Errorf("expected text to be sent, got %q", got)
+	}
+
+	// Text that looks like send's flags is typed as it is.
+	if rec := apiRequest(t, h, "POST", "/panes/1/send", `{"text":"--file /etc/hosts"}`); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body)
+	}
+	if got := strings.Join(fake.Panes[0].Input, "|"); !strings.Contains(got, "--file /etc/hosts") {
+		t.Errorf("expected the text to be typed, got %q", got)
+	}
+	if rec := apiRequest(t, h, "POST", "/panes/--edit/send", `{"text":"hi"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a pane that is no pane ID, got %d", rec.Code)
+	}
+
+	for _, body := range []string{`{"text":""}`, `{"txt":"hi"}`, `not json`} {
+		if rec := apiRequest(t, h, "POST", "/panes/1/send", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d", body, rec.Code)
+		}
+	}
+
+	readOnly = true
+	defer func() { readOnly = false }()
+	if rec := apiRequest(t, h, "POST", "/panes/1/send", `{"text":"hi"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 in read-only mode, got %d", rec.Code)
+	}
+}
+
+func TestCreateRequestArgs(t *testing.T) {
+	req := createRequest{Agent: "codex", Prompt: "fix #42", NewWindow: true}
+	want := []string{"--command", "codex", "--keys", "fix #42", "--new-window"}
+	if got := req.args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("args() = %q, want %q", got, want)
+	}
+}
+
+func TestCreateRequestValidate(t *testing.T) {
+	cfg := &agentConfig{
+		DefaultAgent: "claude",
+		Agents:       map[string]*agentProfile{"aider": {}},
+		Presets:      map[string]*createPreset{"impl": {}},
+	}
+	for _, req := range []createRequest{{}, {Agent: "codex"}, {Agent: "aider"}, {Preset: "impl"}} {
+		if err := req.validate(cfg); err != nil {
+			t.Errorf("%+v: unexpected error %v", req, err)
+		}
+	}
+	for _, req := range []createRequest{{Agent: "rm -rf ~"}, {Agent: "/tmp/x/claude"}, {Preset: "missing"}} {
+		if err := req.validate(cfg); err == nil {
+			t.Errorf("%+v: expected an error", req)
+		}
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	h := newAPIHandler("secret")
+	if rec := apiRequest(t, h, "POST", "/panes", `{"agent":"sh -c 'touch /tmp/x'"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown agent, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	systemdUnitName = "tmux-agent-watch.service"
+	launchdLabel    = "com.github.sat0b.tmux-agent.watch"
+)
+
+// watchLogPath is where `watch --daemon` writes its log.
+func watchLogPath() string {
+	return filepath.Join(configDir(), "watch.log")
+}
+
+// serviceSpec describes the watch service to install.
+type serviceSpec struct {
+	Exe  string   // absolute path to the tmux-agent binary
+	Args []string // arguments after the binary, e.g. "watch --daemon --idle 5m"
+	Path string   // PATH for the service, so tmux and git can be found
+}
+
+// systemdUnit renders a user-level systemd unit for the watch daemon.
+func systemdUnit(s serviceSpec) string {
+	args := []string{s.Exe}
+	for _, a := range s.Args {
+		args = append(args, shellQuote(a))
+	}
+	return fmt.Sprintf(`[Unit]
+Description=tmux-agent watch daemon
+
+[Service]
+ExecStart=%s
+Environment=PATH=%s
+Restart=on-failure
+RestartSec=10
+
+[Install]
+WantedBy=default.target
+`, strings.Join(args, " "), s.Path)
+}
+
+// launchdPlist renders a launchd agent plist for the watch daemon.
+func launchdPlist(s serviceSpec) string {
+	var args strings.Builder
+	for _, a := range append([]string{s.Exe}, s.Args...) {
+		fmt.Fprintf(&args, "\t\t<string>%s</string>\n", html.EscapeString(a))
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>PATH</key>
+		<string>%s</string>
+	</dict>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+</dict>
+</plist>
+`, launchdLabel, args.String(), html.EscapeString(s.Path))
+}
+
+// servicePath returns where the service file for goos is installed.
+func servicePath(goos string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch goos {
+	case "linux":
+		return filepath.Join(home, ".config", "systemd", "user", systemdUnitName), nil
+	case "darwin":
+		return filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist"), nil
+	default:
+		return "", fmt.Errorf("install-service is not supported on %s", goos)
+	}
+}
+
+// runWatchService handles `watch install-service` and `watch uninstall-service`.
+func runWatchService(action string, args []string, w io.Writer) error {
+	return runWatchServiceOS(runtime.GOOS, action, args, w)
+}
+
+// runWatchServiceOS is runWatchService for a given GOOS.
+func runWatchServiceOS(goos, action string, args []string, w io.Writer) error {
+	path, err := servicePath(goos)
+	if err != nil {
+		return err
+	}
+	if action == "uninstall-service" {
+		return uninstallService(goos, path, w)
+	}
+
+	printOnly := false
+	watchArgs := []string{"watch", "--daemon"}
+	for _, a := range args {
+		if a == "--print" {
+			printOnly = true
+			continue
+		}
+		watchArgs = append(watchArgs, a)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	spec := serviceSpec{Exe: exe, Args: watchArgs, Path: os.Getenv("PATH")}
+	content := systemdUnit(spec)
+	if goos == "darwin" {
+		content = launchdPlist(spec)
+	}
+	if printOnly {
+		fmt.Fprint(w, content)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Wrote %s\n", path)
+
+	var cmds [][]string
+	if goos == "darwin" {
+		cmds = [][]string{{"launchctl", "unload", path}, {"launchctl", "load", "-w", path}}
+	} else {
+		cmds = [][]string{{"systemctl", "--user", "daemon-reload"}, {"systemctl", "--user", "enable", "--now", systemdUnitName}}
+	}
+	for i, c := range cmds {
+		output, err := exec.Command(c[0], c[1:]...).CombinedOutput()
+		// Unloading a plist that was never loaded fails; that is fine.
+		if err != nil && !(goos == "darwin" && i == 0) {
+			return fmt.Errorf("%s: %w (output: %s)", strings.Join(c, " "), err, string(output))
+		}
+	}
+	fmt.Fprintf(w, "Watch service enabled; logs go to %s\n", watchLogPath())
+	return nil
+}
+
+// uninstallService stops the watch service and removes its file.
+func uninstallService(goos, path string, w io.Writer) error {
+	var cmd *exec.Cmd
+	if goos == "darwin" {
+		cmd = exec.Command("launchctl", "unload", "-w", path)
+	} else {
+		cmd = exec.Command("systemctl", "--user", "disable", "--now", systemdUnitName)
+	}
+	cmd.Run()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	fmt.Fprintf(w, "Removed %s\n", path)
+	return nil
+}
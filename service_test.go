@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServiceFiles(t *testing.T) {
+	spec := serviceSpec{
+		Exe:  "/usr/local/bin/tmux-agent",
+		Args: []string{"watch", "--daemon", "--log", "/tmp/my log"},
+		Path: "/usr/bin:/bin",
+	}
+
+	unit := systemdUnit(spec)
+	if !strings.Contains(unit, "ExecStart=/usr/local/bin/tmux-agent 'watch' '--daemon' '--log' '/tmp/my log'") {
+		t.Errorf("unexpected ExecStart:\n%s", unit)
+	}
+	if !strings.Contains(unit, "Environment=PATH=/usr/bin:/bin") {
+		t.Errorf("expected PATH in unit:\n%s", unit)
+	}
+
+	spec.Args = []string{"watch", "--log", "/tmp/a&b"}
+	plist := launchdPlist(spec)
+	if !strings.Contains(plist, "<string>/tmp/a&amp;b</string>") {
+		t.Errorf("expected escaped argument in plist:\n%s", plist)
+	}
+	if !strings.Contains(plist, "<string>"+launchdLabel+"</string>") {
+		t.Errorf("expected label in plist:\n%s", plist)
+	}
+}
+
+func TestRunWatchService_Systemd(t *testing.T) {
+	dir := t.TempDir()
+
+	argsFile := filepath.Join(dir, "systemctl-args.txt")
+	os.WriteFile(filepath.Join(dir, "systemctl"), []byte("#!/bin/sh\necho \"$@\" >> "+argsFile+"\n"), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	var buf bytes.Buffer
+	if err := runWatchServiceOS("linux", "install-service", []string{"--idle", "5m"}, &buf); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	unitPath := filepath.Join(dir, ".config", "systemd", "user", systemdUnitName)
+	data, err := os.ReadFile(unitPath)
+	if err != nil {
+		t.Fatalf("expected unit file: %v", err)
+	}
+	if !strings.Contains(string(data), "'watch' '--daemon' '--idle' '5m'") {
+		t.Errorf("expected watch flags in unit:\n%s", string(data))
+	}
+	calls, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(calls), "--user enable --now "+systemdUnitName) {
+		t.Errorf("expected unit to be enabled, got: %s", string(calls))
+	}
+
+	buf.Reset()
+	if err := runWatchServiceOS("linux", "uninstall-service", nil, &buf); err != nil {
+		t.Fatalf("uninstall: %v", err)
+	}
+	if _, err := os.Stat(unitPath); !os.IsNotExist(err) {
+		t.Error("expected unit file to be removed")
+	}
+
+	if err := runWatchServiceOS("plan9", "install-service", nil, &buf); err == nil {
+		t.Error("expected error for unsupported OS")
+	}
+}
+
+func TestRunWatchService_Print(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	var buf bytes.Buffer
+	if err := runWatchServiceOS("darwin", "install-service", []string{"--print"}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<string>--daemon</string>") {
+		t.Errorf("expected plist on stdout, got: %s", buf.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Library")); !os.IsNotExist(err) {
+		t.Error("expected --print not to write files")
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultResumeSessionCommands resume one conversation by its session ID,
+// substituted for {session}. Override per agent with
+// "resume_session_command" in config.json.
+var defaultResumeSessionCommands = map[string]string{
+	"claude": "claude --resume {session}",
+	"codex":  "codex resume {session}",
+}
+
+// sessionFinders return the ID of an agent's most recent session in a
+// directory, from the agent's local state files, or "" when none is found.
+var sessionFinders = map[string]func(dir string) string{
+	"claude": claudeSession,
+	"codex":  codexSession,
+}
+
+// maxCodexSessionFiles bounds how many codex session files are read
+// looking for one started in a directory, newest first.
+const maxCodexSessionFiles = 200
+
+// claudeProjectRe matches the characters claude replaces with "-" when it
+// names a project directory after its working directory.
+var claudeProjectRe = regexp.MustCompile(`[^a-zA-Z0-9]`)
+
+// sessionIDRe matches a session ID that is safe to put on a command line;
+// claude and codex use UUIDs.
+var sessionIDRe = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// claudeConfigDir returns claude's state directory, $CLAUDE_CONFIG_DIR or
+// ~/.claude.
+func claudeConfigDir() string {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".claude")
+}
+
+// codexHome returns codex's state directory, $CODEX_HOME or ~/.codex.
+func codexHome() string {
+	if dir := os.Getenv("CODEX_HOME"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".codex")
+}
+
+// sessionFile is a session transcript and when it was last written.
+type sessionFile struct {
+	path    string
+	modTime int64
+}
+
+// newestFirst sorts session files by modification time, newest first.
+func newestFirst(files []sessionFile) {
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime > files[j].modTime })
+}
+
+// claudeSession returns the most recently written claude session in dir.
+// claude keeps each conversation as projects/<dir>/<session ID>.jsonl,
+// with the directory's non-alphanumeric characters replaced by "-".
+func claudeSession(dir string) string {
+	project := filepath.Join(claudeConfigDir(), "projects", claudeProjectRe.ReplaceAllString(dir, "-"))
+	entries, err := os.ReadDir(project)
+	if err != nil {
+		return ""
+	}
+	var files []sessionFile
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".jsonl" {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			files = append(files, sessionFile{path: e.Name(), modTime: info.ModTime().UnixNano()})
+		}
+	}
+	if len(files) == 0 {
+		return ""
+	}
+	newestFirst(files)
+	return strings.TrimSuffix(files[0].path, ".jsonl")
+}
+
+// codexSessionMeta is the first line of a codex session file.
+type codexSessionMeta struct {
+	Type    string `json:"type"`
+	Payload struct {
+		ID  string `json:"id"`
+		Cwd string `json:"cwd"`
+	} `json:"payload"`
+}
+
+// codexSession returns the most recently written codex session started in
+// dir. codex keeps each conversation as sessions/YYYY/MM/DD/rollout-*.jsonl,
+// whose first line holds the session ID and working directory.
+func codexSession(dir string) string {
+	var files []sessionFile
+	filepath.WalkDir(filepath.Join(codexHome(), "sessions"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasPrefix(d.Name(), "rollout-") || filepath.Ext(path) != ".jsonl" {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files = append(files, sessionFile{path: path, modTime: info.ModTime().UnixNano()})
+		}
+		return nil
+	})
+	newestFirst(files)
+	if len(files) > maxCodexSessionFiles {
+		files = files[:maxCodexSessionFiles]
+	}
+	for _, f := range files {
+		if meta, ok := readCodexSessionMeta(f.path); ok && meta.Payload.Cwd == dir && meta.Payload.ID != "" {
+			return meta.Payload.ID
+		}
+	}
+	return ""
+}
+
+// readCodexSessionMeta reads the session_meta line of a codex session file.
+func readCodexSessionMeta(path string) (codexSessionMeta, bool) {
+	var meta codexSessionMeta
+	f, err := os.Open(path)
+	if err != nil {
+		return meta, false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	if !sc.Scan() {
+		return meta, false
+	}
+	if err := json.Unmarshal(sc.Bytes(), &meta); err != nil || meta.Type != "session_meta" {
+		return meta, false
+	}
+	return meta, true
+}
+
+// resumeSessionCommand returns the command that resumes agent's latest
+// session in dir: its resume_session_command with the session ID found in
+// the agent's state files, or, when none is found, its resume_command,
+// which continues the most recent conversation. It also returns the
+// session ID, if found.
+func (c *agentConfig) resumeSessionCommand(agent, dir string) (string, string) {
+	template := c.agent(agent).ResumeSessionCommand
+	if template == "" {
+		template = defaultResumeSessionCommands[agent]
+	}
+	find := sessionFinders[agent]
+	if template == "" || find == nil || dir == "" {
+		return c.resumeCommand(agent), ""
+	}
+	session := find(dir)
+	if !sessionIDRe.MatchString(session) {
+		return c.resumeCommand(agent), ""
+	}
+	return strings.ReplaceAll(template, "{session}", session), session
+}
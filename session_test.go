@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+// writeSessionFile writes a session file with the given modification time.
+func writeSessionFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, modTime, modTime)
+}
+
+func TestClaudeSession(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", home)
+	project := filepath.Join(home, "projects", "-work-github-com-owner-repo")
+	now := time.Now()
+	writeSessionFile(t, filepath.Join(project, "older-session.jsonl"), "{}\n", now.Add(-time.Hour))
+	writeSessionFile(t, filepath.Join(project, "newer-session.jsonl"), "{}\n", now)
+
+	if got := claudeSession("/work/github.com/owner/repo"); got != "newer-session" {
+		t.Errorf("claudeSession = %q, want newer-session", got)
+	}
+	if got := claudeSession("/work/other"); got != "" {
+		t.Errorf("expected no session for another directory, got %q", got)
+	}
+}
+
+func TestCodexSession(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("CODEX_HOME", home)
+	day := filepath.Join(home, "sessions", "2026", "10", "16")
+	now := time.Now()
+	meta := func(id, cwd string) string {
+		return `{"type":"session_meta","payload":{"id":"` + id + `","cwd":"` + cwd + `"}}` + "\n{}\n"
+	}
+	writeSessionFile(t, filepath.Join(day, "rollout-1.jsonl"), meta("aaa-111", "/work/a"), now.Add(-2*time.Hour))
+	writeSessionFile(t, filepath.Join(day, "rollout-2.jsonl"), meta("bbb-222", "/work/a"), now.Add(-time.Hour))
+	writeSessionFile(t, filepath.Join(day, "rollout-3.jsonl"), meta("ccc-333", "/work/b"), now)
+
+	if got := codexSession("/work/a"); got != "bbb-222" {
+		t.Errorf("codexSession = %q, want bbb-222", got)
+	}
+	if got := codexSession("/work/c"); got != "" {
+		t.Errorf("expected no session for another directory, got %q", got)
+	}
+}
+
+func TestResumeSessionCommand(t *testing.T) {
+	t.Setenv("CLAUDE_CONFIG_DIR", t.TempDir())
+	cfg := &agentConfig{}
+	if cmd, session := cfg.resumeSessionCommand("claude", "/work/a"); cmd != "claude --continue" || session != "" {
+		t.Errorf("expected resume_command fallback, got %q %q", cmd, session)
+	}
+
+	project := filepath.Join(os.Getenv("CLAUDE_CONFIG_DIR"), "projects", "-work-a")
+	writeSessionFile(t, filepath.Join(project, "0b1c-22.jsonl"), "{}\n", time.Now())
+	if cmd, session := cfg.resumeSessionCommand("claude", "/work/a"); cmd != "claude --resume 0b1c-22" || session != "0b1c-22" {
+		t.Errorf("unexpected resume command %q %q", cmd, session)
+	}
+
+	cfg.Agents = map[string]*agentProfile{"claude": {ResumeSessionCommand: "claude --model opus -r {session}"}}
+	if cmd, _ := cfg.resumeSessionCommand("claude", "/work/a"); cmd != "claude --model opus -r 0b1c-22" {
+		t.Errorf("expected the configured command, got %q", cmd)
+	}
+	if cmd, _ := cfg.resumeSessionCommand("aider", "/work/a"); cmd != "aider" {
+		t.Errorf("expected agents without sessions to be started plainly, got %q", cmd)
+	}
+}
+
+func TestRunRestart_Resume(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "codex", Dir: "/work/a"})
+	t.Setenv("HOME", t.TempDir())
+	codexDir := t.TempDir()
+	t.Setenv("CODEX_HOME", codexDir)
+	writeSessionFile(t, filepath.Join(codexDir, "sessions", "2026", "10", "16", "rollout-1.jsonl"),
+		`{"type":"session_meta","payload":{"id":"abc-123","cwd":"/work/a"}}`+"\n", time.Now())
+	origDelay, origLookup, origReady := restartDelay, agentCommandFn, readyTimeout
+	restartDelay, readyTimeout = 0, 0
+	agentCommandFn = func(paneID string) string { return "codex -m o3" }
+	defer func() { restartDelay, agentCommandFn, readyTimeout = origDelay, origLookup, origReady }()
+
+	var buf strings.Builder
+	if err := runRestart([]string{"%5", "--resume"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	input := fake.Panes[0].Input
+	if len(input) == 0 || input[len(input)-1] != "codex resume abc-123 Enter" {
+		t.Errorf("expected codex to resume its session, got %q", input)
+	}
+	if !strings.Contains(buf.String(), "codex resume abc-123") {
+		t.Errorf("expected the resume command in the output, got %q", buf.String())
+	}
+	if err := runRestart([]string{"%5", "--bogus"}, io.Discard); err == nil {
+		t.Error("expected usage error for unknown flag")
+	}
+}
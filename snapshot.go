@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// snapshotDir returns the directory where scrollback snapshots are stored.
+func snapshotDir() string {
+	return filepath.Join(configDir(), "snapshots")
+}
+
+// snapshotPager returns the pager command used to view restored snapshots.
+func snapshotPager() string {
+	if p := os.Getenv("PAGER"); p != "" {
+		return p
+	}
+	return "less -R"
+}
+
+// resolveSnapshot maps a snapshot name to a file path. Names without a
+// directory component are looked up in the snapshot directory.
+func resolveSnapshot(name string) string {
+	if strings.ContainsRune(name, filepath.Separator) {
+		return name
+	}
+	path := filepath.Join(snapshotDir(), name)
+	if !strings.HasSuffix(path, ".txt") {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			path += ".txt"
+		}
+	}
+	return path
+}
+
+// runSnapshot dispatches snapshot subcommands.
+func runSnapshot(args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: tmux-agent snapshot <save-scrollback|list|restore> ...")
+	}
+	switch args[0] {
+	case "save-scrollback", "save":
+		return runSnapshotSave(args[1:], w)
+	case "list":
+		return runSnapshotList(w)
+	case "restore":
+		return runSnapshotRestore(args[1:], w)
+	default:
+		return fmt.Errorf("unknown snapshot command: %s", args[0])
+	}
+}
+
+// runSnapshotSave writes the full scrollback of a pane to a snapshot file.
+func runSnapshotSave(args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: tmux-agent snapshot save-scrollback <pane_id> [--file path]")
+	}
+	paneID := args[0]
+	file := ""
+	for i := 1; i < len(args); i++ {
+		if args[i] == "--file" && i+1 < len(args) {
+			i++
+			file = args[i]
+		}
+	}
+
+	output, err := captureScrollback(paneID)
+	if err != nil {
+		return err
+	}
+
+	if file == "" {
+		dir := snapshotDir()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		// Two snapshots in the same second get distinct names.
+		f, err := createNewFile(filepath.Join(dir, fmt.Sprintf("%s-%s.txt",
+			strings.TrimPrefix(paneID, "%"),
+			time.Now().Format("20060102-150405"))))
+		if err != nil {
+			return fmt.Errorf("writing snapshot: %w", err)
+		}
+		file = f.Name()
+		_, err = f.WriteString(output + "\n")
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("writing snapshot: %w", err)
+		}
+	} else if err := os.WriteFile(file, []byte(output+"\n"), 0644); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	lines := strings.Count(output, "\n") + 1
+	fmt.Fprintf(w, "Saved pane %s scrollback (%d lines) to %s\n", paneID, lines, file)
+	return nil
+}
+
+// runSnapshotList lists saved snapshots, newest first.
+func runSnapshotList(w io.Writer) error {
+	entries, err := os.ReadDir(snapshotDir())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	type snapshot struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	var snaps []snapshot
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		snaps = append(snaps, snapshot{e.Name(), info.Size(), info.ModTime()})
+	}
+	if len(snaps) == 0 {
+		fmt.Fprintln(w, "No snapshots found")
+		return nil
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].modTime.After(snaps[j].modTime) })
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSIZE\tSAVED")
+	for _, s := range snaps {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", s.name, s.size, s.modTime.Format("2006-01-02 15:04:05"))
+	}
+	tw.Flush()
+	return nil
+}
+
+// runSnapshotRestore opens a saved snapshot in a new pane using a pager.
+func runSnapshotRestore(args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: tmux-agent snapshot restore <name|path> [--session name] [--split h|v] [--new-window]")
+	}
+	path := resolveSnapshot(args[0])
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("snapshot not found: %s", path)
+	}
+
+	opts := createPaneOpts{}
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--session":
+			if i+1 < len(args) {
+				i++
+				opts.Session = args[i]
+			}
+		case "--split":
+			if i+1 < len(args) {
+				i++
+				opts.Split = args[i]
+			}
+		case "--new-window":
+			opts.NewWindow = true
+		}
+	}
+	opts.Command = snapshotPager() + " " + shellQuote(path)
+
+	paneID, err := createTmuxPaneWithOpts(opts)
+	if err != nil {
+		return err
+	}
+	renameTmuxPane(paneID, "snapshot:"+filepath.Base(path))
+	fmt.Fprintf(w, "Restored snapshot %s in pane %s\n", filepath.Base(path), paneID)
+	return nil
+}
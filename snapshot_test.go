@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSnapshotSave(t *testing.T) {
+	dir := t.TempDir()
+
+	argsFile := filepath.Join(dir, "tmux-args.txt")
+	tmuxScript := filepath.Join(dir, "tmux")
+	os.WriteFile(tmuxScript, []byte(`#!/bin/sh
+echo "$@" >> `+argsFile+`
+echo "scrollback line 1"
+echo "scrollback line 2"
+`), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	var buf bytes.Buffer
+	err := runSnapshot([]string{"save-scrollback", "%5"}, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Saved pane %5 scrollback (2 lines)") {
+		t.Errorf("expected saved message, got: %s", buf.String())
+	}
+
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("tmux was not called: %v", err)
+	}
+	if !strings.Contains(string(data), "-S -") {
+		t.Errorf("expected full scrollback capture, got: %s", string(data))
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, ".config", "tmux-agent", "snapshots"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one snapshot file, got %v (err %v)", entries, err)
+	}
+	if !strings.HasPrefix(entries[0].Name(), "5-") {
+		t.Errorf("expected snapshot named after pane, got %s", entries[0].Name())
+	}
+
+	buf.Reset()
+	if err := runSnapshot([]string{"list"}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), entries[0].Name()) {
+		t.Errorf("expected snapshot in list, got: %s", buf.String())
+	}
+
+	// Saves within the same second keep the earlier files.
+	runSnapshot([]string{"save-scrollback", "%5"}, &buf)
+	runSnapshot([]string{"save-scrollback", "%5"}, &buf)
+	entries, _ = os.ReadDir(filepath.Join(dir, ".config", "tmux-agent", "snapshots"))
+	if len(entries) != 3 {
+		t.Errorf("expected three snapshot files, got %v", entries)
+	}
+}
+
+func TestRunSnapshotRestore(t *testing.T) {
+	dir := t.TempDir()
+
+	argsFile := filepath.Join(dir, "tmux-args.txt")
+	tmuxScript := filepath.Join(dir, "tmux")
+	os.WriteFile(tmuxScript, []byte(`#!/bin/sh
+echo "$@" >> `+argsFile+`
+case "$1" in
+  split-window)
+    echo "%42"
+    ;;
+esac
+`), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	origPager := os.Getenv("PAGER")
+	os.Setenv("PAGER", "less")
+	defer os.Setenv("PAGER", origPager)
+
+	snapDir := filepath.Join(dir, ".config", "tmux-agent", "snapshots")
+	os.MkdirAll(snapDir, 0755)
+	os.WriteFile(filepath.Join(snapDir, "5-20250101-120000.txt"), []byte("saved\n"), 0644)
+
+	var buf bytes.Buffer
+	err := runSnapshot([]string{"restore", "5-20250101-120000"}, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "in pane %42") {
+		t.Errorf("expected restored pane in output, got: %s", buf.String())
+	}
+
+	data, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(data), "less '"+filepath.Join(snapDir, "5-20250101-120000.txt")+"'") {
+		t.Errorf("expected pager command in tmux args, got: %s", string(data))
+	}
+}
+
+func TestRunSnapshotRestore_NotFound(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	var buf bytes.Buffer
+	if err := runSnapshot([]string{"restore", "missing"}, &buf); err == nil {
+		t.Fatal("expected error for missing snapshot")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "''"},
+		{"plain", "'plain'"},
+		{"it's", `'it'\''s'`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
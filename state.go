@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// staleLockAge is how old a state lock may get before it is assumed to be
+// left over from a process that died holding it.
+const staleLockAge = 30 * time.Second
+
+// lockWait is how long withStateLock waits for another process to release
+// a state lock.
+var lockWait = 5 * time.Second
+
+// statePath returns the path of a named state file in the config directory.
+func statePath(name string) string {
+	return filepath.Join(configDir(), name)
+}
+
+// loadState reads a JSON state file into v. A missing file leaves v unchanged.
+func loadState(name string, v any) error {
+	data, err := os.ReadFile(statePath(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// saveState writes v to a JSON state file. The file is replaced atomically
+// so concurrent readers never see a partial write.
+func saveState(name string, v any) error {
+	dir := configDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), statePath(name))
+}
+
+// withStateLock runs fn while holding an exclusive lock on a state file, so
+// that read-modify-write cycles from different processes (say, `queue add`
+// and watch) don't lose each other's updates. The lock is a separate
+// "<name>.lock" file created exclusively.
+func withStateLock(name string, fn func() error) error {
+	dir := configDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	lock := statePath(name + ".lock")
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s is locked by another process (remove %s if it is stale)", name, lock)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	defer os.Remove(lock)
+	return fn()
+}
+
+// maxLogSize is the size at which an append-only log such as activity.jsonl
+// is rotated: renamed to "<name>.1", replacing the previous one. Each log
+// thus keeps between one and two times this much history.
+var maxLogSize int64 = 8 << 20
+
+// openLog opens an append-only log for appending, rotating it first once
+// it has grown past maxLogSize.
+func openLog(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() >= maxLogSize {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// readLog calls fn with each line of a log written with openLog, oldest
+// first: the rotated part, then the current one. Missing files are skipped.
+func readLog(path string, fn func(line []byte)) error {
+	for _, name := range []string{path + ".1", path} {
+		f, err := os.Open(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		sc := bufio.NewScanner(f)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			fn(sc.Bytes())
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
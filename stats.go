@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// paneStats is one pane's row of `stats`.
+type paneStats struct {
+	utilization
+	Agent   string `json:"agent,omitempty"`
+	Repo    string `json:"repo,omitempty"`
+	Prompts int    `json:"prompts"`
+}
+
+// statsReport is the JSON shape of the stats subcommand.
+type statsReport struct {
+	Since time.Time   `json:"since"`
+	Panes []paneStats `json:"panes"`
+}
+
+// aggregatePaneStats adds up the activity records and sent prompts since
+// the window start per pane: working, idle and waiting time and restarts
+// as in report, with the agent and repo the pane was last seen with.
+func aggregatePaneStats(records []activityRecord, sent []sentRecord, since time.Time) []paneStats {
+	byPane := make(map[string]*paneStats)
+	var order []string
+	for _, u := range aggregateUtilization(records, func(r activityRecord) string { return r.Pane }) {
+		byPane[u.Name] = &paneStats{utilization: u}
+		order = append(order, u.Name)
+	}
+	for _, r := range records {
+		if s := byPane[r.Pane]; s != nil {
+			if r.Agent != "" {
+				s.Agent = r.Agent
+			}
+			if r.Repo != "" {
+				s.Repo = r.Repo
+			}
+		}
+	}
+	for _, r := range sent {
+		if r.Time.Before(since) || r.Pane == "" {
+			continue
+		}
+		s := byPane[r.Pane]
+		if s == nil {
+			s = &paneStats{utilization: utilization{Name: r.Pane}, Repo: r.Repo}
+			byPane[r.Pane] = s
+			order = append(order, r.Pane)
+		}
+		s.Prompts++
+	}
+	out := make([]paneStats, len(order))
+	for i, name := range order {
+		out[i] = *byPane[name]
+	}
+	return out
+}
+
+// runStats prints, per pane, how long it was working, idle and waiting,
+// how many prompts it was sent and how often it was restarted.
+func runStats(args []string, w io.Writer) error {
+	window := defaultReportWindow
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil {
+					return fmt.Errorf("invalid --since value: %s", args[i])
+				}
+				window = d
+			}
+		default:
+			return fmt.Errorf("usage: tmux-agent stats [--since 24h]")
+		}
+	}
+
+	since := time.Now().Add(-window)
+	records, err := loadActivity(since)
+	if err != nil {
+		return fmt.Errorf("reading activity log: %w", err)
+	}
+	sent, err := loadSent()
+	if err != nil {
+		return fmt.Errorf("reading sent log: %w", err)
+	}
+	report := statsReport{Since: since, Panes: aggregatePaneStats(records, sent, since)}
+	if jsonOutput {
+		if report.Panes == nil {
+			report.Panes = []paneStats{}
+		}
+		return writeJSON(w, report)
+	}
+	if len(report.Panes) == 0 {
+		fmt.Fprintln(w, "No activity recorded (run tmux-agent watch to collect data)")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PANE\tAGENT\tREPO\tWORKING\tIDLE\tWAITING\tUTIL\tPROMPTS\tRESTARTS")
+	for _, s := range report.Panes {
+		util := "-"
+		if total := s.Busy + s.Idle + s.Waiting; total > 0 {
+			util = fmt.Sprintf("%.0f%%", s.Busy/total*100)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\n", s.Name, orDash(s.Agent), orDash(s.Repo),
+			formatDuration(seconds(s.Busy)),
+			formatDuration(seconds(s.Idle)),
+			formatDuration(seconds(s.Waiting)),
+			util, s.Prompts, s.Restarts)
+	}
+	return tw.Flush()
+}
+
+// orDash returns s, or "-" when it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregatePaneStats(t *testing.T) {
+	now := time.Now()
+	since := now.Add(-time.Hour)
+	records := []activityRecord{
+		{Kind: activitySample, Pane: "%1", Agent: "claude", Repo: "owner/a", State: stateActive, Span: 60},
+		{Kind: activitySample, Pane: "%1", Agent: "claude", Repo: "owner/a", State: stateWaiting, Span: 20},
+		{Kind: activitySample, Pane: "%2", Agent: "codex", Repo: "owner/b", State: stateIdle, Span: 300},
+		{Kind: activityRestart, Pane: "%2", Agent: "codex"},
+	}
+	sent := []sentRecord{
+		{Time: now.Add(-2 * time.Hour), Pane: "%1", Text: "too old"},
+		{Time: now.Add(-time.Minute), Pane: "%1", Text: "fix the tests"},
+		{Time: now.Add(-time.Minute), Pane: "%1", Text: "commit"},
+		{Time: now.Add(-time.Minute), Pane: "%3", Repo: "owner/c", Text: "review"},
+	}
+
+	got := aggregatePaneStats(records, sent, since)
+	byPane := make(map[string]paneStats)
+	for _, s := range got {
+		byPane[s.Name] = s
+	}
+	if len(byPane) != 3 {
+		t.Fatalf("expected 3 panes, got %+v", got)
+	}
+	if s := byPane["%1"]; s.Agent != "claude" || s.Repo != "owner/a" || s.Busy != 60 || s.Waiting != 20 || s.Prompts != 2 {
+		t.Errorf("unexpected %%1 stats: %+v", s)
+	}
+	if s := byPane["%2"]; s.Idle != 300 || s.Restarts != 1 || s.Prompts != 0 {
+		t.Errorf("unexpected %%2 stats: %+v", s)
+	}
+	if s := byPane["%3"]; s.Repo != "owner/c" || s.Prompts != 1 || s.Busy != 0 {
+		t.Errorf("expected a prompts-only row for %%3, got %+v", s)
+	}
+}
+
+func TestRunStats(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	now := time.Now()
+	appendActivity(
+		activityRecord{Time: now.Add(-48 * time.Hour), Kind: activitySample, Pane: "%9", Agent: "old", State: stateActive, Span: 10},
+		activityRecord{Time: now.Add(-time.Hour), Kind: activitySample, Pane: "%1", Agent: "claude", Repo: "owner/repo", State: stateActive, Span: 600},
+		activityRecord{Time: now.Add(-time.Hour), Kind: activitySample, Pane: "%1", Agent: "claude", Repo: "owner/repo", State: stateIdle, Span: 600},
+	)
+
+	var buf bytes.Buffer
+	if err := runStats([]string{"--since", "24h"}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "%1") || !strings.Contains(output, "owner/repo") || !strings.Contains(output, "10m00s") {
+		t.Errorf("expected a row for %%1, got: %s", output)
+	}
+	if !strings.Contains(output, "50%") {
+		t.Errorf("expected 50%% utilization, got: %s", output)
+	}
+	if strings.Contains(output, "%9") {
+		t.Errorf("expected records outside window to be excluded, got: %s", output)
+	}
+
+	if err := runStats([]string{"--bogus"}, &buf); err == nil {
+		t.Error("expected usage error for unknown flag")
+	}
+
+	useJSONOutput(t)
+	buf.Reset()
+	if err := runStats(nil, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var report statsReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(report.Panes) != 1 || report.Panes[0].Name != "%1" || report.Panes[0].Busy != 600 {
+		t.Errorf("unexpected JSON report: %+v", report)
+	}
+}
+
+func TestAppendActivity_Rotates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer func(size int64) { maxLogSize = size }(maxLogSize)
+	maxLogSize = 200
+
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := appendActivity(activityRecord{Time: now, Kind: "state", Pane: "%1", State: "active"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(activityFilePath() + ".1"); err != nil {
+		t.Fatalf("expected the log to be rotated: %v", err)
+	}
+	records, err := loadActivity(now.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) < 2 || len(records) > 4 {
+		t.Errorf("expected records from both files, got %d", len(records))
+	}
+}
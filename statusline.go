@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statuslineFile caches the pane counts statusline prints, so the frequent
+// refreshes of the tmux status bar do not each list and capture panes.
+const statuslineFile = "statusline.json"
+
+// defaultStatuslineFormat and defaultStatuslineMaxAge apply unless the
+// statusline config or flags say otherwise.
+const (
+	defaultStatuslineFormat = "🤖 {active}▶ {idle}⏸"
+	defaultStatuslineMaxAge = 10 * time.Second
+)
+
+// statuslineConfig configures `statusline`.
+type statuslineConfig struct {
+	// Format is the text printed, with {total}, {active}, {idle}, {busy},
+	// {waiting}, {limited} and {compacting} replaced by pane counts.
+	Format string `json:"format,omitempty"`
+	// MaxAge is how long cached counts are reused, e.g. "10s".
+	MaxAge string `json:"max_age,omitempty"`
+}
+
+// statuslineCache is the content of statuslineFile.
+type statuslineCache struct {
+	Updated time.Time      `json:"updated"`
+	Counts  map[string]int `json:"counts"`
+}
+
+// formatStatusline fills in format from counts of panes by state.
+func formatStatusline(format string, counts map[string]int) string {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	n := func(state string) string { return strconv.Itoa(counts[state]) }
+	return strings.NewReplacer(
+		"{total}", strconv.Itoa(total),
+		"{active}", n(stateActive),
+		"{idle}", n(stateIdle),
+		"{busy}", n(stateBusyCPU),
+		"{waiting}", n(stateWaiting),
+		"{approval}", n(stateNeedsApproval),
+		"{limited}", n(stateRateLimited),
+		"{compacting}", n(stateCompacting),
+	).Replace(format)
+}
+
+// statuslineCounts counts the agent panes by state the way status does.
+func statuslineCounts(cfg *agentConfig) (map[string]int, error) {
+	threshold, err := cfg.idleThresholds(0)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := cfg.idleBackend("")
+	if err != nil {
+		return nil, err
+	}
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return nil, err
+	}
+	trackPanes(panes, true)
+	if backend == idleBackendTmux {
+		if err := applyPaneActivity(panes); err != nil {
+			return nil, err
+		}
+	}
+	busy := cfg.busyChecker()
+	notices := newNoticeDetector(cfg)
+	counts := make(map[string]int)
+	for i := range panes {
+		state := paneState(&panes[i], threshold(panes[i].Command), busy)
+		if notice, _, err := notices.detect(&panes[i], panes[i].LastOutput); err != nil {
+			return nil, err
+		} else if notice != "" {
+			state = notice
+		}
+		counts[state]++
+	}
+	return counts, nil
+}
+
+// runStatusline prints a one-line summary of the agent panes for the tmux
+// status bar, e.g. "🤖 3▶ 1⏸", or nothing when there are no agent panes.
+// Counts younger than --max-age are taken from a cache instead of tmux.
+func runStatusline(args []string, w io.Writer) error {
+	cfg := loadConfig()
+	format := cfg.Statusline.Format
+	if format == "" {
+		format = defaultStatuslineFormat
+	}
+	maxAge := defaultStatuslineMaxAge
+	if cfg.Statusline.MaxAge != "" {
+		d, err := time.ParseDuration(cfg.Statusline.MaxAge)
+		if err != nil {
+			return fmt.Errorf("invalid statusline.max_age: %s", cfg.Statusline.MaxAge)
+		}
+		maxAge = d
+	}
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--format" && i+1 < len(args):
+			i++
+			format = args[i]
+		case args[i] == "--max-age" && i+1 < len(args):
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d < 0 {
+				return fmt.Errorf("invalid --max-age value: %s", args[i])
+			}
+			maxAge = d
+		default:
+			return fmt.Errorf("usage: tmux-agent statusline [--format text] [--max-age duration]")
+		}
+	}
+
+	var cache statuslineCache
+	if err := loadState(statuslineFile, &cache); err != nil || time.Since(cache.Updated) >= maxAge || cache.Counts == nil {
+		counts, err := statuslineCounts(cfg)
+		if err != nil {
+			return err
+		}
+		cache = statuslineCache{Updated: time.Now(), Counts: counts}
+		// A failed save only costs the next refresh a recount.
+		saveState(statuslineFile, cache)
+	}
+	if jsonOutput {
+		return writeJSON(w, cache.Counts)
+	}
+	if len(cache.Counts) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, formatStatusline(format, cache.Counts))
+	return nil
+}
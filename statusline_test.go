@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestFormatStatusline(t *testing.T) {
+	counts := map[string]int{stateActive: 3, stateIdle: 1, stateRateLimited: 2}
+	if got := formatStatusline(defaultStatuslineFormat, counts); got != "🤖 3▶ 1⏸" {
+		t.Errorf("default format = %q", got)
+	}
+	if got := formatStatusline("{total} agents, {limited} limited, {busy} busy", counts); got != "6 agents, 2 limited, 0 busy" {
+		t.Errorf("custom format = %q", got)
+	}
+}
+
+func TestRunStatusline(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Output: "Done."},
+		&runner.FakePane{ID: "%2", Command: "codex", Output: "Working"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	saveState(paneStateFile, map[string]trackedOutput{
+		"%1": {Hash: outputHash("Done."), Changed: time.Now().Add(-time.Hour)},
+	})
+
+	var buf bytes.Buffer
+	if err := runStatusline(nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "🤖 1▶ 1⏸\n" {
+		t.Errorf("statusline = %q", got)
+	}
+
+	// Within max-age the cached counts are printed without asking tmux.
+	calls := len(fake.Calls)
+	buf.Reset()
+	if err := runStatusline([]string{"--format", "{total}"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "2\n" || len(fake.Calls) != calls {
+		t.Errorf("expected cached output, got %q after %d tmux calls", buf.String(), len(fake.Calls)-calls)
+	}
+
+	// No agent panes: nothing to show.
+	fake.Panes = nil
+	buf.Reset()
+	if err := runStatusline([]string{"--max-age", "0s"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+}
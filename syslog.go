@@ -0,0 +1,89 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"log/syslog"
+)
+
+// syslogHandler writes records to the system log (journald on systemd
+// hosts) at the syslog priority matching their level.
+type syslogHandler struct {
+	debug, info, warn, err slog.Handler
+}
+
+// syslogWriter adapts one syslog priority to io.Writer.
+type syslogWriter func(string) error
+
+func (f syslogWriter) Write(p []byte) (int, error) {
+	return len(p), f(string(p))
+}
+
+// newSyslogLogger returns a logger that sends records to syslog with the
+// given tag. Timestamps are left to syslog.
+func newSyslogLogger(tag, component string) (*slog.Logger, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{
+		Level: logLevel,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}
+	text := func(out io.Writer) slog.Handler { return slog.NewTextHandler(out, opts) }
+	h := &syslogHandler{
+		debug: text(syslogWriter(w.Debug)),
+		info:  text(syslogWriter(w.Info)),
+		warn:  text(syslogWriter(w.Warning)),
+		err:   text(syslogWriter(w.Err)),
+	}
+	return slog.New(h).With("component", component), nil
+}
+
+// handler returns the handler for a level.
+func (h *syslogHandler) handler(level slog.Level) slog.Handler {
+	switch {
+	case level >= slog.LevelError:
+		return h.err
+	case level >= slog.LevelWarn:
+		return h.warn
+	case level >= slog.LevelInfo:
+		return h.info
+	default:
+		return h.debug
+	}
+}
+
+func (h *syslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.info.Enabled(ctx, level)
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler(r.Level).Handle(ctx, r)
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{
+		debug: h.debug.WithAttrs(attrs),
+		info:  h.info.WithAttrs(attrs),
+		warn:  h.warn.WithAttrs(attrs),
+		err:   h.err.WithAttrs(attrs),
+	}
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return &syslogHandler{
+		debug: h.debug.WithGroup(name),
+		info:  h.info.WithGroup(name),
+		warn:  h.warn.WithGroup(name),
+		err:   h.err.WithGroup(name),
+	}
+}
@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+)
+
+// newSyslogLogger is unavailable on systems without syslog.
+func newSyslogLogger(tag, component string) (*slog.Logger, error) {
+	return nil, fmt.Errorf("syslog is not supported on %s", runtime.GOOS)
+}
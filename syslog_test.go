@@ -0,0 +1,50 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSyslogHandler_Priorities(t *testing.T) {
+	var debug, info, warn, errs bytes.Buffer
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	h := &syslogHandler{
+		debug: slog.NewTextHandler(&debug, opts),
+		info:  slog.NewTextHandler(&info, opts),
+		warn:  slog.NewTextHandler(&warn, opts),
+		err:   slog.NewTextHandler(&errs, opts),
+	}
+	logger := slog.New(h).With("component", "watch")
+	logger.Debug("scan complete")
+	logger.Info("pane idle", "pane", "%5")
+	logger.Warn("hook failed")
+	logger.Error("task failed")
+
+	for name, tc := range map[string]struct {
+		buf  *bytes.Buffer
+		want string
+	}{
+		"debug": {&debug, "scan complete"},
+		"info":  {&info, `msg="pane idle" component=watch pane=%5`},
+		"warn":  {&warn, "hook failed"},
+		"error": {&errs, "task failed"},
+	} {
+		if !strings.Contains(tc.buf.String(), tc.want) || strings.Count(tc.buf.String(), "\n") != 1 {
+			t.Errorf("%s priority got %q, want one line containing %q", name, tc.buf.String(), tc.want)
+		}
+	}
+}
+
+func TestRunWatch_LogTargetErrors(t *testing.T) {
+	if err := runWatch([]string{"--log-target", "kafka"}, io.Discard); err == nil {
+		t.Error("expected error for unknown log target")
+	}
+	if err := runWatch([]string{"--log-target", "syslog", "--log", "/tmp/x.log"}, io.Discard); err == nil {
+		t.Error("expected error for --log with syslog")
+	}
+}
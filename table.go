@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io"
+	"strings"
+)
+
+// tableGap is the space between table columns.
+const tableGap = 2
+
+// tableColumn describes one column of a width-aware table.
+type tableColumn struct {
+	Title string
+	// Min is the narrowest the column may be truncated to; 0 means it is
+	// never truncated.
+	Min int
+	// Optional columns are left out, rightmost first, when the table does
+	// not fit even with every column at its minimum width.
+	Optional bool
+}
+
+// layoutTable returns the width of each column so the table fits in width
+// cells, or -1 for columns that are left out. Columns are shrunk rightmost
+// first, and optional columns are only left out when shrinking is not
+// enough. A width of 0 or less means unlimited.
+func layoutTable(width int, cols []tableColumn, rows [][]string) []int {
+	natural := make([]int, len(cols))
+	for i, c := range cols {
+		natural[i] = stringWidth(c.Title)
+		for _, row := range rows {
+			if i < len(row) {
+				natural[i] = max(natural[i], stringWidth(row[i]))
+			}
+		}
+	}
+	if width <= 0 {
+		return natural
+	}
+
+	dropped := make([]bool, len(cols))
+	for {
+		widths := make([]int, len(cols))
+		total := -tableGap
+		for i := range cols {
+			widths[i] = -1
+			if !dropped[i] {
+				widths[i] = natural[i]
+				total += natural[i] + tableGap
+			}
+		}
+		for i := len(cols) - 1; i >= 0 && total > width; i-- {
+			if widths[i] > cols[i].Min && cols[i].Min > 0 {
+				cut := min(total-width, widths[i]-cols[i].Min)
+				widths[i] -= cut
+				total -= cut
+			}
+		}
+		if total <= width {
+			return widths
+		}
+		next := -1
+		for i := len(cols) - 1; i >= 0 && next < 0; i-- {
+			if !dropped[i] && cols[i].Optional {
+				next = i
+			}
+		}
+		if next < 0 {
+			return widths
+		}
+		dropped[next] = true
+	}
+}
+
+// renderTable writes rows under the column titles, truncating and leaving
+// out columns so that each line fits in width cells (0 = unlimited).
+func renderTable(w io.Writer, width int, cols []tableColumn, rows [][]string) {
+	widths := layoutTable(width, cols, rows)
+	titles := make([]string, len(cols))
+	for i, c := range cols {
+		titles[i] = c.Title
+	}
+	for _, row := range append([][]string{titles}, rows...) {
+		var cells []string
+		for i, cw := range widths {
+			if cw < 0 {
+				continue
+			}
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			cells = append(cells, padWidth(truncateWidth(cell, cw), cw))
+		}
+		line := strings.Join(cells, strings.Repeat(" ", tableGap))
+		io.WriteString(w, strings.TrimRight(line, " ")+"\n")
+	}
+}
+
+// tableWidth returns the --width value in args (0 = unlimited), or the
+// terminal width when the flag is absent.
+func tableWidth(args []string) (int, error) {
+	width, err := parseIntFlag(args, "--width", -1)
+	if err != nil {
+		return 0, err
+	}
+	if width < 0 {
+		width, _ = terminalSize()
+	}
+	return width, nil
+}
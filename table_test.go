@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestLayoutTable(t *testing.T) {
+	rows := [][]string{
+		{"%1", "claude", "idle", "18%", "fix login bug", "Running go test ./... and checking the output"},
+	}
+	tests := []struct {
+		name  string
+		width int
+		want  []int
+	}{
+		{"unlimited", 0, []int{4, 7, 6, 7, 13, 45}},
+		{"fits", 110, []int{4, 7, 6, 7, 13, 45}},
+		{"shrink last", 69, []int{4, 7, 6, 7, 13, 22}},
+		{"shrink more", 59, []int{4, 7, 6, 7, 13, 12}},
+		{"shrink both", 55, []int{4, 7, 6, 7, 11, 10}},
+		{"drop last", 49, []int{4, 7, 6, 7, 13, -1}},
+		{"drop both", 30, []int{4, 7, 6, 7, -1, -1}},
+		{"drop all", 20, []int{4, 7, 6, -1, -1, -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := layoutTable(tt.width, statusColumns, rows)
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("widths = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderTable(t *testing.T) {
+	cols := []tableColumn{{Title: "ID"}, {Title: "NAME", Min: 4}, {Title: "NOTE", Min: 4, Optional: true}}
+	rows := [][]string{{"1", "日本語のテキスト", "note"}, {"22", "short", ""}}
+
+	var buf bytes.Buffer
+	renderTable(&buf, 20, cols, rows)
+	want := "ID  NAME        NOTE\n1   日本語...   note\n22  short\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if stringWidth(line) > 20 {
+			t.Errorf("line wider than 20 cells: %q", line)
+		}
+	}
+}
+
+func TestRunStatus_Width(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude",
+		Output: "a very long line of agent output that will not fit in a narrow terminal"})
+	var buf bytes.Buffer
+	if err := runStatus([]string{"--width", "30"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "LAST OUTPUT") {
+		t.Errorf("expected last output column to be dropped:\n%s", buf.String())
+	}
+	buf.Reset()
+	runStatus([]string{"--width", "0"}, &buf)
+	if !strings.Contains(buf.String(), "narrow terminal") {
+		t.Errorf("expected full last line with --width 0:\n%s", buf.String())
+	}
+	if err := runStatus([]string{"--width", "wide"}, &buf); err == nil {
+		t.Error("expected error for invalid --width")
+	}
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const tasksFile = "tasks.json"
+
+// Task statuses.
+const (
+	taskTodo       = "todo"
+	taskInProgress = "in-progress"
+	taskDone       = "done"
+	taskFailed     = "failed"
+)
+
+// task is a unit of work tracked alongside the panes that perform it.
+type task struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	Status     string    `json:"status"`
+	Pane       string    `json:"pane,omitempty"`
+	Dir        string    `json:"dir,omitempty"`
+	Agent      string    `json:"agent,omitempty"`
+	Outcome    string    `json:"outcome,omitempty"`
+	Source     string    `json:"source,omitempty"`
+	Priority   int       `json:"priority,omitempty"`
+	DependsOn  []int     `json:"depends_on,omitempty"`
+	Attempts   int       `json:"attempts,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+}
+
+// taskStore is the persisted task list.
+type taskStore struct {
+	NextID int     `json:"next_id"`
+	Tasks  []*task `json:"tasks"`
+}
+
+// loadTasks reads the task store.
+func loadTasks() (*taskStore, error) {
+	store := &taskStore{NextID: 1}
+	if err := loadState(tasksFile, store); err != nil {
+		return nil, fmt.Errorf("reading tasks: %w", err)
+	}
+	return store, nil
+}
+
+// saveTasks writes the task store.
+func saveTasks(store *taskStore) error {
+	return saveState(tasksFile, store)
+}
+
+// updateTasks loads the task store, lets fn change it, and saves it,
+// holding the tasks lock throughout so concurrent updates (task, board,
+// dispatch, reattach) aren't lost. Nothing is saved when fn returns an
+// error or leaves the store as it was. fn should not block for long, as
+// other commands wait for the lock.
+func updateTasks(fn func(store *taskStore) error) error {
+	return withStateLock(tasksFile, func() error {
+		store, err := loadTasks()
+		if err != nil {
+			return err
+		}
+		before, err := json.Marshal(store)
+		if err != nil {
+			return err
+		}
+		if err := fn(store); err != nil {
+			return err
+		}
+		if after, err := json.Marshal(store); err == nil && bytes.Equal(before, after) {
+			return nil
+		}
+		return saveTasks(store)
+	})
+}
+
+// get returns the task with the given ID.
+func (s *taskStore) get(id int) (*task, error) {
+	for _, t := range s.Tasks {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("task %d not found", id)
+}
+
+// add appends a new todo task and returns it.
+func (s *taskStore) add(title string) *task {
+	t := &task{ID: s.NextID, Title: title, Status: taskTodo, CreatedAt: time.Now()}
+	s.NextID++
+	s.Tasks = append(s.Tasks, t)
+	return t
+}
+
+// ready reports whether every task t depends on is done.
+func (s *taskStore) ready(t *task) bool {
+	for _, id := range t.DependsOn {
+		dep, err := s.get(id)
+		if err != nil || dep.Status != taskDone {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTaskIDs parses a comma-separated list of task IDs.
+func parseTaskIDs(s string) ([]int, error) {
+	var ids []int
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		id, err := parseTaskID(f)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// formatTaskIDs renders task IDs as "#1,#2", or "-" when empty.
+func formatTaskIDs(ids []int) string {
+	if len(ids) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("#%d", id)
+	}
+	return strings.Join(parts, ",")
+}
+
+// start marks a task in progress on the given pane.
+func (t *task) start(paneID string) {
+	t.Status = taskInProgress
+	t.StartedAt = time.Now()
+	if paneID != "" {
+		t.Pane = paneID
+	}
+}
+
+// finish marks a task done or failed and logs it to the activity log.
+func (t *task) finish(status, outcome string) {
+	t.Status = status
+	t.Outcome = outcome
+	t.FinishedAt = time.Now()
+	appendActivity(activityRecord{
+		Time:  t.FinishedAt,
+		Kind:  activityTask,
+		Pane:  t.Pane,
+		Agent: t.Agent,
+		Repo:  shortDir(t.Dir),
+		State: status,
+	})
+}
+
+// lookupPane returns the agent pane with the given ID, if it is running.
+func lookupPane(paneID string) (paneInfo, bool) {
+	panes, err := listTmuxPanesOpts("", true)
+	if err != nil {
+		return paneInfo{}, false
+	}
+	for _, p := range panes {
+		if p.ID == paneID {
+			return p, true
+		}
+	}
+	return paneInfo{}, false
+}
+
+// parseTaskID parses a task ID argument.
+func parseTaskID(s string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(s, "#"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid task ID: %s", s)
+	}
+	return id, nil
+}
+
+// runTask dispatches task subcommands.
+func runTask(args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: tmux-agent task <add|start|done|fail|list|rm> ...")
+	}
+	switch args[0] {
+	case "add":
+		return runTaskAdd(args[1:], w)
+	case "start":
+		return runTaskStart(args[1:], w)
+	case "done":
+		return runTaskFinish(args[1:], w, taskDone)
+	case "fail":
+		return runTaskFinish(args[1:], w, taskFailed)
+	case "list", "ls":
+		return runTaskList(args[1:], w)
+	case "rm":
+		return runTaskRemove(args[1:], w)
+	default:
+		return fmt.Errorf("unknown task command: %s", args[0])
+	}
+}
+
+// runTaskAdd records a new task.
+func runTaskAdd(args []string, w io.Writer) error {
+	var words []string
+	var paneID, dir string
+	var priority int
+	var dependsOn []int
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--priority":
+			if i+1 < len(args) {
+				i++
+				n, err := strconv.Atoi(args[i])
+				if err != nil {
+					return fmt.Errorf("invalid --priority value: %s", args[i])
+				}
+				priority = n
+			}
+		case "--depends-on", "--after":
+			if i+1 < len(args) {
+				i++
+				ids, err := parseTaskIDs(args[i])
+				if err != nil {
+					return err
+				}
+				dependsOn = append(dependsOn, ids...)
+			}
+		case "--pane":
+			if i+1 < len(args) {
+				i++
+				paneID = args[i]
+			}
+		case "--dir":
+			if i+1 < len(args) {
+				i++
+				dir = args[i]
+			}
+		default:
+			words = append(words, args[i])
+		}
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("usage: tmux-agent task add <title...> [--pane id] [--dir path] [--priority N] [--depends-on id,...]")
+	}
+
+	var t *task
+	err := updateTasks(func(store *taskStore) error {
+		for _, id := range dependsOn {
+			if _, err := store.get(id); err != nil {
+				return err
+			}
+		}
+		t = store.add(strings.Join(words, " "))
+		t.Pane = paneID
+		t.Dir = dir
+		t.Priority = priority
+		t.DependsOn = dependsOn
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Added task #%d: %s\n", t.ID, t.Title)
+	return nil
+}
+
+// runTaskStart marks a task in progress, optionally sending it to its pane.
+func runTaskStart(args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: tmux-agent task start <id> [--pane id] [--send]")
+	}
+	id, err := parseTaskID(args[0])
+	if err != nil {
+		return err
+	}
+	var paneID string
+	send := false
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--pane":
+			if i+1 < len(args) {
+				i++
+				paneID = args[i]
+			}
+		case "--send":
+			send = true
+		}
+	}
+
+	// The prompt is sent before the lock is taken, since sending may wait
+	// out send_rate_limit.
+	store, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	t, err := store.get(id)
+	if err != nil {
+		return err
+	}
+	if paneID == "" {
+		paneID = t.Pane
+	}
+	if send {
+		if paneID == "" {
+			return fmt.Errorf("task #%d has no pane; use --pane", t.ID)
+		}
+		if err := sendTmuxKeys(paneID, t.Title); err != nil {
+			return err
+		}
+	}
+	err = updateTasks(func(store *taskStore) error {
+		if t, err = store.get(id); err != nil {
+			return err
+		}
+		t.start(paneID)
+		if t.Pane != "" {
+			if p, ok := lookupPane(t.Pane); ok {
+				t.Agent = p.Command
+				if t.Dir == "" {
+					t.Dir = p.Dir
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if t.Pane != "" {
+		fmt.Fprintf(w, "Started task #%d on pane %s\n", t.ID, t.Pane)
+	} else {
+		fmt.Fprintf(w, "Started task #%d\n", t.ID)
+	}
+	return nil
+}
+
+// runTaskFinish marks a task done or failed.
+func runTaskFinish(args []string, w io.Writer, status string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: tmux-agent task %s <id> [outcome...]", strings.TrimSuffix(status, "ed"))
+	}
+	id, err := parseTaskID(args[0])
+	if err != nil {
+		return err
+	}
+
+	var t *task
+	err = updateTasks(func(store *taskStore) error {
+		if t, err = store.get(id); err != nil {
+			return err
+		}
+		t.finish(status, strings.Join(args[1:], " "))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Task #%d %s\n", t.ID, status)
+	return nil
+}
+
+// runTaskRemove deletes a task.
+func runTaskRemove(args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: tmux-agent task rm <id>")
+	}
+	id, err := parseTaskID(args[0])
+	if err != nil {
+		return err
+	}
+	err = updateTasks(func(store *taskStore) error {
+		for i, t := range store.Tasks {
+			if t.ID == id {
+				store.Tasks = append(store.Tasks[:i], store.Tasks[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("task %d not found", id)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Removed task #%d\n", id)
+	return nil
+}
+
+// runTaskList prints tasks, optionally filtered by status or pane.
+func runTaskList(args []string, w io.Writer) error {
+	var status, paneID string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--status":
+			if i+1 < len(args) {
+				i++
+				status = args[i]
+			}
+		case "--pane":
+			if i+1 < len(args) {
+				i++
+				paneID = args[i]
+			}
+		}
+	}
+
+	store, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	var tasks []*task
+	for _, t := range store.Tasks {
+		if (status == "" || t.Status == status) && (paneID == "" || t.Pane == paneID) {
+			tasks = append(tasks, t)
+		}
+	}
+	if jsonOutput {
+		if tasks == nil {
+			tasks = []*task{}
+		}
+		return writeJSON(w, tasks)
+	}
+	if len(tasks) == 0 {
+		fmt.Fprintln(w, "No tasks found")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tPRI\tAFTER\tPANE\tAGE\tTITLE")
+	for _, t := range tasks {
+		fmt.Fprintf(tw, "#%d\t%s\t%d\t%s\t%s\t%s\t%s\n", t.ID, t.Status, t.Priority,
+			formatTaskIDs(t.DependsOn), t.Pane, formatDuration(time.Since(t.CreatedAt)), t.Title)
+	}
+	tw.Flush()
+	return nil
+}
+
+// tasksForPane returns tasks on a pane that were active at or after since.
+func tasksForPane(store *taskStore, paneID string, since time.Time) []*task {
+	var tasks []*task
+	for _, t := range store.Tasks {
+		if t.Pane != paneID {
+			continue
+		}
+		if t.Status == taskInProgress || t.FinishedAt.After(since) {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
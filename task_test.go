@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskLifecycle(t *testing.T) {
+	dir := t.TempDir()
+
+	argsFile := filepath.Join(dir, "tmux-args.txt")
+	tmuxScript := filepath.Join(dir, "tmux")
+	os.WriteFile(tmuxScript, []byte(`#!/bin/sh
+echo "$@" >> `+argsFile+`
+case "$1" in
+  list-panes)
+    printf "%%5\tclaude\t12345\t/home/user/ghq/github.com/owner/repo\n"
+    ;;
+esac
+`), 0755)
+
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+origPath)
+	defer os.Setenv("PATH", origPath)
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	var buf bytes.Buffer
+	if err := runTask([]string{"add", "write", "the", "parser"}, &buf); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Added task #1: write the parser") {
+		t.Errorf("unexpected add output: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := runTask([]string{"start", "1", "--pane", "%5", "--send"}, &buf); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Started task #1 on pane %5") {
+		t.Errorf("unexpected start output: %s", buf.String())
+	}
+	data, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(data), "write the parser") {
+		t.Errorf("expected task title sent to pane, got: %s", string(data))
+	}
+
+	store, _ := loadTasks()
+	tk, _ := store.get(1)
+	if tk.Status != taskInProgress || tk.Agent != "claude" || tk.Dir == "" || tk.StartedAt.IsZero() {
+		t.Errorf("unexpected started task: %+v", tk)
+	}
+
+	buf.Reset()
+	if err := runTask([]string{"done", "#1", "all", "tests", "pass"}, &buf); err != nil {
+		t.Fatalf("done: %v", err)
+	}
+	store, _ = loadTasks()
+	tk, _ = store.get(1)
+	if tk.Status != taskDone || tk.Outcome != "all tests pass" || tk.FinishedAt.IsZero() {
+		t.Errorf("unexpected finished task: %+v", tk)
+	}
+
+	records, _ := loadActivity(time.Time{})
+	if len(records) != 1 || records[0].Kind != activityTask || records[0].Repo != "owner/repo" {
+		t.Errorf("expected task activity record, got %+v", records)
+	}
+
+	buf.Reset()
+	if err := runTask([]string{"list", "--status", "done"}, &buf); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if !strings.Contains(buf.String(), "#1") || !strings.Contains(buf.String(), "write the parser") {
+		t.Errorf("expected task in list, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := runTask([]string{"rm", "1"}, &buf); err != nil {
+		t.Fatalf("rm: %v", err)
+	}
+	store, _ = loadTasks()
+	if len(store.Tasks) != 0 || store.NextID != 2 {
+		t.Errorf("expected empty store with preserved next ID, got %+v", store)
+	}
+}
+
+func TestRunTask_Errors(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	var buf bytes.Buffer
+	if err := runTask(nil, &buf); err == nil {
+		t.Error("expected usage error")
+	}
+	if err := runTask([]string{"add"}, &buf); err == nil {
+		t.Error("expected error for missing title")
+	}
+	if err := runTask([]string{"done", "abc"}, &buf); err == nil {
+		t.Error("expected error for invalid ID")
+	}
+	if err := runTask([]string{"start", "42"}, &buf); err == nil {
+		t.Error("expected error for unknown task")
+	}
+	if err := runTask([]string{"add", "tests", "--depends-on", "42"}, &buf); err == nil {
+		t.Error("expected error for unknown dependency")
+	}
+	if err := runTask([]string{"add", "tests", "--priority", "high"}, &buf); err == nil {
+		t.Error("expected error for invalid priority")
+	}
+}
+
+func TestRunTaskAdd_PriorityAndDependencies(t *testing.T) {
+	dir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	var buf bytes.Buffer
+	runTask([]string{"add", "implement", "feature"}, &buf)
+	runTask([]string{"add", "update", "docs"}, &buf)
+	if err := runTask([]string{"add", "write", "tests", "--priority", "2", "--depends-on", "#1,2"}, &buf); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	store, _ := loadTasks()
+	tk, _ := store.get(3)
+	if tk.Priority != 2 || len(tk.DependsOn) != 2 || tk.DependsOn[0] != 1 || tk.DependsOn[1] != 2 {
+		t.Errorf("unexpected task: %+v", tk)
+	}
+	if store.ready(tk) {
+		t.Error("expected task with pending dependencies not to be ready")
+	}
+
+	buf.Reset()
+	runTask([]string{"list"}, &buf)
+	if !strings.Contains(buf.String(), "#1,#2") {
+		t.Errorf("expected dependencies in list, got: %s", buf.String())
+	}
+}
+
+func TestUpdateTasks(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	saveTasks(&taskStore{NextID: 2, Tasks: []*task{{ID: 1, Title: "first", Status: taskTodo}}})
+
+	err := updateTasks(func(store *taskStore) error {
+		store.add("second")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A failing update is not saved.
+	updateTasks(func(store *taskStore) error {
+		store.add("third")
+		return fmt.Errorf("boom")
+	})
+	store, _ := loadTasks()
+	if len(store.Tasks) != 2 || store.Tasks[1].Title != "second" || store.NextID != 3 {
+		t.Errorf("unexpected store: %+v", store)
+	}
+
+	// Another process holding the lock keeps the update from going through.
+	origWait := lockWait
+	lockWait = 50 * time.Millisecond
+	defer func() { lockWait = origWait }()
+	os.WriteFile(statePath(tasksFile+".lock"), nil, 0644)
+	if err := updateTasks(func(*taskStore) error { return nil }); err == nil {
+		t.Error("expected an error while tasks.json is locked")
+	}
+}
+
+func TestTasksForPane(t *testing.T) {
+	now := time.Now()
+	store := &taskStore{Tasks: []*task{
+		{ID: 1, Pane: "%3", Status: taskInProgress},
+		{ID: 2, Pane: "%3", Status: taskDone, FinishedAt: now.Add(-time.Hour)},
+		{ID: 3, Pane: "%3", Status: taskDone, FinishedAt: now.Add(-48 * time.Hour)},
+		{ID: 4, Pane: "%5", Status: taskInProgress},
+	}}
+	got := tasksForPane(store, "%3", now.Add(-24*time.Hour))
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 2 {
+		t.Errorf("unexpected tasks: %+v", got)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ANSI sequences used by the interactive views.
+const (
+	ansiClear      = "\x1b[H\x1b[2J"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+	ansiReverse    = "\x1b[7m"
+	ansiBold       = "\x1b[1m"
+	ansiReset      = "\x1b[0m"
+)
+
+// stty runs stty against the controlling terminal on stdin.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// enterRawMode switches the terminal to raw, no-echo mode and returns a
+// function that restores the previous settings.
+func enterRawMode() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("not a terminal: %w", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("stty raw: %w", err)
+	}
+	fmt.Fprint(os.Stdout, ansiHideCursor)
+	return func() {
+		stty(saved)
+		fmt.Fprint(os.Stdout, ansiShowCursor)
+	}, nil
+}
+
+// terminalSize returns the terminal width and height, falling back to
+// $COLUMNS/$LINES and then 80x24.
+func terminalSize() (int, int) {
+	if out, err := stty("size"); err == nil {
+		var rows, cols int
+		if _, err := fmt.Sscan(out, &rows, &cols); err == nil && rows > 0 && cols > 0 {
+			return cols, rows
+		}
+	}
+	width, height := 80, 24
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		width = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		height = n
+	}
+	return width, height
+}
+
+// readKeys starts reading keypresses from the terminal and returns them on
+// a channel, which is closed when the input ends, and a function that stops
+// the reader. The terminal is opened as /dev/tty where possible, so that
+// stopping can interrupt a pending read instead of leaving it blocked on
+// stdin.
+func readKeys() (<-chan string, func()) {
+	var in io.Reader = os.Stdin
+	closeIn := func() {}
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		in, closeIn = tty, func() { tty.Close() }
+	}
+	keys := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(keys)
+		r := bufio.NewReader(in)
+		for {
+			k, err := readKey(r)
+			if err != nil {
+				return
+			}
+			select {
+			case keys <- k:
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return keys, func() {
+		once.Do(func() {
+			close(done)
+			closeIn()
+		})
+	}
+}
+
+// readKey reads one keypress in raw mode. Arrow keys are returned as "up",
+// "down", "left", and "right"; Enter as "enter"; Escape as "esc".
+func readKey(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case '\r', '\n':
+		return "enter", nil
+	case 3:
+		return "ctrl-c", nil
+	case 127, 8:
+		return "backspace", nil
+	case 27:
+		if r.Buffered() == 0 {
+			return "esc", nil
+		}
+		next, _ := r.ReadByte()
+		if next != '[' && next != 'O' {
+			return "esc", nil
+		}
+		code, _ := r.ReadByte()
+		switch code {
+		case 'A':
+			return "up", nil
+		case 'B':
+			return "down", nil
+		case 'C':
+			return "right", nil
+		case 'D':
+			return "left", nil
+		}
+		return "esc", nil
+	}
+	r.UnreadByte()
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	return string(ch), nil
+}
+
+// rawLines converts newlines to CRLF for output while the terminal is in raw mode.
+func rawLines(s string) string {
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+// fitString pads or truncates s to exactly width terminal cells.
+func fitString(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	return padWidth(truncateWidth(s, width), width)
+}
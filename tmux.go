@@ -1,29 +1,45 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
-)
 
-// createPaneStartupDelay is the time to wait after creating a pane
-// before sending keys, allowing the TUI to initialize.
-var createPaneStartupDelay = 5 * time.Second
+	"github.com/sat0b/tmux-agent/runner"
+)
 
 // sendKeysTrailingRe matches trailing C-m, Enter, or \n sequences
 // that may have been appended literally. These are stripped because
 // sendTmuxKeys always sends its own C-m after pasting.
 var sendKeysTrailingRe = regexp.MustCompile(`(?i)(\s*(C-m|Enter|\\n))+\s*$`)
 
+// tmuxRunner runs every tmux command. Tests and embedders can replace it,
+// e.g. with runner.NewFake.
+var tmuxRunner runner.TmuxRunner = runner.Exec{}
+
+// tmux_backend config values.
+const (
+	// tmuxBackendExec starts a tmux process for every command.
+	tmuxBackendExec = "exec"
+	// tmuxBackendControl sends commands over one control-mode client.
+	tmuxBackendControl = "control"
+)
+
 // paneInfo holds metadata about a tmux pane running a target command.
 type paneInfo struct {
 	ID           string
 	Command      string
 	PID          string
 	Dir          string
+	Title        string
+	Session      string
+	Window       string // window index within the session
 	LastOutput   string
 	LastChangeAt time.Time
 }
@@ -85,10 +101,19 @@ func findTargetChild(psOutput string, panePID string) string {
 	return ""
 }
 
+// listProcesses runs ps for every process with the given -o columns.
+// With --container, the process table is read inside the container.
+func listProcesses(columns string) ([]byte, error) {
+	cmd := exec.Command("ps", "-o", columns, "-e")
+	if d, ok := runner.Unwrap(tmuxRunner).(runner.Docker); ok {
+		cmd = exec.Command("docker", "exec", d.Container, "ps", "-o", columns, "-e")
+	}
+	return cmd.Output()
+}
+
 // lookupChildProcess checks if the pane's shell has a target command as a descendant.
 func lookupChildProcess(panePID string) string {
-	cmd := exec.Command("ps", "-o", "pid,ppid,comm", "-e")
-	out, err := cmd.Output()
+	out, err := listProcesses("pid,ppid,comm")
 	if err != nil {
 		return ""
 	}
@@ -99,7 +124,102 @@ func lookupChildProcess(panePID string) string {
 // It can be replaced in tests.
 var childLookupFn = lookupChildProcess
 
-// parsePaneList parses tmux list-panes output (tab-separated: id, command, pid, path)
+// interpreters run agents that are scripts, e.g. "node .../cli.js".
+var interpreters = map[string]bool{"node": true, "bun": true, "deno": true}
+
+// plainArgRe matches arguments that need no shell quoting.
+var plainArgRe = regexp.MustCompile(`^[\w@%+=:,./-]+$`)
+
+// findAgentCommand parses "pid ppid comm args..." ps output and returns the
+// command line of the target command running as panePID or one of its
+// descendants, e.g. "claude --model opus". The agent's flags are kept when
+// its arguments show how it was invoked; arguments that contained spaces
+// cannot be told apart and come back split. It returns "" if no agent runs
+// in the pane.
+func findAgentCommand(psOutput, panePID string) string {
+	children := make(map[string][]string)
+	lines := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(psOutput), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		lines[fields[0]] = fields
+		children[fields[1]] = append(children[fields[1]], fields[0])
+	}
+	var search func(pid string) []string
+	search = func(pid string) []string {
+		if f := lines[pid]; f != nil && isTargetCommand(f[2]) {
+			return f
+		}
+		for _, child := range children[pid] {
+			if f := search(child); f != nil {
+				return f
+			}
+		}
+		return nil
+	}
+	f := search(panePID)
+	if f == nil {
+		return ""
+	}
+	agent := filepath.Base(f[2])
+	args := f[3:]
+	switch first := filepath.Base(args[0]); {
+	case first == agent:
+		args = args[1:]
+	case interpreters[first] && len(args) >= 2:
+		args = args[2:]
+	default:
+		args = nil
+	}
+	words := []string{agent}
+	for _, a := range args {
+		if !plainArgRe.MatchString(a) {
+			a = shellQuote(a)
+		}
+		words = append(words, a)
+	}
+	return strings.Join(words, " ")
+}
+
+// lookupAgentCommand returns the command line of the agent running in a
+// pane, or "" if it cannot be determined.
+func lookupAgentCommand(paneID string) string {
+	out, err := tmuxRunner.Run("display-message", "-p", "-t", paneID, "#{pane_pid}")
+	if err != nil {
+		return ""
+	}
+	ps, err := listProcesses("pid=,ppid=,comm=,args=")
+	if err != nil {
+		return ""
+	}
+	return findAgentCommand(string(ps), strings.TrimSpace(string(out)))
+}
+
+// loginShells are the shells a pane is back at once its agent has exited.
+var loginShells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true,
+	"ksh": true, "tcsh": true, "csh": true, "nu": true,
+}
+
+// paneAtShell reports whether tmux shows a pane's foreground process to be
+// a shell, or the pane as dead: positive evidence that its agent exited.
+// A failed lookup reports false.
+func paneAtShell(paneID string) bool {
+	out, err := tmuxRunner.Run("display-message", "-p", "-t", paneID, "#{pane_dead} #{pane_current_command}")
+	if err != nil {
+		return false
+	}
+	dead, command, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+	return dead == "1" || loginShells[strings.TrimPrefix(filepath.Base(command), "-")]
+}
+
+// agentCommandFn is the function used to find the agent running in a pane.
+// It can be replaced in tests.
+var agentCommandFn = lookupAgentCommand
+
+// parsePaneList parses tmux list-panes output (tab-separated: id, command, pid, path, title)
 // and returns only panes running a target command.
 // If the pane's direct command is not a target, it checks descendant processes.
 func parsePaneList(output string) []paneInfo {
@@ -118,12 +238,17 @@ func parsePaneListAll(output string, all bool) []paneInfo {
 		if len(fields) < 3 {
 			continue
 		}
+		// Columns past the PID are optional. A title may itself contain
+		// tabs, so it is whatever lies between the directory and the
+		// session.
+		if len(fields) > 7 {
+			n := len(fields)
+			fields = append(fields[:4], strings.Join(fields[4:n-2], "\t"), fields[n-2], fields[n-1])
+		}
+		fields = append(fields, make([]string, 7-len(fields))...)
 		cmd := fields[1]
 		pid := fields[2]
-		dir := ""
-		if len(fields) >= 4 {
-			dir = fields[3]
-		}
+		dir, title, session, window := fields[3], fields[4], fields[5], fields[6]
 		if !all && !isTargetCommand(cmd) {
 			if child := childLookupFn(pid); child != "" {
 				cmd = child
@@ -136,6 +261,9 @@ func parsePaneListAll(output string, all bool) []paneInfo {
 			Command:      cmd,
 			PID:          pid,
 			Dir:          dir,
+			Title:        title,
+			Session:      session,
+			Window:       window,
 			LastChangeAt: time.Now(),
 		})
 	}
@@ -147,17 +275,20 @@ func detectIdle(p *paneInfo, threshold time.Duration) bool {
 	return time.Since(p.LastChangeAt) >= threshold
 }
 
-// statusShort returns a one-line summary like "tmux-agent: 3 active, 1 idle".
-func statusShort(panes []paneInfo, threshold time.Duration) string {
-	active, idle := 0, 0
-	for i := range panes {
-		if detectIdle(&panes[i], threshold) {
-			idle++
-		} else {
-			active++
+// statusShort returns a one-line summary of pane states like
+// "tmux-agent: 3 active, 1 idle". Other states are listed only when present.
+func statusShort(states []string) string {
+	counts := make(map[string]int)
+	for _, s := range states {
+		counts[s]++
+	}
+	summary := fmt.Sprintf("tmux-agent: %d active", counts[stateActive])
+	for _, state := range []string{stateBusyCPU, stateCompacting, stateWaiting, stateNeedsApproval, stateRateLimited} {
+		if n := counts[state]; n > 0 {
+			summary += fmt.Sprintf(", %d %s", n, state)
 		}
 	}
-	return fmt.Sprintf("tmux-agent: %d active, %d idle", active, idle)
+	return summary + fmt.Sprintf(", %d idle", counts[stateIdle])
 }
 
 // listTmuxPanes runs tmux list-panes and returns parsed results.
@@ -173,15 +304,14 @@ func listTmuxPanesFiltered(session string) ([]paneInfo, error) {
 
 // listTmuxPanesOpts lists panes with session filter and all flag.
 func listTmuxPanesOpts(session string, all bool) ([]paneInfo, error) {
-	format := "#{pane_id}\t#{pane_current_command}\t#{pane_pid}\t#{pane_current_path}"
+	format := "#{pane_id}\t#{pane_current_command}\t#{pane_pid}\t#{pane_current_path}\t#{pane_title}\t#{session_name}\t#{window_index}"
 	var args []string
 	if session != "" {
 		args = []string{"list-panes", "-s", "-t", session, "-F", format}
 	} else {
 		args = []string{"list-panes", "-a", "-F", format}
 	}
-	cmd := exec.Command("tmux", args...)
-	output, err := cmd.Output()
+	output, err := tmuxRunner.Run(args...)
 	if err != nil {
 		return nil, fmt.Errorf("tmux list-panes: %w", err)
 	}
@@ -195,51 +325,195 @@ func currentTmuxSession() (string, error) {
 	if paneID == "" {
 		return "", fmt.Errorf("$TMUX_PANE not set; not running inside tmux")
 	}
-	cmd := exec.Command("tmux", "display-message", "-t", paneID, "-p", "#{session_name}")
-	output, err := cmd.Output()
+	output, err := tmuxRunner.Run("display-message", "-t", paneID, "-p", "#{session_name}")
 	if err != nil {
 		return "", fmt.Errorf("tmux display-message: %w", err)
 	}
 	return strings.TrimSpace(string(output)), nil
 }
 
+// tmuxServerID identifies the running tmux server, so a restart can be
+// told apart from panes being closed. Returns "" if no server is running.
+func tmuxServerID() string {
+	out, err := tmuxRunner.Run("display-message", "-p", "#{pid}:#{start_time}")
+	if err != nil {
+		return ""
+	}
+	id := strings.TrimSpace(string(out))
+	if id == ":" {
+		return ""
+	}
+	return id
+}
+
+// paneCurrentPath returns the working directory of a pane, or "" on error.
+func paneCurrentPath(paneID string) string {
+	output, err := tmuxRunner.Run("display-message", "-p", "-t", paneID, "#{pane_current_path}")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
 // capturePaneOutput captures the last N lines of a tmux pane.
 func capturePaneOutput(paneID string, lines int) (string, error) {
-	cmd := exec.Command("tmux", "capture-pane", "-p", "-t", paneID, "-S", fmt.Sprintf("-%d", lines))
-	output, err := cmd.Output()
+	output, err := tmuxRunner.Run("capture-pane", "-p", "-t", paneID, "-S", fmt.Sprintf("-%d", lines))
 	if err != nil {
 		return "", fmt.Errorf("tmux capture-pane %s: %w", paneID, err)
 	}
 	return strings.TrimSpace(string(output)), nil
 }
 
-// sendTmuxKeys sends text to a tmux pane using send-keys -l (literal mode).
-// Newlines are collapsed to spaces and trailing key sequences are stripped.
-// After sending the text, C-m is sent twice to submit the input.
+// displayTmuxMessage shows a message on the status line of attached clients.
+func displayTmuxMessage(msg string) error {
+	if _, err := tmuxRunner.Run("display-message", "--", msg); err != nil {
+		return fmt.Errorf("tmux display-message: %w", err)
+	}
+	return nil
+}
+
+// defaultEnterCount is how many times C-m is sent after input to submit it,
+// unless the pane's agent sets enter_count.
+const defaultEnterCount = 2
+
+// enterDefault, as an enter count, stands for the pane's agent default.
+const enterDefault = -1
+
+// enterCount returns how many times to press enter after input to paneID:
+// its agent's enter_count, or defaultEnterCount. The pane's agent is only
+// looked up when some agent sets enter_count.
+func enterCount(paneID string) int {
+	cfg := loadConfig()
+	set := false
+	for _, p := range cfg.Agents {
+		set = set || (p != nil && p.EnterCount != nil)
+	}
+	if !set {
+		return defaultEnterCount
+	}
+	if p, ok := lookupPane(paneID); ok {
+		if n := cfg.agent(p.Command).EnterCount; n != nil {
+			return *n
+		}
+	}
+	return defaultEnterCount
+}
+
+// submitInput presses enter n times in paneID (enterDefault: the pane's
+// agent default) to submit the input just typed or pasted.
+func submitInput(paneID string, n int) error {
+	if n == enterDefault {
+		n = enterCount(paneID)
+	}
+	if n <= 0 {
+		return nil
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	for i := 0; i < n; i++ {
+		if _, err := tmuxRunner.Run("send-keys", "-t", paneID, "C-m"); err != nil {
+			return fmt.Errorf("tmux send-keys (enter) to %s: %w", paneID, err)
+		}
+	}
+	return nil
+}
+
+// sendTmuxKeys sends text to a tmux pane and submits it, pressing enter as
+// many times as the pane's agent wants (see sendTmuxKeysEnter).
 func sendTmuxKeys(paneID string, keys string) error {
+	return sendTmuxKeysEnter(paneID, keys, enterDefault)
+}
+
+// sendTmuxKeysEnter sends text to a tmux pane using send-keys -l (literal
+// mode). Newlines are collapsed to spaces and trailing key sequences are
+// stripped. Text matching a configured blocked pattern is refused, and the
+// send waits for the configured rate limits.
+// After sending the text, C-m is sent enter times to submit the input; 0
+// leaves it typed but unsubmitted.
+func sendTmuxKeysEnter(paneID, keys string, enter int) error {
+	keys, err := typeTmuxKeys(paneID, keys, enter)
+	if err != nil || keys == "" {
+		return err
+	}
+	recordSent(paneID, keys)
+	return nil
+}
+
+// typeTmuxKeys does the work of sendTmuxKeysEnter except recording the send,
+// for callers that record something else. It returns the text as sent, ""
+// if there was nothing to send.
+func typeTmuxKeys(paneID, keys string, enter int) (string, error) {
 	keys = strings.ReplaceAll(keys, "\r\n", " ")
 	keys = strings.ReplaceAll(keys, "\n", " ")
 	keys = strings.ReplaceAll(keys, "\r", " ")
 	keys = sendKeysTrailingRe.ReplaceAllString(keys, "")
 	keys = strings.TrimSpace(keys)
 	if keys == "" {
-		return nil
+		return "", nil
+	}
+	if err := checkSendText(keys); err != nil {
+		return "", err
+	}
+	if err := waitForSendSlot(paneID); err != nil {
+		return "", err
 	}
 
-	cmd := exec.Command("tmux", "send-keys", "-t", paneID, "-l", "--", keys)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("tmux send-keys -l to %s: %w (output: %s)", paneID, err, string(output))
+	if _, err := tmuxRunner.Run("send-keys", "-t", paneID, "-l", "--", keys); err != nil {
+		return "", fmt.Errorf("tmux send-keys -l to %s: %w", paneID, err)
 	}
+	if err := submitInput(paneID, enter); err != nil {
+		return "", err
+	}
+	return keys, nil
+}
 
-	time.Sleep(100 * time.Millisecond)
+// pasteBuffer is the tmux buffer pasteTmuxText goes through.
+const pasteBuffer = "tmux-agent-paste"
 
-	for i := 0; i < 2; i++ {
-		cmd = exec.Command("tmux", "send-keys", "-t", paneID, "C-m")
-		if output, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("tmux send-keys (enter) to %s: %w (output: %s)", paneID, err, string(output))
-		}
+// sendTmuxText sends text a user wrote to a pane: pasted if it spans lines
+// (or paste is set), so code blocks and lists arrive verbatim, and typed
+// with send-keys otherwise. enter is as for sendTmuxKeysEnter.
+func sendTmuxText(paneID, text string, enter int, paste bool) error {
+	if paste || strings.Contains(strings.TrimSpace(text), "\n") {
+		return pasteTmuxTextEnter(paneID, text, enter)
+	}
+	return sendTmuxKeysEnter(paneID, text, enter)
+}
+
+// pasteTmuxText pastes text into a tmux pane and submits it, pressing enter
+// as many times as the pane's agent wants (see pasteTmuxTextEnter).
+func pasteTmuxText(paneID, text string) error {
+	return pasteTmuxTextEnter(paneID, text, enterDefault)
+}
+
+// pasteTmuxTextEnter pastes text into a tmux pane as a bracketed paste, so
+// an agent gets multi-line text (a diff, a log) as one message with its
+// line breaks intact, then presses enter as sendTmuxKeysEnter does. Like
+// sendTmuxKeys it honours the send guard and rate limits and records the
+// send.
+func pasteTmuxTextEnter(paneID, text string, enter int) error {
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), " \n")
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	if err := checkSendText(text); err != nil {
+		return err
+	}
+	if err := waitForSendSlot(paneID); err != nil {
+		return err
+	}
+
+	if _, err := tmuxRunner.Run("set-buffer", "-b", pasteBuffer, "--", text); err != nil {
+		return fmt.Errorf("tmux set-buffer: %w", err)
+	}
+	if _, err := tmuxRunner.Run("paste-buffer", "-p", "-d", "-b", pasteBuffer, "-t", paneID); err != nil {
+		return fmt.Errorf("tmux paste-buffer to %s: %w", paneID, err)
+	}
+	if err := submitInput(paneID, enter); err != nil {
+		return err
 	}
 
+	recordSent(paneID, text)
 	return nil
 }
 
@@ -248,8 +522,10 @@ type createPaneOpts struct {
 	Command   string // command to run (e.g., "claude")
 	Dir       string // working directory (empty = inherit)
 	Session   string // target session (empty = current)
+	Target    string // pane to split (overrides Session)
 	Split     string // "h" (horizontal, default) or "v" (vertical)
 	NewWindow bool   // create as new window instead of split
+	Detached  bool   // leave the current pane selected (-d)
 }
 
 // createTmuxPane creates a new tmux pane running the specified command.
@@ -281,39 +557,62 @@ func createTmuxPaneWithOpts(opts createPaneOpts) (string, error) {
 			splitFlag = "-v"
 		}
 		args = []string{"split-window", splitFlag}
-		if opts.Session != "" {
+		if opts.Target != "" {
+			args = append(args, "-t", opts.Target)
+		} else if opts.Session != "" {
 			args = append(args, "-t", opts.Session)
 		}
 	}
+	if opts.Detached {
+		args = append(args, "-d")
+	}
 	args = append(args, "-P", "-F", "#{pane_id}")
 	if opts.Dir != "" {
 		args = append(args, "-c", opts.Dir)
 	}
 	args = append(args, opts.Command)
 
-	cmd := exec.Command("tmux", args...)
-	output, err := cmd.CombinedOutput()
+	output, err := tmuxRunner.Run(args...)
 	if err != nil {
-		subcmd := args[0]
-		return "", fmt.Errorf("tmux %s: %w (output: %s)", subcmd, err, string(output))
+		return "", fmt.Errorf("tmux %s: %w", args[0], err)
 	}
 	return strings.TrimSpace(string(output)), nil
 }
 
+// selectTmuxLayout arranges the panes of paneID's window in a tmux layout
+// such as "tiled".
+func selectTmuxLayout(paneID, layout string) error {
+	if _, err := tmuxRunner.Run("select-layout", "-t", paneID, layout); err != nil {
+		return fmt.Errorf("tmux select-layout %s: %w", layout, err)
+	}
+	return nil
+}
+
 // killTmuxPane kills a tmux pane by pane ID.
 func killTmuxPane(paneID string) error {
-	cmd := exec.Command("tmux", "kill-pane", "-t", paneID)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("tmux kill-pane %s: %w (output: %s)", paneID, err, string(output))
+	if _, err := tmuxRunner.Run("kill-pane", "-t", paneID); err != nil {
+		return fmt.Errorf("tmux kill-pane %s: %w", paneID, err)
+	}
+	return nil
+}
+
+// respawnTmuxPane kills whatever runs in a pane and starts a fresh shell
+// there, in dir when it is not empty.
+func respawnTmuxPane(paneID, dir string) error {
+	args := []string{"respawn-pane", "-k", "-t", paneID}
+	if dir != "" {
+		args = append(args, "-c", dir)
+	}
+	if _, err := tmuxRunner.Run(args...); err != nil {
+		return fmt.Errorf("tmux respawn-pane %s: %w", paneID, err)
 	}
 	return nil
 }
 
 // renameTmuxPane sets the title of a tmux pane.
 func renameTmuxPane(paneID, title string) error {
-	cmd := exec.Command("tmux", "select-pane", "-t", paneID, "-T", title)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("tmux select-pane -T %s: %w (output: %s)", paneID, err, string(output))
+	if _, err := tmuxRunner.Run("select-pane", "-t", paneID, "-T", title); err != nil {
+		return fmt.Errorf("tmux select-pane -T %s: %w", paneID, err)
 	}
 	return nil
 }
@@ -321,9 +620,67 @@ func renameTmuxPane(paneID, title string) error {
 // sendRawTmuxKeys sends raw tmux key sequences (not literal text) to a pane.
 func sendRawTmuxKeys(paneID string, keys ...string) error {
 	args := append([]string{"send-keys", "-t", paneID}, keys...)
-	cmd := exec.Command("tmux", args...)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("tmux send-keys %s: %w (output: %s)", paneID, err, string(output))
+	if _, err := tmuxRunner.Run(args...); err != nil {
+		return fmt.Errorf("tmux send-keys %s: %w", paneID, err)
 	}
 	return nil
 }
+
+// captureScrollback captures the entire scrollback history of a tmux pane.
+// Wrapped lines are joined so the result reads like the original output.
+func captureScrollback(paneID string) (string, error) {
+	output, err := tmuxRunner.Run("capture-pane", "-p", "-J", "-t", paneID, "-S", "-")
+	if err != nil {
+		return "", fmt.Errorf("tmux capture-pane %s: %w", paneID, err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// shellQuote quotes s for safe use as a single word in a POSIX shell command.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// outputTracker remembers a hash of the last captured output of each pane
+// and when it last changed, so idle time can be measured across repeated
+// scans.
+type outputTracker struct {
+	hashes  map[string]string
+	changed map[string]time.Time
+}
+
+// newOutputTracker returns an empty tracker.
+func newOutputTracker() *outputTracker {
+	return &outputTracker{
+		hashes:  make(map[string]string),
+		changed: make(map[string]time.Time),
+	}
+}
+
+// outputHash returns the hash the tracker keeps of a pane's output.
+func outputHash(output string) string {
+	sum := sha256.Sum256([]byte(output))
+	return hex.EncodeToString(sum[:])
+}
+
+// observe records the latest output for a pane and updates the pane's
+// LastOutput and LastChangeAt fields.
+func (t *outputTracker) observe(p *paneInfo, output string) {
+	hash := outputHash(output)
+	prev, exists := t.hashes[p.ID]
+	if !exists || prev != hash {
+		t.hashes[p.ID] = hash
+		t.changed[p.ID] = time.Now()
+	}
+	p.LastOutput = output
+	p.LastChangeAt = t.changed[p.ID]
+}
+
+// forget drops all state for a pane.
+func (t *outputTracker) forget(paneID string) {
+	delete(t.hashes, paneID)
+	delete(t.changed, paneID)
+}
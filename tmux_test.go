@@ -3,11 +3,39 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
 )
 
+// TestMain points HOME at a scratch directory so no test writes to the
+// real ~/.config/tmux-agent.
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "tmux-agent-test-home")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("HOME", home)
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+// useFakeTmux routes tmux commands to an in-memory server for one test.
+// Its panes have no real processes, so CPU sampling sees an empty table.
+func useFakeTmux(t *testing.T, panes ...*runner.FakePane) *runner.Fake {
+	t.Helper()
+	fake := runner.NewFake(panes...)
+	orig, origCPU := tmuxRunner, cpuSampleFn
+	tmuxRunner = fake
+	cpuSampleFn = func() (*cpuUsage, error) { return &cpuUsage{}, nil }
+	t.Cleanup(func() { tmuxRunner, cpuSampleFn = orig, origCPU })
+	return fake
+}
+
 func TestParsePaneList(t *testing.T) {
 	input := "%3\tclaude\t12345\n%5\tnode\t12346\n%8\tbash\t12347\n%10\tcodex\t12348\n"
 
@@ -29,6 +57,34 @@ func TestParsePaneList(t *testing.T) {
 	}
 }
 
+func TestFindAgentCommand(t *testing.T) {
+	psOut := `  100     1 zsh      -zsh
+  200   100 claude   claude --model opus --permission-mode plan
+  300   200 node     node /tmp/mcp-server.js
+  400     1 zsh      -zsh
+  500   400 codex    node /usr/lib/node_modules/@openai/codex/bin/codex.js -m o3
+  600     1 zsh      -zsh
+  700   600 bash     bash -c sleep 1
+  800   700 claude   /home/me/.local/bin/claude --append-system-prompt be $terse
+  900     1 claude   claude
+`
+	tests := []struct {
+		panePID, want string
+	}{
+		{"100", "claude --model opus --permission-mode plan"},
+		{"400", "codex -m o3"},
+		{"600", "claude --append-system-prompt be '$terse'"},
+		{"900", "claude"},
+		{"300", ""},
+		{"999", ""},
+	}
+	for _, tt := range tests {
+		if got := findAgentCommand(psOut, tt.panePID); got != tt.want {
+			t.Errorf("findAgentCommand(%s) = %q, want %q", tt.panePID, got, tt.want)
+		}
+	}
+}
+
 func TestFindTargetChild(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -170,7 +226,11 @@ func TestStatusShort(t *testing.T) {
 		{ID: "%4", Command: "codex", LastChangeAt: time.Now()},
 	}
 
-	got := statusShort(panes, 10*time.Minute)
+	var states []string
+	for i := range panes {
+		states = append(states, paneState(&panes[i], 10*time.Minute, &busyChecker{percent: -1}))
+	}
+	got := statusShort(states)
 	if !strings.Contains(got, "3 active") {
 		t.Errorf("expected '3 active', got: %s", got)
 	}
@@ -223,8 +283,9 @@ echo "$@" >> `+argsFile+`
 	}
 	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
 
-	if len(lines) != 3 {
-		t.Fatalf("expected 3 tmux invocations, got %d: %v", len(lines), lines)
+	// The fourth call looks up the pane's directory for the sent-prompt log.
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 tmux invocations, got %d: %v", len(lines), lines)
 	}
 
 	if !strings.Contains(lines[0], "send-keys") || !strings.Contains(lines[0], "-l") {
@@ -404,3 +465,102 @@ esac
 		t.Errorf("expected pane ID %%99, got %q", paneID)
 	}
 }
+
+func TestSendTmuxKeys_Fake(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "claude"})
+
+	if err := sendTmuxKeys("%5", "run the tests\n"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fake.Pane("%5").Input; len(got) != 3 || got[0] != "run the tests" || got[1] != "C-m" {
+		t.Errorf("unexpected keys: %q", got)
+	}
+	if err := sendTmuxKeys("%9", "hello"); err == nil {
+		t.Error("expected error for unknown pane")
+	}
+}
+
+func TestPasteTmuxText_Fake(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%5", Command: "claude"})
+
+	if err := pasteTmuxText("%5", "diff --git a/x b/x\r\n+added\n\n"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fake.Pane("%5").Input; len(got) != 3 || got[0] != "diff --git a/x b/x\n+added" || got[1] != "C-m" {
+		t.Errorf("unexpected input: %q", got)
+	}
+	if len(fake.Buffers) != 0 {
+		t.Errorf("expected the paste buffer to be deleted, got %v", fake.Buffers)
+	}
+	var bracketed bool
+	for _, c := range fake.Calls {
+		if c[0] == "paste-buffer" && slices.Contains(c, "-p") {
+			bracketed = true
+		}
+	}
+	if !bracketed {
+		t.Error("expected a bracketed paste")
+	}
+}
+
+func TestSendTmuxKeysEnter(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude"},
+		&runner.FakePane{ID: "%2", Command: "codex"},
+	)
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	os.MkdirAll(filepath.Join(dir, ".config", "tmux-agent"), 0755)
+	os.WriteFile(filepath.Join(dir, ".config", "tmux-agent", "config.json"), []byte(`{
+  "agents": {"codex": {"enter_count": 1}}
+}`), 0644)
+
+	sendTmuxKeys("%1", "a")
+	sendTmuxKeys("%2", "b")
+	sendTmuxKeysEnter("%2", "c", 0)
+	sendTmuxKeysEnter("%1", "d", 3)
+	if got, want := fake.Pane("%1").Input, []string{"a", "C-m", "C-m", "d", "C-m", "C-m", "C-m"}; !slices.Equal(got, want) {
+		t.Errorf("claude input = %q, want %q", got, want)
+	}
+	if got, want := fake.Pane("%2").Input, []string{"b", "C-m", "c"}; !slices.Equal(got, want) {
+		t.Errorf("codex input = %q, want %q", got, want)
+	}
+}
+
+func TestSendTmuxText(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%1", Command: "claude"})
+
+	sendTmuxText("%1", "one line\n", enterDefault, false)
+	sendTmuxText("%1", "first\n\n  second\n", enterDefault, false)
+	sendTmuxText("%1", "/review", 0, true)
+	want := []string{"one line", "C-m", "C-m", "first\n\n  second", "C-m", "C-m", "/review"}
+	if got := fake.Pane("%1").Input; !slices.Equal(got, want) {
+		t.Errorf("input = %q, want %q", got, want)
+	}
+	pastes := 0
+	for _, c := range fake.Calls {
+		if c[0] == "paste-buffer" {
+			pastes++
+		}
+	}
+	if pastes != 2 {
+		t.Errorf("expected 2 pastes, got %d", pastes)
+	}
+}
+
+func TestParsePaneListAll_SessionAndWindow(t *testing.T) {
+	input := "%3\tclaude\t12345\t/work/api\timpl\tdev\t2\n%4\tcodex\t12346\t/work/web\ta\tb\tdocs\t0\n%5\tcodex\t12347\n"
+	panes := parsePaneListAll(input, true)
+	if len(panes) != 3 {
+		t.Fatalf("expected 3 panes, got %d", len(panes))
+	}
+	if p := panes[0]; p.Title != "impl" || p.Session != "dev" || p.Window != "2" {
+		t.Errorf("unexpected pane %+v", p)
+	}
+	if p := panes[1]; p.Title != "a\tb" || p.Session != "docs" || p.Window != "0" {
+		t.Errorf("expected a title with a tab to be kept whole, got %+v", p)
+	}
+	if p := panes[2]; p.Dir != "" || p.Session != "" {
+		t.Errorf("expected missing columns to be empty, got %+v", p)
+	}
+}
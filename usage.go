@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// usageFile holds token and cost usage per pane and day.
+const usageFile = "usage.json"
+
+// defaultUsageWindow is how far back `usage` reports without --since.
+const defaultUsageWindow = 7 * 24 * time.Hour
+
+// usageCaptureLines is how much scrollback `usage` reads from each pane,
+// enough to find a /cost summary printed a while ago.
+const usageCaptureLines = 200
+
+// usageRetention is how long daily usage entries are kept.
+const usageRetention = 90 * 24 * time.Hour
+
+// usageDay is the layout of usageEntry.Day.
+const usageDay = "2006-01-02"
+
+var (
+	// usageCostRe matches claude's cost summary, e.g. "Total cost: $1.23".
+	usageCostRe = regexp.MustCompile(`(?i)\btotal cost:\s*\$(\d+(?:\.\d+)?)`)
+	// usageClaudeTokensRe matches claude's "Usage: 12.3k input, 4.5k output".
+	usageClaudeTokensRe = regexp.MustCompile(`(?i)\busage:\s*(\d+(?:\.\d+)?[km]?)\s+input,\s*(\d+(?:\.\d+)?[km]?)\s+output`)
+	// usageCodexTokensRe matches codex's "Token usage: total=15,000
+	// input=12,000 (+ 40,000 cached) output=3,000".
+	usageCodexTokensRe = regexp.MustCompile(`(?i)\btoken usage:.*?\binput=([\d,]+)(?:\s*\(\+\s*[\d,]+\s+cached\))?\s+output=([\d,]+)`)
+)
+
+// usageTotals are tokens and cost, either as an agent reports them for its
+// session so far or as the spend added up for a pane and day.
+type usageTotals struct {
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd,omitempty"`
+}
+
+// add adds o to u.
+func (u *usageTotals) add(o usageTotals) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CostUSD += o.CostUSD
+}
+
+// since returns the spend from last to u, two reports of the same session.
+// A report below last is a new session, all of whose spend is new.
+func (u usageTotals) since(last usageTotals) usageTotals {
+	if u.InputTokens < last.InputTokens || u.OutputTokens < last.OutputTokens || u.CostUSD < last.CostUSD {
+		return u
+	}
+	return usageTotals{
+		InputTokens:  u.InputTokens - last.InputTokens,
+		OutputTokens: u.OutputTokens - last.OutputTokens,
+		CostUSD:      u.CostUSD - last.CostUSD,
+	}
+}
+
+// isZero reports whether u holds no usage.
+func (u usageTotals) isZero() bool {
+	return u.InputTokens == 0 && u.OutputTokens == 0 && u.CostUSD == 0
+}
+
+// usageEntry is the spend of one pane on one day.
+type usageEntry struct {
+	Pane  string `json:"pane"`
+	Agent string `json:"agent"`
+	Repo  string `json:"repo,omitempty"`
+	Day   string `json:"day"`
+	usageTotals
+}
+
+// usageState is the persisted usage: the spend per pane and day, and the
+// session totals each pane last showed, to tell new spend from old.
+type usageState struct {
+	Entries []usageEntry           `json:"entries"`
+	Last    map[string]usageTotals `json:"last,omitempty"`
+}
+
+// usageRow is one line of `usage`: the spend grouped by pane, repo or day.
+type usageRow struct {
+	Name string `json:"name"`
+	usageTotals
+}
+
+// usageJSON is the output of `usage --json`.
+type usageJSON struct {
+	Since time.Time  `json:"since"`
+	Panes []usageRow `json:"panes,omitempty"`
+	Repos []usageRow `json:"repos,omitempty"`
+	Days  []usageRow `json:"days,omitempty"`
+}
+
+// parseTokenCount parses a token count such as "12,000", "12.3k" or "1.2m".
+func parseTokenCount(s string) int64 {
+	s = strings.ToLower(strings.ReplaceAll(s, ",", ""))
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		mult, s = 1e3, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "m"):
+		mult, s = 1e6, strings.TrimSuffix(s, "m")
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(f*mult + 0.5)
+}
+
+// parseUsage finds the latest token and cost summary an agent printed in
+// output: claude's /cost report or codex's token usage line.
+func parseUsage(output string) (usageTotals, bool) {
+	var u usageTotals
+	var tokens, cost bool
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0 && !(tokens && cost); i-- {
+		line := lines[i]
+		if !cost {
+			if m := usageCostRe.FindStringSubmatch(line); m != nil {
+				u.CostUSD, _ = strconv.ParseFloat(m[1], 64)
+				cost = true
+			}
+		}
+		if !tokens {
+			m := usageClaudeTokensRe.FindStringSubmatch(line)
+			if m == nil {
+				m = usageCodexTokensRe.FindStringSubmatch(line)
+			}
+			if m != nil {
+				u.InputTokens, u.OutputTokens = parseTokenCount(m[1]), parseTokenCount(m[2])
+				tokens = true
+			}
+		}
+	}
+	return u, tokens || cost
+}
+
+// loadUsage returns the persisted usage.
+func loadUsage() (*usageState, error) {
+	s := &usageState{}
+	if err := loadState(usageFile, s); err != nil {
+		return nil, err
+	}
+	if s.Last == nil {
+		s.Last = make(map[string]usageTotals)
+	}
+	return s, nil
+}
+
+// observe records the usage summary in p's output, if any, adding what is
+// new since the pane's last summary to its entry for today. It reports
+// whether anything was added.
+func (s *usageState) observe(p *paneInfo, output string, now time.Time) bool {
+	cur, ok := parseUsage(output)
+	if !ok {
+		return false
+	}
+	last, seen := s.Last[p.ID]
+	s.Last[p.ID] = cur
+	if seen && cur == last {
+		return false
+	}
+	delta := cur.since(last)
+	if delta.isZero() {
+		return false
+	}
+	day := now.Format(usageDay)
+	for i := range s.Entries {
+		e := &s.Entries[i]
+		if e.Pane == p.ID && e.Agent == p.Command && e.Day == day {
+			e.add(delta)
+			return true
+		}
+	}
+	s.Entries = append(s.Entries, usageEntry{Pane: p.ID, Agent: p.Command, Repo: shortDir(p.Dir), Day: day, usageTotals: delta})
+	return true
+}
+
+// prune drops the entries older than usageRetention, and the last totals
+// of panes that are no longer among panes.
+func (s *usageState) prune(now time.Time, panes []paneInfo) {
+	first := now.Add(-usageRetention).Format(usageDay)
+	var entries []usageEntry
+	for _, e := range s.Entries {
+		if e.Day >= first {
+			entries = append(entries, e)
+		}
+	}
+	s.Entries = entries
+	live := make(map[string]bool)
+	for _, p := range panes {
+		live[p.ID] = true
+	}
+	for id := range s.Last {
+		if !live[id] {
+			delete(s.Last, id)
+		}
+	}
+}
+
+// saveUsage prunes and saves usage updated during a scan of panes.
+func saveUsage(s *usageState, panes []paneInfo) error {
+	s.prune(time.Now(), panes)
+	return saveState(usageFile, s)
+}
+
+// aggregateUsage adds up entries by the name key returns.
+func aggregateUsage(entries []usageEntry, key func(e usageEntry) string) []usageRow {
+	byName := make(map[string]*usageRow)
+	var rows []*usageRow
+	for _, e := range entries {
+		name := key(e)
+		if name == "" {
+			name = "(unknown)"
+		}
+		r, ok := byName[name]
+		if !ok {
+			r = &usageRow{Name: name}
+			byName[name] = r
+			rows = append(rows, r)
+		}
+		r.add(e.usageTotals)
+	}
+	out := make([]usageRow, len(rows))
+	for i, r := range rows {
+		out[i] = *r
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// runUsage reads the token and cost summaries the agent panes show now,
+// then prints the spend recorded (by watch and earlier runs) per pane, repo
+// and day.
+func runUsage(args []string, w io.Writer) error {
+	window := defaultUsageWindow
+	by := ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil {
+					return fmt.Errorf("invalid --since value: %s", args[i])
+				}
+				window = d
+			}
+		case "--by":
+			if i+1 < len(args) {
+				i++
+				by = args[i]
+			}
+		default:
+			return fmt.Errorf("usage: tmux-agent usage [--since 168h] [--by pane|repo|day]")
+		}
+	}
+	switch by {
+	case "", "pane", "repo", "day":
+	default:
+		return fmt.Errorf("invalid --by value: %s (want pane, repo or day)", by)
+	}
+
+	s, err := loadUsage()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", usageFile, err)
+	}
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return err
+	}
+	changed := false
+	now := time.Now()
+	for i := range panes {
+		output, err := capturePaneOutput(panes[i].ID, usageCaptureLines)
+		if err == nil && s.observe(&panes[i], output, now) {
+			changed = true
+		}
+	}
+	if changed {
+		if err := saveUsage(s, panes); err != nil {
+			return err
+		}
+	}
+
+	since := now.Add(-window)
+	first := since.Format(usageDay)
+	var entries []usageEntry
+	for _, e := range s.Entries {
+		if e.Day >= first {
+			entries = append(entries, e)
+		}
+	}
+	out := usageJSON{Since: since}
+	if by == "" || by == "pane" {
+		out.Panes = aggregateUsage(entries, func(e usageEntry) string { return e.Pane + " " + e.Agent })
+	}
+	if by == "" || by == "repo" {
+		out.Repos = aggregateUsage(entries, func(e usageEntry) string { return e.Repo })
+	}
+	if by == "" || by == "day" {
+		out.Days = aggregateUsage(entries, func(e usageEntry) string { return e.Day })
+	}
+	if jsonOutput {
+		return writeJSON(w, out)
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No token or cost usage recorded (agents print it with /cost, or on exit)")
+		return nil
+	}
+	sep := ""
+	for _, t := range []struct {
+		column string
+		rows   []usageRow
+	}{{"PANE", out.Panes}, {"REPO", out.Repos}, {"DAY", out.Days}} {
+		if t.rows == nil {
+			continue
+		}
+		fmt.Fprint(w, sep)
+		writeUsageTable(w, t.column, t.rows)
+		sep = "\n"
+	}
+	return nil
+}
+
+// writeUsageTable prints one usage table with the given name column.
+func writeUsageTable(w io.Writer, column string, rows []usageRow) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tINPUT\tOUTPUT\tCOST\n", column)
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t$%.2f\n", r.Name, r.InputTokens, r.OutputTokens, r.CostUSD)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestParseUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   usageTotals
+		ok     bool
+	}{
+		{"claude cost", "> /cost\n  ⎿  Total cost:            $1.25\n     Total duration (API):  4m 2s\n     Usage:                 12.5k input, 3.2k output, 150k cache read",
+			usageTotals{InputTokens: 12500, OutputTokens: 3200, CostUSD: 1.25}, true},
+		{"codex tokens", "Token usage: total=15,300 input=12,000 (+ 40,000 cached) output=3,300\nTo continue this session, run codex resume",
+			usageTotals{InputTokens: 12000, OutputTokens: 3300}, true},
+		{"latest wins", "Total cost: $0.50\n...\nTotal cost: $0.75", usageTotals{CostUSD: 0.75}, true},
+		{"none", "all tests passed", usageTotals{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseUsage(tt.output)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("got %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestUsageObserve(t *testing.T) {
+	s := &usageState{Last: make(map[string]usageTotals)}
+	p := &paneInfo{ID: "%1", Command: "claude", Dir: "/src/github.com/owner/repo"}
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+
+	if !s.observe(p, "Total cost: $1.00", day1) {
+		t.Fatal("expected the first summary to be recorded")
+	}
+	if s.observe(p, "Total cost: $1.00", day1.Add(time.Minute)) {
+		t.Error("expected the same summary to be ignored")
+	}
+	s.observe(p, "Total cost: $1.50", day1.Add(time.Hour))
+	// A new session starts over from zero.
+	s.observe(p, "Total cost: $0.20", day1.Add(24*time.Hour))
+
+	if len(s.Entries) != 2 {
+		t.Fatalf("expected one entry per day, got %+v", s.Entries)
+	}
+	if e := s.Entries[0]; e.Day != "2026-03-01" || e.CostUSD != 1.50 || e.Repo != "owner/repo" {
+		t.Errorf("unexpected first day: %+v", e)
+	}
+	if e := s.Entries[1]; e.Day != "2026-03-02" || e.CostUSD != 0.20 {
+		t.Errorf("unexpected second day: %+v", e)
+	}
+}
+
+func TestUsagePrune(t *testing.T) {
+	now := time.Date(2026, 6, 1, 10, 0, 0, 0, time.Local)
+	s := &usageState{
+		Entries: []usageEntry{
+			{Pane: "%1", Day: now.Add(-100 * 24 * time.Hour).Format(usageDay)},
+			{Pane: "%1", Day: now.Format(usageDay)},
+		},
+		Last: map[string]usageTotals{"%1": {CostUSD: 1}, "%2": {CostUSD: 2}},
+	}
+	s.prune(now, []paneInfo{{ID: "%1"}})
+	if len(s.Entries) != 1 || s.Entries[0].Day != "2026-06-01" {
+		t.Errorf("expected only the recent entry, got %+v", s.Entries)
+	}
+	if _, ok := s.Last["%2"]; ok || len(s.Last) != 1 {
+		t.Errorf("expected the gone pane's totals to be dropped, got %+v", s.Last)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Dir: "/src/github.com/owner/api", Output: "Total cost: $2.00\nUsage: 10k input, 2k output"},
+		&runner.FakePane{ID: "%2", Command: "codex", Dir: "/src/github.com/owner/api", Output: "Token usage: total=1,500 input=1,000 output=500"},
+	)
+
+	var buf bytes.Buffer
+	if err := runUsage(nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"PANE", "REPO", "DAY", "owner/api", "11000", "2500", "$2.00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	// Running again counts nothing twice.
+	fake.Pane("%1").Output = "Total cost: $3.00\nUsage: 12k input, 3k output"
+	buf.Reset()
+	if err := runUsage([]string{"--by", "repo"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "$3.00") || strings.Contains(buf.String(), "PANE") {
+		t.Errorf("unexpected repo usage:\n%s", buf.String())
+	}
+	if err := runUsage([]string{"--by", "week"}, &buf); err == nil {
+		t.Error("expected an error for an unknown --by")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// waitPollInterval is how often wait checks the pane. Tests shorten it.
+var waitPollInterval = 2 * time.Second
+
+// Reasons wait returns for.
+const (
+	waitIdle  = "idle"
+	waitMatch = "match"
+)
+
+// waitJSON is the JSON result of wait.
+type waitJSON struct {
+	Pane   string `json:"pane"`
+	Reason string `json:"reason"`
+	// Line is the output line that matched --match.
+	Line        string `json:"line,omitempty"`
+	IdleSeconds int64  `json:"idle_seconds,omitempty"`
+	// Outcome and Error classify the pane's output, as in run.
+	Outcome string `json:"outcome,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// checkWait captures pane paneID once and reports why the wait is over:
+// waitMatch with the latest matching line if its output since before (a
+// capture of its scrollback when the wait started) matches re (when set),
+// or waitIdle if the pane is idle by threshold (when set). An empty reason
+// means keep waiting.
+func checkWait(paneID string, threshold func(agent string) time.Duration, backend string, busy *busyChecker, re *regexp.Regexp, before string) (waitJSON, error) {
+	p, ok := lookupPane(paneID)
+	if !ok {
+		return waitJSON{}, fmt.Errorf("pane %s not found", paneID)
+	}
+	panes := []paneInfo{p}
+	trackPanes(panes, false)
+	if backend == idleBackendTmux {
+		if err := applyPaneActivity(panes); err != nil {
+			return waitJSON{}, err
+		}
+	}
+	p = panes[0]
+	if re != nil {
+		after, err := captureScrollback(paneID)
+		if err != nil {
+			return waitJSON{}, err
+		}
+		lines := strings.Split(newOutput(before, after), "\n")
+		for i := len(lines) - 1; i >= 0; i-- {
+			if re.MatchString(lines[i]) {
+				return waitJSON{Pane: p.ID, Reason: waitMatch, Line: lines[i]}, nil
+			}
+		}
+	}
+	if threshold != nil && paneState(&p, threshold(p.Command), busy) == stateIdle {
+		return waitJSON{Pane: p.ID, Reason: waitIdle, IdleSeconds: idleSeconds(&p)}, nil
+	}
+	return waitJSON{Pane: p.ID}, nil
+}
+
+// runWait blocks until a pane goes idle or its new output matches a
+// pattern. It fails when the pane's output then ends in a match of its
+// error_patterns.
+// Without --idle or --match it waits for the agent's idle threshold; with
+// only --match it waits for the match alone.
+func runWait(args []string, w io.Writer) error {
+	if len(args) < 1 || len(args[0]) == 0 || args[0][0] == '-' {
+		return fmt.Errorf("usage: tmux-agent wait <pane_id> [--idle duration] [--match regex] [--timeout duration]")
+	}
+	paneID := args[0]
+	var idle, timeout time.Duration
+	var re *regexp.Regexp
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--idle":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil || d <= 0 {
+					return fmt.Errorf("invalid --idle value: %s", args[i])
+				}
+				idle = d
+			}
+		case "--timeout":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil || d <= 0 {
+					return fmt.Errorf("invalid --timeout value: %s", args[i])
+				}
+				timeout = d
+			}
+		case "--match":
+			if i+1 < len(args) {
+				i++
+				var err error
+				if re, err = regexp.Compile(args[i]); err != nil {
+					return fmt.Errorf("invalid --match pattern: %w", err)
+				}
+			}
+		}
+	}
+
+	cfg := loadConfig()
+	var threshold func(agent string) time.Duration
+	if idle > 0 || re == nil {
+		var err error
+		if threshold, err = cfg.idleThresholds(idle); err != nil {
+			return err
+		}
+	}
+	backend, err := cfg.idleBackend("")
+	if err != nil {
+		return err
+	}
+	busy := cfg.busyChecker()
+	// Only output that appears from now on can match, not a line already
+	// on screen.
+	before := ""
+	if re != nil {
+		if before, err = captureScrollback(paneID); err != nil {
+			return err
+		}
+	}
+
+	res, err := pollUntil(timeout, "pane "+paneID, func() (waitJSON, error) {
+		return checkWait(paneID, threshold, backend, busy.fresh(), re, before)
+	})
+	if err != nil {
+		return err
+	}
+	output, err := captureScrollback(paneID)
+	if err != nil {
+		return err
+	}
+	if res.Outcome, res.Error, err = paneOutcome(paneID, output); err != nil {
+		return err
+	}
+	if jsonOutput {
+		err = writeJSON(w, res)
+	} else if res.Reason == waitMatch {
+		fmt.Fprintf(w, "Pane %s matched: %s\n", res.Pane, res.Line)
+	} else {
+		fmt.Fprintf(w, "Pane %s is idle (%s)\n", res.Pane, formatDuration(time.Duration(res.IdleSeconds)*time.Second))
+	}
+	if err == nil && res.Outcome == taskFailed {
+		err = taskFailedError(paneID, res.Error)
+	}
+	return err
+}
+
+// pollUntil calls check every waitPollInterval until it returns a reason
+// or an error, or until timeout (if positive) has passed. what names the
+// awaited thing in the timeout error.
+func pollUntil(timeout time.Duration, what string, check func() (waitJSON, error)) (waitJSON, error) {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		deadline = time.After(timeout)
+	}
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+	for {
+		res, err := check()
+		if err != nil || res.Reason != "" {
+			return res, err
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return waitJSON{}, fmt.Errorf("timed out after %s waiting for %s", timeout, what)
+		}
+	}
+}
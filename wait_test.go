@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestRunWait(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Output: "ok  \texample.com/old\nRunning tests"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	orig := waitPollInterval
+	waitPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { waitPollInterval = orig })
+
+	// A match already on screen does not count.
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		fake.Print("%1", "\nok  \texample.com/a")
+	}()
+	var buf bytes.Buffer
+	if err := runWait([]string{"%1", "--match", `^ok\s`}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "Pane %1 matched: ok  \texample.com/a\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+
+	// The output just changed, so the pane is not idle yet.
+	err := runWait([]string{"%1", "--match", "FAIL", "--idle", "1h", "--timeout", "50ms"}, &buf)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms waiting for pane %1") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+
+	saveState(paneStateFile, map[string]trackedOutput{
+		"%1": {Hash: outputHash(fake.Pane("%1").Output), Changed: time.Now().Add(-10 * time.Minute)},
+	})
+	buf.Reset()
+	if err := runWait([]string{"%1", "--idle", "5m"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "Pane %1 is idle (10m00s)\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+
+	if err := runWait([]string{"%9", "--idle", "5m"}, &buf); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a missing pane error, got %v", err)
+	}
+	if err := runWait([]string{"%1", "--match", "("}, &buf); err == nil {
+		t.Error("expected an invalid pattern error")
+	}
+}
+
+func TestRunWait_ErrorPatterns(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "codex", Output: "--- FAIL: TestParse\nFixed it\nok  \texample.com/a"},
+		&runner.FakePane{ID: "%2", Command: "codex", Output: "ok  \texample.com/a\n--- FAIL: TestLex"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	saveConfig(&agentConfig{Agents: map[string]*agentProfile{
+		"codex": {outcomePatterns: outcomePatterns{ErrorPatterns: []string{"^--- FAIL"}, SuccessPatterns: []string{`^ok\s`}}},
+	}})
+	saveState(paneStateFile, map[string]trackedOutput{
+		"%1": {Hash: outputHash("--- FAIL: TestParse\nFixed it\nok  \texample.com/a"), Changed: time.Now().Add(-time.Hour)},
+		"%2": {Hash: outputHash("ok  \texample.com/a\n--- FAIL: TestLex"), Changed: time.Now().Add(-time.Hour)},
+	})
+
+	// The last matching line decides, so the later success wins.
+	if err := runWait([]string{"%1", "--idle", "1m"}, io.Discard); err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+	var buf bytes.Buffer
+	err := runWait([]string{"%2", "--idle", "1m"}, &buf)
+	if err == nil || err.Error() != "pane %2 failed: --- FAIL: TestLex" || !strings.HasPrefix(buf.String(), "Pane %2 is idle") {
+		t.Errorf("expected the idle pane to be reported as failed, got %v (%q)", err, buf.String())
+	}
+}
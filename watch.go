@@ -4,20 +4,471 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
 const defaultScanInterval = 10 * time.Second
 
-// runWatch monitors tmux panes and logs idle detection.
-func runWatch(args []string) error {
+// watcher holds the state watch keeps between scans.
+type watcher struct {
+	scanInterval  time.Duration
+	idleThreshold func(agent string) time.Duration
+	// idleBackend is idleBackendOutput or idleBackendTmux.
+	idleBackend string
+	hooks       map[string]string
+	tracker     *outputTracker
+	// busy holds the busy(cpu) settings; the process table is read again
+	// on every scan.
+	busy *busyChecker
+	// seen holds the panes found by the previous scan, so panes that
+	// disappear can be reported.
+	seen map[string]paneInfo
+	// panes holds the panes of the last scan, in tmux order.
+	panes []paneInfo
+	// notice detects rate-limit, compaction, approval and waiting messages;
+	// notices holds the one each pane showed on the previous scan.
+	notice  *noticeDetector
+	notices map[string]paneNotice
+	// autoResume, if set, resumes rate-limited panes after their reset
+	// time; resumes holds when each waiting pane is due.
+	autoResume *autoResume
+	resumes    map[string]time.Time
+	// quotaSeen holds the last usage message recorded for each pane.
+	quotaSeen map[string]string
+	// states holds each pane's state on the previous scan; notify is the
+	// --notify value, e.g. notifyDesktop, or empty.
+	states map[string]string
+	notify string
+	// webhook is the URL idle and closed panes are posted to, or empty.
+	webhook string
+	// notifiers are the configured chat notifiers.
+	notifiers []*notifierConfig
+	// contextWarning is the context_warning percentage (0 = off);
+	// contextLow holds the panes already warned about.
+	contextWarning int
+	contextLow     map[string]bool
+	// nudge, if set, prompts panes that stay idle too long.
+	nudge *nudger
+	// events, if set, receives the --event-log lines.
+	events io.Writer
+	// lastDelivery is closed once the latest hook, webhook or chat
+	// notification handed to deliver is done; pending counts those not
+	// done yet.
+	deliveryMu   sync.Mutex
+	lastDelivery chan struct{}
+	pending      sync.WaitGroup
+	// metrics, if set, is updated after every scan for --metrics-listen.
+	metrics *watchMetrics
+	logger  *slog.Logger
+}
+
+// newWatcher returns a watcher with empty pane state and the hooks from
+// the config file.
+func newWatcher(scanInterval time.Duration, idleThreshold func(agent string) time.Duration, logger *slog.Logger) *watcher {
+	cfg := loadConfig()
+	return &watcher{
+		scanInterval:   scanInterval,
+		idleThreshold:  idleThreshold,
+		idleBackend:    idleBackendOutput,
+		hooks:          cfg.Hooks,
+		notifiers:      cfg.Notifiers,
+		busy:           cfg.busyChecker(),
+		tracker:        loadOutputTracker(),
+		seen:           make(map[string]paneInfo),
+		notice:         newNoticeDetector(cfg),
+		notices:        make(map[string]paneNotice),
+		resumes:        make(map[string]time.Time),
+		quotaSeen:      make(map[string]string),
+		states:         make(map[string]string),
+		contextWarning: cfg.contextWarning(),
+		contextLow:     make(map[string]bool),
+		logger:         logger,
+	}
+}
+
+// deliver runs fn, which runs a hook or posts a notification, in the
+// background so that a slow hook or endpoint does not hold up the scan.
+// Deliveries still run one at a time, in the order they were handed in.
+func (wt *watcher) deliver(fn func()) {
+	wt.deliveryMu.Lock()
+	defer wt.deliveryMu.Unlock()
+	prev, done := wt.lastDelivery, make(chan struct{})
+	wt.lastDelivery = done
+	wt.pending.Add(1)
+	go func() {
+		defer wt.pending.Done()
+		defer close(done)
+		if prev != nil {
+			<-prev
+		}
+		fn()
+	}()
+}
+
+// flush waits for the deliveries started so far to finish.
+func (wt *watcher) flush() {
+	wt.pending.Wait()
+}
+
+// scan checks every agent pane once: it logs idle panes, records activity
+// samples, and reports panes that have closed since the last scan. It fails
+// only if the panes cannot be listed.
+func (wt *watcher) scan() error {
+	panes, err := listTmuxPanes()
+	if err != nil {
+		return fmt.Errorf("listing panes: %w", err)
+	}
+	logReattach(panes, wt.logger)
+	if wt.idleBackend == idleBackendTmux {
+		if err := applyPaneActivity(panes); err != nil {
+			wt.logger.Warn("reading pane activity failed", "err", err)
+		}
+	}
+
+	live := make(map[string]bool)
+	var samples []activityRecord
+	busy := wt.busy.fresh()
+	// The token and cost summaries the panes show are recorded for usage.
+	usage, err := loadUsage()
+	if err != nil {
+		wt.logger.Warn("reading usage failed", "err", err)
+	}
+	usageChanged := false
+	for i := range panes {
+		live[panes[i].ID] = true
+		wt.seen[panes[i].ID] = panes[i]
+
+		output, err := capturePaneOutput(panes[i].ID, noticeCaptureLines)
+		if err != nil {
+			continue
+		}
+		if wt.idleBackend == idleBackendOutput {
+			wt.tracker.observe(&panes[i], output)
+		} else {
+			panes[i].LastOutput = output
+		}
+
+		state := paneState(&panes[i], wt.idleThreshold(panes[i].Command), busy)
+		notice, msg := wt.checkNotice(panes[i], output)
+		if notice != "" {
+			state = notice
+		}
+		wt.checkQuota(panes[i], notice, msg, output)
+		if usage != nil && usage.observe(&panes[i], output, time.Now()) {
+			usageChanged = true
+		}
+		// A pane handed its next queued prompt is busy again, not waiting.
+		if wt.checkQueue(&panes[i], state) {
+			state = stateActive
+		}
+		wt.checkTransition(&panes[i], state)
+		wt.checkContext(&panes[i], output)
+		wt.checkNudge(&panes[i], state)
+		switch state {
+		case stateIdle:
+			wt.logger.Info("pane idle", append(paneAttrs(&panes[i]),
+				"idle", time.Since(panes[i].LastChangeAt).Truncate(time.Second))...)
+			wt.notifyChat(eventPaneIdle, &panes[i], "")
+		case stateBusyCPU:
+			wt.logger.Debug("pane busy without output", append(paneAttrs(&panes[i]),
+				"idle", time.Since(panes[i].LastChangeAt).Truncate(time.Second))...)
+		}
+		samples = append(samples, activityRecord{
+			Time:  time.Now(),
+			Kind:  activitySample,
+			Pane:  panes[i].ID,
+			Agent: panes[i].Command,
+			Repo:  shortDir(panes[i].Dir),
+			State: state,
+			Span:  wt.scanInterval.Seconds(),
+		})
+	}
+
+	for id, p := range wt.seen {
+		if live[id] {
+			continue
+		}
+		delete(wt.seen, id)
+		wt.tracker.forget(id)
+		delete(wt.notices, id)
+		delete(wt.resumes, id)
+		delete(wt.quotaSeen, id)
+		delete(wt.states, id)
+		delete(wt.contextLow, id)
+		if wt.nudge != nil {
+			wt.nudge.forget(id)
+		}
+		wt.paneClosed(p)
+		samples = append(samples, activityRecord{
+			Time:  time.Now(),
+			Kind:  activityPaneClosed,
+			Pane:  p.ID,
+			Agent: p.Command,
+			Repo:  shortDir(p.Dir),
+		})
+	}
+
+	if wt.autoResume != nil {
+		wt.runResumes(panes)
+	}
+
+	if usageChanged {
+		if err := saveUsage(usage, panes); err != nil {
+			wt.logger.Warn("recording usage failed", "err", err)
+		}
+	}
+	if err := appendActivity(samples...); err != nil {
+		wt.logger.Warn("recording activity failed", "err", err)
+	}
+	if wt.idleBackend == idleBackendOutput {
+		wt.tracker.prune(panes)
+		if err := wt.tracker.save(); err != nil {
+			wt.logger.Warn("saving pane state failed", "err", err)
+		}
+	}
+	wt.logScan(wt.states)
+	wt.panes = panes
+	if wt.metrics != nil {
+		wt.metrics.update(panes, wt.states)
+	}
+	wt.logger.Debug("scan complete", "panes", len(panes))
+	return nil
+}
+
+// paneNotice is the rate-limit, compaction, approval or waiting message a
+// pane is showing.
+type paneNotice struct {
+	state   string
+	message string
+}
+
+// checkNotice looks for a rate-limit, compaction, approval or waiting
+// message in a pane's output and returns the matching state and message.
+// The first scan that sees a new notice logs it and runs its hook. Rate
+// limits are also shown on the tmux status line, with an auto-resume
+// scheduled if enabled, and permission prompts alerted right away (see
+// alertApproval). A new message (e.g. a later reset time) counts as a new
+// notice.
+func (wt *watcher) checkNotice(p paneInfo, output string) (string, string) {
+	state, msg, err := wt.notice.detect(&p, output)
+	if err != nil {
+		wt.logger.Warn("checking for notices failed", append(paneAttrs(&p), "err", err)...)
+		return "", ""
+	}
+	if state == "" {
+		if prev, ok := wt.notices[p.ID]; ok {
+			delete(wt.notices, p.ID)
+			delete(wt.resumes, p.ID)
+			wt.logger.Info("pane resumed", append(paneAttrs(&p), "after", prev.state)...)
+		}
+		return "", ""
+	}
+	notice := paneNotice{state: state, message: msg}
+	if wt.notices[p.ID] == notice {
+		return state, msg
+	}
+	wt.notices[p.ID] = notice
+
+	event := eventRateLimited
+	switch state {
+	case stateCompacting:
+		event = eventCompacting
+	case stateWaiting:
+		event = eventPaneWaiting
+	case stateNeedsApproval:
+		event = eventNeedsApproval
+	}
+	wt.logger.Info("pane "+state, append(paneAttrs(&p), "event", event, "message", msg)...)
+	wt.logEvent(newPaneEvent(event, &p, state))
+	if state == stateRateLimited && wt.autoResume != nil {
+		wt.scheduleResume(p, msg)
+	}
+	if state == stateRateLimited {
+		if err := displayTmuxMessage(fmt.Sprintf("tmux-agent: %s (%s) is rate-limited: %q", p.ID, p.Command, msg)); err != nil {
+			wt.logger.Warn("notifying failed", "err", err)
+		}
+	}
+	if state == stateNeedsApproval {
+		wt.alertApproval(&p, msg)
+	}
+	wt.runHook(event, &p, msg)
+	wt.notifyChat(event, &p, msg)
+	return state, msg
+}
+
+// alertApproval tells the user right away that pane p is blocked on a
+// permission prompt: on the tmux status line and, with --notify desktop,
+// in a desktop notification.
+func (wt *watcher) alertApproval(p *paneInfo, msg string) {
+	title := fmt.Sprintf("%s (%s) needs approval", p.ID, p.Command)
+	if err := displayTmuxMessage(fmt.Sprintf("tmux-agent: %s: %q", title, msg)); err != nil {
+		wt.logger.Warn("notifying failed", "err", err)
+	}
+	if wt.notify != notifyDesktop {
+		return
+	}
+	if err := desktopNotifyFn("tmux-agent: "+title, shortDir(p.Dir)+": "+truncateWidth(msg, maxLastOutputWidth)); err != nil {
+		wt.logger.Warn("desktop notification failed", append(paneAttrs(p), "err", err)...)
+	}
+}
+
+// checkTransition remembers each pane's state and reports panes that
+// become idle or active again: it runs the pane_idle, pane_error and
+// pane_active hooks, posts idle panes to the --webhook, and with --notify
+// desktop shows a notification. A pane's first scan only sets its state and
+// logs it as added.
+func (wt *watcher) checkTransition(p *paneInfo, state string) {
+	prev, seen := wt.states[p.ID]
+	wt.states[p.ID] = state
+	if !seen {
+		wt.logEvent(newPaneEvent(eventPaneAdded, p, state))
+		return
+	}
+	switch {
+	case state == stateIdle && prev != stateIdle:
+		wt.logEvent(newPaneEvent(eventPaneIdle, p, state))
+		wt.runHook(eventPaneIdle, p, "")
+		if wt.hooks[eventPaneError] != "" {
+			if excerpt, failed := wt.paneError(p); failed {
+				wt.runHook(eventPaneError, p, excerpt)
+			}
+		}
+		wt.sendWebhook(eventPaneIdle, p)
+	case state == stateActive && prev == stateIdle:
+		wt.logEvent(newPaneEvent(eventPaneActive, p, state))
+		wt.runHook(eventPaneActive, p, "")
+	}
+	if wt.notify != notifyDesktop {
+		return
+	}
+	title, body, ok := idleTransition(p, prev, state)
+	if !ok {
+		return
+	}
+	if err := desktopNotifyFn("tmux-agent: "+title, body); err != nil {
+		wt.logger.Warn("desktop notification failed", append(paneAttrs(p), "err", err)...)
+	}
+}
+
+// paneError reports whether p's output ends in a match of its agent's or
+// project's error_patterns, and returns the matching excerpt.
+func (wt *watcher) paneError(p *paneInfo) (string, bool) {
+	rules, err := loadConfig().outcomeRules(p.Command, p.Dir)
+	if err != nil {
+		wt.logger.Warn("checking for errors failed", append(paneAttrs(p), "err", err)...)
+		return "", false
+	}
+	outcome, excerpt := rules.classify(p.LastOutput)
+	return excerpt, outcome == taskFailed
+}
+
+// checkQuota records usage information shown in a pane for the quota
+// command, once per message.
+func (wt *watcher) checkQuota(p paneInfo, notice, msg, output string) {
+	q, ok := observeQuota(&p, notice, msg, output)
+	if !ok || wt.quotaSeen[p.ID] == q.Message {
+		return
+	}
+	wt.quotaSeen[p.ID] = q.Message
+	if err := recordQuota(q); err != nil {
+		wt.logger.Warn("recording quota failed", append(paneAttrs(&p), "err", err)...)
+	}
+}
+
+// paneClosed logs a pane_closed event and runs its hook, if configured.
+func (wt *watcher) paneClosed(p paneInfo) {
+	wt.logger.Info("pane closed", append(paneAttrs(&p), "event", eventPaneClosed)...)
+	wt.logEvent(newPaneEvent(eventPaneClosed, &p, ""))
+	wt.sendWebhook(eventPaneClosed, &p)
+	wt.notifyChat(eventPaneClosed, &p, "")
+	wt.runHook(eventPaneClosed, &p, "")
+}
+
+// waitingOn returns the panes of the last scan that are not idle.
+func (wt *watcher) waitingOn() []string {
+	var ids []string
+	for _, p := range wt.panes {
+		if wt.states[p.ID] != stateIdle {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids
+}
+
+// watchColumns are the columns of `watch --once`.
+var watchColumns = []tableColumn{
+	{Title: "PANE"},
+	{Title: "COMMAND"},
+	{Title: "STATUS"},
+	{Title: "REPO", Min: 10, Optional: true},
+	{Title: "LAST OUTPUT", Min: 10, Optional: true},
+}
+
+// report writes the panes of the last scan with their states, as a table
+// of the given width.
+func (wt *watcher) report(w io.Writer, width int) error {
+	if jsonOutput {
+		out := make([]paneJSON, len(wt.panes))
+		for i := range wt.panes {
+			p := &wt.panes[i]
+			out[i] = newPaneJSON(p, "")
+			out[i].State, out[i].Message = wt.states[p.ID], wt.notices[p.ID].message
+			out[i].IdleSeconds = idleSeconds(p)
+			if last := lastLines(p.LastOutput, 1); len(last) > 0 {
+				out[i].LastLine = last[0]
+			}
+		}
+		return writeJSON(w, out)
+	}
+	if len(wt.panes) == 0 {
+		fmt.Fprintln(w, "No coding agent panes found")
+		return nil
+	}
+	var rows [][]string
+	for i := range wt.panes {
+		p := &wt.panes[i]
+		status := wt.states[p.ID]
+		if status == stateIdle {
+			status += " " + formatDuration(time.Since(p.LastChangeAt))
+		}
+		lastLine := truncateLastLine(p.LastOutput, maxLastOutputWidth)
+		if msg := wt.notices[p.ID].message; msg != "" {
+			lastLine = truncateWidth(msg, maxLastOutputWidth)
+		}
+		rows = append(rows, []string{p.ID, p.Command, status, shortDir(p.Dir), lastLine})
+	}
+	renderTable(w, width, watchColumns, rows)
+	return nil
+}
+
+// runWatch monitors tmux panes and logs idle detection. With --once it
+// scans once and writes the panes to w; with --until-idle it returns once
+// every pane is idle.
+func runWatch(args []string, w io.Writer) error {
+	if len(args) > 0 && (args[0] == "install-service" || args[0] == "uninstall-service") {
+		return runWatchService(args[0], args[1:], w)
+	}
+
 	scanInterval := defaultScanInterval
-	idleThreshold := defaultIdleThreshold
+	var idle time.Duration
+	backendFlag := ""
+	autoResumeOn := false
+	notify, webhook, eventLog := "", "", ""
+	metricsAddr := ""
+	nudge, nudgeAfter, nudgeLimit := "", time.Duration(0), -1
 	logFile := ""
+	logTarget := "stdout"
+	daemon := false
+	once, untilIdle := false, false
+	var timeout time.Duration
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -37,78 +488,252 @@ func runWatch(args []string) error {
 				if err != nil {
 					return fmt.Errorf("invalid --idle value: %s", args[i])
 				}
-				idleThreshold = d
+				idle = d
 			}
 		case "--log":
 			if i+1 < len(args) {
 				i++
 				logFile = args[i]
 			}
+		case "--log-target":
+			if i+1 < len(args) {
+				i++
+				logTarget = args[i]
+			}
+		case "--idle-backend":
+			if i+1 < len(args) {
+				i++
+				backendFlag = args[i]
+			}
+		case "--notify":
+			if i+1 < len(args) {
+				i++
+				notify = args[i]
+			}
+		case "--webhook":
+			if i+1 < len(args) {
+				i++
+				webhook = args[i]
+			}
+		case "--event-log":
+			if i+1 < len(args) {
+				i++
+				eventLog = args[i]
+			}
+		case "--metrics-listen":
+			if i+1 < len(args) {
+				i++
+				metricsAddr = args[i]
+			}
+		case "--nudge":
+			if i+1 < len(args) {
+				i++
+				nudge = args[i]
+			}
+		case "--nudge-after":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil || d <= 0 {
+					return fmt.Errorf("invalid --nudge-after value: %s", args[i])
+				}
+				nudgeAfter = d
+			}
+		case "--nudge-limit":
+			if i+1 < len(args) {
+				i++
+				n, err := strconv.Atoi(args[i])
+				if err != nil || n < 0 {
+					return fmt.Errorf("invalid --nudge-limit value: %s", args[i])
+				}
+				nudgeLimit = n
+			}
+		case "--timeout":
+			if i+1 < len(args) {
+				i++
+				d, err := time.ParseDuration(args[i])
+				if err != nil || d <= 0 {
+					return fmt.Errorf("invalid --timeout value: %s", args[i])
+				}
+				timeout = d
+			}
+		case "--once":
+			once = true
+		case "--until-idle":
+			untilIdle = true
+		case "--daemon":
+			daemon = true
+		case "--auto-resume":
+			autoResumeOn = true
+		}
+	}
+	if logTarget != "stdout" && logTarget != "syslog" {
+		return fmt.Errorf("invalid --log-target value: %s (want stdout or syslog)", logTarget)
+	}
+	if notify != "" && notify != notifyDesktop {
+		return fmt.Errorf("invalid --notify value: %s (want %s)", notify, notifyDesktop)
+	}
+	if once && untilIdle {
+		return fmt.Errorf("--once cannot be combined with --until-idle")
+	}
+	if once && metricsAddr != "" {
+		return fmt.Errorf("--once cannot be combined with --metrics-listen")
+	}
+	if timeout > 0 && !untilIdle {
+		return fmt.Errorf("--timeout needs --until-idle")
+	}
+	if nudge == "" && (nudgeAfter > 0 || nudgeLimit >= 0) {
+		return fmt.Errorf("--nudge-after and --nudge-limit need --nudge")
+	}
+	if webhook != "" {
+		if err := validWebhookURL(webhook); err != nil {
+			return err
+		}
+	}
+	if logTarget == "syslog" && logFile != "" {
+		return fmt.Errorf("--log cannot be combined with --log-target syslog")
+	}
+	if daemon && logFile == "" && logTarget != "syslog" {
+		logFile = watchLogPath()
+		if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+			return err
 		}
 	}
 
+	cfg := loadConfig()
+	idleThreshold, err := cfg.idleThresholds(idle)
+	if err != nil {
+		return err
+	}
+	backend, err := cfg.idleBackend(backendFlag)
+	if err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-	var writers []io.Writer
-	writers = append(writers, os.Stdout)
-	if logFile != "" {
-		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	var logger *slog.Logger
+	if logTarget == "syslog" {
+		var err error
+		if logger, err = newSyslogLogger("tmux-agent", "watch"); err != nil {
+			return fmt.Errorf("connecting to syslog: %w", err)
+		}
+	} else {
+		var writers []io.Writer
+		switch {
+		case daemon:
+			// Log only to the file.
+		case once:
+			// w gets the results.
+			writers = append(writers, os.Stderr)
+		default:
+			writers = append(writers, w)
+		}
+		if logFile != "" {
+			f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return fmt.Errorf("opening log file: %w", err)
+			}
+			defer f.Close()
+			writers = append(writers, f)
+		}
+		logger = newLogger(io.MultiWriter(writers...), "watch")
+	}
+	wt := newWatcher(scanInterval, idleThreshold, logger)
+	defer wt.flush()
+	wt.idleBackend = backend
+	wt.notify, wt.webhook = notify, webhook
+	if eventLog != "" {
+		f, err := os.OpenFile(eventLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
-			return fmt.Errorf("opening log file: %w", err)
+			return fmt.Errorf("opening event log: %w", err)
 		}
 		defer f.Close()
-		writers = append(writers, f)
+		wt.events = f
+	}
+	if nudge != "" {
+		if nudgeAfter == 0 {
+			nudgeAfter = defaultNudgeAfter
+		}
+		if nudgeLimit < 0 {
+			nudgeLimit = defaultNudgeLimit
+		}
+		wt.nudge = newNudger(nudge, nudgeAfter, nudgeLimit)
+	}
+	if autoResumeOn {
+		if wt.autoResume, err = cfg.autoResume(); err != nil {
+			return err
+		}
+	}
+	if metricsAddr != "" {
+		wt.metrics = &watchMetrics{}
+		tmuxRunner = countingRunner{TmuxRunner: tmuxRunner, errors: &wt.metrics.tmuxErrors}
+		srv, err := serveMetrics(metricsAddr, wt.metrics, logger)
+		if err != nil {
+			return err
+		}
+		defer srv.Close()
 	}
 
-	logger := log.New(io.MultiWriter(writers...), "[tmux-agent:watch] ", log.LstdFlags)
-
-	paneOutputs := make(map[string]string)
-	paneLastChange := make(map[string]time.Time)
+	if once {
+		width, err := tableWidth(args)
+		if err != nil {
+			return err
+		}
+		if err := wt.scan(); err != nil {
+			return err
+		}
+		return wt.report(w, width)
+	}
 
 	scanTicker := time.NewTicker(scanInterval)
 	defer scanTicker.Stop()
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		deadline = time.After(timeout)
+	}
+	// scan reports whether --until-idle is done.
+	scan := func() bool {
+		if err := wt.scan(); err != nil {
+			logger.Warn("scan failed", "err", err)
+			return false
+		}
+		return untilIdle && len(wt.waitingOn()) == 0
+	}
 
-	logger.Printf("watching tmux panes (scan: %s, idle threshold: %s)", scanInterval, idleThreshold)
+	attrs := []any{"scan", scanInterval, "backend", backend}
+	if idle > 0 {
+		attrs = append(attrs, "idle", idle)
+	}
+	if metricsAddr != "" {
+		attrs = append(attrs, "metrics", metricsAddr)
+	}
+	if wt.nudge != nil {
+		attrs = append(attrs, "nudge_after", wt.nudge.after, "nudge_limit", wt.nudge.limit)
+	}
+	logger.Info("watching tmux panes", attrs...)
 
+	// Waiting starts with a scan, so panes idle already end it at once.
+	if untilIdle && scan() {
+		logger.Info("all panes idle")
+		return nil
+	}
 	for {
 		select {
 		case <-scanTicker.C:
-			panes, err := listTmuxPanes()
-			if err != nil {
-				logger.Printf("[warn] failed to list panes: %v", err)
-				continue
-			}
-
-			for i := range panes {
-				output, err := capturePaneOutput(panes[i].ID, 10)
-				if err != nil {
-					continue
-				}
-
-				prev, exists := paneOutputs[panes[i].ID]
-				if !exists || prev != output {
-					paneOutputs[panes[i].ID] = output
-					paneLastChange[panes[i].ID] = time.Now()
-				}
-
-				if lastChange, ok := paneLastChange[panes[i].ID]; ok {
-					panes[i].LastChangeAt = lastChange
-					panes[i].LastOutput = output
-				}
-
-				if detectIdle(&panes[i], idleThreshold) {
-					logger.Printf("[idle] pane %s (%s) idle for %s",
-						panes[i].ID, panes[i].Command,
-						time.Since(panes[i].LastChangeAt).Truncate(time.Second))
-				}
+			if scan() {
+				logger.Info("all panes idle")
+				return nil
 			}
+		case <-deadline:
+			return fmt.Errorf("timed out after %s waiting for idle panes: %s", timeout, strings.Join(wt.waitingOn(), ", "))
 
 		case sig := <-sigCh:
-			logger.Printf("received %s, shutting down", sig)
+			logger.Info("shutting down", "signal", sig.String())
 			return nil
 		case <-ctx.Done():
 			return nil
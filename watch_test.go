@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestWatcherScan_PaneClosed(t *testing.T) {
+	dir := t.TempDir()
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude", Dir: "/work/a", Output: "output"},
+		&runner.FakePane{ID: "%5", Command: "codex", Dir: "/work/b", Output: "output"},
+	)
+
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	hookOut := filepath.Join(dir, "hook.txt")
+	saveConfig(&agentConfig{
+		DefaultAgent: "claude",
+		Hooks:        map[string]string{"pane_closed": `echo "$TMUX_AGENT_EVENT $TMUX_AGENT_PANE $TMUX_AGENT_AGENT $TMUX_AGENT_DIR" >> ` + hookOut},
+	})
+
+	var logs bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(&logs, "test"))
+	wt.scan()
+	if len(wt.seen) != 2 || len(wt.tracker.hashes) != 2 {
+		t.Fatalf("expected two tracked panes, got %d seen, %d hashes", len(wt.seen), len(wt.tracker.hashes))
+	}
+
+	fake.Run("kill-pane", "-t", "%5")
+	wt.scan()
+	wt.flush()
+
+	if !strings.Contains(logs.String(), `msg="pane closed" component=test pane=%5 agent=codex repo=b event=pane_closed`) {
+		t.Errorf("expected pane_closed event, got: %s", logs.String())
+	}
+	if _, ok := wt.seen["%5"]; ok {
+		t.Error("expected closed pane to be forgotten")
+	}
+	if _, ok := wt.tracker.hashes["%5"]; ok {
+		t.Error("expected closed pane output to be dropped")
+	}
+	data, _ := os.ReadFile(hookOut)
+	if strings.TrimSpace(string(data)) != "pane_closed %5 codex /work/b" {
+		t.Errorf("unexpected hook output: %q", string(data))
+	}
+
+	// The event is reported only once.
+	logs.Reset()
+	wt.scan()
+	if strings.Contains(logs.String(), "pane closed") {
+		t.Errorf("expected no repeated event, got: %s", logs.String())
+	}
+
+	data, _ = os.ReadFile(activityFilePath())
+	if strings.Count(string(data), `"kind":"pane_closed"`) != 1 {
+		t.Errorf("expected one pane_closed activity record, got:\n%s", string(data))
+	}
+}
+
+func TestWatcherScan_TransitionHooks(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%3", Command: "claude", Dir: "/work/a", Output: "go test ./...\n--- FAIL: TestParse"},
+		&runner.FakePane{ID: "%5", Command: "codex", Dir: "/work/b", Output: "ok  	example.com/b"},
+	)
+	hookOut := filepath.Join(dir, "hook.txt")
+	record := `echo "$TMUX_AGENT_EVENT $TMUX_AGENT_PANE $TMUX_AGENT_MESSAGE" >> ` + hookOut
+	saveConfig(&agentConfig{
+		DefaultAgent: "claude",
+		Agents: map[string]*agentProfile{
+			"claude": {outcomePatterns: outcomePatterns{ErrorPatterns: []string{"^--- FAIL"}}},
+			"codex":  {outcomePatterns: outcomePatterns{ErrorPatterns: []string{"^--- FAIL"}}},
+		},
+		Hooks: map[string]string{"pane_idle": record, "pane_active": record, "pane_error": record},
+	})
+
+	var logs bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(&logs, "test"))
+	wt.scan()
+	wt.tracker.changed["%3"] = time.Now().Add(-time.Hour)
+	wt.tracker.changed["%5"] = time.Now().Add(-time.Hour)
+	wt.scan()
+	wt.scan()
+	fake.Pane("%3").Output = "Fixing the parser"
+	wt.scan()
+	wt.flush()
+
+	data, _ := os.ReadFile(hookOut)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	want := []string{
+		"pane_idle %3",
+		"pane_error %3 --- FAIL: TestParse",
+		"pane_idle %5",
+		"pane_active %3",
+	}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("hooks ran:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWatcherScan_PaneErrorTmuxBackend(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	useFakeTmux(t, &runner.FakePane{ID: "%3", Command: "claude", Output: "go test ./...\n--- FAIL: TestParse", Activity: time.Now().Add(-time.Hour)})
+	hookOut := filepath.Join(dir, "hook.txt")
+	saveConfig(&agentConfig{
+		DefaultAgent: "claude",
+		Agents:       map[string]*agentProfile{"claude": {outcomePatterns: outcomePatterns{ErrorPatterns: []string{"^--- FAIL"}}}},
+		Hooks:        map[string]string{"pane_error": `echo "$TMUX_AGENT_MESSAGE" >> ` + hookOut},
+	})
+
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(io.Discard, "test"))
+	wt.idleBackend = idleBackendTmux
+	wt.states["%3"] = stateActive
+	wt.scan()
+	wt.flush()
+
+	if data, _ := os.ReadFile(hookOut); strings.TrimSpace(string(data)) != "--- FAIL: TestParse" {
+		t.Errorf("expected pane_error under the tmux backend, got %q", data)
+	}
+}
+
+func TestWatcherDeliver_DoesNotBlockScan(t *testing.T) {
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(io.Discard, "test"))
+	release := make(chan struct{})
+	var order []int
+	wt.deliver(func() { <-release; order = append(order, 1) })
+	wt.deliver(func() { order = append(order, 2) })
+	close(release)
+	wt.flush()
+	if len(order) != 2 || order[0] != 1 || order[1] != 2 {
+		t.Errorf("expected deliveries in order, got %v", order)
+	}
+}
+
+func TestRunWatch_Once(t *testing.T) {
+	useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Dir: "/src/github.com/owner/repo", Output: "Done."},
+		&runner.FakePane{ID: "%2", Command: "codex", Output: "Working"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	saveState(paneStateFile, map[string]trackedOutput{
+		"%1": {Hash: outputHash("Done."), Changed: time.Now().Add(-90 * time.Minute)},
+	})
+
+	var buf bytes.Buffer
+	if err := runWatch([]string{"--once", "--idle", "5m", "--width", "120"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "PANE") {
+		t.Fatalf("expected a header and two panes, got:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[1], "idle 1h30m") || !strings.Contains(lines[1], "owner/repo") {
+		t.Errorf("unexpected row for %%1: %q", lines[1])
+	}
+	if !strings.Contains(lines[2], stateActive) {
+		t.Errorf("unexpected row for %%2: %q", lines[2])
+	}
+}
+
+func TestRunWatch_UntilIdle(t *testing.T) {
+	fake := useFakeTmux(t,
+		&runner.FakePane{ID: "%1", Command: "claude", Output: "Done."},
+		&runner.FakePane{ID: "%2", Command: "codex", Output: "Working"},
+	)
+	t.Setenv("HOME", t.TempDir())
+	hourAgo := time.Now().Add(-time.Hour)
+	saveState(paneStateFile, map[string]trackedOutput{
+		"%1": {Hash: outputHash("Done."), Changed: hourAgo},
+	})
+
+	var logs bytes.Buffer
+	err := runWatch([]string{"--until-idle", "--idle", "5m", "--scan", "10ms", "--timeout", "100ms"}, &logs)
+	if err == nil || !strings.Contains(err.Error(), "waiting for idle panes: %2") {
+		t.Fatalf("expected a timeout naming %%2, got %v", err)
+	}
+
+	fake.Run("kill-pane", "-t", "%2")
+	if err := runWatch([]string{"--until-idle", "--idle", "5m", "--scan", "10ms", "--timeout", "5s"}, &logs); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "all panes idle") {
+		t.Errorf("expected the exit to be logged, got:\n%s", logs.String())
+	}
+}
+
+func TestRunWatch_ModeErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"--once", "--until-idle"},
+		{"--timeout", "1m"},
+		{"--until-idle", "--timeout", "soon"},
+	} {
+		if err := runWatch(args, io.Discard); err == nil {
+			t.Errorf("runWatch(%v): expected an error", args)
+		}
+	}
+}
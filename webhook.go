@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// webhookSnippetLines is how many trailing output lines a payload carries.
+const webhookSnippetLines = 5
+
+// webhookClient posts webhooks and chat notifications. The timeout keeps a
+// slow endpoint from stalling watch's scans for long.
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// webhookPayload is the JSON body watch --webhook posts.
+type webhookPayload struct {
+	Event       string    `json:"event"`
+	Time        time.Time `json:"time"`
+	Pane        string    `json:"pane"`
+	Agent       string    `json:"agent"`
+	Repo        string    `json:"repo,omitempty"`
+	Dir         string    `json:"dir,omitempty"`
+	IdleSeconds int64     `json:"idle_seconds,omitempty"`
+	LastOutput  string    `json:"last_output,omitempty"`
+}
+
+// newWebhookPayload describes an event on pane p.
+func newWebhookPayload(event string, p *paneInfo) webhookPayload {
+	payload := webhookPayload{
+		Event:      event,
+		Time:       time.Now(),
+		Pane:       p.ID,
+		Agent:      p.Command,
+		Repo:       shortDir(p.Dir),
+		Dir:        p.Dir,
+		LastOutput: strings.Join(lastLines(p.LastOutput, webhookSnippetLines), "\n"),
+	}
+	if event == eventPaneIdle {
+		payload.IdleSeconds = idleSeconds(p)
+	}
+	return payload
+}
+
+// validWebhookURL reports an error unless u is an http or https URL.
+func validWebhookURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid --webhook value: %s (want an http or https URL)", u)
+	}
+	return nil
+}
+
+// postJSON posts v as JSON to u. Errors leave out the URL, since webhook
+// URLs of chat services are secrets.
+func postJSON(u string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(u, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("POST: %w", urlErr.Err)
+		}
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST: %s", resp.Status)
+	}
+	return nil
+}
+
+// sendWebhook posts an event on p to the --webhook URL, if one is set, in
+// the background (see deliver).
+func (wt *watcher) sendWebhook(event string, p *paneInfo) {
+	if wt.webhook == "" {
+		return
+	}
+	pane := *p
+	wt.deliver(func() {
+		if err := postJSON(wt.webhook, newWebhookPayload(event, &pane)); err != nil {
+			wt.logger.Warn("webhook failed", append(paneAttrs(&pane), "event", event, "err", err)...)
+		}
+	})
+}
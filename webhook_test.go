@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sat0b/tmux-agent/runner"
+)
+
+func TestWatcherScan_Webhook(t *testing.T) {
+	fake := useFakeTmux(t, &runner.FakePane{ID: "%3", Command: "claude", Dir: "/work/api", Output: "Running tests\nAll tests pass"})
+	var events []webhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("bad webhook request: %v", err)
+		}
+		events = append(events, p)
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	wt := newWatcher(10*time.Second, func(string) time.Duration { return time.Minute }, newLogger(&logs, "test"))
+	wt.webhook = srv.URL
+
+	wt.scan()
+	wt.tracker.changed["%3"] = time.Now().Add(-time.Hour)
+	wt.scan()
+	wt.scan()
+	wt.flush()
+	if len(events) != 1 {
+		t.Fatalf("expected one pane_idle event, got %+v", events)
+	}
+	idle := events[0]
+	if idle.Event != eventPaneIdle || idle.Pane != "%3" || idle.Agent != "claude" || idle.Repo != "api" ||
+		idle.IdleSeconds < 3600 || idle.LastOutput != "Running tests\nAll tests pass" {
+		t.Errorf("unexpected idle payload: %+v", idle)
+	}
+
+	fake.Run("kill-pane", "-t", "%3")
+	wt.scan()
+	wt.flush()
+	if len(events) != 2 || events[1].Event != eventPaneClosed || events[1].Pane != "%3" {
+		t.Errorf("expected a pane_closed event, got %+v", events)
+	}
+}
+
+func TestWebhookFailureIsLogged(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	wt := newWatcher(time.Second, func(string) time.Duration { return time.Minute }, newLogger(&logs, "test"))
+	wt.webhook = srv.URL
+	wt.sendWebhook(eventPaneClosed, &paneInfo{ID: "%1", Command: "codex"})
+	wt.flush()
+	if !bytes.Contains(logs.Bytes(), []byte("webhook failed")) || !bytes.Contains(logs.Bytes(), []byte("502")) {
+		t.Errorf("expected the failure to be logged, got: %s", logs.String())
+	}
+}
+
+func TestValidWebhookURL(t *testing.T) {
+	for u, ok := range map[string]bool{
+		"https://example.com/hook": true,
+		"http://localhost:5678/x":  true,
+		"example.com/hook":         false,
+		"ftp://example.com":        false,
+		"https://":                 false,
+	} {
+		if err := validWebhookURL(u); (err == nil) != ok {
+			t.Errorf("validWebhookURL(%q) = %v", u, err)
+		}
+	}
+}
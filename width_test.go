@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestStringWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"hello", 5},
+		{"日本語", 6},
+		{"한글", 4},
+		{"ｆｕｌｌ", 8},
+		{"done 🎉", 7},
+		{"é", 1},
+		{"←→↑↓", 4},
+	}
+	for _, tt := range tests {
+		if got := stringWidth(tt.s); got != tt.want {
+			t.Errorf("stringWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"hello world", 8, "hello..."},
+		{"日本語のテキスト", 9, "日本語..."},
+		{"日本語のテキスト", 8, "日本..."},
+		{"日本語", 3, "日"},
+		{"🎉🎉🎉🎉", 7, "🎉🎉..."},
+	}
+	for _, tt := range tests {
+		got := truncateWidth(tt.s, tt.width)
+		if got != tt.want {
+			t.Errorf("truncateWidth(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if stringWidth(got) > tt.width {
+			t.Errorf("truncateWidth(%q, %d) is %d cells wide", tt.s, tt.width, stringWidth(got))
+		}
+	}
+}
+
+func TestFitString(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"日本", 5, "日本 "},
+		{"日本語のテキスト", 7, "日本..."},
+		{"日本語", 3, "日 "},
+		{"anything", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := fitString(tt.s, tt.width); got != tt.want {
+			t.Errorf("fitString(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML used for playbooks: block mappings
+// and sequences, plain and quoted scalars, literal (|) and folded (>)
+// block scalars, and single-line [flow, sequences] and {flow: mappings}.
+// Anchors, aliases, tags and multiple documents are rejected. Values come
+// back as map[string]any, []any, string, int64, float64, bool or nil, so
+// they can be re-encoded as JSON and decoded into structs.
+func parseYAML(data []byte) (any, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		text := strings.TrimLeft(raw, " ")
+		line := yamlLine{num: i + 1, indent: len(raw) - len(text), text: strings.TrimRight(text, " \t")}
+		if strings.HasPrefix(line.text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs cannot be used for indentation", line.num)
+		}
+		p.lines = append(p.lines, line)
+	}
+	i := p.skip(0)
+	if i < len(p.lines) && p.lines[i].text == "---" {
+		i = p.skip(i + 1)
+	}
+	if i == len(p.lines) {
+		return nil, nil
+	}
+	v, i, err := p.node(i, p.lines[i].indent)
+	if err != nil {
+		return nil, err
+	}
+	if i = p.skip(i); i < len(p.lines) {
+		return nil, p.errorf(i, "unexpected content (check the indentation)")
+	}
+	return v, nil
+}
+
+// yamlLine is one line of a YAML document, split into its indentation and
+// the rest. Comments are left in, since they may be part of block scalars.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// yamlParser parses block structure line by line.
+type yamlParser struct {
+	lines []yamlLine
+}
+
+// errorf returns an error pointing at line i.
+func (p *yamlParser) errorf(i int, format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.lines[i].num, fmt.Sprintf(format, args...))
+}
+
+// skip returns the index of the first line at or after i that is neither
+// blank nor a comment.
+func (p *yamlParser) skip(i int) int {
+	for i < len(p.lines) && (p.lines[i].text == "" || strings.HasPrefix(p.lines[i].text, "#")) {
+		i++
+	}
+	return i
+}
+
+// isYAMLItem reports whether text starts a sequence item.
+func isYAMLItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// node parses the node starting at line i, which is indented by indent,
+// and returns it with the index of the line after it.
+func (p *yamlParser) node(i, indent int) (any, int, error) {
+	text := p.lines[i].text
+	if isYAMLItem(text) {
+		return p.sequence(i, indent)
+	}
+	if _, _, ok := splitYAMLKey(text); ok {
+		return p.mapping(i, indent)
+	}
+	return p.value(i, indent, text)
+}
+
+// child parses the node nested below line i, or returns nil if the next
+// line is not indented further.
+func (p *yamlParser) child(i, indent int) (any, int, error) {
+	j := p.skip(i + 1)
+	if j < len(p.lines) && p.lines[j].indent > indent {
+		return p.node(j, p.lines[j].indent)
+	}
+	return nil, i + 1, nil
+}
+
+// sequence parses the "- item" lines at indent starting at line i.
+func (p *yamlParser) sequence(i, indent int) (any, int, error) {
+	items := []any{}
+	for i < len(p.lines) && p.lines[i].indent == indent && isYAMLItem(p.lines[i].text) {
+		rest := strings.TrimLeft(strings.TrimPrefix(p.lines[i].text, "-"), " ")
+		var v any
+		var err error
+		if rest == "" || strings.HasPrefix(rest, "#") {
+			v, i, err = p.child(i, indent)
+		} else {
+			// The item starts a node at its own column, as in
+			// "- key: value" followed by more keys below "key".
+			p.lines[i].indent += len(p.lines[i].text) - len(rest)
+			p.lines[i].text = rest
+			v, i, err = p.node(i, p.lines[i].indent)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, v)
+		i = p.skip(i)
+	}
+	if i < len(p.lines) && p.lines[i].indent > indent {
+		return nil, 0, p.errorf(i, "unexpected indentation")
+	}
+	return items, i, nil
+}
+
+// mapping parses the "key: value" lines at indent starting at line i.
+func (p *yamlParser) mapping(i, indent int) (any, int, error) {
+	m := make(map[string]any)
+	for i < len(p.lines) && p.lines[i].indent == indent {
+		key, rest, ok := splitYAMLKey(p.lines[i].text)
+		if !ok {
+			return nil, 0, p.errorf(i, "expected \"key: value\"")
+		}
+		if key == "" {
+			return nil, 0, p.errorf(i, "empty key")
+		}
+		if _, dup := m[key]; dup {
+			return nil, 0, p.errorf(i, "duplicate key %q", key)
+		}
+		var v any
+		var err error
+		if rest == "" || strings.HasPrefix(rest, "#") {
+			// A sequence may sit at the same indentation as its key.
+			if j := p.skip(i + 1); j < len(p.lines) && p.lines[j].indent == indent && isYAMLItem(p.lines[j].text) {
+				v, i, err = p.sequence(j, indent)
+			} else {
+				v, i, err = p.child(i, indent)
+			}
+		} else {
+			v, i, err = p.value(i, indent, rest)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		m[key] = v
+		i = p.skip(i)
+	}
+	if i < len(p.lines) && p.lines[i].indent > indent {
+		return nil, 0, p.errorf(i, "unexpected indentation")
+	}
+	return m, i, nil
+}
+
+// value parses the scalar or flow collection text found on line i, which
+// may also introduce a block scalar on the following lines.
+func (p *yamlParser) value(i, indent int, text string) (any, int, error) {
+	if text[0] == '|' || text[0] == '>' {
+		return p.block(i, indent, text)
+	}
+	v, err := parseYAMLScalar(text)
+	if err != nil {
+		return nil, 0, p.errorf(i, "%v", err)
+	}
+	return v, i + 1, nil
+}
+
+// block parses the block scalar introduced by header ("|", ">-", ...) on
+// line i: the following lines indented further than indent.
+func (p *yamlParser) block(i, indent int, header string) (any, int, error) {
+	chomp := strings.TrimSpace(strings.SplitN(header[1:], "#", 2)[0])
+	if chomp != "" && chomp != "-" && chomp != "+" {
+		return nil, 0, p.errorf(i, "unsupported block scalar header %q", header)
+	}
+	j := i + 1
+	blockIndent := -1
+	var lines []string
+	for ; j < len(p.lines) && (p.lines[j].text == "" || p.lines[j].indent > indent); j++ {
+		l := p.lines[j]
+		if l.text == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if blockIndent < 0 {
+			blockIndent = l.indent
+		}
+		if l.indent < blockIndent {
+			return nil, 0, p.errorf(j, "block scalar lines must be indented at least as much as the first")
+		}
+		lines = append(lines, strings.Repeat(" ", l.indent-blockIndent)+l.text)
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var s string
+	if header[0] == '|' {
+		s = strings.Join(lines, "\n")
+	} else {
+		// Folding joins lines with spaces; blank lines become newlines.
+		var b strings.Builder
+		for k, l := range lines {
+			switch {
+			case k == 0:
+			case l == "":
+				b.WriteString("\n")
+			case lines[k-1] != "":
+				b.WriteString(" ")
+			}
+			b.WriteString(l)
+		}
+		s = b.String()
+	}
+	if s != "" && chomp != "-" {
+		s += "\n"
+	}
+	return s, j, nil
+}
+
+// splitYAMLKey splits a "key: value" line into its (unquoted) key and the
+// rest. ok is false if text is not a mapping entry; the key of ": value" is
+// empty, which the callers reject.
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	if text == "" || isYAMLItem(text) || strings.ContainsRune("[{#|>&*!", rune(text[0])) {
+		return "", "", false
+	}
+	end := 0
+	if text[0] == '"' || text[0] == '\'' {
+		n := closingQuote(text)
+		if n < 0 {
+			return "", "", false
+		}
+		end = n + 1
+	}
+	for i := end; i < len(text); i++ {
+		if text[i] != ':' || (i+1 < len(text) && text[i+1] != ' ') {
+			continue
+		}
+		key = strings.TrimSpace(text[:i])
+		if key != "" && (key[0] == '"' || key[0] == '\'') {
+			v, err := parseYAMLScalar(key)
+			if err != nil {
+				return "", "", false
+			}
+			key = fmt.Sprint(v)
+		}
+		return key, strings.TrimSpace(text[i+1:]), true
+	}
+	return "", "", false
+}
+
+// closingQuote returns the index of the quote closing the quoted scalar s
+// starts with, or -1.
+func closingQuote(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case s[i] == q && q == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlNumberRe matches the plain scalars taken as numbers.
+var yamlNumberRe = regexp.MustCompile(`^[-+]?(\d+(\.\d*)?|\.\d+)([eE][-+]?\d+)?$`)
+
+// parseYAMLScalar parses a single-line value: a quoted or plain scalar, or
+// a flow sequence or mapping of scalars, with an optional trailing comment.
+func parseYAMLScalar(s string) (any, error) {
+	switch s[0] {
+	case '"', '\'':
+		end := closingQuote(s)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated quoted string")
+		}
+		if rest := strings.TrimSpace(s[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return nil, fmt.Errorf("unexpected text after quoted string: %s", rest)
+		}
+		if s[0] == '\'' {
+			return strings.ReplaceAll(s[1:end], "''", "'"), nil
+		}
+		v, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s", s[:end+1])
+		}
+		return v, nil
+	case '[', '{':
+		return parseYAMLFlow(s)
+	case '&', '*', '!':
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported")
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if yamlNumberRe.MatchString(s) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	}
+	return s, nil
+}
+
+// parseYAMLFlow parses a single-line [sequence] or {mapping} of scalars.
+func parseYAMLFlow(s string) (any, error) {
+	closing := map[byte]byte{'[': ']', '{': '}'}[s[0]]
+	end := strings.LastIndexByte(s, closing)
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated flow collection")
+	}
+	if rest := strings.TrimSpace(s[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+		return nil, fmt.Errorf("unexpected text after flow collection: %s", rest)
+	}
+	var parts []string
+	inner := strings.TrimSpace(s[1:end])
+	for start, i := 0, 0; i <= len(inner) && inner != ""; i++ {
+		if i < len(inner) && (inner[i] == '"' || inner[i] == '\'') {
+			n := closingQuote(inner[i:])
+			if n < 0 {
+				return nil, fmt.Errorf("unterminated quoted string")
+			}
+			i += n
+			continue
+		}
+		if i < len(inner) && (inner[i] == '[' || inner[i] == '{') {
+			return nil, fmt.Errorf("nested flow collections are not supported")
+		}
+		if i == len(inner) || inner[i] == ',' {
+			parts = append(parts, strings.TrimSpace(inner[start:i]))
+			start = i + 1
+		}
+	}
+	if s[0] == '[' {
+		items := []any{}
+		for _, part := range parts {
+			if part == "" {
+				return nil, fmt.Errorf("empty item in flow sequence")
+			}
+			v, err := parseYAMLScalar(part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	}
+	m := make(map[string]any)
+	for _, part := range parts {
+		key, rest, ok := splitYAMLKey(part)
+		if !ok {
+			return nil, fmt.Errorf("expected \"key: value\" in flow mapping, got %q", part)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("empty key in flow mapping %q", part)
+		}
+		var v any
+		if rest != "" {
+			var err error
+			if v, err = parseYAMLScalar(rest); err != nil {
+				return nil, err
+			}
+		}
+		m[key] = v
+	}
+	return m, nil
+}
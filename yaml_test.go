@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	doc := `---
+# A playbook
+name: release   # trailing comment
+retries: 3
+ratio: 0.5
+dry_run: false
+empty:
+tags: [a, "b, c", 7]
+wait: {idle: 2m, match: "All tests passed"}
+panes:
+  impl:
+    repo: owner/repo
+    pane: "%5"
+steps:
+- pane: impl
+  send: 'it''s time: go'
+  notes:
+    - one
+    -
+      nested: true
+- send: |
+    line one
+      indented
+
+    after a blank # not a comment
+  fold: >-
+    joined
+    together
+
+    new paragraph
+`
+	v, err := parseYAML([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(v)
+	want := `{"dry_run":false,"empty":null,"name":"release",` +
+		`"panes":{"impl":{"pane":"%5","repo":"owner/repo"}},"ratio":0.5,"retries":3,` +
+		`"steps":[{"notes":["one",{"nested":true}],"pane":"impl","send":"it's time: go"},` +
+		`{"fold":"joined together\nnew paragraph","send":"line one\n  indented\n\nafter a blank # not a comment\n"}],` +
+		`"tags":["a","b, c",7],"wait":{"idle":"2m","match":"All tests passed"}}`
+	if string(got) != want {
+		t.Errorf("parseYAML =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseYAML_Errors(t *testing.T) {
+	tests := []struct{ doc, want string }{
+		{"a: 1\na: 2", "line 2: duplicate key"},
+		{"a: 1\n  b: 2", "line 2: unexpected indentation"},
+		{"a:\n\t- b", "line 2: tabs"},
+		{"a: &x 1", "anchors"},
+		{`a: "open`, "line 1: unterminated"},
+		{"- a\nb: 1", "line 2: unexpected content"},
+		{": x", "line 1: empty key"},
+		{"a: 1\n: x", "line 2: empty key"},
+		{"steps:\n  - {: x}", "empty key"},
+	}
+	for _, tt := range tests {
+		if _, err := parseYAML([]byte(tt.doc)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseYAML(%q) error = %v, want %q", tt.doc, err, tt.want)
+		}
+	}
+}